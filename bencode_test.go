@@ -2,7 +2,10 @@ package bencode
 
 import (
 	"bytes"
+	"crypto/sha1"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -55,3 +58,150 @@ func TestMarshalUnmarshal(t *testing.T) {
 		t.Errorf("Unmarshal() = %v, want %v", decodedStruct, metainfoTestData)
 	}
 }
+
+type (
+	testPort  uint16
+	testEvent string
+	testDelta int32
+)
+
+func TestNamedScalarTypes(t *testing.T) {
+	type announce struct {
+		Event  testEvent              `bencode:"event"`
+		Port   testPort               `bencode:"port"`
+		Delta  testDelta              `bencode:"delta"`
+		Ports  []testPort             `bencode:"ports"`
+		ByName map[testEvent]testPort `bencode:"by name"`
+	}
+	value := announce{
+		Event:  "started",
+		Port:   6881,
+		Delta:  -3,
+		Ports:  []testPort{6881, 6889},
+		ByName: map[testEvent]testPort{"started": 6881},
+	}
+	expected := "d7:by named7:startedi6881ee5:deltai-3e5:event7:started4:porti6881e5:portsli6881ei6889eee"
+
+	encoded, err := Marshal(value)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(encoded) != expected {
+		t.Errorf("Marshal() = %q, want %q", encoded, expected)
+	}
+
+	var decoded announce
+	if err := Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded, value) {
+		t.Errorf("Unmarshal() = %+v, want %+v", decoded, value)
+	}
+
+	var overflow testPort
+	var bErr *Error
+	if err := Unmarshal([]byte("i70000e"), &overflow); !errors.As(err, &bErr) || bErr.Type != ErrUnmarshalOverflow {
+		t.Errorf("Unmarshal() overflow error = %v, want %q", err, ErrUnmarshalOverflow)
+	}
+}
+
+func TestInlineField(t *testing.T) {
+	type signed struct {
+		Doc       RawMessage `bencode:",inline"`
+		Signature string     `bencode:"sig"`
+	}
+	input := "d4:name4:spam3:sig3:abc4:sizei3ee"
+	var s signed
+	if err := Unmarshal([]byte(input), &s); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if s.Signature != "abc" || string(s.Doc) != input {
+		t.Errorf("Unmarshal() = %+v", s)
+	}
+	s.Signature = "xyz"
+	encoded, err := Marshal(s)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want := "d4:name4:spam3:sig3:xyz4:sizei3ee"; string(encoded) != want {
+		t.Errorf("Marshal() = %q, want %q", encoded, want)
+	}
+
+	type wrapper struct {
+		Info    Info           `bencode:",inline"`
+		Private int64          `bencode:"private"`
+		Extra   map[string]any `bencode:",inline"`
+	}
+	var w wrapper
+	if err := Unmarshal([]byte("d6:lengthi5e4:name1:x7:privatei1e1:zi9ee"), &w); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if w.Info != (Info{Length: 5, Name: "x"}) || w.Private != 1 || len(w.Extra) != 4 {
+		t.Errorf("Unmarshal() = %+v", w)
+	}
+	encoded, err = Marshal(wrapper{Info: Info{Name: "y"}, Private: 1, Extra: map[string]any{"z": 9, "name": "ignored"}})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	// The inline field declared later wins for the shared key, and
+	// fields named in tags win over both.
+	if want := "d6:lengthi0e4:name7:ignored12:piece lengthi0e7:privatei1e1:zi9ee"; string(encoded) != want {
+		t.Errorf("Marshal() = %q, want %q", encoded, want)
+	}
+
+	var bErr *Error
+	if _, err := Marshal(struct {
+		N int `bencode:",inline"`
+	}{1}); !errors.As(err, &bErr) || bErr.Type != ErrEncodeUnsupportedType {
+		t.Errorf("Marshal() of inline integer error = %v, want %q", err, ErrEncodeUnsupportedType)
+	}
+}
+
+func TestRestFieldReencode(t *testing.T) {
+	type torrent struct {
+		Announce string                `bencode:"announce"`
+		Info     RawMessage            `bencode:"info"`
+		Rest     map[string]RawMessage `bencode:",rest"`
+	}
+	info := "d6:lengthi5e4:name1:x12:piece lengthi16384e6:pieces20:aaaaaaaaaaaaaaaaaaaae"
+	input := "d8:announce9:http://a/7:comment4:test13:creation datei1700000000e4:info" + info + "7:x-extrali1ei-2eee"
+
+	var tor torrent
+	if err := Unmarshal([]byte(input), &tor); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if len(tor.Rest) != 3 || string(tor.Rest["x-extra"]) != "li1ei-2ee" {
+		t.Fatalf("Unmarshal() rest = %q, want the 3 unknown keys", tor.Rest)
+	}
+	hash := sha1.Sum(tor.Info)
+
+	tor.Announce = "udp://b:80"
+	encoded, err := Marshal(tor)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := strings.Replace(input, "8:announce9:http://a/", "8:announce10:udp://b:80", 1)
+	if string(encoded) != want {
+		t.Errorf("Marshal() = %q, want %q", encoded, want)
+	}
+
+	doc, err := Index(encoded)
+	if err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	if got, err := doc.SHA1Sum("info"); err != nil || got != hash {
+		t.Errorf("info hash after re-encode = %x, %v, want %x", got, err, hash)
+	}
+
+	// A tagged field wins over a rest entry of the same key, and keys
+	// removed from the rest map are dropped.
+	tor.Rest["announce"] = RawMessage("i1e")
+	delete(tor.Rest, "x-extra")
+	encoded, err = Marshal(tor)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if bytes.Contains(encoded, []byte("x-extra")) || !bytes.Contains(encoded, []byte("8:announce10:udp://b:80")) {
+		t.Errorf("Marshal() = %q", encoded)
+	}
+}
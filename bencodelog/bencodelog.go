@@ -0,0 +1,108 @@
+// Package bencodelog persists sequences of values, such as DHT routing
+// tables or peer statistics, as append-only logs of bencoded records.
+//
+// Bencode values are self-delimiting, so records are simply concatenated
+// with no extra framing. Any proper prefix of a value is incomplete, which
+// lets a Reader tell a record cut short by a crash from a damaged one.
+package bencodelog
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/stupoid/bencode"
+)
+
+const (
+	// ErrTruncated indicates the log ends part way through a record, as
+	// left by a crash during Append.
+	ErrTruncated bencode.ErrorType = "truncated log record"
+	// ErrWrite indicates a record could not be written to the log.
+	ErrWrite bencode.ErrorType = "log write error"
+)
+
+// MaxRecordSize is the largest record a Reader accepts.
+const MaxRecordSize = 64 << 20
+
+// Writer appends records to a log.
+type Writer struct {
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+// NewWriter returns a Writer that appends records to w, which is typically
+// a file opened with os.O_APPEND.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Append encodes v and writes it to the log as one record. The record is
+// encoded in full before being written with a single call to Write, so an
+// encoding error leaves the log untouched. Durability is up to the caller,
+// for example by syncing the file afterwards.
+func (w *Writer) Append(v any) error {
+	w.buf.Reset()
+	if err := bencode.NewEncoder(&w.buf).Encode(v); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(w.buf.Bytes()); err != nil {
+		return &bencode.Error{Type: ErrWrite, Msg: fmt.Sprintf("appending %d byte record", w.buf.Len()), WrappedErr: err}
+	}
+	return nil
+}
+
+// Reader reads the records of a log in order.
+type Reader struct {
+	s      *bufio.Scanner
+	offset int64
+	err    error
+}
+
+// NewReader returns a Reader over the log read from r.
+func NewReader(r io.Reader) *Reader {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, MaxRecordSize)
+	s.Split(bencode.ScanValues)
+	return &Reader{s: s}
+}
+
+// Next decodes the next record into the value pointed to by v. It returns
+// io.EOF after the last complete record. If the log ends with a partial
+// record, Next returns an ErrTruncated error instead; the log can be repaired
+// by truncating it to Offset. Other errors report a damaged log, or a record
+// that does not fit v, in which case reading may continue with the next
+// record.
+func (r *Reader) Next(v any) error {
+	if r.err != nil {
+		return r.err
+	}
+	if !r.s.Scan() {
+		r.err = r.scanErr()
+		return r.err
+	}
+	record := r.s.Bytes()
+	r.offset += int64(len(record))
+	return bencode.Unmarshal(record, v)
+}
+
+// Offset returns the number of bytes of the log occupied by the records read
+// so far, which is where the next record starts.
+func (r *Reader) Offset() int64 {
+	return r.offset
+}
+
+// scanErr converts the state of a finished scanner into the error for Next.
+func (r *Reader) scanErr() error {
+	err := r.s.Err()
+	if err == nil {
+		return io.EOF
+	}
+	var bErr *bencode.Error
+	if errors.As(err, &bErr) && bErr.Type == bencode.ErrSyntaxEOF {
+		return &bencode.Error{Type: ErrTruncated, Msg: fmt.Sprintf("partial record at offset %d", r.offset), WrappedErr: err}
+	}
+	return err
+}
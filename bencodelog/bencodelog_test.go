@@ -0,0 +1,96 @@
+package bencodelog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stupoid/bencode"
+)
+
+type node struct {
+	ID       string `bencode:"id"`
+	Addr     string `bencode:"addr"`
+	LastSeen int64  `bencode:"last_seen"`
+}
+
+func TestLogRoundTrip(t *testing.T) {
+	nodes := []node{
+		{ID: "aaaa", Addr: "10.0.0.1:6881", LastSeen: 100},
+		{ID: "bbbb", Addr: "10.0.0.2:6881", LastSeen: 200},
+	}
+	var log bytes.Buffer
+	w := NewWriter(&log)
+	for _, n := range nodes {
+		if err := w.Append(n); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	complete := int64(log.Len())
+
+	r := NewReader(bytes.NewReader(log.Bytes()))
+	for i, want := range nodes {
+		var got node
+		if err := r.Next(&got); err != nil {
+			t.Fatalf("Next() %d error = %v", i, err)
+		}
+		if got != want {
+			t.Errorf("Next() %d = %+v, want %+v", i, got, want)
+		}
+	}
+	if err := r.Next(new(node)); err != io.EOF {
+		t.Errorf("Next() at end error = %v, want io.EOF", err)
+	}
+	if r.Offset() != complete {
+		t.Errorf("Offset() = %d, want %d", r.Offset(), complete)
+	}
+}
+
+func TestLogTruncated(t *testing.T) {
+	var log bytes.Buffer
+	w := NewWriter(&log)
+	if err := w.Append(node{ID: "aaaa"}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	complete := int64(log.Len())
+	if err := w.Append(node{ID: "bbbb"}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	// Every cut inside the second record must be detected as truncation.
+	for cut := complete + 1; cut < int64(log.Len()); cut++ {
+		r := NewReader(bytes.NewReader(log.Bytes()[:cut]))
+		if err := r.Next(new(node)); err != nil {
+			t.Fatalf("cut %d: Next() error = %v", cut, err)
+		}
+		err := r.Next(new(node))
+		var bErr *bencode.Error
+		if !errors.As(err, &bErr) || bErr.Type != ErrTruncated {
+			t.Fatalf("cut %d: Next() error = %v, want %q", cut, err, ErrTruncated)
+		}
+		if r.Offset() != complete {
+			t.Errorf("cut %d: Offset() = %d, want %d", cut, r.Offset(), complete)
+		}
+	}
+
+	r := NewReader(bytes.NewReader([]byte("i1eXi2e")))
+	if err := r.Next(new(int)); err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	var bErr *bencode.Error
+	if err := r.Next(new(int)); !errors.As(err, &bErr) || bErr.Type == ErrTruncated {
+		t.Errorf("Next() on damaged log error = %v, want a syntax error", err)
+	}
+}
+
+func TestAppendEncodeError(t *testing.T) {
+	var log bytes.Buffer
+	w := NewWriter(&log)
+	if err := w.Append(map[string]any{"ok": 1, "bad": func() {}}); err == nil {
+		t.Fatal("Append() error = nil, want an encoding error")
+	}
+	if log.Len() != 0 {
+		t.Errorf("log = %q after failed Append, want empty", log.String())
+	}
+}
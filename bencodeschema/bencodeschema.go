@@ -0,0 +1,163 @@
+// Package bencodeschema validates bencoded documents against declared
+// constraints, without a Go type describing them. A gateway can use it to
+// reject malformed messages before they reach code that decodes them:
+//
+//	announce := &bencodeschema.Schema{
+//		Kind: bencode.KindDict,
+//		Keys: map[string]*bencodeschema.Schema{
+//			"info_hash": {Kind: bencode.KindString, Required: true, MinLen: 20, MaxLen: 20},
+//			"port":      {Kind: bencode.KindInteger, Required: true},
+//		},
+//	}
+//	if err := announce.Validate(msg); err != nil { ... }
+package bencodeschema
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/stupoid/bencode"
+)
+
+// ErrSchema indicates a document does not conform to a Schema.
+const ErrSchema bencode.ErrorType = "schema violation"
+
+// Schema describes the values allowed at one place in a document. The zero
+// Schema allows any value.
+type Schema struct {
+	// Kind is the kind the value must have. KindInvalid allows any kind.
+	Kind bencode.Kind
+	// Required makes the key a Schema is declared for in its parent's Keys
+	// mandatory. It has no effect elsewhere.
+	Required bool
+	// MinLen and MaxLen bound the length of a string in bytes, of a list in
+	// elements or of a dictionary in entries. MaxLen 0 means no upper bound.
+	MinLen, MaxLen int
+	// Keys holds the schemas of the dictionary keys it names. A nil schema
+	// allows any value.
+	Keys map[string]*Schema
+	// Closed rejects dictionary keys not named in Keys.
+	Closed bool
+	// Values, if not nil, is the schema of every list element and of every
+	// dictionary value whose key is not named in Keys.
+	Values *Schema
+}
+
+// Violation describes one way in which a document departs from a Schema.
+type Violation struct {
+	// Path is the dotted path of dictionary keys and list indices leading to
+	// the offending value, joined by bencode.JoinPath, empty for the root.
+	Path string
+	// Msg describes the violation.
+	Msg string
+}
+
+// Error returns the path and description of the violation.
+func (v Violation) Error() string {
+	path := v.Path
+	if path == "" {
+		path = "(root)"
+	}
+	return path + ": " + v.Msg
+}
+
+// Violations lists the violations found in a document. It is the error
+// wrapped by ErrSchema errors.
+type Violations []Violation
+
+// Error returns the violations joined by "; ".
+func (vs Violations) Error() string {
+	msgs := make([]string, len(vs))
+	for i, v := range vs {
+		msgs[i] = v.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate decodes data, which must hold exactly one bencode value, and
+// checks it against s. It returns the decoding error if data is not valid
+// bencode or has data after the value, an ErrSchema error wrapping
+// Violations, in document order, if it does not conform, and nil otherwise.
+func (s *Schema) Validate(data []byte) error {
+	v, err := bencode.ParseValue(data)
+	if err != nil {
+		return err
+	}
+	return s.ValidateValue(v)
+}
+
+// ValidateValue is like Validate for an already decoded Value.
+func (s *Schema) ValidateValue(v *bencode.Value) error {
+	var vs Violations
+	s.check(v, "", &vs)
+	if len(vs) == 0 {
+		return nil
+	}
+	msg := "1 violation"
+	if len(vs) > 1 {
+		msg = fmt.Sprintf("%d violations", len(vs))
+	}
+	return &bencode.Error{Type: ErrSchema, Msg: msg, WrappedErr: vs}
+}
+
+// check appends the violations of v, found at path, to vs.
+func (s *Schema) check(v *bencode.Value, path string, vs *Violations) {
+	if s == nil {
+		return
+	}
+	report := func(format string, args ...any) {
+		*vs = append(*vs, Violation{Path: path, Msg: fmt.Sprintf(format, args...)})
+	}
+	if s.Kind != bencode.KindInvalid && v.Kind() != s.Kind {
+		report("got %s, want %s", v.Kind(), s.Kind)
+		return
+	}
+	if v.Kind() == bencode.KindInteger {
+		return
+	}
+
+	n := v.Len()
+	if v.Kind() == bencode.KindString {
+		n = len(v.Bytes())
+	}
+	unit := map[bencode.Kind]string{bencode.KindString: "bytes", bencode.KindList: "elements", bencode.KindDict: "entries"}[v.Kind()]
+	if n < s.MinLen {
+		report("%d %s, want at least %d", n, unit, s.MinLen)
+	}
+	if s.MaxLen > 0 && n > s.MaxLen {
+		report("%d %s, want at most %d", n, unit, s.MaxLen)
+	}
+
+	switch v.Kind() {
+	case bencode.KindList:
+		if s.Values != nil {
+			for i := range n {
+				s.Values.check(v.Index(i), bencode.JoinPath(path, strconv.Itoa(i)), vs)
+			}
+		}
+	case bencode.KindDict:
+		keys := v.Keys()
+		for k, ks := range s.Keys {
+			if _, ok := v.Key(k); !ok && ks != nil && ks.Required {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			elem, ok := v.Key(k)
+			keySchema, declared := s.Keys[k]
+			switch {
+			case !ok:
+				*vs = append(*vs, Violation{Path: bencode.JoinPath(path, k), Msg: "required key missing"})
+			case declared:
+				keySchema.check(elem, bencode.JoinPath(path, k), vs)
+			case s.Closed:
+				*vs = append(*vs, Violation{Path: bencode.JoinPath(path, k), Msg: "key not allowed"})
+			case s.Values != nil:
+				s.Values.check(elem, bencode.JoinPath(path, k), vs)
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package bencodeschema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stupoid/bencode"
+)
+
+var torrent = &Schema{
+	Kind: bencode.KindDict,
+	Keys: map[string]*Schema{
+		"announce": {Kind: bencode.KindString, Required: true, MinLen: 1},
+		"comment":  {Kind: bencode.KindString},
+		"info": {
+			Kind:     bencode.KindDict,
+			Required: true,
+			Closed:   true,
+			Keys: map[string]*Schema{
+				"name":         {Kind: bencode.KindString, Required: true},
+				"piece length": {Kind: bencode.KindInteger, Required: true},
+				"pieces":       {Kind: bencode.KindString, Required: true, MinLen: 20},
+				"files": {
+					Kind:   bencode.KindList,
+					MinLen: 1,
+					Values: &Schema{
+						Kind: bencode.KindDict,
+						Keys: map[string]*Schema{
+							"length": {Kind: bencode.KindInteger, Required: true},
+							"path":   {Kind: bencode.KindList, Required: true, MinLen: 1, Values: &Schema{Kind: bencode.KindString}},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestValidate(t *testing.T) {
+	valid, _ := bencode.Marshal(map[string]any{
+		"announce":   "http://tracker.example/announce",
+		"created by": "test",
+		"info": map[string]any{
+			"name":         "dir",
+			"piece length": 16384,
+			"pieces":       make([]byte, 20),
+			"files": []any{
+				map[string]any{"length": 3, "path": []string{"a", "b"}},
+			},
+		},
+	})
+	if err := torrent.Validate(valid); err != nil {
+		t.Errorf("Validate(valid) error = %v", err)
+	}
+
+	invalid, _ := bencode.Marshal(map[string]any{
+		"announce": "",
+		"comment":  42,
+		"info": map[string]any{
+			"name":   "dir",
+			"pieces": make([]byte, 19),
+			"files": []any{
+				map[string]any{"length": 3, "path": []any{"a", 1}},
+				"oops",
+			},
+			"private": 1,
+		},
+	})
+	err := torrent.Validate(invalid)
+	var vs Violations
+	if !errors.Is(err, ErrSchema) || !errors.As(err, &vs) {
+		t.Fatalf("Validate(invalid) error = %v, want %q", err, ErrSchema)
+	}
+	want := []string{
+		"announce: 0 bytes, want at least 1",
+		"comment: got integer, want string",
+		"info.files.0.path.1: got integer, want string",
+		"info.files.1: got string, want dictionary",
+		"info.piece length: required key missing",
+		"info.pieces: 19 bytes, want at least 20",
+		"info.private: key not allowed",
+	}
+	if len(vs) != len(want) {
+		t.Fatalf("Validate(invalid) = %v, want %d violations", vs, len(want))
+	}
+	for i, v := range vs {
+		if v.Error() != want[i] {
+			t.Errorf("violation %d = %q, want %q", i, v.Error(), want[i])
+		}
+	}
+}
+
+func TestValidateRoot(t *testing.T) {
+	list := &Schema{Kind: bencode.KindList, MaxLen: 2, Values: &Schema{Kind: bencode.KindInteger}}
+	if err := list.Validate([]byte("li1ei2ee")); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	var vs Violations
+	if err := list.Validate([]byte("li1ei2ei3ee")); !errors.As(err, &vs) || vs.Error() != "(root): 3 elements, want at most 2" {
+		t.Errorf("Validate() of long list error = %v", err)
+	}
+	if err := list.Validate([]byte("li1e")); errors.Is(err, ErrSchema) || !errors.Is(err, bencode.ErrSyntaxEOF) {
+		t.Errorf("Validate() of malformed input error = %v, want %q", err, bencode.ErrSyntaxEOF)
+	}
+	if err := (&Schema{}).Validate([]byte("i1e")); err != nil {
+		t.Errorf("zero Schema Validate() error = %v", err)
+	}
+	if err := (&Schema{}).Validate([]byte("de garbage")); !errors.Is(err, bencode.ErrTrailingData) {
+		t.Errorf("Validate() with trailing data error = %v, want %q", err, bencode.ErrTrailingData)
+	}
+	anyX := &Schema{Kind: bencode.KindDict, Keys: map[string]*Schema{"x": nil}}
+	if err := anyX.Validate([]byte("de")); err != nil {
+		t.Errorf("Validate() with a nil key schema error = %v", err)
+	}
+	if err := anyX.Validate([]byte("d1:xli1eee")); err != nil {
+		t.Errorf("Validate() with a nil key schema error = %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+// Package bencodetest provides helpers for testing code built on the bencode
+// package: generators of random canonical bencode values, round-trip
+// assertions that check a type against the package's invariants, a
+// structural Diff of encodings for test failures, and a conformance suite
+// that other implementations can be checked against.
+package bencodetest
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+
+	"github.com/stupoid/bencode"
+)
+
+// Value returns a random value of the generic form produced by
+// bencode.Decoder.DecodeValue: []byte, int64, []any or map[string]any.
+// Lists and dictionaries are nested at most depth levels deep.
+func Value(r *rand.Rand, depth int) any {
+	kinds := 2
+	if depth > 0 {
+		kinds = 4
+	}
+	switch r.IntN(kinds) {
+	case 0:
+		return randomBytes(r)
+	case 1:
+		return randomInt(r)
+	case 2:
+		list := make([]any, r.IntN(5))
+		for i := range list {
+			list[i] = Value(r, depth-1)
+		}
+		return list
+	default:
+		dict := make(map[string]any)
+		for range r.IntN(5) {
+			dict[string(randomBytes(r))] = Value(r, depth-1)
+		}
+		return dict
+	}
+}
+
+// Canonical returns the canonical encoding of a random value generated as by
+// Value.
+func Canonical(r *rand.Rand, depth int) []byte {
+	data, err := bencode.Marshal(Value(r, depth))
+	if err != nil {
+		panic("bencodetest: generated value failed to marshal: " + err.Error())
+	}
+	return data
+}
+
+func randomBytes(r *rand.Rand) []byte {
+	b := make([]byte, r.IntN(12))
+	for i := range b {
+		b[i] = byte(r.Uint32())
+	}
+	return b
+}
+
+func randomInt(r *rand.Rand) int64 {
+	switch r.IntN(4) {
+	case 0:
+		return 0
+	case 1:
+		return -r.Int64()
+	case 2:
+		return r.Int64N(1000)
+	default:
+		return r.Int64()
+	}
+}
+
+// RoundTrip checks that v survives a round trip through the bencode package:
+// it must marshal to canonical bencode, unmarshal into a new value of the
+// same type that is deeply equal to v, and marshal again to identical bytes.
+// v must not be a pointer. Failures are reported with t.Errorf.
+func RoundTrip(t testing.TB, v any) {
+	t.Helper()
+
+	first, err := bencode.Marshal(v)
+	if err != nil {
+		t.Errorf("bencodetest: Marshal(%#v) error = %v", v, err)
+		return
+	}
+	violations, err := bencode.CheckCanonical(first)
+	if err != nil {
+		t.Errorf("bencodetest: Marshal(%#v) produced invalid bencode %q: %v", v, first, err)
+		return
+	}
+	if len(violations) > 0 {
+		t.Errorf("bencodetest: Marshal(%#v) produced non-canonical bencode %q: %v", v, first, violations)
+	}
+
+	decoded := reflect.New(reflect.TypeOf(v))
+	if err := bencode.Unmarshal(first, decoded.Interface()); err != nil {
+		t.Errorf("bencodetest: Unmarshal(%q) into %T error = %v", first, v, err)
+		return
+	}
+	if !reflect.DeepEqual(decoded.Elem().Interface(), v) {
+		t.Errorf("bencodetest: round trip of %#v produced %#v", v, decoded.Elem().Interface())
+	}
+
+	second, err := bencode.Marshal(decoded.Elem().Interface())
+	if err != nil {
+		t.Errorf("bencodetest: re-Marshal error = %v", err)
+		return
+	}
+	if !bytes.Equal(first, second) {
+		t.Errorf("bencodetest: re-Marshal produced %q, first encoding was %q", second, first)
+	}
+}
+
+// RoundTripRandom runs RoundTrip on n random values generated as by Value,
+// decoded as `any`, using a generator seeded with seed.
+func RoundTripRandom(t testing.TB, seed uint64, n int) {
+	t.Helper()
+	r := rand.New(rand.NewPCG(seed, seed))
+	for range n {
+		v := Value(r, 3)
+		data, err := bencode.Marshal(v)
+		if err != nil {
+			t.Errorf("bencodetest: Marshal(%#v) error = %v", v, err)
+			continue
+		}
+		var decoded any
+		if err := bencode.Unmarshal(data, &decoded); err != nil {
+			t.Errorf("bencodetest: Unmarshal(%q) error = %v", data, err)
+			continue
+		}
+		again, err := bencode.Marshal(decoded)
+		if err != nil || !bytes.Equal(data, again) {
+			t.Errorf("bencodetest: round trip of %q produced %q (err %v)", data, again, err)
+		}
+	}
+}
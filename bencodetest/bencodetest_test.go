@@ -0,0 +1,139 @@
+package bencodetest
+
+import (
+	"math/rand/v2"
+	"reflect"
+	"testing"
+
+	"github.com/stupoid/bencode"
+)
+
+func TestRoundTripRandom(t *testing.T) {
+	RoundTripRandom(t, 1, 200)
+}
+
+func TestCanonical(t *testing.T) {
+	r := rand.New(rand.NewPCG(2, 2))
+	for range 100 {
+		data := Canonical(r, 3)
+		violations, err := bencode.CheckCanonical(data)
+		if err != nil || len(violations) > 0 {
+			t.Fatalf("Canonical() = %q: %v %v", data, violations, err)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	type Info struct {
+		Name   string   `bencode:"name"`
+		Length int64    `bencode:"length"`
+		Tags   []string `bencode:"tags"`
+	}
+	RoundTrip(t, Info{Name: "file", Length: 42, Tags: []string{"a", "b"}})
+	RoundTrip(t, map[string]int{"x": 1, "y": -2})
+}
+
+// recorder captures failures so RoundTrip's failure path can be tested.
+type recorder struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recorder) Helper()               {}
+func (r *recorder) Errorf(string, ...any) { r.failed = true }
+
+func TestRoundTripReportsLoss(t *testing.T) {
+	type Lossy struct {
+		hidden string // unexported fields are not encoded
+		Shown  string
+	}
+	rec := &recorder{TB: t}
+	RoundTrip(rec, Lossy{hidden: "x", Shown: "y"})
+	if !rec.failed {
+		t.Errorf("RoundTrip() did not report a lossy round trip")
+	}
+}
+
+func TestCorpus(t *testing.T) {
+	samples := Corpus()
+	if len(samples) != len(corpusInfo) {
+		t.Fatalf("Corpus() returned %d samples, want %d", len(samples), len(corpusInfo))
+	}
+	for _, s := range samples {
+		t.Run(s.Name, func(t *testing.T) {
+			if s.Description == "" {
+				t.Errorf("sample has no description")
+			}
+			var v any
+			err := bencode.Unmarshal(s.Data, &v)
+			if s.Strict && err != nil {
+				t.Errorf("Unmarshal() error = %v, want strict sample to decode", err)
+			}
+			if !s.Strict && err == nil {
+				t.Errorf("Unmarshal() succeeded, want irregular sample to be rejected")
+			}
+		})
+	}
+}
+
+func TestConformance(t *testing.T) {
+	t.Run("strict", func(t *testing.T) {
+		RunConformance(t, ProfileStrict, DecodeWith(bencode.StrictBEP3()))
+	})
+	t.Run("lenient", func(t *testing.T) {
+		RunConformance(t, ProfileLenient, DecodeWith(bencode.LenientInterop()))
+	})
+}
+
+func TestVectors(t *testing.T) {
+	vectors := Vectors()
+	names := make(map[string]bool)
+	for _, v := range vectors {
+		if names[v.Name] {
+			t.Errorf("Vectors() has duplicate name %q", v.Name)
+		}
+		names[v.Name] = true
+		for _, o := range []Outcome{v.Strict, v.Lenient} {
+			if o != Accept && o != Reject {
+				t.Errorf("vector %q has outcome %q", v.Name, o)
+			}
+		}
+	}
+	v := vectors[0]
+	v.Input[0] = 'x'
+	if Vectors()[0].Input[0] == 'x' {
+		t.Errorf("Vectors() returned shared data")
+	}
+}
+
+func TestDifferences(t *testing.T) {
+	tests := []struct {
+		name      string
+		want, got string
+		diffs     []string
+	}{
+		{name: "equal", want: "d1:ai1ee", got: "d1:ai1ee"},
+		{
+			name:  "nested",
+			want:  "d4:infod5:filesld1:ai1eed1:bi2eee12:piece lengthi16384eee",
+			got:   "d4:infod5:filesld1:ai1eee12:piece lengthi32768ee1:xi0ee",
+			diffs: []string{"info.files.1: missing, want dict (1 entry)", "info.piece length: got 32768, want 16384", "x: got 0, want nothing"},
+		},
+		{name: "kind", want: "l3:abce", got: "li1ee", diffs: []string{`0: got 1, want "abc"`}},
+		{name: "root", want: "i1e", got: "le", diffs: []string{"(root): got list (0 elements), want 1"}},
+		{name: "undecodable", want: "i1e", got: "i1", diffs: []string{`got "i1" does not decode: bencode: integer not terminated by 'e': bencode: unexpected end of input`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Differences([]byte(tt.want), []byte(tt.got))
+			if !reflect.DeepEqual(got, tt.diffs) {
+				t.Errorf("Differences() = %q, want %q", got, tt.diffs)
+			}
+		})
+	}
+
+	rec := &recorder{TB: t}
+	if Diff(rec, []byte("i1e"), []byte("i2e")) || !rec.failed {
+		t.Error("Diff() did not report differing values")
+	}
+}
@@ -0,0 +1,147 @@
+package bencodetest
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stupoid/bencode"
+)
+
+//go:embed conformance.json
+var conformanceJSON []byte
+
+// Profile names a set of decoding options under which conformance vectors
+// have an expected outcome.
+type Profile string
+
+const (
+	// ProfileStrict corresponds to bencode.StrictBEP3.
+	ProfileStrict Profile = "strict"
+	// ProfileLenient corresponds to bencode.LenientInterop.
+	ProfileLenient Profile = "lenient"
+)
+
+// Outcome is the expected result of decoding a conformance vector.
+type Outcome string
+
+const (
+	// Accept means the input must decode and re-encode to identical bytes.
+	Accept Outcome = "accept"
+	// Reject means decoding the input must fail.
+	Reject Outcome = "reject"
+)
+
+// Vector is one entry of the conformance suite returned by Vectors.
+type Vector struct {
+	// Name describes what the vector exercises, e.g. "dict unsorted keys".
+	Name string
+	// Input is the data to decode. It holds at most one value; anything
+	// after it must be rejected.
+	Input []byte
+	// Strict and Lenient are the expected outcomes under each profile.
+	Strict  Outcome
+	Lenient Outcome
+	// Error is the bencode.ErrorType a rejection is reported with by this
+	// package, if the vector pins one down. Other implementations need not
+	// match it.
+	Error bencode.ErrorType
+}
+
+// Expect returns the expected outcome of v under p.
+func (v Vector) Expect(p Profile) Outcome {
+	if p == ProfileLenient {
+		return v.Lenient
+	}
+	return v.Strict
+}
+
+// Vectors returns the conformance suite: inputs covering valid and invalid
+// integers, strings, lists and dictionaries with their expected outcomes.
+// The suite is kept as JSON in conformance.json so that implementations in
+// other languages can use it too; in that file each character of an input,
+// which is always in the range U+0000 to U+00FF, stands for one byte.
+// Each call returns fresh copies of the vectors.
+func Vectors() []Vector {
+	var raw []struct {
+		Name    string  `json:"name"`
+		Input   string  `json:"input"`
+		Strict  Outcome `json:"strict"`
+		Lenient Outcome `json:"lenient"`
+		Error   string  `json:"error"`
+	}
+	if err := json.Unmarshal(conformanceJSON, &raw); err != nil {
+		panic("bencodetest: reading embedded conformance suite: " + err.Error())
+	}
+	vectors := make([]Vector, len(raw))
+	for i, r := range raw {
+		input := make([]byte, 0, len(r.Input))
+		for _, c := range r.Input {
+			if c > 0xff {
+				panic("bencodetest: conformance vector " + r.Name + " has a character above U+00FF")
+			}
+			input = append(input, byte(c))
+		}
+		vectors[i] = Vector{
+			Name:    r.Name,
+			Input:   input,
+			Strict:  r.Strict,
+			Lenient: r.Lenient,
+			Error:   bencode.ErrorType(r.Error),
+		}
+	}
+	return vectors
+}
+
+// DecodeFunc is an implementation under test. It decodes data, which must
+// hold exactly one value, and returns the value encoded again.
+type DecodeFunc func(data []byte) ([]byte, error)
+
+// DecodeWith returns a DecodeFunc that decodes with a bencode.Decoder
+// configured by opts into a bencode.RawMessage, which holds the value
+// encoded again, keeping the original digits of integers accepted by
+// AllowNonMinimalIntegers.
+func DecodeWith(opts bencode.Options) DecodeFunc {
+	return func(data []byte) ([]byte, error) {
+		dec := opts.NewDecoder(bytes.NewReader(data))
+		var raw bencode.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		if err := dec.ExpectEOF(); err != nil {
+			return nil, err
+		}
+		return raw, nil
+	}
+}
+
+// RunConformance runs decode against every vector of the suite as a subtest
+// of t, checking the outcome expected under p. Accepted inputs must come
+// back byte for byte. When a rejection is reported with a *bencode.Error,
+// its Type must match the vector's Error, if any.
+func RunConformance(t *testing.T, p Profile, decode DecodeFunc) {
+	t.Helper()
+	for _, v := range Vectors() {
+		t.Run(v.Name, func(t *testing.T) {
+			got, err := decode(v.Input)
+			if v.Expect(p) == Accept {
+				if err != nil {
+					t.Fatalf("decode(%q) error = %v, want success", v.Input, err)
+				}
+				if !bytes.Equal(got, v.Input) {
+					t.Errorf("decode(%q) re-encoded as %q", v.Input, got)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("decode(%q) succeeded, want %s rejection", v.Input, p)
+			}
+			var bErr *bencode.Error
+			if v.Error != "" && errors.As(err, &bErr) && bErr.Type != v.Error {
+				t.Errorf("decode(%q) error = %v, want %q", v.Input, err, v.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package bencodetest
+
+import (
+	"embed"
+	"io/fs"
+	"path"
+)
+
+//go:embed testdata
+var corpusFS embed.FS
+
+// Sample is one payload of the corpus returned by Corpus.
+type Sample struct {
+	// Name is the sample's file name, e.g. "single-file.torrent".
+	Name string
+	// Description explains what the sample is representative of.
+	Description string
+	// Data is the encoded payload.
+	Data []byte
+	// Strict reports whether the default Decoder accepts the sample. Samples
+	// with Strict false exercise irregularities found in the wild that only
+	// lenient decoding options can be expected to handle.
+	Strict bool
+}
+
+// corpusInfo describes the files in testdata.
+var corpusInfo = map[string]struct {
+	description string
+	strict      bool
+}{
+	"single-file.torrent":      {"single-file torrent with announce, created by and creation date", true},
+	"multi-file.torrent":       {"private multi-file torrent with announce-list tiers and a comment", true},
+	"huge-pieces.torrent":      {"torrent with a 40000-byte pieces string", true},
+	"v2-hybrid.torrent":        {"BitTorrent v1/v2 hybrid with file tree and piece layers keyed by binary roots", true},
+	"unsorted-keys.torrent":    {"torrent whose info dictionary keys are not sorted, as written by some old clients", false},
+	"duplicate-keys.torrent":   {"torrent repeating the announce key", false},
+	"announce-compact.bencode": {"tracker announce response with compact binary peers", true},
+	"announce-dict.bencode":    {"tracker announce response with a list of peer dictionaries", true},
+	"announce-failure.bencode": {"tracker announce response carrying a failure reason", true},
+	"scrape.bencode":           {"tracker scrape response keyed by binary info hashes", true},
+}
+
+// Corpus returns a corpus of representative real-world payloads: torrents
+// and tracker responses, including irregular ones. Samples are returned in
+// name order and each call returns fresh copies of the data.
+func Corpus() []Sample {
+	entries, err := fs.ReadDir(corpusFS, "testdata")
+	if err != nil {
+		panic("bencodetest: reading embedded corpus: " + err.Error())
+	}
+	samples := make([]Sample, 0, len(entries))
+	for _, entry := range entries {
+		data, err := fs.ReadFile(corpusFS, path.Join("testdata", entry.Name()))
+		if err != nil {
+			panic("bencodetest: reading embedded corpus: " + err.Error())
+		}
+		info := corpusInfo[entry.Name()]
+		samples = append(samples, Sample{
+			Name:        entry.Name(),
+			Description: info.description,
+			Data:        data,
+			Strict:      info.strict,
+		})
+	}
+	return samples
+}
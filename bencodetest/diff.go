@@ -0,0 +1,140 @@
+package bencodetest
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stupoid/bencode"
+)
+
+// Diff reports, with t.Errorf, how the encoded values want and got differ,
+// and returns whether they are identical. Rather than dumping both byte
+// strings, it decodes them and lists each difference with the dotted path
+// of dictionary keys and list indices leading to it:
+//
+//	info.piece length: got 32768, want 16384
+//	info.files.1: missing, want dict (2 entries)
+//
+// Inputs that fail to decode, or that decode to equal values but are encoded
+// differently, are reported as such.
+func Diff(t testing.TB, want, got []byte) bool {
+	t.Helper()
+	diffs := Differences(want, got)
+	if len(diffs) == 0 {
+		return true
+	}
+	t.Errorf("bencodetest: encodings differ:\n\t%s", strings.Join(diffs, "\n\t"))
+	return false
+}
+
+// Differences returns the differences between the encoded values want and
+// got as reported by Diff, one per element, or nil if they are identical.
+func Differences(want, got []byte) []string {
+	if bytes.Equal(want, got) {
+		return nil
+	}
+	wantVal, err := bencode.UnmarshalAny(want)
+	if err != nil {
+		return []string{fmt.Sprintf("want %q does not decode: %v", want, err)}
+	}
+	gotVal, err := bencode.UnmarshalAny(got)
+	if err != nil {
+		return []string{fmt.Sprintf("got %q does not decode: %v", got, err)}
+	}
+	var diffs []string
+	diffValues(&diffs, "", wantVal, gotVal)
+	if len(diffs) == 0 {
+		diffs = append(diffs, fmt.Sprintf("values are equal but encoded differently: got %q, want %q", got, want))
+	}
+	return diffs
+}
+
+// diffValues appends the differences between the generic values want and
+// got, found at path, to diffs.
+func diffValues(diffs *[]string, path string, want, got any) {
+	at := path
+	if at == "" {
+		at = "(root)"
+	}
+	switch w := want.(type) {
+	case []byte:
+		if g, ok := got.([]byte); !ok || !bytes.Equal(w, g) {
+			*diffs = append(*diffs, fmt.Sprintf("%s: got %s, want %s", at, describe(got), describe(want)))
+		}
+	case int64:
+		if g, ok := got.(int64); !ok || w != g {
+			*diffs = append(*diffs, fmt.Sprintf("%s: got %s, want %s", at, describe(got), describe(want)))
+		}
+	case []any:
+		g, ok := got.([]any)
+		if !ok {
+			*diffs = append(*diffs, fmt.Sprintf("%s: got %s, want %s", at, describe(got), describe(want)))
+			return
+		}
+		for i := range max(len(w), len(g)) {
+			elemPath := bencode.JoinPath(path, strconv.Itoa(i))
+			switch {
+			case i >= len(g):
+				*diffs = append(*diffs, fmt.Sprintf("%s: missing, want %s", elemPath, describe(w[i])))
+			case i >= len(w):
+				*diffs = append(*diffs, fmt.Sprintf("%s: got %s, want nothing", elemPath, describe(g[i])))
+			default:
+				diffValues(diffs, elemPath, w[i], g[i])
+			}
+		}
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			*diffs = append(*diffs, fmt.Sprintf("%s: got %s, want %s", at, describe(got), describe(want)))
+			return
+		}
+		keys := slices.Collect(maps.Keys(w))
+		for key := range g {
+			if _, ok := w[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			wv, inWant := w[key]
+			gv, inGot := g[key]
+			keyPath := bencode.JoinPath(path, key)
+			switch {
+			case !inGot:
+				*diffs = append(*diffs, fmt.Sprintf("%s: missing, want %s", keyPath, describe(wv)))
+			case !inWant:
+				*diffs = append(*diffs, fmt.Sprintf("%s: got %s, want nothing", keyPath, describe(gv)))
+			default:
+				diffValues(diffs, keyPath, wv, gv)
+			}
+		}
+	}
+}
+
+// describe formats a generic value for a difference: strings and integers
+// in full, containers by their size.
+func describe(v any) string {
+	switch v := v.(type) {
+	case []byte:
+		return strconv.Quote(string(v))
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case []any:
+		if len(v) == 1 {
+			return "list (1 element)"
+		}
+		return fmt.Sprintf("list (%d elements)", len(v))
+	case map[string]any:
+		if len(v) == 1 {
+			return "dict (1 entry)"
+		}
+		return fmt.Sprintf("dict (%d entries)", len(v))
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,180 @@
+package bencode
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha1"
+	"fmt"
+)
+
+const (
+	// ErrBEP44 indicates a BEP 44 item violates the size or field constraints of the specification.
+	ErrBEP44 ErrorType = "BEP 44 item error"
+
+	// BEP44MaxValueSize is the maximum size in bytes of the bencoded "v" value of a BEP 44 item.
+	BEP44MaxValueSize = 1000
+	// BEP44MaxSaltSize is the maximum size in bytes of the "salt" of a BEP 44 mutable item.
+	BEP44MaxSaltSize = 64
+)
+
+// ImmutableItem is a BEP 44 immutable item. Its target is the SHA-1 hash
+// of the bencoded value.
+type ImmutableItem struct {
+	V any `bencode:"v"`
+}
+
+// EncodedValue returns the bencoding of the item's value, validating its size.
+func (it ImmutableItem) EncodedValue() ([]byte, error) {
+	return encodeBEP44Value(it.V)
+}
+
+// Target returns the DHT target of the item: SHA-1 over the bencoded value.
+func (it ImmutableItem) Target() ([20]byte, error) {
+	v, err := it.EncodedValue()
+	if err != nil {
+		return [20]byte{}, err
+	}
+	return sha1.Sum(v), nil
+}
+
+// PutArgs returns the "a" dictionary of a DHT put query storing the item.
+func (it ImmutableItem) PutArgs(id, token []byte) (map[string]any, error) {
+	if _, err := it.EncodedValue(); err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"id":    id,
+		"token": token,
+		"v":     it.V,
+	}, nil
+}
+
+// MutableItem is a BEP 44 mutable item. It can be decoded directly from the
+// "r" dictionary of a DHT get response.
+//
+// K is the 32-byte ed25519 public key and Sig the 64-byte signature over the
+// buffer returned by SigningBuffer, both held as raw binary strings. Salt and
+// Cas, the sequence number a put is conditional on, are optional, and
+// omitted from the encoding when absent, so that marshalling the item gives
+// the keys of a BEP 44 put message.
+type MutableItem struct {
+	V    any              `bencode:"v"`
+	K    string           `bencode:"k"`
+	Sig  string           `bencode:"sig"`
+	Seq  int64            `bencode:"seq"`
+	Salt Optional[string] `bencode:"salt"`
+	Cas  Optional[int64]  `bencode:"cas"`
+}
+
+// SigningBuffer returns the exact bytes that are signed for the item, as
+// produced by MutableSigningBuffer.
+func (it MutableItem) SigningBuffer() ([]byte, error) {
+	return MutableSigningBuffer([]byte(it.Salt.Or("")), it.Seq, it.V)
+}
+
+// Target returns the DHT target of the item: SHA-1 over the public key
+// followed by the salt.
+func (it MutableItem) Target() ([20]byte, error) {
+	if len(it.K) != ed25519.PublicKeySize {
+		return [20]byte{}, &Error{Type: ErrBEP44, Msg: fmt.Sprintf("public key must be %d bytes, got %d", ed25519.PublicKeySize, len(it.K)), FieldName: "k"}
+	}
+	h := sha1.New()
+	h.Write([]byte(it.K))
+	h.Write([]byte(it.Salt.Or("")))
+	var target [20]byte
+	h.Sum(target[:0])
+	return target, nil
+}
+
+// Sign sets K and Sig by signing the item's signing buffer with priv.
+func (it *MutableItem) Sign(priv ed25519.PrivateKey) error {
+	if len(priv) != ed25519.PrivateKeySize {
+		return &Error{Type: ErrBEP44, Msg: fmt.Sprintf("private key must be %d bytes, got %d", ed25519.PrivateKeySize, len(priv))}
+	}
+	buf, err := it.SigningBuffer()
+	if err != nil {
+		return err
+	}
+	it.K = string(priv.Public().(ed25519.PublicKey))
+	it.Sig = string(ed25519.Sign(priv, buf))
+	return nil
+}
+
+// Verify reports whether Sig is a valid signature of the item by K.
+func (it MutableItem) Verify() error {
+	if len(it.K) != ed25519.PublicKeySize {
+		return &Error{Type: ErrBEP44, Msg: fmt.Sprintf("public key must be %d bytes, got %d", ed25519.PublicKeySize, len(it.K)), FieldName: "k"}
+	}
+	if len(it.Sig) != ed25519.SignatureSize {
+		return &Error{Type: ErrBEP44, Msg: fmt.Sprintf("signature must be %d bytes, got %d", ed25519.SignatureSize, len(it.Sig)), FieldName: "sig"}
+	}
+	buf, err := it.SigningBuffer()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(ed25519.PublicKey(it.K), buf, []byte(it.Sig)) {
+		return &Error{Type: ErrBEP44, Msg: "signature verification failed", FieldName: "sig"}
+	}
+	return nil
+}
+
+// PutArgs returns the "a" dictionary of a DHT put query storing the item.
+// The optional "salt" and "cas" keys are only included when present, and
+// "salt" only when non-empty, as in the signing buffer.
+func (it MutableItem) PutArgs(id, token []byte) (map[string]any, error) {
+	if err := it.Verify(); err != nil {
+		return nil, err
+	}
+	args := map[string]any{
+		"id":    id,
+		"token": token,
+		"v":     it.V,
+		"k":     it.K,
+		"sig":   it.Sig,
+		"seq":   it.Seq,
+	}
+	if salt := it.Salt.Or(""); salt != "" {
+		args["salt"] = salt
+	}
+	if cas, ok := it.Cas.Get(); ok {
+		args["cas"] = cas
+	}
+	return args, nil
+}
+
+// MutableSigningBuffer returns the buffer signed for a BEP 44 mutable item.
+// It is the bencoding of a dictionary holding "salt" (only when non-empty),
+// "seq" and "v", with the outer 'd' and 'e' stripped:
+//
+//	4:salt6:foobar3:seqi1e1:v12:Hello World!
+func MutableSigningBuffer(salt []byte, seq int64, v any) ([]byte, error) {
+	if len(salt) > BEP44MaxSaltSize {
+		return nil, &Error{Type: ErrBEP44, Msg: fmt.Sprintf("salt is %d bytes, maximum is %d", len(salt), BEP44MaxSaltSize), FieldName: "salt"}
+	}
+	encodedV, err := encodeBEP44Value(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if len(salt) > 0 {
+		fmt.Fprintf(&buf, "4:salt%d:%s", len(salt), salt)
+	}
+	fmt.Fprintf(&buf, "3:seqi%de1:v", seq)
+	buf.Write(encodedV)
+	return buf.Bytes(), nil
+}
+
+// encodeBEP44Value bencodes v and checks it against BEP44MaxValueSize.
+func encodeBEP44Value(v any) ([]byte, error) {
+	if v == nil {
+		return nil, &Error{Type: ErrBEP44, Msg: "missing value", FieldName: "v"}
+	}
+	encoded, err := Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(encoded) > BEP44MaxValueSize {
+		return nil, &Error{Type: ErrBEP44, Msg: fmt.Sprintf("bencoded value is %d bytes, maximum is %d", len(encoded), BEP44MaxValueSize), FieldName: "v"}
+	}
+	return encoded, nil
+}
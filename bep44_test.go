@@ -0,0 +1,149 @@
+package bencode
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Test vectors from BEP 44.
+var bep44PublicKey, _ = hex.DecodeString("77ff84905a91936367c01360803104f92432fcd904a43511876df5cdf3e7e548")
+
+func TestMutableSigningBuffer(t *testing.T) {
+	tests := []struct {
+		name     string
+		salt     []byte
+		seq      int64
+		v        any
+		expected string
+	}{
+		{
+			name:     "without salt",
+			seq:      1,
+			v:        "Hello World!",
+			expected: "3:seqi1e1:v12:Hello World!",
+		},
+		{
+			name:     "with salt",
+			salt:     []byte("foobar"),
+			seq:      1,
+			v:        "Hello World!",
+			expected: "4:salt6:foobar3:seqi1e1:v12:Hello World!",
+		},
+		{
+			name:     "dictionary value",
+			seq:      4,
+			v:        map[string]any{"b": 2, "a": 1},
+			expected: "3:seqi4e1:vd1:ai1e1:bi2ee",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MutableSigningBuffer(tt.salt, tt.seq, tt.v)
+			if err != nil {
+				t.Fatalf("MutableSigningBuffer() error = %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("MutableSigningBuffer() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBEP44Targets(t *testing.T) {
+	immutable, err := ImmutableItem{V: "Hello World!"}.Target()
+	if err != nil {
+		t.Fatalf("ImmutableItem.Target() error = %v", err)
+	}
+	if got := hex.EncodeToString(immutable[:]); got != "e5f96f6f38320f0f33959cb4d3d656452117aadb" {
+		t.Errorf("ImmutableItem.Target() = %s", got)
+	}
+
+	mutable, err := MutableItem{K: string(bep44PublicKey)}.Target()
+	if err != nil {
+		t.Fatalf("MutableItem.Target() error = %v", err)
+	}
+	if got := hex.EncodeToString(mutable[:]); got != "4a533d47ec9c7d95b1ad75f576cffc641853b750" {
+		t.Errorf("MutableItem.Target() = %s", got)
+	}
+
+	salted, err := MutableItem{K: string(bep44PublicKey), Salt: Some("foobar")}.Target()
+	if err != nil {
+		t.Fatalf("MutableItem.Target() error = %v", err)
+	}
+	if got := hex.EncodeToString(salted[:]); got != "411eba73b6f087ca51a3795d9c8c938d365e32c1" {
+		t.Errorf("MutableItem.Target() with salt = %s", got)
+	}
+}
+
+func TestMutableItemSignVerify(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := MutableItem{V: "Hello World!", Seq: 1, Salt: Some("foobar")}
+	if err := item.Sign(priv); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if err := item.Verify(); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	args, err := item.PutArgs([]byte("id"), []byte("token"))
+	if err != nil {
+		t.Fatalf("PutArgs() error = %v", err)
+	}
+	if _, ok := args["cas"]; ok {
+		t.Errorf("PutArgs() included cas when unset")
+	}
+	encoded, err := Marshal(args)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	// The item itself marshals to the same keys, less id and token.
+	delete(args, "id")
+	delete(args, "token")
+	want, _ := Marshal(args)
+	if got, err := Marshal(item); err != nil || string(got) != string(want) {
+		t.Errorf("Marshal(item) = %q, %v, want %q", got, err, want)
+	}
+	withCas := item
+	withCas.Cas = Some[int64](0)
+	if got, _ := Marshal(withCas); !bytes.Contains(got, []byte("3:casi0e")) {
+		t.Errorf("Marshal() of item with cas 0 = %q, want cas included", got)
+	}
+
+	var decoded MutableItem
+	if err := Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if err := decoded.Verify(); err != nil {
+		t.Errorf("Verify() after round trip error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded.V, []byte("Hello World!")) {
+		t.Errorf("decoded V = %v", decoded.V)
+	}
+
+	decoded.Seq++
+	var bErr *Error
+	if err := decoded.Verify(); !errors.As(err, &bErr) || bErr.Type != ErrBEP44 {
+		t.Errorf("Verify() with tampered seq error = %v, want %q", err, ErrBEP44)
+	}
+}
+
+func TestBEP44Limits(t *testing.T) {
+	var bErr *Error
+	_, err := MutableSigningBuffer(make([]byte, BEP44MaxSaltSize+1), 1, "v")
+	if !errors.As(err, &bErr) || bErr.Type != ErrBEP44 || bErr.FieldName != "salt" {
+		t.Errorf("oversized salt error = %v", err)
+	}
+	_, err = ImmutableItem{V: strings.Repeat("x", BEP44MaxValueSize)}.Target()
+	if !errors.As(err, &bErr) || bErr.Type != ErrBEP44 || bErr.FieldName != "v" {
+		t.Errorf("oversized value error = %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package bencode
+
+import "unicode/utf8"
+
+// UseBytesViews makes the Decoder produce a string rather than a []byte for
+// each dictionary value that is a valid UTF-8 string, wherever it produces
+// generic values: in the result of DecodeValue and in values decoded into an
+// interface, such as a map[string]any or a field of type any. Callers that
+// treat a document as text, as configuration files are, then need no
+// conversions. Strings that are not valid UTF-8, list elements, a string
+// decoded at the top level and strings bound for typed destinations stay as
+// they are, so binary data such as piece hashes is never altered.
+func (d *Decoder) UseBytesViews() {
+	d.bytesViews = true
+}
+
+// stringView returns the string held by v, if v is a valid UTF-8 []byte, and
+// v itself otherwise.
+func stringView(v any) any {
+	if b, ok := v.([]byte); ok && utf8.Valid(b) {
+		return string(b)
+	}
+	return v
+}
+
+// withBytesViews replaces, in place, the dictionary values of a tree of
+// generic decoded values with their string views.
+func withBytesViews(generic any) any {
+	switch g := generic.(type) {
+	case []any:
+		for i, elem := range g {
+			g[i] = withBytesViews(elem)
+		}
+	case map[string]any:
+		for key, elem := range g {
+			g[key] = stringView(withBytesViews(elem))
+		}
+	}
+	return generic
+}
@@ -0,0 +1,62 @@
+package bencode
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUseBytesViews(t *testing.T) {
+	const input = "d4:hash2:\xff\x004:listl1:ae4:named1:k1:vee"
+	want := map[string]any{
+		"hash": []byte("\xff\x00"),
+		"list": []any{[]byte("a")},
+		"name": map[string]any{"k": "v"},
+	}
+
+	dec := NewDecoder(strings.NewReader(input))
+	dec.UseBytesViews()
+	got, err := dec.DecodeValue()
+	if err != nil {
+		t.Fatalf("DecodeValue() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeValue() = %#v, want %#v", got, want)
+	}
+
+	var m map[string]any
+	dec = NewDecoder(strings.NewReader(input))
+	dec.UseBytesViews()
+	if err := dec.Decode(&m); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("Decode(map[string]any) = %#v, want %#v", m, want)
+	}
+
+	var s struct {
+		Hash []byte `bencode:"hash"`
+		List any    `bencode:"list"`
+		Name any    `bencode:"name"`
+		Text any    `bencode:"text"`
+	}
+	dec = NewDecoder(strings.NewReader("d4:hash1:x4:listl1:ae4:named1:k1:ve4:text2:hie"))
+	dec.UseBytesViews()
+	if err := dec.Decode(&s); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if string(s.Hash) != "x" || !reflect.DeepEqual(s.List, []any{[]byte("a")}) || !reflect.DeepEqual(s.Name, map[string]any{"k": "v"}) || s.Text != "hi" {
+		t.Errorf("Decode(struct) = %#v", s)
+	}
+
+	// Without the option, strings stay []byte, as does a top-level string.
+	got, _ = NewDecoder(strings.NewReader("d1:k1:ve")).DecodeValue()
+	if !reflect.DeepEqual(got, map[string]any{"k": []byte("v")}) {
+		t.Errorf("DecodeValue() without UseBytesViews = %#v", got)
+	}
+	dec = NewDecoder(strings.NewReader("2:hi"))
+	dec.UseBytesViews()
+	if got, _ := dec.DecodeValue(); !reflect.DeepEqual(got, []byte("hi")) {
+		t.Errorf("DecodeValue() of top-level string = %#v, want []byte", got)
+	}
+}
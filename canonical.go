@@ -0,0 +1,193 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/stupoid/bencode/scanner"
+)
+
+// ErrEncodeNonCanonical indicates that a pre-encoded value passed to an
+// Encoder requiring canonical output is not canonically encoded.
+const ErrEncodeNonCanonical ErrorType = "encode: non-canonical raw value"
+
+// Violation describes one way in which an encoding departs from canonical
+// bencode.
+type Violation struct {
+	// Offset is the byte offset of the offending token in the input.
+	Offset int
+	// Type is ErrSyntaxInteger or ErrSyntaxStringLength for non-minimal
+	// numbers, or ErrStructureDictKeySort or ErrStructureDictKeyDup for
+	// dictionary key order problems.
+	Type ErrorType
+	// Msg provides a human-readable description of the violation.
+	Msg string
+}
+
+// Error returns a string representation of the violation.
+func (v Violation) Error() string {
+	return fmt.Sprintf("offset %d: %s: %s", v.Offset, v.Type, v.Msg)
+}
+
+// Violations is a list of canonical encoding violations. It is the error
+// wrapped by ErrEncodeNonCanonical errors.
+type Violations []Violation
+
+// Error returns the violations joined by "; ".
+func (vs Violations) Error() string {
+	msgs := make([]string, len(vs))
+	for i, v := range vs {
+		msgs[i] = v.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// CompareKeys compares dictionary keys a and b in canonical order, returning
+// -1, 0 or +1 as a sorts before, equal to or after b. Keys are compared as
+// raw bytes, unsigned and without regard to any character encoding, with a
+// key sorting before every longer key it is a prefix of. This is the order
+// in which the Encoder writes keys and in which the Decoder, Index and
+// CheckCanonical require them.
+func CompareKeys(a, b []byte) int {
+	return bytes.Compare(a, b)
+}
+
+// CheckCanonical scans data, which must hold exactly one bencode value, and
+// reports every place where it is not canonically encoded: integers and
+// string lengths with leading zeros or a negative zero, and dictionaries
+// whose keys are unsorted or duplicated. Unlike the Decoder, it does not stop
+// at the first such problem. A non-nil error is returned only if data is not
+// parseable bencode at all.
+func CheckCanonical(data []byte) (Violations, error) {
+	c := &canonicalChecker{data: data}
+	c.scan.Reset(data)
+	tok, err := c.scan.Next()
+	if err != nil {
+		return nil, scanError(err)
+	}
+	if err := c.value(tok); err != nil {
+		return nil, err
+	}
+	if c.scan.More() {
+		return nil, &Error{Type: ErrSyntax, Msg: fmt.Sprintf("unexpected data after bencode value at offset %d", c.scan.Offset())}
+	}
+	return c.violations, nil
+}
+
+// canonicalChecker walks scanner tokens and records canonical encoding
+// violations instead of rejecting them.
+type canonicalChecker struct {
+	data       []byte
+	scan       scanner.Scanner
+	violations Violations
+}
+
+func (c *canonicalChecker) report(offset int, typ ErrorType, msg string) {
+	c.violations = append(c.violations, Violation{Offset: offset, Type: typ, Msg: msg})
+}
+
+// checkNumber checks the decimal digits at offset for minimality.
+func (c *canonicalChecker) checkNumber(offset int, digits []byte, typ ErrorType) error {
+	n, err := strconv.ParseInt(string(digits), 10, 64)
+	if err != nil {
+		return &Error{Type: typ, Msg: fmt.Sprintf("cannot parse number %q at offset %d", digits, offset), WrappedErr: err}
+	}
+	if canonical := strconv.FormatInt(n, 10); canonical != string(digits) {
+		c.report(offset, typ, fmt.Sprintf("%q is not minimal, want %q", digits, canonical))
+	}
+	return nil
+}
+
+// value checks the value starting with tok.
+func (c *canonicalChecker) value(tok scanner.Token) error {
+	switch tok.Kind {
+	case scanner.String:
+		return c.checkNumber(tok.Offset, c.data[tok.Offset:tok.ValueOffset-1], ErrSyntaxStringLength)
+	case scanner.Integer:
+		return c.checkNumber(tok.ValueOffset, c.data[tok.ValueOffset:tok.ValueOffset+tok.ValueLen], ErrSyntaxInteger)
+	case scanner.ListStart:
+		for {
+			next, err := c.scan.Next()
+			if err != nil {
+				return scanError(err)
+			}
+			if next.Kind == scanner.End {
+				return nil
+			}
+			if err := c.value(next); err != nil {
+				return err
+			}
+		}
+	case scanner.DictStart:
+		var prevKey []byte
+		seen := make(map[string]struct{})
+		for {
+			keyTok, err := c.scan.Next()
+			if err != nil {
+				return scanError(err)
+			}
+			if keyTok.Kind == scanner.End {
+				return nil
+			}
+			if keyTok.Kind != scanner.String {
+				return &Error{Type: ErrStructureDict, Msg: fmt.Sprintf("dictionary key at offset %d is not a bencode string", keyTok.Offset)}
+			}
+			if err := c.value(keyTok); err != nil {
+				return err
+			}
+			key := c.data[keyTok.ValueOffset : keyTok.ValueOffset+keyTok.ValueLen]
+			if _, dup := seen[string(key)]; dup {
+				c.report(keyTok.Offset, ErrStructureDictKeyDup, fmt.Sprintf("key %q", key))
+			} else if prevKey != nil && CompareKeys(prevKey, key) > 0 {
+				c.report(keyTok.Offset, ErrStructureDictKeySort, fmt.Sprintf("key %q is not lexicographically after %q", key, prevKey))
+			}
+			seen[string(key)] = struct{}{}
+			prevKey = key
+
+			valTok, err := c.scan.Next()
+			if err != nil {
+				return scanError(err)
+			}
+			if valTok.Kind == scanner.End {
+				return &Error{Type: ErrStructureDictValue, Msg: fmt.Sprintf("missing value for key %q", key), FieldName: string(key)}
+			}
+			if err := c.value(valTok); err != nil {
+				return err
+			}
+		}
+	default:
+		return &Error{Type: ErrSyntaxUnexpectedToken, Msg: fmt.Sprintf("unexpected %s at offset %d", tok.Kind, tok.Offset)}
+	}
+}
+
+// checkMinimal reports an error, as the Decoder does, if the string token
+// tok has a length with leading zeros or the integer token tok has leading
+// zeros or is a negative zero.
+func checkMinimal(data []byte, tok scanner.Token) error {
+	switch tok.Kind {
+	case scanner.String:
+		if digits := data[tok.Offset : tok.ValueOffset-1]; len(digits) > 1 && digits[0] == '0' {
+			return &Error{Type: ErrSyntaxStringLength, Msg: fmt.Sprintf("invalid string length format (leading zero): %s", digits)}
+		}
+	case scanner.Integer:
+		digits := data[tok.ValueOffset : tok.ValueOffset+tok.ValueLen]
+		switch unsigned := bytes.TrimPrefix(digits, []byte("-")); {
+		case string(digits) == "-0":
+			return &Error{Type: ErrSyntaxInteger, Msg: "invalid integer format: -0"}
+		case len(unsigned) > 1 && unsigned[0] == '0':
+			return &Error{Type: ErrSyntaxInteger, Msg: fmt.Sprintf("invalid integer format (leading zero): %s", digits)}
+		}
+	}
+	return nil
+}
+
+// scanError converts an error from the scanner package into an *Error.
+func scanError(err error) error {
+	if errors.Is(err, scanner.ErrUnexpectedEOF) {
+		return &Error{Type: ErrSyntaxEOF, Msg: err.Error(), WrappedErr: ErrUnexpectedEOF}
+	}
+	return &Error{Type: ErrSyntax, Msg: "malformed input", WrappedErr: err}
+}
@@ -0,0 +1,124 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCheckCanonical(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Violations
+	}{
+		{
+			name:  "canonical",
+			input: "d1:ai1e1:bli-2e0:ee",
+		},
+		{
+			name:  "integer leading zero",
+			input: "i007e",
+			expected: Violations{
+				{Offset: 1, Type: ErrSyntaxInteger, Msg: `"007" is not minimal, want "7"`},
+			},
+		},
+		{
+			name:  "negative zero",
+			input: "li-0ee",
+			expected: Violations{
+				{Offset: 2, Type: ErrSyntaxInteger, Msg: `"-0" is not minimal, want "0"`},
+			},
+		},
+		{
+			name:  "string length leading zero",
+			input: "04:spam",
+			expected: Violations{
+				{Offset: 0, Type: ErrSyntaxStringLength, Msg: `"04" is not minimal, want "4"`},
+			},
+		},
+		{
+			name:  "unsorted and duplicate keys",
+			input: "d1:bi1e1:ai2e1:ai3ee",
+			expected: Violations{
+				{Offset: 7, Type: ErrStructureDictKeySort, Msg: `key "a" is not lexicographically after "b"`},
+				{Offset: 13, Type: ErrStructureDictKeyDup, Msg: `key "a"`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CheckCanonical([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("CheckCanonical() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("CheckCanonical() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheckCanonicalErrors(t *testing.T) {
+	for _, input := range []string{"", "i12", "l", "d1:a", "x", "i1ei2e", "4:ab", "di1ei2ee"} {
+		if _, err := CheckCanonical([]byte(input)); err == nil {
+			t.Errorf("CheckCanonical(%q) expected an error", input)
+		}
+	}
+}
+
+func TestEncoderRequireCanonical(t *testing.T) {
+	var b bytes.Buffer
+	enc := NewEncoder(&b)
+	enc.RequireCanonical()
+
+	if err := enc.Encode(map[string]any{"raw": RawMessage("d1:ai1ee")}); err != nil {
+		t.Fatalf("Encode() canonical raw error = %v", err)
+	}
+
+	err := enc.Encode(RawMessage("d1:bi01e1:ai2ee"))
+	var bErr *Error
+	if !errors.As(err, &bErr) || bErr.Type != ErrEncodeNonCanonical {
+		t.Fatalf("Encode() error = %v, want %q", err, ErrEncodeNonCanonical)
+	}
+	var violations Violations
+	if !errors.As(err, &violations) || len(violations) != 2 {
+		t.Errorf("Encode() violations = %v, want 2", violations)
+	}
+}
+
+func TestCompareKeys(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "a", b: "a", want: 0},
+		{a: "", b: "a", want: -1},
+		{a: "a", b: "aa", want: -1},
+		{a: "B", b: "a", want: -1},
+		{a: "\x7f", b: "\x80", want: -1},
+		{a: "\xff", b: "z", want: 1},
+	}
+	for _, tt := range tests {
+		if got := CompareKeys([]byte(tt.a), []byte(tt.b)); got != tt.want {
+			t.Errorf("CompareKeys(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	// The Encoder writes keys in the same order.
+	keys := map[string]int{"\xff": 1, "z": 2, "B": 3, "": 4, "aa": 5, "a": 6}
+	encoded, err := Marshal(keys)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	entries, err := rawDictEntries(encoded)
+	if err != nil {
+		t.Fatalf("rawDictEntries() error = %v", err)
+	}
+	for i := 1; i < len(entries); i++ {
+		if CompareKeys([]byte(entries[i-1].key), []byte(entries[i].key)) >= 0 {
+			t.Errorf("Marshal() wrote %q before %q", entries[i-1].key, entries[i].key)
+		}
+	}
+}
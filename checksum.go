@@ -0,0 +1,48 @@
+package bencode
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+)
+
+// SHA1Sum returns the SHA-1 hash of data, which must hold exactly one
+// bencode value acceptable to Index, and so to the Decoder, such as a
+// RawMessage returned by Document.Get. The hash is taken over the bytes as they are, which is what
+// info hashes and BEP 44 immutable targets are defined on; checking them
+// first catches truncated or concatenated values that would otherwise hash
+// without complaint.
+func SHA1Sum(data []byte) ([sha1.Size]byte, error) {
+	if _, err := Index(data); err != nil {
+		return [sha1.Size]byte{}, err
+	}
+	return sha1.Sum(data), nil
+}
+
+// SHA256Sum is like SHA1Sum but returns the SHA-256 hash, as used by
+// BitTorrent v2 info hashes.
+func SHA256Sum(data []byte) ([sha256.Size]byte, error) {
+	if _, err := Index(data); err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(data), nil
+}
+
+// SHA1Sum returns the SHA-1 hash of the encoded value at path, as found by
+// Get. For a metainfo file, doc.SHA1Sum("info") is its info hash.
+func (doc *Document) SHA1Sum(path ...string) ([sha1.Size]byte, error) {
+	v, err := doc.Get(path...)
+	if err != nil {
+		return [sha1.Size]byte{}, err
+	}
+	return sha1.Sum(v), nil
+}
+
+// SHA256Sum returns the SHA-256 hash of the encoded value at path, as found
+// by Get.
+func (doc *Document) SHA256Sum(path ...string) ([sha256.Size]byte, error) {
+	v, err := doc.Get(path...)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(v), nil
+}
@@ -0,0 +1,44 @@
+package bencode
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+func TestChecksums(t *testing.T) {
+	data := []byte("d4:infod6:lengthi12e4:name4:spame3:seqi1ee")
+	info := []byte("d6:lengthi12e4:name4:spame")
+
+	if got, err := SHA1Sum(info); err != nil || got != sha1.Sum(info) {
+		t.Errorf("SHA1Sum() = %x, %v, want %x", got, err, sha1.Sum(info))
+	}
+	if got, err := SHA256Sum(info); err != nil || got != sha256.Sum256(info) {
+		t.Errorf("SHA256Sum() = %x, %v, want %x", got, err, sha256.Sum256(info))
+	}
+
+	doc, err := Index(data)
+	if err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	if got, err := doc.SHA1Sum("info"); err != nil || got != sha1.Sum(info) {
+		t.Errorf("Document.SHA1Sum(info) = %x, %v, want %x", got, err, sha1.Sum(info))
+	}
+	if got, err := doc.SHA256Sum("info"); err != nil || got != sha256.Sum256(info) {
+		t.Errorf("Document.SHA256Sum(info) = %x, %v, want %x", got, err, sha256.Sum256(info))
+	}
+	var bErr *Error
+	if _, err := doc.SHA1Sum("missing"); !errors.As(err, &bErr) || bErr.Type != ErrPathNotFound {
+		t.Errorf("Document.SHA1Sum(missing) error = %v, want %q", err, ErrPathNotFound)
+	}
+
+	for _, bad := range []string{"d6:lengthi12e", "i1ei2e", "d1:bi1e1:ai2ee", "d4:infod1:ai01eee", "d4:info01:ae"} {
+		if _, err := SHA1Sum([]byte(bad)); err == nil {
+			t.Errorf("SHA1Sum(%q) succeeded, want error", bad)
+		}
+		if _, err := SHA256Sum([]byte(bad)); err == nil {
+			t.Errorf("SHA256Sum(%q) succeeded, want error", bad)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package bencode
+
+import (
+	"bufio"
+	"bytes"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// Coverage describes how the dictionaries of a decoded document matched the
+// structs they were decoded into. Each entry is a dotted path, such as
+// "info.files.1.length", in the form of Error.Path.
+type Coverage struct {
+	// Consumed lists the keys of the input that a struct field received,
+	// including those gathered by a field with the rest option.
+	Consumed []string
+	// Ignored lists the keys of the input that no struct field received.
+	Ignored []string
+	// Unfilled lists the struct fields, by their keys, that the input did
+	// not have a key for.
+	Unfilled []string
+}
+
+// UnmarshalStrictInto decodes data into the value pointed to by v as
+// UnmarshalStrict does, and also reports, for every dictionary decoded into
+// a struct, which of its keys were consumed or ignored and which fields were
+// left unfilled. Nothing in the report is an error: it is meant for tools
+// such as linters and compatibility dashboards that follow how a protocol's
+// messages drift from the structs that model them. The paths are sorted.
+//
+// Struct fields are followed into nested structs, and through pointers,
+// slices, arrays and the values of maps. Fields holding an Optional, a
+// database/sql Null type or a union are not followed further.
+func UnmarshalStrictInto(data []byte, v any) (*Coverage, error) {
+	elem, err := decodeTarget(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := &Decoder{r: bufio.NewReaderSize(bytes.NewReader(data), len(data))}
+	dec.presize(data)
+	if err := dec.guard.acquire("Decode"); err != nil {
+		return nil, err
+	}
+	defer dec.guard.release()
+
+	var decoded any
+	if _, err := dec.decodeRoot(func(d any) error {
+		decoded = d
+		return dec.assignDecodedToValue(elem, d)
+	}); err != nil {
+		return nil, err
+	}
+	if err := dec.ExpectEOF(); err != nil {
+		return nil, err
+	}
+
+	c := &Coverage{}
+	c.walk(elem.Type(), decoded, "")
+	slices.Sort(c.Consumed)
+	slices.Sort(c.Ignored)
+	slices.Sort(c.Unfilled)
+	return c, nil
+}
+
+// walk records the coverage of the decoded value generic by the type typ.
+func (c *Coverage) walk(typ reflect.Type, generic any, path string) {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == rawMessageType || typ.Implements(optionalType) || isSQLNull(typ) {
+		return
+	}
+	switch typ.Kind() {
+	case reflect.Struct:
+		if dict, ok := generic.(map[string]any); ok {
+			c.walkStruct(typ, dict, path)
+		}
+	case reflect.Slice, reflect.Array:
+		if list, ok := generic.([]any); ok {
+			for i, elem := range list {
+				c.walk(typ.Elem(), elem, JoinPath(path, strconv.Itoa(i)))
+			}
+		}
+	case reflect.Map:
+		if dict, ok := generic.(map[string]any); ok {
+			for key, elem := range dict {
+				c.walk(typ.Elem(), elem, JoinPath(path, key))
+			}
+		}
+	}
+}
+
+// walkStruct records the coverage of dict by the fields of the struct type
+// typ, including those of inline structs.
+func (c *Coverage) walkStruct(typ reflect.Type, dict map[string]any, path string) {
+	claimed := make(map[string]bool)
+	rest := c.walkFields(typ, dict, path, claimed)
+	for _, key := range slices.Sorted(maps.Keys(dict)) {
+		switch {
+		case claimed[key]:
+		case rest:
+			c.Consumed = append(c.Consumed, JoinPath(path, key))
+		default:
+			c.Ignored = append(c.Ignored, JoinPath(path, key))
+		}
+	}
+}
+
+// walkFields records the fields of typ and the keys of dict they consume,
+// marking those keys in claimed. It reports whether a rest field, or an
+// inline field that is not a struct, takes the keys left unclaimed.
+func (c *Coverage) walkFields(typ reflect.Type, dict map[string]any, path string, claimed map[string]bool) (rest bool) {
+	for _, f := range getCachedStructInfo(typ) {
+		fieldType := f.typ
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		switch {
+		case f.inline && fieldType.Kind() == reflect.Struct && !fieldType.Implements(optionalType):
+			if c.walkFields(fieldType, dict, path, claimed) {
+				rest = true
+			}
+			continue
+		case f.mergesEntries():
+			rest = true
+			continue
+		}
+		value, ok := dict[f.bencodeTag]
+		if !ok {
+			c.Unfilled = append(c.Unfilled, JoinPath(path, f.bencodeTag))
+			continue
+		}
+		claimed[f.bencodeTag] = true
+		c.Consumed = append(c.Consumed, JoinPath(path, f.bencodeTag))
+		if f.union == nil {
+			c.walk(f.typ, value, JoinPath(path, f.bencodeTag))
+		}
+	}
+	return rest
+}
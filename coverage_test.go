@@ -0,0 +1,56 @@
+package bencode
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshalStrictInto(t *testing.T) {
+	type file struct {
+		Length int64    `bencode:"length"`
+		Path   []string `bencode:"path"`
+	}
+	type common struct {
+		Comment string `bencode:"comment"`
+	}
+	type torrent struct {
+		Common   common `bencode:",inline"`
+		Announce string `bencode:"announce"`
+		Info     *struct {
+			Files []file           `bencode:"files"`
+			Name  string           `bencode:"name"`
+			Extra map[string]any   `bencode:",rest"`
+			Nodes Optional[string] `bencode:"nodes"`
+		} `bencode:"info"`
+		Sources map[string]file `bencode:"sources"`
+		Private int64           `bencode:"private"`
+	}
+	input := "d8:announce3:url7:comment2:hi10:created by3:cli" +
+		"4:infod5:filesld6:lengthi1e4:pathl1:aeed6:lengthi2e6:md5sum1:xee4:name1:n6:x-herei1ee" +
+		"7:sourcesd1:sd4:pathl1:beeee"
+
+	var got torrent
+	c, err := UnmarshalStrictInto([]byte(input), &got)
+	if err != nil {
+		t.Fatalf("UnmarshalStrictInto() error = %v", err)
+	}
+	want := &Coverage{
+		Consumed: []string{"announce", "comment", "info", "info.files", "info.files.0.length", "info.files.0.path", "info.files.1.length", "info.name", "info.x-here", "sources", "sources.s.path"},
+		Ignored:  []string{"created by", "info.files.1.md5sum"},
+		Unfilled: []string{"info.files.1.path", "info.nodes", "private", "sources.s.length"},
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("UnmarshalStrictInto() coverage =\n%+v\nwant\n%+v", c, want)
+	}
+	if got.Announce != "url" || got.Common.Comment != "hi" || got.Info.Extra["x-here"] != int64(1) || got.Sources["s"].Path[0] != "b" {
+		t.Errorf("UnmarshalStrictInto() decoded %+v", got)
+	}
+
+	if _, err := UnmarshalStrictInto([]byte("de1:x"), &got); !errors.Is(err, ErrTrailingData) {
+		t.Errorf("UnmarshalStrictInto() with trailing data error = %v, want %q", err, ErrTrailingData)
+	}
+	if _, err := UnmarshalStrictInto([]byte("d8:announcei1ee"), &got); !errors.Is(err, ErrUnmarshalType) {
+		t.Errorf("UnmarshalStrictInto() of mismatched type error = %v, want %q", err, ErrUnmarshalType)
+	}
+}
@@ -5,9 +5,16 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"log/slog"
+	"maps"
+	"math"
 	"reflect"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 	"unicode"
 )
 
@@ -44,6 +51,8 @@ const (
 	ErrUnmarshalToInvalid ErrorType = "unmarshal to invalid Go type"
 	// ErrUnmarshalMapKey indicates the Go map's key type is not string.
 	ErrUnmarshalMapKey ErrorType = "unmarshal map key type error"
+	// ErrUnmarshalMissingField indicates a struct field tagged as required is absent from the dictionary.
+	ErrUnmarshalMissingField ErrorType = "unmarshal missing required field"
 
 	// ErrUsage indicates incorrect usage of the bencode API.
 	ErrUsage ErrorType = "API usage error"
@@ -60,15 +69,99 @@ var (
 )
 
 // Unmarshal parses the bencode-encoded data and stores the result
-// in the value pointed to by v. If v is nil or not a pointer,
-// Unmarshal returns an ErrUsage.
+// in the value pointed to by v. If v is nil or not a pointer, or a
+// reflect.Value that cannot be set, Unmarshal returns an ErrUsage.
+//
+// A bencode string may be stored in a string, a []byte or a byte array such
+// as [20]byte. A []byte receives the string's bytes without copying them
+// again; a byte array must have exactly the string's length.
+//
+// A pointer, such as *int64 or *string, is allocated when its key is present
+// and left nil otherwise, as is an Optional left absent. The Null types of
+// database/sql, such as sql.NullInt64 and sql.NullString, are likewise valid
+// only when their key is present; they accept the bencode kind matching the
+// value they hold.
 func Unmarshal(data []byte, v any) error {
 	dec := &Decoder{r: bufio.NewReaderSize(bytes.NewReader(data), len(data))}
+	dec.presize(data)
 	return dec.Decode(v)
 }
 
+// UnmarshalPrefix decodes the bencode value at the start of data into the
+// value pointed to by v, as Unmarshal does, and returns the bytes that follow
+// it untouched. It suits protocols that send a bencoded header followed by a
+// raw payload, such as ut_metadata data messages carrying a piece of the
+// info dictionary after the message dictionary. On error, rest is nil.
+func UnmarshalPrefix(data []byte, v any) (rest []byte, err error) {
+	dec := &Decoder{r: bufio.NewReaderSize(bytes.NewReader(data), len(data))}
+	dec.presize(data)
+	if err := dec.Decode(v); err != nil {
+		return nil, err
+	}
+	return data[dec.offset:], nil
+}
+
+// UnmarshalAny parses the bencode-encoded data and returns it as the generic
+// tree described at Decoder.DecodeValue: []byte, int64, []any and
+// map[string]any. It is the shortcut for callers that do not know the shape
+// of the data in advance, and avoids decoding into an any through a pointer.
+func UnmarshalAny(data []byte) (any, error) {
+	dec := &Decoder{r: bufio.NewReaderSize(bytes.NewReader(data), len(data))}
+	dec.presize(data)
+	return dec.DecodeValue()
+}
+
+// DecodeAt decodes the bencode value that starts at offset off of r into the
+// value pointed to by v, for values embedded in larger files such as resume
+// bundles or caches. It returns the number of bytes the value occupies, so
+// that a following value can be read at off+n. On error, n is the number of
+// bytes consumed before the problem was detected.
+func DecodeAt(r io.ReaderAt, off int64, v any) (n int64, err error) {
+	dec := NewDecoder(io.NewSectionReader(r, off, math.MaxInt64-off))
+	err = dec.Decode(v)
+	return dec.offset, err
+}
+
+// A Decoder reads and decodes bencode values from an input stream.
+//
+// A Decoder is not safe for concurrent use by multiple goroutines. Callers
+// sharing one must serialise calls to Decode and DecodeValue themselves;
+// DetectConcurrentUse can be enabled to catch violations.
 type Decoder struct {
-	r *bufio.Reader
+	r      *bufio.Reader
+	guard  useGuard
+	stats  DecodeStats
+	depth  int
+	limits decodeLimits
+
+	collectErrors  bool
+	alloc          func(n int) []byte
+	onWarning      func(Warning)
+	decodeHooks    []DecodeHookFunc
+	utf8Mode       UTF8Mode
+	keys           map[string]string // interned dictionary keys, when enabled
+	sizes          []int             // container sizes recorded by presize
+	useNumber      bool
+	nonMinimalInts bool // accept integers with leading zeros or a negative zero
+	sawVerbatim    bool // the current value holds a verbatimInt
+	bytesViews     bool // generic dictionary values are strings where valid UTF-8
+	unsortedKeys   bool // accept dictionary keys out of order
+	emptyListGaps  bool // an empty list for a non-list field means absent
+	trailingSpace  bool // ExpectEOF skips ASCII whitespace
+	metrics        *Metrics
+	logger         *slog.Logger
+
+	pending   int64 // unread bytes of the string opened by StringReader
+	readerGen int   // identifies the current StringReader
+
+	tee     io.Writer // receives consumed input, when set by TeeRaw
+	teeErr  error     // first error writing to tee
+	teeHash hash.Hash // hashes each value's input, when set by TeeHash
+
+	offset      int64  // bytes consumed from r
+	contextSize int    // bytes of error context to capture, 0 to disable
+	history     []byte // most recently consumed bytes, when contextSize > 0
+	readErr     error  // error returned by r, after which nothing more is read
 }
 
 // NewDecoder returns a new decoder that reads from r.
@@ -78,25 +171,94 @@ func NewDecoder(r io.Reader) *Decoder {
 	return &Decoder{r: bufio.NewReader(r)}
 }
 
+// NewDecoderSize returns a new decoder that reads from r through a buffer of
+// at least size bytes. Tokens are not limited by the buffer size: strings and
+// integers longer than the buffer are read across as many fills as needed.
+// A larger buffer means fewer reads from r; if r is already a *bufio.Reader
+// of at least size bytes, it is used directly.
+func NewDecoderSize(r io.Reader, size int) *Decoder {
+	return &Decoder{r: bufio.NewReaderSize(r, size)}
+}
+
+// DetectConcurrentUse makes Decode and DecodeValue return an ErrUsage error,
+// without consuming input, when called while another call on the same
+// Decoder is still in progress. It must be called before the Decoder is
+// shared between goroutines.
+func (d *Decoder) DetectConcurrentUse() {
+	d.guard.enabled = true
+}
+
+// CollectErrors makes struct decoding continue past field-level errors, such
+// as type mismatches and missing required fields, instead of stopping at the
+// first one. When a struct has more than one failing field, Decode returns an
+// *Error whose WrappedErr is an errors.Join of the individual field errors,
+// so every problem can be reported at once. Fields without errors are still
+// populated. Slice elements and map values are treated the same way, so that
+// every failing element of a list of dictionaries, or value of a dictionary
+// of dictionaries, is reported.
+func (d *Decoder) CollectErrors() {
+	d.collectErrors = true
+}
+
+// UseAllocator makes the Decoder obtain the buffer for each decoded string
+// from alloc instead of allocating it. alloc must return a slice of exactly
+// n bytes; its contents are overwritten. This lets high-throughput callers
+// carve strings out of pooled arenas and release them together once the
+// decoded values are no longer referenced. Strings copied into Go string
+// destinations do not retain the buffer. Passing nil restores the default.
+func (d *Decoder) UseAllocator(alloc func(n int) []byte) {
+	d.alloc = alloc
+}
+
 // Decode reads the next bencode-encoded value from its input
 // and stores it in the value pointed to by v.
 //
+// v may also be a settable reflect.Value, such as one obtained from
+// reflect.New(t).Elem() or from a field of an addressable struct, for
+// callers that build destinations dynamically; the value is stored into it
+// directly.
+//
 // See the documentation for Unmarshal for details about the
 // conversion of bencode into a Go value.
 func (d *Decoder) Decode(v any) error {
-	val := reflect.ValueOf(v)
-	if val.Kind() != reflect.Ptr || val.IsNil() {
-		return &Error{Type: ErrUsage, Msg: fmt.Sprintf("expected a non-nil pointer, got %T", v)}
+	elem, err := decodeTarget(v)
+	if err != nil {
+		return err
 	}
 
-	elem := val.Elem()
-
-	decoded, err := d.decode()
-	if err != nil {
+	if err := d.guard.acquire("Decode"); err != nil {
 		return err
 	}
+	defer d.guard.release()
 
-	return d.assignDecodedToValue(elem, decoded)
+	_, err = d.decodeRoot(func(decoded any) error {
+		return d.assignDecodedToValue(elem, decoded)
+	})
+	return err
+}
+
+// decodeTarget returns the value Decode stores into for v, or an ErrUsage
+// error explaining how to pass a valid destination.
+func decodeTarget(v any) (reflect.Value, error) {
+	if rv, ok := v.(reflect.Value); ok {
+		if !rv.IsValid() {
+			return reflect.Value{}, &Error{Type: ErrUsage, Msg: "Decode(invalid reflect.Value): pass a settable value, e.g. reflect.New(t).Elem()"}
+		}
+		if !rv.CanSet() {
+			return reflect.Value{}, &Error{Type: ErrUsage, Msg: fmt.Sprintf("Decode(reflect.Value of type %s): value is not settable; pass reflect.New(t).Elem() or the Elem of a pointer", rv.Type())}
+		}
+		return rv, nil
+	}
+	val := reflect.ValueOf(v)
+	switch {
+	case v == nil:
+		return reflect.Value{}, &Error{Type: ErrUsage, Msg: "Decode(nil): expected a non-nil pointer, e.g. &v"}
+	case val.Kind() != reflect.Ptr:
+		return reflect.Value{}, &Error{Type: ErrUsage, Msg: fmt.Sprintf("Decode(non-pointer %T): expected a non-nil pointer; pass &v instead of v", v)}
+	case val.IsNil():
+		return reflect.Value{}, &Error{Type: ErrUsage, Msg: fmt.Sprintf("Decode(nil %T): expected a non-nil pointer; allocate the value first, e.g. with new(%s)", v, val.Type().Elem())}
+	}
+	return val.Elem(), nil
 }
 
 // DecodeValue decodes the next bencode value from the stream
@@ -110,9 +272,53 @@ func (d *Decoder) Decode(v any) error {
 // This method allows direct access to the decoded bencode structure,
 // which can be useful for custom processing or when the target Go type
 // is not known in advance. The caller is responsible for appropriate
-// type assertions on the returned value.
+// type assertions on the returned value. UnmarshalAny does the same for a
+// byte slice.
 func (d *Decoder) DecodeValue() (any, error) {
-	return d.decode()
+	if err := d.guard.acquire("DecodeValue"); err != nil {
+		return nil, err
+	}
+	defer d.guard.release()
+	decoded, err := d.decodeRoot(nil)
+	if d.sawVerbatim {
+		decoded = plainGeneric(decoded)
+	}
+	if d.bytesViews {
+		decoded = withBytesViews(decoded)
+	}
+	return decoded, err
+}
+
+// decodeRoot decodes the next top-level value, passes it to assign if
+// non-nil, and reports the outcome to the configured metrics and logger.
+func (d *Decoder) decodeRoot(assign func(decoded any) error) (any, error) {
+	start, began := d.offset, time.Now()
+	before := d.stats.Strings + d.stats.Integers + d.stats.Lists + d.stats.Dicts
+
+	d.sawVerbatim = false
+	if d.teeHash != nil {
+		d.teeHash.Reset()
+	}
+	d.limits.elements = 0
+	d.limits.bytes = 0
+	d.limits.path = d.limits.path[:0]
+	decoded, err := d.decode()
+	if err != nil {
+		err = d.annotate(err)
+	} else if assign != nil {
+		err = assign(decoded)
+	}
+	if err == nil && d.teeErr != nil {
+		err = d.teeErr
+	}
+
+	d.recordDecode(start, err)
+	values := d.stats.Strings + d.stats.Integers + d.stats.Lists + d.stats.Dicts - before
+	d.traceDecode(start, began, values, kindOfDecoded(decoded), err)
+	if err != nil {
+		return nil, err
+	}
+	return decoded, nil
 }
 
 // assignDecodedToValue populates 'destVal' with 'srcData'.
@@ -126,6 +332,20 @@ func (d *Decoder) assignDecodedToValue(destVal reflect.Value, srcData any) error
 		return &Error{Type: ErrUnmarshalToInvalid, Msg: fmt.Sprintf("cannot set destination value of type %s", destVal.Type())}
 	}
 
+	if d.sawVerbatim && destVal.Type() != rawMessageType && (destVal.Kind() == reflect.Interface || len(d.decodeHooks) > 0) {
+		srcData = plainGeneric(srcData)
+	}
+	if len(d.decodeHooks) > 0 && srcData != nil {
+		var err error
+		if srcData, err = d.runDecodeHooks(destVal.Type(), srcData); err != nil {
+			return err
+		}
+		if srcData != nil && reflect.TypeOf(srcData).AssignableTo(destVal.Type()) && kindOfDecoded(srcData) == KindInvalid {
+			destVal.Set(reflect.ValueOf(srcData))
+			return nil
+		}
+	}
+
 	if srcData == nil {
 		switch destVal.Kind() {
 		case reflect.Interface, reflect.Slice, reflect.Map, reflect.Ptr:
@@ -144,15 +364,52 @@ func (d *Decoder) assignDecodedToValue(destVal reflect.Value, srcData any) error
 
 	srcType := reflect.TypeOf(srcData)
 
+	if destVal.Type() == rawMessageType {
+		raw, err := Marshal(srcData)
+		if err != nil {
+			return &Error{Type: ErrInternal, Msg: "re-encoding raw message", WrappedErr: err}
+		}
+		destVal.SetBytes(raw)
+		return nil
+	}
+	if v, ok := srcData.(verbatimInt); ok {
+		srcData = v.n // only RawMessage keeps the original form
+	}
+	if o, ok := asOptionalDecoder(destVal); ok {
+		return o.decodeOptional(func(v reflect.Value) error { return d.assignDecodedToValue(v, srcData) })
+	}
+	if destVal.Type() == uint64StringType {
+		u, err := parseUint64String(srcData)
+		if err != nil {
+			return err
+		}
+		destVal.SetUint(uint64(u))
+		return nil
+	}
+	if isSQLNull(destVal.Type()) {
+		return d.scanSQLNull(destVal, srcData)
+	}
+
 	switch destVal.Kind() {
 	case reflect.String:
 		byteSlice, ok := srcData.([]byte)
 		if !ok {
 			return &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("expected []byte for string destination, got %T", srcData)}
 		}
-		destVal.SetString(string(byteSlice))
+		str, err := d.decodeString(byteSlice)
+		if err != nil {
+			return err
+		}
+		destVal.SetString(str)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		intVal, ok := srcData.(int64)
+		if n, isNumber := srcData.(Number); isNumber {
+			var err error
+			if intVal, err = numberToInt(n, destVal.Type()); err != nil {
+				return err
+			}
+			ok = true
+		}
 		if !ok {
 			return &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("expected int64 for numeric type %s, got %T", destVal.Type(), srcData)}
 		}
@@ -161,6 +418,17 @@ func (d *Decoder) assignDecodedToValue(destVal reflect.Value, srcData any) error
 		}
 		destVal.SetInt(intVal)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, isNumber := srcData.(Number); isNumber {
+			uintVal, err := numberToUint(n, destVal.Type())
+			if err != nil {
+				return err
+			}
+			if destVal.OverflowUint(uintVal) {
+				return &Error{Type: ErrUnmarshalOverflow, Msg: fmt.Sprintf("value %d overflows type %s", uintVal, destVal.Type())}
+			}
+			destVal.SetUint(uintVal)
+			return nil
+		}
 		intVal, ok := srcData.(int64) // Bencode integers are signed
 		if !ok {
 			return &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("expected int64 for numeric type %s, got %T", destVal.Type(), srcData)}
@@ -174,6 +442,10 @@ func (d *Decoder) assignDecodedToValue(destVal reflect.Value, srcData any) error
 		}
 		destVal.SetUint(uintVal)
 	case reflect.Slice:
+		if byteSlice, ok := srcData.([]byte); ok && destVal.Type().Elem().Kind() == reflect.Uint8 {
+			destVal.SetBytes(byteSlice)
+			return nil
+		}
 		srcSlice, ok := srcData.([]any)
 		if !ok {
 			return &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("expected []any for slice destination, got %T", srcData)}
@@ -181,20 +453,46 @@ func (d *Decoder) assignDecodedToValue(destVal reflect.Value, srcData any) error
 		sliceType := destVal.Type()
 		elemType := sliceType.Elem()
 		newSlice := reflect.MakeSlice(sliceType, len(srcSlice), len(srcSlice))
+		var elemErrs []error
 		for i, item := range srcSlice {
 			sliceElemVal := reflect.New(elemType).Elem()
 			if err := d.assignDecodedToValue(sliceElemVal, item); err != nil {
 				// err is already *Error
-				return &Error{
+				elemErr := &Error{
 					Type:       err.(*Error).Type, // Propagate original error type
 					Msg:        fmt.Sprintf("decoding slice element %d", i),
 					WrappedErr: err,
 					FieldName:  strconv.Itoa(i),
 				}
+				if !d.collectErrors {
+					return elemErr
+				}
+				elemErrs = append(elemErrs, elemErr)
 			}
 			newSlice.Index(i).Set(sliceElemVal)
 		}
 		destVal.Set(newSlice)
+		return joinErrors(elemErrs, fmt.Sprintf("%d element errors in %s", len(elemErrs), sliceType))
+	case reflect.Array:
+		if srcList, ok := srcData.([]any); ok && destVal.Type().Elem().Kind() == reflect.Uint8 {
+			if len(srcList) != destVal.Len() {
+				return &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("list of %d elements does not fit %s", len(srcList), destVal.Type())}
+			}
+			for i, item := range srcList {
+				if err := d.assignDecodedToValue(destVal.Index(i), item); err != nil {
+					return &Error{Type: err.(*Error).Type, Msg: fmt.Sprintf("decoding array element %d", i), WrappedErr: err, FieldName: strconv.Itoa(i)}
+				}
+			}
+			return nil
+		}
+		byteSlice, ok := srcData.([]byte)
+		if !ok || destVal.Type().Elem().Kind() != reflect.Uint8 {
+			return &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("expected []byte for byte array destination %s, got %T", destVal.Type(), srcData)}
+		}
+		if len(byteSlice) != destVal.Len() {
+			return &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("string of %d bytes does not fit %s", len(byteSlice), destVal.Type())}
+		}
+		copy(destVal.Bytes(), byteSlice)
 	case reflect.Map:
 		if destVal.Type().Key().Kind() != reflect.String {
 			return &Error{Type: ErrUnmarshalMapKey, Msg: fmt.Sprintf("map keys must be strings for destination type %s, got key type %s", destVal.Type(), destVal.Type().Key())}
@@ -203,33 +501,60 @@ func (d *Decoder) assignDecodedToValue(destVal reflect.Value, srcData any) error
 		if !ok {
 			return &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("expected map[string]any for map destination, got %T", srcData)}
 		}
+		if len(d.decodeHooks) == 0 && d.assignStringMap(destVal, srcMap) {
+			return nil
+		}
 		mapType := destVal.Type()
 		elemType := mapType.Elem()
-		newMap := reflect.MakeMap(mapType)
-		for key, item := range srcMap {
+		newMap := reflect.MakeMapWithSize(mapType, len(srcMap))
+		var elemErrs []error
+		// Keys are visited in input order so that the error reported, or
+		// the order of those collected, does not vary from run to run.
+		for _, key := range slices.Sorted(maps.Keys(srcMap)) {
 			mapElemVal := reflect.New(elemType).Elem()
+			item := srcMap[key]
+			if d.bytesViews && elemType.Kind() == reflect.Interface {
+				item = stringView(item)
+			}
 			if err := d.assignDecodedToValue(mapElemVal, item); err != nil {
 				// err is already *Error
-				return &Error{
+				elemErr := &Error{
 					Type:       err.(*Error).Type,
 					Msg:        fmt.Sprintf("decoding map value for key %q", key),
 					WrappedErr: err,
 					FieldName:  key,
 				}
+				if !d.collectErrors {
+					return elemErr
+				}
+				elemErrs = append(elemErrs, elemErr)
 			}
-			newMap.SetMapIndex(reflect.ValueOf(key), mapElemVal)
+			newMap.SetMapIndex(reflect.ValueOf(key).Convert(mapType.Key()), mapElemVal)
 		}
 		destVal.Set(newMap)
+		return joinErrors(elemErrs, fmt.Sprintf("%d value errors in %s", len(elemErrs), mapType))
 	case reflect.Struct:
 		srcMap, ok := srcData.(map[string]any)
 		if !ok {
 			return &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("expected map[string]any for struct destination %s, got %T", destVal.Type(), srcData)}
 		}
 		return d.populateStruct(destVal, srcMap)
+	case reflect.Pointer:
+		elem := destVal
+		if destVal.IsNil() {
+			elem = reflect.New(destVal.Type().Elem())
+		}
+		if err := d.assignDecodedToValue(elem.Elem(), srcData); err != nil {
+			return err
+		}
+		destVal.Set(elem)
 	default:
 		if !srcType.AssignableTo(destVal.Type()) {
 			return &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("unhandled destination type %s (source type %s)", destVal.Type(), srcType)}
 		}
+		if d.bytesViews && destVal.Kind() == reflect.Interface {
+			srcData = withBytesViews(srcData)
+		}
 		destVal.Set(reflect.ValueOf(srcData))
 	}
 	return nil
@@ -246,124 +571,289 @@ func (d *Decoder) populateStruct(structVal reflect.Value, dictData map[string]an
 	typ := structVal.Type()
 	cachedFields := getCachedStructInfo(typ)
 
+	var fieldErrs []error
 	for _, fieldInfo := range cachedFields {
 		fieldRuntimeVal := structVal.Field(fieldInfo.index)
 		bencodeValue, exists := dictData[fieldInfo.bencodeTag]
+		if exists && d.emptyListGaps && isEmptyList(bencodeValue) && !acceptsList(fieldInfo) {
+			exists = false
+		}
+		switch {
+		case fieldInfo.inline:
+			bencodeValue, exists = dictData, true
+		case fieldInfo.rest:
+			rest := unclaimedEntries(cachedFields, dictData)
+			bencodeValue, exists = rest, len(rest) > 0
+		}
 
 		if !exists {
+			if fieldInfo.required {
+				err := &Error{
+					Type:      ErrUnmarshalMissingField,
+					Msg:       fmt.Sprintf("missing required field %s (tag %q)", fieldInfo.fieldName, fieldInfo.bencodeTag),
+					FieldName: fieldInfo.bencodeTag,
+				}
+				if !d.collectErrors {
+					return err
+				}
+				fieldErrs = append(fieldErrs, err)
+			}
 			continue
 		}
 
-		if err := d.assignDecodedToValue(fieldRuntimeVal, bencodeValue); err != nil {
+		if d.bytesViews && fieldInfo.typ.Kind() == reflect.Interface {
+			bencodeValue = stringView(bencodeValue)
+		}
+		assign := d.assignDecodedToValue
+		if fieldInfo.union != nil {
+			assign = func(v reflect.Value, src any) error { return d.assignUnion(v, src, fieldInfo) }
+		}
+		if err := assign(fieldRuntimeVal, bencodeValue); err != nil {
+			var fieldErr *Error
 			// Ensure err is *Error before accessing Type
 			bencodeErr, ok := err.(*Error)
 			if !ok {
 				// This case should ideally not happen if assignDecodedToValue always returns *Error
-				return &Error{
+				fieldErr = &Error{
 					Type:       ErrUnmarshalType, // Generic fallback
 					Msg:        fmt.Sprintf("setting field %s (tag %q): unknown error type", fieldInfo.fieldName, fieldInfo.bencodeTag),
 					WrappedErr: err,
 					FieldName:  fieldInfo.bencodeTag,
 				}
+			} else {
+				fieldErr = &Error{
+					Type:       bencodeErr.Type,
+					Msg:        fmt.Sprintf("setting field %s (tag %q)", fieldInfo.fieldName, fieldInfo.bencodeTag),
+					WrappedErr: err, // err is already bencodeErr here
+					FieldName:  fieldInfo.bencodeTag,
+				}
 			}
-			return &Error{
-				Type:       bencodeErr.Type,
-				Msg:        fmt.Sprintf("setting field %s (tag %q)", fieldInfo.fieldName, fieldInfo.bencodeTag),
-				WrappedErr: err, // err is already bencodeErr here
-				FieldName:  fieldInfo.bencodeTag,
+			if !d.collectErrors {
+				return fieldErr
 			}
+			fieldErrs = append(fieldErrs, fieldErr)
 		}
 	}
 
-	return nil
+	if d.onWarning != nil && !slices.ContainsFunc(cachedFields, cachedStructFieldInfo.mergesEntries) {
+		d.warnUnknownFields(typ, cachedFields, dictData)
+	}
+
+	return joinErrors(fieldErrs, fmt.Sprintf("%d field errors in %s", len(fieldErrs), typ))
+}
+
+// joinErrors combines the *Errors collected under CollectErrors: nil for
+// none, the error itself for one, and otherwise an *Error described by msg
+// wrapping all of them, typed after the first.
+func joinErrors(errs []error, msg string) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	default:
+		return &Error{
+			Type:       errs[0].(*Error).Type,
+			Msg:        msg,
+			WrappedErr: errors.Join(errs...),
+		}
+	}
+}
+
+// unclaimedEntries returns the entries of dictData whose keys no field of
+// fields is tagged with, for a field with the rest option.
+func unclaimedEntries(fields []cachedStructFieldInfo, dictData map[string]any) map[string]any {
+	rest := maps.Clone(dictData)
+	for _, f := range fields {
+		if !f.mergesEntries() {
+			delete(rest, f.bencodeTag)
+		}
+	}
+	return rest
+}
+
+// warnUnknownFields raises a WarnUnknownField warning, in key order, for
+// each key of dictData that does not match a field of the struct type typ.
+func (d *Decoder) warnUnknownFields(typ reflect.Type, fields []cachedStructFieldInfo, dictData map[string]any) {
+	for _, key := range slices.Sorted(maps.Keys(dictData)) {
+		_, found := slices.BinarySearchFunc(fields, key, func(f cachedStructFieldInfo, k string) int {
+			return strings.Compare(f.bencodeTag, k)
+		})
+		if !found {
+			d.warn(Warning{Type: WarnUnknownField, Msg: fmt.Sprintf("no field of %s has tag %q", typ, key), FieldName: key})
+		}
+	}
+}
+
+// readStringLength reads the length prefix of a string, including the
+// colon, returning the length and the size of the prefix.
+func (d *Decoder) readStringLength() (length, headerLen int, err error) {
+	lengthString, err := d.readNumber(':', maxLengthDigits)
+	if err != nil {
+		if errors.Is(err, ErrIntegerTooLong) {
+			return 0, 0, &Error{Type: ErrIntegerTooLong, Msg: "string length too long", WrappedErr: err}
+		}
+		if errors.Is(err, io.EOF) {
+			return 0, 0, &Error{Type: ErrSyntaxEOF, Msg: "unterminated string length", WrappedErr: ErrUnexpectedEOF}
+		}
+		return 0, 0, d.readError(err, "reading string length")
+	}
+	digits := lengthString[:len(lengthString)-1]
+	if len(digits) > 1 && digits[0] == '0' {
+		return 0, 0, &Error{Type: ErrSyntaxStringLength, Msg: fmt.Sprintf("invalid string length format (leading zero): %s", digits)}
+	}
+	length, convErr := strconv.Atoi(digits)
+	if convErr != nil {
+		return 0, 0, &Error{Type: ErrSyntaxStringLength, Msg: "invalid string length format", WrappedErr: convErr}
+	}
+	if length < 0 {
+		return 0, 0, &Error{Type: ErrSyntaxStringLength, Msg: fmt.Sprintf("negative string length: %d", length)}
+	}
+	return length, len(lengthString), nil
 }
 
 // decode is the internal recursive decoding function.
 // It parses the next bencode token from the reader and returns its generic Go representation.
 func (d *Decoder) decode() (any, error) {
+	if err := d.failed(); err != nil {
+		return nil, err
+	}
+	if err := d.drainString(); err != nil {
+		return nil, err
+	}
 	next, err := d.r.Peek(1)
 	if err != nil {
 		if errors.Is(err, io.EOF) {
 			return nil, ErrNullRootValue // End of stream before any token
 		}
-		return nil, &Error{Type: ErrSyntaxEOF, Msg: "failed to peek next token", WrappedErr: err}
+		return nil, d.readError(err, "peeking next token")
+	}
+	if err := d.countElement(); err != nil {
+		return nil, err
+	}
+	if err := d.chargeBytes(valueOverhead); err != nil {
+		return nil, err
 	}
 	token := rune(next[0])
 	switch {
 	case unicode.IsDigit(token):
-		lengthString, err := d.r.ReadString(':')
+		length, headerLen, err := d.readStringLength()
 		if err != nil {
-			if errors.Is(err, io.EOF) {
-				return nil, &Error{Type: ErrSyntaxEOF, Msg: "unterminated string length", WrappedErr: ErrUnexpectedEOF}
-			}
-			return nil, &Error{Type: ErrSyntaxStringLength, Msg: "error reading string length", WrappedErr: err}
+			return nil, err
 		}
-		length, convErr := strconv.Atoi(lengthString[:len(lengthString)-1])
-		if convErr != nil {
-			return nil, &Error{Type: ErrSyntaxStringLength, Msg: "invalid string length format", WrappedErr: convErr}
+		if err := d.chargeBytes(int64(length)); err != nil {
+			return nil, err
 		}
-		if length < 0 {
-			return nil, &Error{Type: ErrSyntaxStringLength, Msg: fmt.Sprintf("negative string length: %d", length)}
+		var data []byte
+		if d.alloc != nil {
+			if data = d.alloc(length); len(data) != length {
+				return nil, &Error{Type: ErrUsage, Msg: fmt.Sprintf("allocator returned %d bytes, want %d", len(data), length)}
+			}
+		} else {
+			data = make([]byte, length)
 		}
-		data := make([]byte, length)
 		n, readErr := io.ReadFull(d.r, data)
+		d.consumedBytes(data[:n])
 		if readErr != nil {
-			// Use ErrUnexpectedEOF as the wrapped error for consistency if it's an EOF variant
-			wrapped := readErr
 			if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) {
-				wrapped = ErrUnexpectedEOF
+				return nil, &Error{Type: ErrSyntaxEOF, Msg: fmt.Sprintf("expected %d bytes for string, got %d", length, n), WrappedErr: ErrUnexpectedEOF}
 			}
-			return nil, &Error{Type: ErrSyntaxEOF, Msg: fmt.Sprintf("expected %d bytes for string, got %d", length, n), WrappedErr: wrapped}
+			return nil, d.readError(readErr, fmt.Sprintf("reading string of %d bytes after %d", length, n))
 		}
+		d.stats.Strings++
+		d.stats.MaxStringLen = max(d.stats.MaxStringLen, length)
+		d.stats.Bytes += int64(headerLen + length)
 		return data, nil
 
 	case token == 'i':
 		_, _ = d.r.Discard(1) // discard 'i'
-		numString, err := d.r.ReadString('e')
+		d.consumed("i")
+		numString, err := d.readNumber('e', d.intDigits())
 		if err != nil {
+			if errors.Is(err, ErrIntegerTooLong) {
+				return nil, err
+			}
 			if errors.Is(err, io.EOF) {
 				return nil, &Error{Type: ErrSyntaxEOF, Msg: "integer not terminated by 'e'", WrappedErr: ErrUnexpectedEOF}
 			}
-			return nil, &Error{Type: ErrSyntaxInteger, Msg: "error reading integer", WrappedErr: err}
+			return nil, d.readError(err, "reading integer")
 		}
+		tokenLen := 1 + len(numString)           // 'i' + digits + 'e'
 		numString = numString[:len(numString)-1] // remove trailing 'e'
 		if len(numString) == 0 {
 			return nil, &Error{Type: ErrSyntaxInteger, Msg: "empty integer"}
 		}
 
-		if (len(numString) > 1 && numString[0] == '0') || (len(numString) > 2 && numString[0] == '-' && numString[1] == '0') {
-			return nil, &Error{Type: ErrSyntaxInteger, Msg: fmt.Sprintf("invalid integer format (leading zero): %s", numString)}
+		if numString[0] == '+' {
+			return nil, &Error{Type: ErrSyntaxInteger, Msg: fmt.Sprintf("invalid integer format (plus sign): %s", numString)}
 		}
-		if numString == "-0" { // "-0" is invalid
-			return nil, &Error{Type: ErrSyntaxInteger, Msg: "invalid integer format: -0"}
+		var verbatim string
+		if minimal := minimalInteger(numString); minimal != numString {
+			if !d.nonMinimalInts {
+				if numString == "-0" { // "-0" is invalid
+					return nil, &Error{Type: ErrSyntaxInteger, Msg: "invalid integer format: -0"}
+				}
+				return nil, &Error{Type: ErrSyntaxInteger, Msg: fmt.Sprintf("invalid integer format (leading zero): %s", numString)}
+			}
+			d.warn(Warning{Type: WarnNonMinimalInteger, Msg: fmt.Sprintf("integer %q is not minimal", numString)})
+			verbatim, numString = numString, minimal
 		}
 
+		if d.useNumber {
+			if !validNumber(numString) {
+				return nil, &Error{Type: ErrSyntaxInteger, Msg: fmt.Sprintf("cannot parse integer %q", numString)}
+			}
+			d.stats.Integers++
+			d.stats.Bytes += int64(tokenLen)
+			if verbatim != "" {
+				d.sawVerbatim = true
+				return verbatimInt{n: Number(numString), text: verbatim}, nil
+			}
+			return Number(numString), nil
+		}
 		num, convErr := strconv.ParseInt(numString, 10, 64)
 		if convErr != nil {
 			return nil, &Error{Type: ErrSyntaxInteger, Msg: fmt.Sprintf("cannot parse integer %q", numString), WrappedErr: convErr}
 		}
+		d.stats.Integers++
+		d.stats.Bytes += int64(tokenLen)
+		if verbatim != "" {
+			d.sawVerbatim = true
+			return verbatimInt{n: num, text: verbatim}, nil
+		}
 		return num, nil
 
 	case token == 'l':
 		_, _ = d.r.Discard(1) // discard 'l'
+		d.consumed("l")
+		d.enterContainer()
+		defer d.leaveContainer()
 		var list []any
+		if n := d.nextSize(); n > 0 {
+			list = make([]any, 0, n)
+		}
 		for {
 			peeked, err := d.r.Peek(1)
 			if err != nil {
 				if errors.Is(err, io.EOF) {
 					return nil, &Error{Type: ErrSyntaxEOF, Msg: "list not terminated by 'e'", WrappedErr: ErrUnexpectedEOF}
 				}
-				return nil, &Error{Type: ErrSyntax, Msg: "peeking in list", WrappedErr: err}
+				return nil, d.readError(err, "peeking in list")
 			}
 
 			if rune(peeked[0]) == 'e' {
 				if _, err = d.r.Discard(1); err != nil { // Consume 'e'
 					return nil, &Error{Type: ErrSyntax, Msg: "consuming list terminator 'e'", WrappedErr: err}
 				}
+				d.consumed("e")
 				break // End of list
 			}
 
+			if d.tracksPath() { // formatting the index would allocate
+				d.pushPath(strconv.Itoa(len(list)))
+			}
 			item, decodeErr := d.decode()
+			d.popPath()
 			if decodeErr != nil {
 				// If d.decode() returned ErrNullRootValue, it means EOF was hit where an item was expected.
 				if errors.Is(decodeErr, ErrNullRootValue) {
@@ -374,11 +864,16 @@ func (d *Decoder) decode() (any, error) {
 			}
 			list = append(list, item)
 		}
+		d.stats.Lists++
+		d.stats.Bytes += 2
 		return list, nil
 
 	case token == 'd':
 		_, _ = d.r.Discard(1) // discard 'd'
-		dict := make(map[string]any)
+		d.consumed("d")
+		d.enterContainer()
+		defer d.leaveContainer()
+		dict := make(map[string]any, d.nextSize())
 		var prevKey string
 		firstKey := true
 
@@ -388,16 +883,21 @@ func (d *Decoder) decode() (any, error) {
 				if errors.Is(err, io.EOF) {
 					return nil, &Error{Type: ErrSyntaxEOF, Msg: "dictionary not terminated by 'e'", WrappedErr: ErrUnexpectedEOF}
 				}
-				return nil, &Error{Type: ErrSyntax, Msg: "peeking in dictionary", WrappedErr: err}
+				return nil, d.readError(err, "peeking in dictionary")
 			}
 
 			if rune(peeked[0]) == 'e' {
 				if _, err = d.r.Discard(1); err != nil { // Consume 'e'
 					return nil, &Error{Type: ErrSyntax, Msg: "consuming dictionary terminator 'e'", WrappedErr: err}
 				}
+				d.consumed("e")
 				break // End of dictionary
 			}
+			if err := d.checkDictEntries(len(dict) + 1); err != nil {
+				return nil, err
+			}
 
+			keyOffset := d.offset
 			keyVal, keyErr := d.decode()
 			if keyErr != nil {
 				if errors.Is(keyErr, ErrNullRootValue) {
@@ -409,17 +909,26 @@ func (d *Decoder) decode() (any, error) {
 			if !ok {
 				return nil, &Error{Type: ErrStructureDict, Msg: fmt.Sprintf("dictionary key type %T is not a bencode string", keyVal)}
 			}
-			strKey := string(byteKey)
+			strKey := d.internKey(byteKey)
+			if err := d.checkKey(strKey, keyOffset); err != nil {
+				return nil, err
+			}
 
 			if _, exists := dict[strKey]; exists {
 				return nil, &Error{Type: ErrStructureDictKeyDup, Msg: fmt.Sprintf("key %q", strKey), WrappedErr: ErrDuplicateDictionaryKey, FieldName: strKey}
 			}
 
 			if !firstKey && prevKey >= strKey {
-				return nil, &Error{Type: ErrStructureDictKeySort, Msg: fmt.Sprintf("key %q is not lexicographically after %q", strKey, prevKey), WrappedErr: ErrDictionaryKeysNotSorted, FieldName: strKey}
+				msg := fmt.Sprintf("key %q is not lexicographically after %q", strKey, prevKey)
+				if !d.unsortedKeys {
+					return nil, &Error{Type: ErrStructureDictKeySort, Msg: msg, WrappedErr: ErrDictionaryKeysNotSorted, FieldName: strKey}
+				}
+				d.warn(Warning{Type: WarnUnsortedKey, Msg: msg, FieldName: strKey})
 			}
 
+			d.pushPath(strKey)
 			value, valErr := d.decode()
+			d.popPath()
 			if valErr != nil {
 				if errors.Is(valErr, ErrNullRootValue) {
 					return nil, &Error{Type: ErrStructureDictValue, Msg: "missing value (unexpected EOF)", WrappedErr: ErrUnexpectedEOF, FieldName: strKey}
@@ -431,6 +940,8 @@ func (d *Decoder) decode() (any, error) {
 			prevKey = strKey
 			firstKey = false
 		}
+		d.stats.Dicts++
+		d.stats.Bytes += 2
 		return dict, nil
 	default:
 		return nil, &Error{Type: ErrSyntaxUnexpectedToken, Msg: fmt.Sprintf("unexpected token %q", token)}
@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestDecoder(t *testing.T) {
@@ -125,6 +126,18 @@ func TestDecoderErr(t *testing.T) {
 			expectedErrType: ErrSyntaxInteger,
 			expectedMsg:     "invalid integer format (leading zero): -01",
 		},
+		{
+			name:            "integer plus sign",
+			input:           "i+1e",
+			expectedErrType: ErrSyntaxInteger,
+			expectedMsg:     "invalid integer format (plus sign): +1",
+		},
+		{
+			name:            "string length leading zero",
+			input:           "04:spam",
+			expectedErrType: ErrSyntaxStringLength,
+			expectedMsg:     "invalid string length format (leading zero): 04",
+		},
 		{
 			name:            "integer empty - just 'ie'",
 			input:           "ide",
@@ -389,3 +402,388 @@ func TestDecodeTypeMapAny(t *testing.T) {
 		t.Errorf("Expected %v, got %v", expected, got)
 	}
 }
+
+func TestDecodeRequiredField(t *testing.T) {
+	type TestStruct struct {
+		Name  string `bencode:"name,required"`
+		Value int64  `bencode:"value"`
+	}
+
+	var got TestStruct
+	err := Unmarshal([]byte("d5:valuei1ee"), &got)
+	var bErr *Error
+	if !errors.As(err, &bErr) || bErr.Type != ErrUnmarshalMissingField {
+		t.Fatalf("Unmarshal() error = %v, want %q", err, ErrUnmarshalMissingField)
+	}
+	if bErr.FieldName != "name" {
+		t.Errorf("Expected error field name %q, got %q", "name", bErr.FieldName)
+	}
+}
+
+func TestDecoderCollectErrors(t *testing.T) {
+	type TestStruct struct {
+		Name  string `bencode:"name,required"`
+		Count uint8  `bencode:"count"`
+		Tags  []int  `bencode:"tags"`
+		Ok    string `bencode:"ok"`
+	}
+
+	input := "d5:counti300e2:ok3:yes4:tags3:abce"
+
+	var first TestStruct
+	err := Unmarshal([]byte(input), &first)
+	if bErr, ok := err.(*Error); !ok || bErr.FieldName != "count" {
+		t.Fatalf("Unmarshal() error = %v, want first field error only", err)
+	}
+
+	var got TestStruct
+	decoder := NewDecoder(strings.NewReader(input))
+	decoder.CollectErrors()
+	err = decoder.Decode(&got)
+	if err == nil {
+		t.Fatalf("Decode() expected an error")
+	}
+
+	var fields []string
+	for _, e := range err.(*Error).WrappedErr.(interface{ Unwrap() []error }).Unwrap() {
+		fields = append(fields, e.(*Error).FieldName)
+	}
+	expectedFields := []string{"count", "name", "tags"}
+	if !reflect.DeepEqual(fields, expectedFields) {
+		t.Errorf("Expected errors for fields %v, got %v", expectedFields, fields)
+	}
+	if err.(*Error).Type != ErrUnmarshalOverflow {
+		t.Errorf("Expected aggregated error type %q, got %q", ErrUnmarshalOverflow, err.(*Error).Type)
+	}
+	if got.Ok != "yes" {
+		t.Errorf("Expected valid field to be populated, got %q", got.Ok)
+	}
+}
+
+func TestDecoderUseAllocator(t *testing.T) {
+	arena := make([]byte, 0, 64)
+	calls := 0
+	decoder := NewDecoder(strings.NewReader("l4:spam4:eggse"))
+	decoder.UseAllocator(func(n int) []byte {
+		calls++
+		start := len(arena)
+		arena = arena[:start+n]
+		return arena[start : start+n : start+n]
+	})
+
+	got, err := decoder.DecodeValue()
+	if err != nil {
+		t.Fatalf("DecodeValue() error = %v", err)
+	}
+	expected := []any{[]byte("spam"), []byte("eggs")}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if calls != 2 || string(arena) != "spameggs" {
+		t.Errorf("Expected strings to be decoded into the arena, got %d calls and %q", calls, arena)
+	}
+
+	bad := NewDecoder(strings.NewReader("4:spam"))
+	bad.UseAllocator(func(n int) []byte { return make([]byte, n-1) })
+	_, err = bad.DecodeValue()
+	var bErr *Error
+	if !errors.As(err, &bErr) || bErr.Type != ErrUsage {
+		t.Errorf("Expected %q for short allocation, got %v", ErrUsage, err)
+	}
+}
+
+func TestNewDecoderSize(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	input := "d4:long100:" + long + "3:numi1234567890123456789ee" + "l" + strings.Repeat("le", 20) + "e"
+
+	// A 16-byte buffer, the smallest bufio allows, fed one byte per read,
+	// splits every token longer than the buffer across fills.
+	decoder := NewDecoderSize(iotest.OneByteReader(strings.NewReader(input)), 16)
+	decoder.ErrorContext(32)
+	var got struct {
+		Long string `bencode:"long"`
+		Num  int64  `bencode:"num"`
+	}
+	if err := decoder.Decode(&got); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got.Long != long || got.Num != 1234567890123456789 {
+		t.Errorf("Decode() = %+v", got)
+	}
+	var lists [][]int
+	if err := decoder.Decode(&lists); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if len(lists) != 20 {
+		t.Errorf("Decode() = %d lists, want 20", len(lists))
+	}
+
+	truncated := NewDecoderSize(strings.NewReader("100:"+long[:50]), 16)
+	truncated.ErrorContext(32)
+	_, err := truncated.DecodeValue()
+	var bErr *Error
+	if !errors.As(err, &bErr) || bErr.Type != ErrSyntaxEOF {
+		t.Errorf("DecodeValue() error = %v, want %q", err, ErrSyntaxEOF)
+	}
+}
+
+func TestDecodeAt(t *testing.T) {
+	r := strings.NewReader("header|d4:name4:spame|4:eggs|i7e|")
+	var off int64 = 7
+
+	var dict map[string]string
+	n, err := DecodeAt(r, off, &dict)
+	if err != nil {
+		t.Fatalf("DecodeAt() error = %v", err)
+	}
+	if n != 14 || dict["name"] != "spam" {
+		t.Errorf("DecodeAt() = %d, %v, want 14, map[name:spam]", n, dict)
+	}
+	off += n + 1
+
+	var s string
+	if n, err = DecodeAt(r, off, &s); err != nil || n != 6 || s != "eggs" {
+		t.Errorf("DecodeAt() = %d, %q, %v, want 6, \"eggs\", nil", n, s, err)
+	}
+	off += n + 1
+
+	var i int
+	if n, err = DecodeAt(r, off, &i); err != nil || n != 3 || i != 7 {
+		t.Errorf("DecodeAt() = %d, %d, %v, want 3, 7, nil", n, i, err)
+	}
+	off += n + 1
+
+	if _, err = DecodeAt(r, off, &i); err != ErrNullRootValue {
+		t.Errorf("DecodeAt() at end error = %v, want %v", err, ErrNullRootValue)
+	}
+}
+
+func TestDecodeSliceOfStructs(t *testing.T) {
+	type fileInfo struct {
+		Length int64    `bencode:"length,required"`
+		Path   []string `bencode:"path"`
+	}
+	type info struct {
+		Files []fileInfo `bencode:"files"`
+	}
+	const input = "d5:filesld6:lengthi1e4:pathl1:aeed4:pathl1:beed6:lengthi3e4:pathi1eeee"
+
+	var got info
+	err := Unmarshal([]byte(input), &got)
+	var bErr *Error
+	if !errors.As(err, &bErr) || bErr.Type != ErrUnmarshalMissingField || bErr.Path() != "files.1.length" {
+		t.Fatalf("Unmarshal() error = %v, want %q at files.1.length", err, ErrUnmarshalMissingField)
+	}
+
+	dec := NewDecoder(strings.NewReader(input))
+	dec.CollectErrors()
+	err = dec.Decode(&got)
+	if err == nil {
+		t.Fatal("Decode() error = nil, want element errors")
+	}
+	// The "files" field error wraps the element errors joined together.
+	if paths := joinedErrorPaths(t, err); !reflect.DeepEqual(paths, []string{"1.length", "2.path"}) {
+		t.Errorf("element error paths = %q, want %q", paths, []string{"1.length", "2.path"})
+	}
+	want := []fileInfo{{Length: 1, Path: []string{"a"}}, {Path: []string{"b"}}, {Length: 3}}
+	if !reflect.DeepEqual(got.Files, want) {
+		t.Errorf("Decode() = %+v, want %+v", got.Files, want)
+	}
+}
+
+func TestDecodeMapOfStructs(t *testing.T) {
+	type stats struct {
+		Complete   int64 `bencode:"complete,required"`
+		Downloaded int64 `bencode:"downloaded"`
+	}
+	type scrape struct {
+		Files map[string]stats `bencode:"files"`
+	}
+	const input = "d5:filesd1:ad8:completei1ee1:bd10:downloadedi2ee1:cd8:complete1:xe1:dd8:completei4eeee"
+
+	for range 10 {
+		var got scrape
+		err := Unmarshal([]byte(input), &got)
+		var bErr *Error
+		if !errors.As(err, &bErr) || bErr.Type != ErrUnmarshalMissingField || bErr.Path() != "files.b.complete" {
+			t.Fatalf("Unmarshal() error = %v, want %q at files.b.complete", err, ErrUnmarshalMissingField)
+		}
+	}
+
+	var got scrape
+	dec := NewDecoder(strings.NewReader(input))
+	dec.CollectErrors()
+	err := dec.Decode(&got)
+	if err == nil {
+		t.Fatal("Decode() error = nil, want value errors")
+	}
+	if paths := joinedErrorPaths(t, err); !reflect.DeepEqual(paths, []string{"b.complete", "c.complete"}) {
+		t.Errorf("value error paths = %q, want %q", paths, []string{"b.complete", "c.complete"})
+	}
+	want := map[string]stats{"a": {Complete: 1}, "b": {Downloaded: 2}, "c": {}, "d": {Complete: 4}}
+	if !reflect.DeepEqual(got.Files, want) {
+		t.Errorf("Decode() = %+v, want %+v", got.Files, want)
+	}
+
+	encoded, err := Marshal(scrape{Files: want})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if wantEnc := "d5:filesd1:ad8:completei1e10:downloadedi0ee1:bd8:completei0e10:downloadedi2ee1:cd8:completei0e10:downloadedi0ee1:dd8:completei4e10:downloadedi0eeee"; string(encoded) != wantEnc {
+		t.Errorf("Marshal() = %q, want %q", encoded, wantEnc)
+	}
+
+	var ptrs map[string]*stats
+	if err := Unmarshal([]byte("d1:ad8:completei1eee"), &ptrs); err != nil || ptrs["a"] == nil || ptrs["a"].Complete != 1 {
+		t.Errorf("Unmarshal() into map of pointers = %v, %v", ptrs, err)
+	}
+}
+
+func TestDecodeTargets(t *testing.T) {
+	var n int
+	tests := []struct {
+		name   string
+		target any
+		hint   string
+	}{
+		{name: "nil", target: nil, hint: "Decode(nil)"},
+		{name: "non-pointer", target: n, hint: "pass &v"},
+		{name: "nil pointer", target: (*int)(nil), hint: "new(int)"},
+		{name: "unsettable reflect.Value", target: reflect.ValueOf(n), hint: "not settable"},
+		{name: "invalid reflect.Value", target: reflect.Value{}, hint: "reflect.New"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Unmarshal([]byte("i1e"), tt.target)
+			var bErr *Error
+			if !errors.As(err, &bErr) || bErr.Type != ErrUsage || !strings.Contains(bErr.Msg, tt.hint) {
+				t.Errorf("Unmarshal() error = %v, want %q mentioning %q", err, ErrUsage, tt.hint)
+			}
+		})
+	}
+
+	dest := reflect.New(reflect.TypeFor[[]string]()).Elem()
+	if err := Unmarshal([]byte("l1:a1:be"), dest); err != nil {
+		t.Fatalf("Unmarshal() into reflect.Value error = %v", err)
+	}
+	if got := dest.Interface().([]string); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("Unmarshal() into reflect.Value = %q", got)
+	}
+}
+
+func TestDecodeBinaryFields(t *testing.T) {
+	type pieces []byte
+	type torrent struct {
+		Pieces pieces   `bencode:"pieces"`
+		PeerID [4]byte  `bencode:"peer id"`
+		Raw    []byte   `bencode:"raw"`
+		Bytes  []uint8  `bencode:"bytes"`
+		Hashes [][]byte `bencode:"hashes"`
+	}
+	input := "d5:bytesli1ei2ee6:hashesl2:\x00\x012:\xff\xfee7:peer id4:-AZ-6:pieces3:\x00\xff\x103:raw0:e"
+	var got torrent
+	if err := Unmarshal([]byte(input), &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	want := torrent{
+		Pieces: pieces("\x00\xff\x10"),
+		PeerID: [4]byte{'-', 'A', 'Z', '-'},
+		Raw:    []byte{},
+		Bytes:  []uint8{1, 2},
+		Hashes: [][]byte{[]byte("\x00\x01"), []byte("\xff\xfe")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal() = %+v, want %+v", got, want)
+	}
+
+	tests := []struct {
+		name   string
+		input  string
+		target any
+	}{
+		{name: "array too short", input: "d7:peer id3:abce", target: &torrent{}},
+		{name: "array from list", input: "d7:peer idli1eee", target: &torrent{}},
+		{name: "slice from integer", input: "d6:piecesi1ee", target: &torrent{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Unmarshal([]byte(tt.input), tt.target)
+			var bErr *Error
+			if !errors.As(err, &bErr) || bErr.Type != ErrUnmarshalType {
+				t.Errorf("Unmarshal() error = %v, want %q", err, ErrUnmarshalType)
+			}
+		})
+	}
+}
+
+func TestUnmarshalAny(t *testing.T) {
+	got, err := UnmarshalAny([]byte("d4:listli1e1:ae3:numi-7ee"))
+	if err != nil {
+		t.Fatalf("UnmarshalAny() error = %v", err)
+	}
+	want := map[string]any{"list": []any{int64(1), []byte("a")}, "num": int64(-7)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UnmarshalAny() = %#v, want %#v", got, want)
+	}
+
+	var viaPointer any
+	if err := Unmarshal([]byte("d4:listli1e1:ae3:numi-7ee"), &viaPointer); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, viaPointer) {
+		t.Errorf("UnmarshalAny() = %#v, Unmarshal() into any = %#v", got, viaPointer)
+	}
+
+	if _, err := UnmarshalAny([]byte("d1:ai1e")); err == nil {
+		t.Errorf("UnmarshalAny() of truncated input succeeded")
+	}
+}
+
+func TestUnmarshalPrefix(t *testing.T) {
+	type metadataMsg struct {
+		MsgType   int `bencode:"msg_type"`
+		Piece     int `bencode:"piece"`
+		TotalSize int `bencode:"total_size"`
+	}
+	payload := "d4:name4:spame\x00\xffraw"
+	data := []byte("d8:msg_typei1e5:piecei0e10:total_sizei8ee" + payload)
+	var msg metadataMsg
+	rest, err := UnmarshalPrefix(data, &msg)
+	if err != nil {
+		t.Fatalf("UnmarshalPrefix() error = %v", err)
+	}
+	if msg != (metadataMsg{MsgType: 1, Piece: 0, TotalSize: 8}) {
+		t.Errorf("UnmarshalPrefix() decoded %+v", msg)
+	}
+	if string(rest) != payload {
+		t.Errorf("UnmarshalPrefix() rest = %q, want %q", rest, payload)
+	}
+
+	rest, err = UnmarshalPrefix([]byte("i1e"), new(int))
+	if err != nil || rest == nil || len(rest) != 0 {
+		t.Errorf("UnmarshalPrefix() without payload = %q, %v, want empty rest", rest, err)
+	}
+
+	if rest, err := UnmarshalPrefix([]byte("d1:ai1e"), &msg); err == nil || rest != nil {
+		t.Errorf("UnmarshalPrefix() of truncated header = %q, %v, want error and nil rest", rest, err)
+	}
+}
+
+// joinedErrorPaths returns the paths of the errors joined by the first
+// errors.Join found in the chain of err.
+func joinedErrorPaths(t *testing.T, err error) []string {
+	t.Helper()
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) {
+		t.Fatalf("error %v does not join several errors", err)
+	}
+	var paths []string
+	for _, e := range joined.Unwrap() {
+		var bErr *Error
+		if !errors.As(e, &bErr) {
+			t.Fatalf("joined error %v is not an *Error", e)
+		}
+		paths = append(paths, bErr.Path())
+	}
+	return paths
+}
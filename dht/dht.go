@@ -0,0 +1,174 @@
+// Package dht persists BitTorrent DHT routing tables on top of the bencode
+// package.
+//
+// Tables are stored in the dictionary layout used by common clients: the
+// local node ID under "id" (Transmission) and "node-id" (libtorrent), and
+// the known nodes as compact node info under "nodes" for IPv4 (BEP 5) and
+// "nodes6" for IPv6 (BEP 32). Last-seen times, which those clients do not
+// keep, are stored as lists of Unix times under "last-seen" and
+// "last-seen6", parallel to the compact strings, and are ignored by them.
+//
+// Version handles the client version carried by KRPC messages.
+package dht
+
+import (
+	"fmt"
+	"io"
+	"net/netip"
+	"time"
+
+	"github.com/stupoid/bencode"
+)
+
+const (
+	// ErrRoutingTable indicates a routing table dump is malformed.
+	ErrRoutingTable bencode.ErrorType = "routing table error"
+
+	// CompactNodeSize is the size of the compact info of an IPv4 node: its
+	// 20-byte ID followed by a 4-byte address and 2-byte big-endian port.
+	CompactNodeSize = 26
+	// CompactNode6Size is the size of the compact info of an IPv6 node.
+	CompactNode6Size = 38
+)
+
+// NodeID is the 160-bit identifier of a DHT node.
+type NodeID [20]byte
+
+// Node is an entry of a routing table.
+type Node struct {
+	ID   NodeID
+	Addr netip.AddrPort
+	// LastSeen is when the node last responded, or the zero Time if unknown.
+	LastSeen time.Time
+}
+
+// RoutingTable is a persisted DHT routing table.
+type RoutingTable struct {
+	// ID is the ID of the local node.
+	ID    NodeID
+	Nodes []Node
+}
+
+// dump is the dictionary layout of a persisted routing table.
+type dump struct {
+	ID        string  `bencode:"id"`
+	NodeID    string  `bencode:"node-id"`
+	Nodes     string  `bencode:"nodes"`
+	Nodes6    string  `bencode:"nodes6"`
+	LastSeen  []int64 `bencode:"last-seen"`
+	LastSeen6 []int64 `bencode:"last-seen6"`
+}
+
+// Load decodes a routing table from r. Either of "id" and "node-id" may hold
+// the local node ID; "id" is preferred when both are present.
+func Load(r io.Reader) (*RoutingTable, error) {
+	var d dump
+	if err := bencode.NewDecoder(r).Decode(&d); err != nil {
+		return nil, err
+	}
+	id := d.ID
+	if id == "" {
+		id = d.NodeID
+	}
+	if len(id) != len(NodeID{}) {
+		return nil, &bencode.Error{Type: ErrRoutingTable, Msg: fmt.Sprintf("local node ID is %d bytes, want %d", len(id), len(NodeID{})), FieldName: "id"}
+	}
+	rt := &RoutingTable{ID: NodeID([]byte(id))}
+	for _, family := range []struct {
+		key      string
+		compact  string
+		lastSeen []int64
+	}{
+		{key: "nodes", compact: d.Nodes, lastSeen: d.LastSeen},
+		{key: "nodes6", compact: d.Nodes6, lastSeen: d.LastSeen6},
+	} {
+		nodes, err := ParseCompactNodes([]byte(family.compact), family.key == "nodes6")
+		if err != nil {
+			err.(*bencode.Error).FieldName = family.key
+			return nil, err
+		}
+		if family.lastSeen != nil && len(family.lastSeen) != len(nodes) {
+			return nil, &bencode.Error{Type: ErrRoutingTable, Msg: fmt.Sprintf("%d last-seen times for %d nodes", len(family.lastSeen), len(nodes)), FieldName: family.key}
+		}
+		for i := range nodes {
+			if family.lastSeen != nil && family.lastSeen[i] != 0 {
+				nodes[i].LastSeen = time.Unix(family.lastSeen[i], 0)
+			}
+		}
+		rt.Nodes = append(rt.Nodes, nodes...)
+	}
+	return rt, nil
+}
+
+// Write bencodes rt to w. Nodes are written in order within each address
+// family; last-seen times are rounded down to whole seconds and are omitted
+// if no node of the family has one.
+func (rt *RoutingTable) Write(w io.Writer) error {
+	d := map[string]any{
+		"id":      rt.ID[:],
+		"node-id": rt.ID[:],
+	}
+	var nodes, nodes6 []byte
+	var lastSeen, lastSeen6 []int64
+	var seen, seen6 bool
+	for _, n := range rt.Nodes {
+		if !n.Addr.IsValid() {
+			return &bencode.Error{Type: bencode.ErrUsage, Msg: fmt.Sprintf("node %x has no address", n.ID)}
+		}
+		var unix int64
+		if !n.LastSeen.IsZero() {
+			unix = n.LastSeen.Unix()
+		}
+		if n.Addr.Addr().Unmap().Is4() {
+			nodes = AppendCompactNode(nodes, n)
+			lastSeen = append(lastSeen, unix)
+			seen = seen || unix != 0
+		} else {
+			nodes6 = AppendCompactNode(nodes6, n)
+			lastSeen6 = append(lastSeen6, unix)
+			seen6 = seen6 || unix != 0
+		}
+	}
+	if nodes != nil {
+		d["nodes"] = nodes
+		if seen {
+			d["last-seen"] = lastSeen
+		}
+	}
+	if nodes6 != nil {
+		d["nodes6"] = nodes6
+		if seen6 {
+			d["last-seen6"] = lastSeen6
+		}
+	}
+	return bencode.NewEncoder(w).Encode(d)
+}
+
+// AppendCompactNode appends the compact info of n to dst: CompactNodeSize
+// bytes for an IPv4 (or IPv4-mapped IPv6) address, CompactNode6Size bytes
+// otherwise.
+func AppendCompactNode(dst []byte, n Node) []byte {
+	dst = append(dst, n.ID[:]...)
+	addr := n.Addr.Addr().Unmap()
+	dst = append(dst, addr.AsSlice()...)
+	return append(dst, byte(n.Addr.Port()>>8), byte(n.Addr.Port()))
+}
+
+// ParseCompactNodes splits a string of concatenated compact node info into
+// nodes, of IPv6 addresses if ipv6 is set and IPv4 otherwise.
+func ParseCompactNodes(b []byte, ipv6 bool) ([]Node, error) {
+	size := CompactNodeSize
+	if ipv6 {
+		size = CompactNode6Size
+	}
+	if len(b)%size != 0 {
+		return nil, &bencode.Error{Type: ErrRoutingTable, Msg: fmt.Sprintf("compact node info of %d bytes is not a multiple of %d", len(b), size)}
+	}
+	nodes := make([]Node, 0, len(b)/size)
+	for ; len(b) > 0; b = b[size:] {
+		addr, _ := netip.AddrFromSlice(b[20 : size-2])
+		port := uint16(b[size-2])<<8 | uint16(b[size-1])
+		nodes = append(nodes, Node{ID: NodeID(b[:20]), Addr: netip.AddrPortFrom(addr, port)})
+	}
+	return nodes, nil
+}
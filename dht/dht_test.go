@@ -0,0 +1,95 @@
+package dht
+
+import (
+	"bytes"
+	"errors"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stupoid/bencode"
+)
+
+func TestRoutingTableRoundTrip(t *testing.T) {
+	rt := &RoutingTable{
+		ID: NodeID(bytes.Repeat([]byte{0xaa}, 20)),
+		Nodes: []Node{
+			{ID: NodeID(bytes.Repeat([]byte{1}, 20)), Addr: netip.MustParseAddrPort("10.0.0.1:6881"), LastSeen: time.Unix(1700000000, 0)},
+			{ID: NodeID(bytes.Repeat([]byte{2}, 20)), Addr: netip.MustParseAddrPort("10.0.0.2:51413")},
+			{ID: NodeID(bytes.Repeat([]byte{3}, 20)), Addr: netip.MustParseAddrPort("[2001:db8::1]:6881")},
+		},
+	}
+	var buf bytes.Buffer
+	if err := rt.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	var raw map[string]any
+	if err := bencode.Unmarshal(buf.Bytes(), &raw); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if len(raw["nodes"].([]byte)) != 2*CompactNodeSize || len(raw["nodes6"].([]byte)) != CompactNode6Size {
+		t.Errorf("Write() compact nodes = %d and %d bytes", len(raw["nodes"].([]byte)), len(raw["nodes6"].([]byte)))
+	}
+	if _, ok := raw["last-seen6"]; ok {
+		t.Errorf("Write() stored last-seen6 although no IPv6 node has a last-seen time")
+	}
+
+	got, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, rt) {
+		t.Errorf("Load() = %+v, want %+v", got, rt)
+	}
+}
+
+func TestLoadClientDumps(t *testing.T) {
+	compact := string(bytes.Repeat([]byte{7}, 20)) + "\x7f\x00\x00\x01\x1a\xe1"
+	want := &RoutingTable{
+		ID:    NodeID(bytes.Repeat([]byte{0xbb}, 20)),
+		Nodes: []Node{{ID: NodeID(bytes.Repeat([]byte{7}, 20)), Addr: netip.MustParseAddrPort("127.0.0.1:6881")}},
+	}
+	id := strings.Repeat("\xbb", 20)
+	for _, key := range []string{"id", "node-id"} {
+		input, err := bencode.Marshal(map[string]string{key: id, "nodes": compact})
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		got, err := Load(bytes.NewReader(input))
+		if err != nil {
+			t.Fatalf("Load() with %q error = %v", key, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Load() with %q = %+v, want %+v", key, got, want)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	id := strings.Repeat("x", 20)
+	tests := []struct {
+		name  string
+		dump  map[string]any
+		field string
+	}{
+		{name: "short id", dump: map[string]any{"id": "x"}, field: "id"},
+		{name: "ragged nodes", dump: map[string]any{"id": id, "nodes": strings.Repeat("n", 27)}, field: "nodes"},
+		{name: "last-seen mismatch", dump: map[string]any{"id": id, "nodes6": strings.Repeat("n", 38), "last-seen6": []int{1, 2}}, field: "nodes6"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, err := bencode.Marshal(tt.dump)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			_, err = Load(bytes.NewReader(input))
+			var bErr *bencode.Error
+			if !errors.As(err, &bErr) || bErr.Type != ErrRoutingTable || bErr.FieldName != tt.field {
+				t.Errorf("Load() error = %v, want %q for %q", err, ErrRoutingTable, tt.field)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package dht
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/stupoid/bencode"
+)
+
+// ErrVersion indicates the "v" key of a KRPC message is malformed.
+const ErrVersion bencode.ErrorType = "krpc version error"
+
+// Version is the client version carried under the "v" key of KRPC messages
+// (BEP 5): by convention, a two-character client code followed by a
+// two-byte version number, such as "UT" and 0x0102 for µTorrent 1.2.
+// It encodes as a 4-byte string.
+type Version [4]byte
+
+// NewVersion returns the Version of client at version number. A client
+// code that is not two bytes long is an ErrVersion error.
+func NewVersion(client string, number uint16) (Version, error) {
+	if len(client) != 2 {
+		return Version{}, &bencode.Error{Type: ErrVersion, Msg: fmt.Sprintf("client code %q is not two bytes long", client)}
+	}
+	var v Version
+	copy(v[:2], client)
+	binary.BigEndian.PutUint16(v[2:], number)
+	return v, nil
+}
+
+// Client returns the two-character client code of v.
+func (v Version) Client() string {
+	return string(v[:2])
+}
+
+// Number returns the version number of v.
+func (v Version) Number() uint16 {
+	return binary.BigEndian.Uint16(v[2:])
+}
+
+// String returns the client code of v followed by its version number in
+// hexadecimal, e.g. "UT0102".
+func (v Version) String() string {
+	return fmt.Sprintf("%s%04x", v.Client(), v.Number())
+}
+
+// ParseVersion returns the Version held by b, which must be 4 bytes long.
+func ParseVersion(b []byte) (Version, error) {
+	if len(b) != len(Version{}) {
+		return Version{}, &bencode.Error{Type: ErrVersion, Msg: fmt.Sprintf("version is %d bytes, want %d", len(b), len(Version{})), FieldName: "v"}
+	}
+	return Version(b), nil
+}
+
+// MessageVersion returns the Version of the encoded KRPC message msg and
+// whether it has one. Messages without a "v" key are not an error, as the
+// key is optional.
+func MessageVersion(msg []byte) (Version, bool, error) {
+	var m struct {
+		V bencode.Optional[[]byte] `bencode:"v"`
+	}
+	if err := bencode.Unmarshal(msg, &m); err != nil {
+		return Version{}, false, err
+	}
+	b, ok := m.V.Get()
+	if !ok {
+		return Version{}, false, nil
+	}
+	v, err := ParseVersion(b)
+	return v, err == nil, err
+}
+
+// ConfigureEncoder makes e add v under the "v" key of every message it
+// encodes that does not carry a version already.
+func (v Version) ConfigureEncoder(e *bencode.Encoder) {
+	if err := e.AddRootKey("v", v[:]); err != nil {
+		panic("dht: encoding version: " + err.Error()) // a byte slice always encodes
+	}
+}
@@ -0,0 +1,53 @@
+package dht
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stupoid/bencode"
+	"github.com/stupoid/bencode/bencodetest"
+)
+
+func TestVersion(t *testing.T) {
+	v, err := NewVersion("UT", 0x0102)
+	if err != nil || v.Client() != "UT" || v.Number() != 0x0102 || v.String() != "UT0102" {
+		t.Errorf("NewVersion() = %q, %v, client %q, number %#x", v, err, v.Client(), v.Number())
+	}
+	if _, err := NewVersion("uTorrent", 1); !errors.Is(err, ErrVersion) {
+		t.Errorf("NewVersion() of a long client code error = %v, want %q", err, ErrVersion)
+	}
+
+	var buf bytes.Buffer
+	enc := bencode.NewEncoder(&buf)
+	v.ConfigureEncoder(enc)
+	ping := map[string]any{"t": "aa", "y": "q", "q": "ping", "a": map[string]any{"id": "abcdefghij0123456789"}}
+	if err := enc.Encode(ping); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	want := "d1:ad2:id20:abcdefghij0123456789e1:q4:ping1:t2:aa1:v4:UT\x01\x021:y1:qe"
+	bencodetest.Diff(t, []byte(want), buf.Bytes())
+
+	got, ok, err := MessageVersion(buf.Bytes())
+	if err != nil || !ok || got != v {
+		t.Errorf("MessageVersion() = %q, %v, %v, want %q", got, ok, err, v)
+	}
+
+	// A version already present is kept.
+	buf.Reset()
+	ping["v"] = "LT\x01\x00"
+	if err := enc.Encode(ping); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if got, _, _ := MessageVersion(buf.Bytes()); got.Client() != "LT" {
+		t.Errorf("Encode() replaced the message's version with %q", got)
+	}
+
+	if _, ok, err := MessageVersion([]byte("d1:y1:qe")); ok || err != nil {
+		t.Errorf("MessageVersion() without v = %v, %v, want false, nil", ok, err)
+	}
+	var bErr *bencode.Error
+	if _, _, err := MessageVersion([]byte("d1:v2:UTe")); !errors.As(err, &bErr) || bErr.Type != ErrVersion {
+		t.Errorf("MessageVersion() of short version error = %v, want %q", err, ErrVersion)
+	}
+}
@@ -0,0 +1,72 @@
+package bencode
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ErrEncodeKeyOrder indicates a key was added to an OrderedDictBuilder out of
+// order while RequireSortedKeys is in effect.
+const ErrEncodeKeyOrder ErrorType = "encode: dictionary key out of order"
+
+// OrderedDictBuilder assembles a dictionary one entry at a time, as a safer
+// alternative to filling a map[string]any by hand: each value is encoded as
+// it is added, so an unsupported value is reported at the offending key, and
+// a duplicate key is rejected instead of silently replacing the earlier
+// value. Keys may be added in any order and are sorted by Build, unless
+// RequireSortedKeys is called to have out-of-order keys rejected.
+//
+// The zero value is an empty builder ready to use.
+type OrderedDictBuilder struct {
+	entries    []rawEntry
+	keys       map[string]struct{}
+	sortedOnly bool
+	buf        bytes.Buffer
+}
+
+// RequireSortedKeys makes Add reject a key that does not sort after every
+// key added before it with an ErrEncodeKeyOrder error, for callers who
+// mirror a canonical layout and want mistakes in it caught.
+func (b *OrderedDictBuilder) RequireSortedKeys() {
+	b.sortedOnly = true
+}
+
+// Add encodes value and adds it under key. On error nothing is added: an
+// encoding error is returned with FieldName set to key, and a key that was
+// already added yields an ErrEncodeDuplicateKey error.
+func (b *OrderedDictBuilder) Add(key string, value any) error {
+	if _, dup := b.keys[key]; dup {
+		return &Error{Type: ErrEncodeDuplicateKey, Msg: fmt.Sprintf("key %q added twice", key), FieldName: key}
+	}
+	if b.sortedOnly && len(b.entries) > 0 {
+		if prev := b.entries[len(b.entries)-1].key; prev > key {
+			return &Error{Type: ErrEncodeKeyOrder, Msg: fmt.Sprintf("key %q is not lexicographically after %q", key, prev), FieldName: key}
+		}
+	}
+	b.buf.Reset()
+	if err := NewEncoder(&b.buf).Encode(value); err != nil {
+		if bErr, ok := errPath(err, key).(*Error); ok && bErr.FieldName == "" {
+			bErr.FieldName = key
+		}
+		return err
+	}
+	if b.keys == nil {
+		b.keys = make(map[string]struct{})
+	}
+	b.keys[key] = struct{}{}
+	b.entries = append(b.entries, rawEntry{key: key, value: bytes.Clone(b.buf.Bytes())})
+	return nil
+}
+
+// Len returns the number of entries added.
+func (b *OrderedDictBuilder) Len() int {
+	return len(b.entries)
+}
+
+// Build returns the encoding of the dictionary holding the entries added so
+// far, with its keys sorted. The builder may be added to further and built
+// again.
+func (b *OrderedDictBuilder) Build() RawMessage {
+	entries := append([]rawEntry(nil), b.entries...)
+	return appendRawDict(nil, entries)
+}
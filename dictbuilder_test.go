@@ -0,0 +1,61 @@
+package bencode
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOrderedDictBuilder(t *testing.T) {
+	var b OrderedDictBuilder
+	for _, e := range []struct {
+		key   string
+		value any
+	}{
+		{key: "name", value: "file.txt"},
+		{key: "length", value: 5},
+		{key: "files", value: []any{map[string]any{"length": 1}}},
+	} {
+		if err := b.Add(e.key, e.value); err != nil {
+			t.Fatalf("Add(%q) error = %v", e.key, err)
+		}
+	}
+
+	var bErr *Error
+	if err := b.Add("name", "other"); !errors.As(err, &bErr) || bErr.Type != ErrEncodeDuplicateKey || bErr.FieldName != "name" {
+		t.Errorf("Add() of duplicate key error = %v, want %q", err, ErrEncodeDuplicateKey)
+	}
+	if err := b.Add("bad", func() {}); !errors.As(err, &bErr) || bErr.Type != ErrEncodeUnsupportedType || bErr.FieldName != "bad" {
+		t.Errorf("Add() of unsupported value error = %v, want %q", err, ErrEncodeUnsupportedType)
+	}
+	if err := b.Add("peers", []any{nil}); !errors.As(err, &bErr) || bErr.Type != ErrEncodeNil || bErr.FieldName != "peers.0" {
+		t.Errorf("Add() of nil element error = %v, want %q at %q", err, ErrEncodeNil, "peers.0")
+	}
+
+	if b.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", b.Len())
+	}
+	if got, want := string(b.Build()), "d5:filesld6:lengthi1eee6:lengthi5e4:name8:file.txte"; got != want {
+		t.Errorf("Build() = %q, want %q", got, want)
+	}
+	if violations, err := CheckCanonical(b.Build()); err != nil || len(violations) > 0 {
+		t.Errorf("CheckCanonical(Build()) = %v, %v", violations, err)
+	}
+}
+
+func TestOrderedDictBuilderRequireSortedKeys(t *testing.T) {
+	var b OrderedDictBuilder
+	b.RequireSortedKeys()
+	if err := b.Add("b", 1); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	var bErr *Error
+	if err := b.Add("a", 2); !errors.As(err, &bErr) || bErr.Type != ErrEncodeKeyOrder {
+		t.Errorf("Add() out of order error = %v, want %q", err, ErrEncodeKeyOrder)
+	}
+	if err := b.Add("c", 3); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if got := string(b.Build()); got != "d1:bi1e1:ci3ee" {
+		t.Errorf("Build() = %q", got)
+	}
+}
@@ -0,0 +1,309 @@
+package bencode
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/stupoid/bencode/scanner"
+)
+
+// UnmarshalDirect decodes data into the value pointed to by v as Unmarshal
+// does, but walks the input with a scanner and stores each value straight
+// into its destination instead of first building the generic tree the
+// Decoder works from. Storage already in v is reused: byte slices and
+// RawMessages are refilled within their capacity, strings already equal to
+// the decoded bytes are left as they are, and structs behind non-nil
+// pointers are filled in place. Decoding a stream of small messages, such as
+// KRPC queries, into one reused struct therefore need not allocate at all.
+//
+// Strings, integers, byte slices and arrays, RawMessage, structs and
+// pointers to these are stored directly. Other types, and structs with
+// fields tagged required, list, union, inline or rest, are decoded by
+// Unmarshal from their part of the input, as are lists, such as lists of
+// integers filling byte slices and arrays, so every destination Unmarshal
+// accepts works here too. The input must be canonical as for Unmarshal,
+// including in values no field receives; any data following the value is
+// ignored.
+func UnmarshalDirect(data []byte, v any) error {
+	elem, err := decodeTarget(v)
+	if err != nil {
+		return err
+	}
+	dd := directDecoder{data: data}
+	dd.scan.Reset(data)
+	return dd.value(elem)
+}
+
+// directDecoder stores scanner tokens into Go values without building
+// intermediate ones.
+type directDecoder struct {
+	data []byte
+	scan scanner.Scanner
+}
+
+// isDirect reports whether UnmarshalDirect stores values of typ itself
+// rather than handing them to Unmarshal.
+func isDirect(typ reflect.Type) bool {
+	switch typ {
+	case rawMessageType:
+		return true
+	case numberType, uint64StringType, reflectValueType:
+		return false
+	}
+	if typ.Implements(optionalType) || isSQLNull(typ) {
+		return false
+	}
+	switch typ.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Slice, reflect.Array:
+		return typ.Elem().Kind() == reflect.Uint8
+	case reflect.Pointer:
+		return isDirect(typ.Elem())
+	case reflect.Struct:
+		for _, f := range getCachedStructInfo(typ) {
+			if f.required || f.asList || f.union != nil || f.mergesEntries() {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// value decodes the next value of the input into dest.
+func (dd *directDecoder) value(dest reflect.Value) error {
+	typ := dest.Type()
+	if !isDirect(typ) {
+		tok, err := dd.skip()
+		if err != nil {
+			return err
+		}
+		return Unmarshal(dd.data[tok.Offset:tok.End()], dest.Addr().Interface())
+	}
+	switch {
+	case typ == rawMessageType:
+		tok, err := dd.skip()
+		if err != nil {
+			return err
+		}
+		dest.SetBytes(append(dest.Bytes()[:0], dd.data[tok.Offset:tok.End()]...))
+		return nil
+	case typ.Kind() == reflect.Pointer:
+		if dest.IsNil() {
+			dest.Set(reflect.New(typ.Elem()))
+		}
+		return dd.value(dest.Elem())
+	}
+
+	if dd.scan.Peek() == scanner.ListStart {
+		// Lists of integers can still fill byte slices and arrays, as
+		// Unmarshal stores them.
+		tok, err := dd.skip()
+		if err != nil {
+			return err
+		}
+		return Unmarshal(dd.data[tok.Offset:tok.End()], dest.Addr().Interface())
+	}
+	tok, err := dd.scan.Next()
+	if err != nil {
+		return scanError(err)
+	}
+	switch tok.Kind {
+	case scanner.String:
+		b, err := dd.str(tok)
+		if err != nil {
+			return err
+		}
+		return storeDirectString(dest, b)
+	case scanner.Integer:
+		n, err := dd.integer(tok)
+		if err != nil {
+			return err
+		}
+		return storeDirectInt(dest, n)
+	case scanner.DictStart:
+		if typ.Kind() != reflect.Struct {
+			return &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("cannot store dictionary in %s", typ)}
+		}
+		return dd.structFields(dest)
+	default:
+		return &Error{Type: ErrSyntaxUnexpectedToken, Msg: fmt.Sprintf("unexpected %s at offset %d", tok.Kind, tok.Offset)}
+	}
+}
+
+// structFields decodes the entries of the dictionary whose 'd' has been
+// read into the fields of dest. Both the keys and the cached fields are
+// sorted, so they are matched in a single pass.
+func (dd *directDecoder) structFields(dest reflect.Value) error {
+	fields := getCachedStructInfo(dest.Type())
+	var prevKey []byte
+	for first := true; ; first = false {
+		keyTok, err := dd.scan.Next()
+		if err != nil {
+			return scanError(err)
+		}
+		if keyTok.Kind == scanner.End {
+			return nil
+		}
+		if keyTok.Kind != scanner.String {
+			return &Error{Type: ErrStructureDict, Msg: fmt.Sprintf("dictionary key at offset %d is not a bencode string", keyTok.Offset)}
+		}
+		key, err := dd.str(keyTok)
+		if err != nil {
+			return err
+		}
+		if !first {
+			switch c := CompareKeys(prevKey, key); {
+			case c == 0:
+				return &Error{Type: ErrStructureDictKeyDup, Msg: fmt.Sprintf("key %q", key), WrappedErr: ErrDuplicateDictionaryKey, FieldName: string(key)}
+			case c > 0:
+				return &Error{Type: ErrStructureDictKeySort, Msg: fmt.Sprintf("key %q is not lexicographically after %q", key, prevKey), WrappedErr: ErrDictionaryKeysNotSorted, FieldName: string(key)}
+			}
+		}
+		prevKey = key
+		if !dd.scan.More() || dd.scan.Peek() == scanner.End {
+			return &Error{Type: ErrStructureDictValue, Msg: "missing value", WrappedErr: ErrUnexpectedEOF, FieldName: string(key)}
+		}
+
+		for len(fields) > 0 && fields[0].bencodeTag < string(key) {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 || fields[0].bencodeTag != string(key) {
+			if _, err := dd.skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		f := fields[0]
+		if err := dd.value(dest.Field(f.index)); err != nil {
+			return &Error{
+				Type:       typeOf(err),
+				Msg:        fmt.Sprintf("setting field %s (tag %q)", f.fieldName, f.bencodeTag),
+				FieldName:  f.bencodeTag,
+				WrappedErr: err,
+			}
+		}
+	}
+}
+
+// skip reads the next value, checking it as Unmarshal would, and returns
+// its span.
+func (dd *directDecoder) skip() (scanner.Token, error) {
+	start := dd.scan.Offset()
+	tok, err := dd.scan.Next()
+	if err != nil {
+		return scanner.Token{}, scanError(err)
+	}
+	switch tok.Kind {
+	case scanner.String:
+		_, err = dd.str(tok)
+	case scanner.Integer:
+		_, err = dd.integer(tok)
+	case scanner.ListStart:
+		for err == nil && dd.scan.Peek() != scanner.End {
+			_, err = dd.skip()
+		}
+		if err == nil {
+			_, err = dd.scan.Next()
+		}
+	case scanner.DictStart:
+		err = dd.structFields(reflect.ValueOf(struct{}{}))
+	default:
+		return scanner.Token{}, &Error{Type: ErrSyntaxUnexpectedToken, Msg: fmt.Sprintf("unexpected %s at offset %d", tok.Kind, tok.Offset)}
+	}
+	if err != nil {
+		return scanner.Token{}, err
+	}
+	tok.Len = dd.scan.Offset() - start
+	return tok, nil
+}
+
+// str returns the contents of the string token tok after checking that its
+// length is minimally encoded.
+func (dd *directDecoder) str(tok scanner.Token) ([]byte, error) {
+	if err := checkMinimal(dd.data, tok); err != nil {
+		return nil, err
+	}
+	return dd.data[tok.ValueOffset : tok.ValueOffset+tok.ValueLen], nil
+}
+
+// integer parses the integer token tok, which must be minimally encoded and
+// fit in an int64, without allocating.
+func (dd *directDecoder) integer(tok scanner.Token) (int64, error) {
+	if err := checkMinimal(dd.data, tok); err != nil {
+		return 0, err
+	}
+	digits := dd.data[tok.ValueOffset : tok.ValueOffset+tok.ValueLen]
+	neg := digits[0] == '-'
+	if neg {
+		digits = digits[1:]
+	}
+	limit := uint64(1<<63 - 1)
+	if neg {
+		limit++
+	}
+	var n uint64
+	for _, c := range digits {
+		d := uint64(c - '0')
+		if n > (limit-d)/10 {
+			return 0, &Error{Type: ErrSyntaxInteger, Msg: fmt.Sprintf("cannot parse integer %s: out of range", dd.data[tok.ValueOffset:tok.ValueOffset+tok.ValueLen])}
+		}
+		n = n*10 + d
+	}
+	if neg {
+		return int64(-n), nil
+	}
+	return int64(n), nil
+}
+
+// storeDirectString stores the string b in dest, reusing dest's storage.
+func storeDirectString(dest reflect.Value, b []byte) error {
+	switch dest.Kind() {
+	case reflect.String:
+		if dest.String() != string(b) {
+			dest.SetString(string(b))
+		}
+	case reflect.Slice:
+		if dest.Type().Elem().Kind() != reflect.Uint8 {
+			return &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("cannot store string in %s", dest.Type())}
+		}
+		dest.SetBytes(append(dest.Bytes()[:0], b...))
+	case reflect.Array:
+		if dest.Type().Elem().Kind() != reflect.Uint8 {
+			return &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("cannot store string in %s", dest.Type())}
+		}
+		if len(b) != dest.Len() {
+			return &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("string of %d bytes does not fit %s", len(b), dest.Type())}
+		}
+		copy(dest.Bytes(), b)
+	default:
+		return &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("cannot store string in %s", dest.Type())}
+	}
+	return nil
+}
+
+// storeDirectInt stores n in the integer dest.
+func storeDirectInt(dest reflect.Value, n int64) error {
+	switch dest.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if dest.OverflowInt(n) {
+			return &Error{Type: ErrUnmarshalOverflow, Msg: fmt.Sprintf("value %d overflows type %s", n, dest.Type())}
+		}
+		dest.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n < 0 {
+			return &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("cannot assign negative value %d to unsigned type %s", n, dest.Type())}
+		}
+		if dest.OverflowUint(uint64(n)) {
+			return &Error{Type: ErrUnmarshalOverflow, Msg: fmt.Sprintf("value %d overflows type %s", n, dest.Type())}
+		}
+		dest.SetUint(uint64(n))
+	default:
+		return &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("cannot store integer in %s", dest.Type())}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// krpcPing is a KRPC ping query as sent between DHT nodes.
+type krpcPing struct {
+	A struct {
+		ID [20]byte `bencode:"id"`
+	} `bencode:"a"`
+	Q string `bencode:"q"`
+	T []byte `bencode:"t"`
+	Y string `bencode:"y"`
+}
+
+const krpcPingInput = "d1:ad2:id20:abcdefghij0123456789e1:q4:ping1:t2:aa1:y1:qe"
+
+func TestUnmarshalDirect(t *testing.T) {
+	var ping krpcPing
+	if err := UnmarshalDirect([]byte(krpcPingInput), &ping); err != nil {
+		t.Fatalf("UnmarshalDirect() error = %v", err)
+	}
+	var want krpcPing
+	if err := Unmarshal([]byte(krpcPingInput), &want); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(ping, want) {
+		t.Errorf("UnmarshalDirect() = %+v, want %+v", ping, want)
+	}
+
+	type mixed struct {
+		Count  uint8          `bencode:"count"`
+		Extra  map[string]any `bencode:"extra"`
+		List   []int          `bencode:"list"`
+		Next   *mixed         `bencode:"next"`
+		Opt    Optional[int]  `bencode:"opt"`
+		Raw    RawMessage     `bencode:"raw"`
+		Signed int16          `bencode:"signed"`
+	}
+	input := "d5:counti200e5:extrad1:xi1ee4:listli1ei2ee4:nextd6:signedi-5ee3:opti3e3:rawl1:ae6:signedi-300e7:unknownd1:ai1eee"
+	var got mixed
+	if err := UnmarshalDirect([]byte(input), &got); err != nil {
+		t.Fatalf("UnmarshalDirect() error = %v", err)
+	}
+	var wantMixed mixed
+	if err := Unmarshal([]byte(input), &wantMixed); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, wantMixed) {
+		t.Errorf("UnmarshalDirect() = %+v, want %+v", got, wantMixed)
+	}
+
+	// Storage already in the destination is reused.
+	buf := make([]byte, 0, 8)
+	ping.T = buf
+	if err := UnmarshalDirect([]byte(krpcPingInput), &ping); err != nil {
+		t.Fatalf("UnmarshalDirect() error = %v", err)
+	}
+	if &ping.T[:1][0] != &buf[:1][0] || string(ping.T) != "aa" {
+		t.Errorf("UnmarshalDirect() T = %q, want %q in the existing buffer", ping.T, "aa")
+	}
+}
+
+func TestUnmarshalDirectErrors(t *testing.T) {
+	type message struct {
+		N int8   `bencode:"n"`
+		S string `bencode:"s"`
+		U uint   `bencode:"u"`
+	}
+	tests := []struct {
+		input string
+		want  ErrorType
+	}{
+		{"d1:si1ee", ErrUnmarshalType},
+		{"d1:ni300ee", ErrUnmarshalOverflow},
+		{"d1:ui-1ee", ErrUnmarshalType},
+		{"d1:ni01ee", ErrSyntaxInteger},
+		{"d1:ni-0ee", ErrSyntaxInteger},
+		{"d1:ni99999999999999999999ee", ErrSyntaxInteger},
+		{"d1:s01:xe", ErrSyntaxStringLength},
+		{"d1:s1:x1:n1:1e", ErrStructureDictKeySort},
+		{"d1:ni1e1:ni1ee", ErrStructureDictKeyDup},
+		{"d1:xd1:bi1e1:ai1eee", ErrStructureDictKeySort},
+		{"d1:ne", ErrStructureDictValue},
+		{"di1ei1ee", ErrStructureDict},
+		{"d1:s1:x", ErrSyntaxEOF},
+		{"l1:xe", ErrUnmarshalType},
+		{"", ErrSyntaxEOF},
+	}
+	for _, tt := range tests {
+		var m message
+		err := UnmarshalDirect([]byte(tt.input), &m)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("UnmarshalDirect(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+
+	// Lists of integers fill byte arrays and slices, as with Unmarshal.
+	var bytesMsg struct {
+		A [2]byte `bencode:"a"`
+		B []byte  `bencode:"b"`
+	}
+	if err := UnmarshalDirect([]byte("d1:ali1ei2ee1:bli3eee"), &bytesMsg); err != nil || bytesMsg.A != [2]byte{1, 2} || string(bytesMsg.B) != "\x03" {
+		t.Errorf("UnmarshalDirect() of integer lists = %+v, %v", bytesMsg, err)
+	}
+	var arr [2]byte
+	if err := UnmarshalDirect([]byte("li1ei2ee"), &arr); err != nil || arr != [2]byte{1, 2} {
+		t.Errorf("UnmarshalDirect() of integer list = %v, %v", arr, err)
+	}
+
+	var m message
+	if err := UnmarshalDirect([]byte("de"), m); !errors.Is(err, ErrUsage) {
+		t.Errorf("UnmarshalDirect(non-pointer) error = %v, want %q", err, ErrUsage)
+	}
+}
+
+func TestKRPCPingAllocs(t *testing.T) {
+	data := []byte(krpcPingInput)
+	var ping krpcPing
+	if allocs := testing.AllocsPerRun(100, func() { _ = UnmarshalDirect(data, &ping) }); allocs != 0 {
+		t.Errorf("UnmarshalDirect() allocated %v times per run, want 0", allocs)
+	}
+	if string(ping.A.ID[:]) != "abcdefghij0123456789" || ping.Q != "ping" || string(ping.T) != "aa" || ping.Y != "q" {
+		t.Fatalf("UnmarshalDirect() = %+v", ping)
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf.Reset()
+		_ = enc.Encode(&ping)
+	})
+	if allocs != 0 {
+		t.Errorf("Encode() allocated %v times per run, want 0", allocs)
+	}
+	if buf.String() != krpcPingInput {
+		t.Errorf("Encode() = %q, want %q", buf.String(), krpcPingInput)
+	}
+}
@@ -0,0 +1,285 @@
+package bencode
+
+import (
+	"fmt"
+	"iter"
+	"sort"
+	"strconv"
+
+	"github.com/stupoid/bencode/scanner"
+)
+
+// ErrPathNotFound indicates a path does not resolve to a value in a Document.
+const ErrPathNotFound ErrorType = "path not found"
+
+// Document is an index over an encoded bencode value that serves repeated
+// lookups without rescanning the data. It is built once with Index and is
+// safe for concurrent use, as it is never modified afterwards.
+type Document struct {
+	data     []byte
+	nodes    []docNode
+	children []int32 // node indices; each container's children are contiguous
+	lenient  bool    // numbers need not be minimal, as Set and Delete allow
+}
+
+// docNode records the byte range of one value and, for containers, where its
+// children are listed in Document.children.
+type docNode struct {
+	kind       Kind
+	start, end int
+	// keyOffset is the start of the key's token, and keyStart and keyEnd
+	// delimit the key's bytes, when the parent is a dictionary.
+	keyOffset, keyStart, keyEnd int
+	// firstChild and numChildren index into Document.children.
+	firstChild, numChildren int
+}
+
+// Index scans data, which must hold exactly one bencode value, and returns a
+// Document indexing it. As for the Decoder, integers and string lengths must
+// be minimally encoded and dictionary keys sorted and unique. The Document refers to data, which must not be modified while
+// the Document is in use.
+func Index(data []byte) (*Document, error) {
+	return indexDocument(&Document{data: data})
+}
+
+// indexDocument indexes doc.data into doc.
+func indexDocument(doc *Document) (*Document, error) {
+	data := doc.data
+	var s scanner.Scanner
+	s.Reset(data)
+	tok, err := s.Next()
+	if err != nil {
+		return nil, scanError(err)
+	}
+	if _, err := doc.index(&s, tok, scanner.Token{}); err != nil {
+		return nil, err
+	}
+	if s.More() {
+		return nil, &Error{Type: ErrSyntax, Msg: fmt.Sprintf("unexpected data after bencode value at offset %d", s.Offset())}
+	}
+	return doc, nil
+}
+
+// index records the value starting with tok, under the dictionary key keyTok
+// if the parent is a dictionary, and returns its node index.
+func (doc *Document) index(s *scanner.Scanner, tok, keyTok scanner.Token) (int32, error) {
+	idx := int32(len(doc.nodes))
+	doc.nodes = append(doc.nodes, docNode{
+		start:     tok.Offset,
+		keyOffset: keyTok.Offset,
+		keyStart:  keyTok.ValueOffset,
+		keyEnd:    keyTok.ValueOffset + keyTok.ValueLen,
+	})
+
+	var kind Kind
+	var kids []int32
+	if err := doc.checkMinimal(tok); err != nil {
+		return 0, err
+	}
+	switch tok.Kind {
+	case scanner.String:
+		kind = KindString
+	case scanner.Integer:
+		kind = KindInteger
+	case scanner.ListStart:
+		kind = KindList
+		for {
+			next, err := s.Next()
+			if err != nil {
+				return 0, scanError(err)
+			}
+			if next.Kind == scanner.End {
+				break
+			}
+			child, err := doc.index(s, next, scanner.Token{})
+			if err != nil {
+				return 0, err
+			}
+			kids = append(kids, child)
+		}
+	case scanner.DictStart:
+		kind = KindDict
+		var prevKey []byte
+		for {
+			keyTok, err := s.Next()
+			if err != nil {
+				return 0, scanError(err)
+			}
+			if keyTok.Kind == scanner.End {
+				break
+			}
+			if keyTok.Kind != scanner.String {
+				return 0, &Error{Type: ErrStructureDict, Msg: fmt.Sprintf("dictionary key at offset %d is not a bencode string", keyTok.Offset)}
+			}
+			if err := doc.checkMinimal(keyTok); err != nil {
+				return 0, err
+			}
+			key := doc.data[keyTok.ValueOffset : keyTok.ValueOffset+keyTok.ValueLen]
+			if prevKey != nil {
+				switch CompareKeys(prevKey, key) {
+				case 0:
+					return 0, &Error{Type: ErrStructureDictKeyDup, Msg: fmt.Sprintf("key %q", key), WrappedErr: ErrDuplicateDictionaryKey, FieldName: string(key)}
+				case 1:
+					return 0, &Error{Type: ErrStructureDictKeySort, Msg: fmt.Sprintf("key %q is not lexicographically after %q", key, prevKey), WrappedErr: ErrDictionaryKeysNotSorted, FieldName: string(key)}
+				}
+			}
+			prevKey = key
+
+			valTok, err := s.Next()
+			if err != nil {
+				return 0, scanError(err)
+			}
+			if valTok.Kind == scanner.End {
+				return 0, &Error{Type: ErrStructureDictValue, Msg: "missing value", FieldName: string(key)}
+			}
+			child, err := doc.index(s, valTok, keyTok)
+			if err != nil {
+				return 0, err
+			}
+			kids = append(kids, child)
+		}
+	default:
+		return 0, &Error{Type: ErrSyntaxUnexpectedToken, Msg: fmt.Sprintf("unexpected %s at offset %d", tok.Kind, tok.Offset)}
+	}
+
+	node := &doc.nodes[idx]
+	node.kind = kind
+	node.end = s.Offset()
+	node.firstChild = len(doc.children)
+	node.numChildren = len(kids)
+	doc.children = append(doc.children, kids...)
+	return idx, nil
+}
+
+// lookup resolves path to a node index.
+func (doc *Document) lookup(path []string) (int32, error) {
+	var idx int32
+	for i, elem := range path {
+		j, found, err := doc.child(idx, elem, path[:i])
+		if err != nil {
+			return 0, err
+		}
+		if !found {
+			return 0, &Error{Type: ErrPathNotFound, Msg: fmt.Sprintf("no key %q at %v", elem, path[:i]), FieldName: elem}
+		}
+		node := doc.nodes[idx]
+		idx = doc.children[node.firstChild+j]
+	}
+	return idx, nil
+}
+
+// child finds elem among the children of the container node idx, reached by
+// parentPath. It returns the child's position and whether it exists; for a
+// missing dictionary key the position is where the key would be inserted.
+func (doc *Document) child(idx int32, elem string, parentPath []string) (int, bool, error) {
+	node := doc.nodes[idx]
+	kids := doc.children[node.firstChild : node.firstChild+node.numChildren]
+	switch node.kind {
+	case KindDict:
+		j := sort.Search(len(kids), func(j int) bool {
+			return doc.key(kids[j]) >= elem
+		})
+		return j, j < len(kids) && doc.key(kids[j]) == elem, nil
+	case KindList:
+		j, err := strconv.Atoi(elem)
+		if err != nil || j < 0 || j >= len(kids) {
+			return 0, false, &Error{Type: ErrPathNotFound, Msg: fmt.Sprintf("no list index %q at %v", elem, parentPath), FieldName: elem}
+		}
+		return j, true, nil
+	default:
+		return 0, false, &Error{Type: ErrPathNotFound, Msg: fmt.Sprintf("cannot index %s at %v", node.kind, parentPath), FieldName: elem}
+	}
+}
+
+// key returns the dictionary key of node idx.
+func (doc *Document) key(idx int32) string {
+	node := doc.nodes[idx]
+	return string(doc.data[node.keyStart:node.keyEnd])
+}
+
+// Get returns the encoded value at path. Each path element is a dictionary
+// key or, within a list, a decimal index. An empty path returns the whole
+// document. The returned RawMessage aliases the indexed data.
+func (doc *Document) Get(path ...string) (RawMessage, error) {
+	idx, err := doc.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	node := doc.nodes[idx]
+	return RawMessage(doc.data[node.start:node.end:node.end]), nil
+}
+
+// Kind returns the kind of the value at path.
+func (doc *Document) Kind(path ...string) (Kind, error) {
+	idx, err := doc.lookup(path)
+	if err != nil {
+		return KindInvalid, err
+	}
+	return doc.nodes[idx].kind, nil
+}
+
+// Decode decodes the value at path into the value pointed to by v, as
+// Unmarshal does.
+func (doc *Document) Decode(path []string, v any) error {
+	raw, err := doc.Get(path...)
+	if err != nil {
+		return err
+	}
+	return Unmarshal(raw, v)
+}
+
+// Wildcard is the path element that matches every element of a list and
+// every value of a dictionary in Select.
+const Wildcard = "*"
+
+// Select returns an iterator over the values matching path, which is as for
+// Get except that a Wildcard element matches every child of a list or
+// dictionary, so Select("info", "files", Wildcard, "length") yields the
+// length of every file. Each match is yielded with its full concrete path,
+// such as ["info" "files" "3" "length"], in document order. Branches that do
+// not have the rest of the path, such as a file without a "length" key, are
+// skipped rather than reported as errors. The path slice is reused between
+// matches and must be copied to be retained.
+func (doc *Document) Select(path ...string) iter.Seq2[[]string, RawMessage] {
+	return func(yield func([]string, RawMessage) bool) {
+		doc.selectFrom(0, path, make([]string, 0, len(path)), yield)
+	}
+}
+
+// selectFrom yields the matches of path below node idx, reached by prefix.
+// It reports whether iteration should continue.
+func (doc *Document) selectFrom(idx int32, path, prefix []string, yield func([]string, RawMessage) bool) bool {
+	node := doc.nodes[idx]
+	if len(path) == 0 {
+		return yield(prefix, RawMessage(doc.data[node.start:node.end:node.end]))
+	}
+	if node.kind != KindList && node.kind != KindDict {
+		return true
+	}
+	kids := doc.children[node.firstChild : node.firstChild+node.numChildren]
+	if path[0] != Wildcard {
+		j, found, err := doc.child(idx, path[0], nil)
+		if err != nil || !found {
+			return true
+		}
+		return doc.selectFrom(kids[j], path[1:], append(prefix, path[0]), yield)
+	}
+	for j, kid := range kids {
+		elem := strconv.Itoa(j)
+		if node.kind == KindDict {
+			elem = doc.key(kid)
+		}
+		if !doc.selectFrom(kid, path[1:], append(prefix, elem), yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkMinimal applies checkMinimal to tok unless doc is lenient.
+func (doc *Document) checkMinimal(tok scanner.Token) error {
+	if doc.lenient {
+		return nil
+	}
+	return checkMinimal(doc.data, tok)
+}
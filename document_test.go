@@ -0,0 +1,125 @@
+package bencode
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDocument(t *testing.T) {
+	doc, err := Index(unmarshalTestData)
+	if err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+
+	tests := []struct {
+		path     []string
+		expected string
+		kind     Kind
+	}{
+		{path: nil, expected: string(unmarshalTestData), kind: KindDict},
+		{path: []string{"comment"}, expected: "33:Debian CD from cdimage.debian.org", kind: KindString},
+		{path: []string{"info", "length"}, expected: "i170917888e", kind: KindInteger},
+		{path: []string{"announce-list", "1", "0"}, expected: "44:udp://tracker.openbittorrent.com:80/announce", kind: KindString},
+		{path: []string{"announce-list", "0"}, expected: "l38:udp://tracker.publicbt.com:80/announcee", kind: KindList},
+	}
+	for _, tt := range tests {
+		got, err := doc.Get(tt.path...)
+		if err != nil {
+			t.Errorf("Get(%q) error = %v", tt.path, err)
+			continue
+		}
+		if string(got) != tt.expected {
+			t.Errorf("Get(%q) = %q, want %q", tt.path, got, tt.expected)
+		}
+		if kind, _ := doc.Kind(tt.path...); kind != tt.kind {
+			t.Errorf("Kind(%q) = %v, want %v", tt.path, kind, tt.kind)
+		}
+	}
+
+	var info Info
+	if err := doc.Decode([]string{"info"}, &info); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !reflect.DeepEqual(info, metainfoTestData.Info) {
+		t.Errorf("Decode() = %+v, want %+v", info, metainfoTestData.Info)
+	}
+
+	for _, path := range [][]string{{"missing"}, {"info", "zzz"}, {"announce-list", "2"}, {"announce-list", "x"}, {"comment", "0"}} {
+		_, err := doc.Get(path...)
+		var bErr *Error
+		if !errors.As(err, &bErr) || bErr.Type != ErrPathNotFound {
+			t.Errorf("Get(%q) error = %v, want %q", path, err, ErrPathNotFound)
+		}
+	}
+}
+
+func TestIndexErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ErrorType
+	}{
+		{input: "d1:bi1e1:ai2ee", expected: ErrStructureDictKeySort},
+		{input: "d1:ai1e1:ai2ee", expected: ErrStructureDictKeyDup},
+		{input: "di1ei2ee", expected: ErrStructureDict},
+		{input: "d1:ae", expected: ErrStructureDictValue},
+		{input: "l4:spam", expected: ErrSyntaxEOF},
+		{input: "i1ei2e", expected: ErrSyntax},
+		{input: "i01e", expected: ErrSyntaxInteger},
+		{input: "i-0e", expected: ErrSyntaxInteger},
+		{input: "03:abc", expected: ErrSyntaxStringLength},
+		{input: "d01:ai1ee", expected: ErrSyntaxStringLength},
+		{input: "d1:ali007eee", expected: ErrSyntaxInteger},
+	}
+	for _, tt := range tests {
+		_, err := Index([]byte(tt.input))
+		var bErr *Error
+		if !errors.As(err, &bErr) || bErr.Type != tt.expected {
+			t.Errorf("Index(%q) error = %v, want %q", tt.input, err, tt.expected)
+		}
+	}
+}
+
+func TestDocumentSelect(t *testing.T) {
+	data := []byte("d4:infod5:filesld6:lengthi1e4:pathl1:aeed4:pathl1:beed6:lengthi3e4:pathl1:ceee4:name4:roote5:nodesd1:xli1ei2ee1:yli3eeee")
+	doc, err := Index(data)
+	if err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	collect := func(path ...string) []string {
+		var got []string
+		for p, v := range doc.Select(path...) {
+			got = append(got, strings.Join(p, ".")+"="+string(v))
+		}
+		return got
+	}
+	tests := []struct {
+		path     []string
+		expected []string
+	}{
+		{path: []string{"info", "files", Wildcard, "length"}, expected: []string{"info.files.0.length=i1e", "info.files.2.length=i3e"}},
+		{path: []string{"info", "files", Wildcard, "path", "0"}, expected: []string{"info.files.0.path.0=1:a", "info.files.1.path.0=1:b", "info.files.2.path.0=1:c"}},
+		{path: []string{"nodes", Wildcard, Wildcard}, expected: []string{"nodes.x.0=i1e", "nodes.x.1=i2e", "nodes.y.0=i3e"}},
+		{path: []string{"info", "name"}, expected: []string{"info.name=4:root"}},
+		{path: []string{"info", "name", Wildcard}, expected: nil},
+		{path: []string{"missing", Wildcard}, expected: nil},
+	}
+	for _, tt := range tests {
+		if got := collect(tt.path...); !slices.Equal(got, tt.expected) {
+			t.Errorf("Select(%q) = %q, want %q", tt.path, got, tt.expected)
+		}
+	}
+
+	n := 0
+	for range doc.Select("nodes", Wildcard, Wildcard) {
+		n++
+		if n == 2 {
+			break
+		}
+	}
+	if n != 2 {
+		t.Errorf("Select() did not stop after break")
+	}
+}
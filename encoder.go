@@ -2,10 +2,15 @@ package bencode
 
 import (
 	"bytes"
+	"cmp"
 	"fmt"
+	"hash"
 	"io"
+	"log/slog"
 	"reflect"
 	"slices"
+	"strconv"
+	"time"
 )
 
 var (
@@ -15,6 +20,14 @@ var (
 	ErrEncodeMapKeyNotString ErrorType = "encode: map key not string"
 	// ErrEncodeWriteError indicates an error occurred while writing to the output stream.
 	ErrEncodeWriteError ErrorType = "encode: write error"
+	// ErrEncodeDuplicateKey indicates that a sequence encoded as a dictionary yielded the same key twice.
+	ErrEncodeDuplicateKey ErrorType = "encode: duplicate dictionary key"
+	// ErrEncodeNil indicates a nil interface value, which has no bencode representation.
+	ErrEncodeNil ErrorType = "encode: nil value"
+	// ErrEncodeCycle indicates a map or slice that contains itself, which would encode forever.
+	ErrEncodeCycle ErrorType = "encode: cycle"
+	// ErrEncodeInvalidRaw indicates a RawMessage that does not hold exactly one well-formed bencode value.
+	ErrEncodeInvalidRaw ErrorType = "encode: invalid raw value"
 )
 
 // Marshal returns the bencode encoding of v.
@@ -22,11 +35,47 @@ var (
 // Marshal traverses the value v recursively.
 // Supported types are:
 //   - int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64: encoded as bencode integers.
-//   - string, []byte: encoded as bencode strings.
-//   - slices: encoded as bencode lists.
+//     Named types with these underlying types are supported too.
+//   - string, and slices and arrays of any byte kind such as []byte, InfoHash or
+//     []MyByte: encoded as bencode strings. A struct field tagged with the list
+//     option, e.g. `bencode:"flags,list"`, is encoded as a list of integers
+//     instead.
+//   - other slices: encoded as bencode lists.
 //   - maps with string keys: encoded as bencode dictionaries. Keys are sorted lexicographically.
 //   - structs: encoded as bencode dictionaries. Exported fields are used, respecting 'bencode' tags
 //     for key names (e.g., `bencode:"custom_name"`).
+//   - RawMessage: written verbatim, after validation if enabled with
+//     Encoder.ValidateRaw or Encoder.RequireCanonical. An empty RawMessage
+//     struct field is omitted; an empty RawMessage anywhere else is an
+//     ErrEncodeInvalidRaw error.
+//   - iter.Seq[T]: encoded as a bencode list, streaming elements as they are yielded.
+//   - iter.Seq2[K, V] with a string K: encoded as a bencode dictionary. Pairs are
+//     buffered so that keys can be sorted; yielding a key twice is an error.
+//   - reflect.Value: encoded as the value it holds.
+//   - Optional[T]: encoded as the value it holds. An absent Optional struct
+//     field is omitted; an absent Optional anywhere else is an error.
+//   - pointers: encoded as the value they point to. A nil pointer struct
+//     field is omitted; a nil pointer anywhere else is an ErrEncodeNil error.
+//   - sql.NullInt64, sql.NullString and the other Null types of database/sql
+//     holding a string or an integer: encoded as the value they hold, and
+//     omitted or an error when not valid, as for Optional. Those holding
+//     other values, such as sql.NullTime, are an ErrEncodeUnsupportedType
+//     error.
+//
+// A nil interface value, such as the "x" entry of map[string]any{"x": nil},
+// is an ErrEncodeNil error whose FieldName is the dotted path to the value,
+// e.g. "peers.2.ip". Encoder.OmitNil skips such values instead.
+//
+// A map, slice or pointer that contains itself, directly or through other
+// values, is an ErrEncodeCycle error rather than an endless recursion. Its
+// FieldName is the path to where the value recurs and its message gives the
+// repeating part of that path.
+//
+// Hooks added with RegisterEncodeHook may convert each value before it is
+// encoded.
+//
+// The generic values returned by Decoder.DecodeValue ([]byte, int64, []any and
+// map[string]any) re-encode to exactly the bytes they were decoded from.
 //
 // Unsupported types will result in an error.
 func Marshal(v any) ([]byte, error) {
@@ -38,8 +87,30 @@ func Marshal(v any) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// An Encoder writes bencode values to an output stream.
+//
+// An Encoder is not safe for concurrent use by multiple goroutines. Callers
+// sharing one must serialise calls to Encode themselves; DetectConcurrentUse
+// can be enabled to catch violations.
 type Encoder struct {
-	w io.Writer
+	w                io.Writer
+	guard            useGuard
+	requireCanonical bool
+	omitNil          bool
+	validateRaw      bool
+	fieldOrder       bool
+	teeHash          hash.Hash        // hashes each top-level value, when set by TeeHash
+	broadcast        *broadcastWriter // the writers of NewMultiEncoder
+	encodeHooks      []EncodeHookFunc
+	metrics          *Metrics
+	logger           *slog.Logger
+	scratch          [24]byte // buffer for integers and string lengths
+
+	rootKeys   []rawEntry      // entries added to top-level dictionaries
+	open       []openContainer // containers begun with BeginList or BeginDict
+	broken     error           // failure of a value inside open, which cannot be completed
+	visiting   []containerRef  // maps and slices on the path being encoded
+	cycleStart int             // index in visiting where a detected cycle starts
 }
 
 // NewEncoder returns a new encoder that writes to w.
@@ -47,22 +118,379 @@ func NewEncoder(w io.Writer) *Encoder {
 	return &Encoder{w: w}
 }
 
+// DetectConcurrentUse makes Encode return an ErrUsage error, without writing
+// anything, when it is called while another call to Encode on the same
+// Encoder is still in progress. It must be called before the Encoder is
+// shared between goroutines.
+func (e *Encoder) DetectConcurrentUse() {
+	e.guard.enabled = true
+}
+
+// RequireCanonical makes the Encoder check every RawMessage with
+// CheckCanonical before writing it. A RawMessage that is not canonically
+// encoded is rejected with an ErrEncodeNonCanonical error wrapping the
+// Violations found, rather than being passed through verbatim.
+func (e *Encoder) RequireCanonical() {
+	e.requireCanonical = true
+}
+
+// ValidateRaw makes the Encoder check that every RawMessage holds exactly
+// one well-formed bencode value before writing it, so that a corrupted
+// fragment is rejected with an ErrEncodeInvalidRaw error instead of making
+// the whole output unreadable. Unlike RequireCanonical, it accepts values
+// that are well-formed but not canonically encoded, such as dictionaries
+// with unsorted keys.
+func (e *Encoder) ValidateRaw() {
+	e.validateRaw = true
+}
+
+// UseFieldOrder makes the Encoder write struct fields in the order given by
+// their order=N tag options, lowest first, and otherwise in declaration
+// order, rather than sorted by key. Fields without the option have weight 0.
+// This is for legacy consumers that parse positionally; the output is not
+// canonical bencode, and Decoder, which requires sorted keys, rejects it.
+// Map keys are still sorted, as are the keys of structs with inline or rest
+// fields.
+func (e *Encoder) UseFieldOrder() {
+	e.fieldOrder = true
+}
+
+// orderedFields returns the fields of the struct type typ in the order
+// written by UseFieldOrder.
+func orderedFields(typ reflect.Type, fields []cachedStructFieldInfo) ([]cachedStructFieldInfo, error) {
+	for _, f := range fields {
+		if f.orderErr {
+			return nil, &Error{Type: ErrUsage, Msg: fmt.Sprintf("field %s of %s has a non-integer order option", f.fieldName, typ), FieldName: f.bencodeTag}
+		}
+	}
+	return slices.SortedFunc(slices.Values(fields), func(a, b cachedStructFieldInfo) int {
+		return cmp.Or(cmp.Compare(a.order, b.order), cmp.Compare(a.index, b.index))
+	}), nil
+}
+
+// OmitNil makes the Encoder skip nil interface values inside lists,
+// dictionaries and struct fields rather than failing with ErrEncodeNil. A nil
+// dictionary value or struct field has its key omitted and a nil list element
+// is dropped, shifting the elements after it. A nil top-level value is still
+// an error.
+func (e *Encoder) OmitNil() {
+	e.omitNil = true
+}
+
 // Encode writes the bencode encoding of v to the stream.
 //
 // See the documentation for Marshal for details about the conversion
 // of a Go value to bencode.
 func (e *Encoder) Encode(v any) error {
+	if err := e.guard.acquire("Encode"); err != nil {
+		return err
+	}
+	defer e.guard.release()
+	if e.metrics == nil && e.logger == nil {
+		return e.writerErrors(e.encodeRoot(v))
+	}
+
+	began := time.Now()
+	cw := &countingWriter{w: e.w}
+	e.w = cw
+	err := e.writerErrors(e.encodeRoot(v))
+	e.w = cw.w
+	e.traceEncode(v, cw.n, began, err)
+	if e.metrics != nil {
+		e.metrics.bytesEncoded.Add(cw.n)
+		if err != nil {
+			e.metrics.recordError(err)
+		} else {
+			e.metrics.valuesEncoded.Add(1)
+		}
+	}
+	return err
+}
+
+// AddRootKey makes the Encoder add key, holding the encoding of value as
+// by Marshal, to every top-level dictionary it writes that lacks the key.
+// This suits protocol-wide entries such as the client version of KRPC
+// messages. Values other than dictionaries are written unchanged. Since the
+// entry must be placed in key order, top-level values are encoded into a
+// buffer before being written while any keys have been added.
+func (e *Encoder) AddRootKey(key string, value any) error {
+	encoded, err := Marshal(value)
+	if err != nil {
+		return &Error{Type: err.(*Error).Type, Msg: fmt.Sprintf("encoding root key %q", key), WrappedErr: err, FieldName: key}
+	}
+	e.rootKeys = append(e.rootKeys, rawEntry{key: key, value: encoded})
+	return nil
+}
+
+// encodeRoot encodes the top-level value v, adding the root keys to it, or
+// a value inside a container begun with BeginList or BeginDict.
+func (e *Encoder) encodeRoot(v any) error {
+	if len(e.open) > 0 {
+		if err := e.startValue(nil, false); err != nil {
+			return err
+		}
+		if err := e.encode(v); err != nil {
+			return e.breakOpen(err)
+		}
+		return nil
+	}
+	e.resetHash()
+	if len(e.rootKeys) == 0 {
+		return e.encode(v)
+	}
+	w := e.w
+	var buf bytes.Buffer
+	e.w = &buf
+	err := e.encode(v)
+	e.w = w
+	if err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	if data[0] == 'd' {
+		entries, err := rawDictEntries(data)
+		if err != nil {
+			return err
+		}
+		for _, root := range e.rootKeys {
+			if !slices.ContainsFunc(entries, func(entry rawEntry) bool { return entry.key == root.key }) {
+				entries = append(entries, root)
+			}
+		}
+		data = appendRawDict(nil, entries)
+	}
+	if _, err := w.Write(data); err != nil {
+		return &Error{Type: ErrEncodeWriteError, Msg: "failed to write top-level value", WrappedErr: err}
+	}
+	return nil
+}
+
+// EncodeValue writes the bencode encoding of the value held by v to the
+// stream, for callers already working with reflection. It is equivalent to
+// Encode(v), but integers and strings held by v are written without
+// converting v to an interface value first, so they do not allocate when no
+// encode hooks, metrics or logger are in use.
+func (e *Encoder) EncodeValue(v reflect.Value) error {
+	if e.metrics != nil || e.logger != nil || len(e.open) > 0 || !v.IsValid() || !v.CanInterface() {
+		return e.Encode(v)
+	}
+	if err := e.guard.acquire("EncodeValue"); err != nil {
+		return err
+	}
+	defer e.guard.release()
+	e.resetHash()
+	if done, err := e.encodeScalarValue(v); done {
+		return e.writerErrors(err)
+	}
+	return e.writerErrors(e.encodeRoot(v))
+}
+
+// encodeScalarValue writes v directly if it holds an integer or string of a
+// type with no special encoding, reporting whether it did.
+func (e *Encoder) encodeScalarValue(v reflect.Value) (bool, error) {
+	if e.hooked() || v.Type() == uint64StringType || v.Type() == numberType {
+		return false, nil
+	}
+	var err error
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		_, err = e.w.Write(append(strconv.AppendInt(append(e.scratch[:0], 'i'), v.Int(), 10), 'e'))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		_, err = e.w.Write(append(strconv.AppendUint(append(e.scratch[:0], 'i'), v.Uint(), 10), 'e'))
+	case reflect.String:
+		err = e.writeString(v.String())
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return true, &Error{Type: ErrEncodeWriteError, Msg: fmt.Sprintf("failed to write %s", v.Kind()), WrappedErr: err}
+	}
+	return true, nil
+}
+
+var reflectValueType = reflect.TypeFor[reflect.Value]()
+
+// encodeDirect writes v without converting it to an interface value, which
+// would allocate, if it is a scalar, a byte string or a struct with no
+// special encoding, reporting whether it did.
+func (e *Encoder) encodeDirect(v reflect.Value) (bool, error) {
+	if done, err := e.encodeScalarValue(v); done {
+		return true, err
+	}
+	if e.hooked() {
+		return false, nil
+	}
+	typ := v.Type()
+	switch {
+	case isByteSequence(typ) && typ != rawMessageType:
+		return true, e.encodeByteString(v)
+	case typ.Kind() == reflect.Struct && typ != reflectValueType && !typ.Implements(optionalType) && !isSQLNull(typ):
+		return true, e.encodeStruct(v)
+	}
+	return false, nil
+}
+
+// writeByte writes the single token c.
+func (e *Encoder) writeByte(c byte) error {
+	e.scratch[0] = c
+	_, err := e.w.Write(e.scratch[:1])
+	return err
+}
+
+// isByteSequence reports whether typ is a slice or array whose elements are
+// of a byte kind, including named types such as []MyByte.
+func isByteSequence(typ reflect.Type) bool {
+	return (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array) && typ.Elem().Kind() == reflect.Uint8
+}
+
+// encodeByteString writes val, a slice or array of a byte kind, as a bencode
+// string.
+func (e *Encoder) encodeByteString(val reflect.Value) error {
+	var b []byte
+	if val.Kind() == reflect.Slice || val.CanAddr() {
+		b = val.Bytes()
+	} else {
+		arr := reflect.New(val.Type()).Elem()
+		arr.Set(val)
+		b = arr.Bytes()
+	}
+	if err := e.writeBytes(b); err != nil {
+		return &Error{Type: ErrEncodeWriteError, Msg: fmt.Sprintf("failed to write %s", val.Type()), WrappedErr: err}
+	}
+	return nil
+}
+
+// writeBytes writes b as a bencode string.
+func (e *Encoder) writeBytes(b []byte) error {
+	header := append(strconv.AppendInt(e.scratch[:0], int64(len(b)), 10), ':')
+	if _, err := e.w.Write(header); err != nil {
+		return err
+	}
+	_, err := e.w.Write(b)
+	return err
+}
+
+// encodeByteList writes val, a slice or array of a byte kind, as a list of
+// integers, for fields tagged with the list option.
+func (e *Encoder) encodeByteList(val reflect.Value) error {
+	buf := append(e.scratch[:0], 'l')
+	for i := range val.Len() {
+		buf = append(strconv.AppendUint(append(buf, 'i'), val.Index(i).Uint(), 10), 'e')
+	}
+	if _, err := e.w.Write(append(buf, 'e')); err != nil {
+		return &Error{Type: ErrEncodeWriteError, Msg: fmt.Sprintf("failed to write %s as a list", val.Type()), WrappedErr: err}
+	}
+	return nil
+}
+
+// writeString writes s as a bencode string. When the underlying writer
+// implements io.StringWriter, as bytes.Buffer, bufio.Writer and
+// strings.Builder do, the contents are passed to WriteString so that s is
+// not converted to a []byte first.
+func (e *Encoder) writeString(s string) error {
+	header := append(strconv.AppendInt(e.scratch[:0], int64(len(s)), 10), ':')
+	sw, ok := e.w.(io.StringWriter)
+	if !ok {
+		_, err := e.w.Write(append(header, s...))
+		return err
+	}
+	if _, err := e.w.Write(header); err != nil {
+		return err
+	}
+	_, err := sw.WriteString(s)
+	return err
+}
+
+// encode is the internal recursive encoding function.
+func (e *Encoder) encode(v any) error {
+	if e.hooked() {
+		var err error
+		if v, err = e.runEncodeHooks(v); err != nil {
+			return err
+		}
+	}
+	return e.encodeValue(v)
+}
+
+// encodeValue encodes v, which has already been passed through the hooks.
+func (e *Encoder) encodeValue(v any) error {
 	switch valTyped := v.(type) {
+	case nil:
+		return &Error{Type: ErrEncodeNil, Msg: "cannot marshal nil interface value"}
 	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
 		if _, err := fmt.Fprintf(e.w, "i%de", valTyped); err != nil {
 			return &Error{Type: ErrEncodeWriteError, Msg: "failed to write integer", WrappedErr: err}
 		}
 		return nil
 	case string:
-		if _, err := fmt.Fprintf(e.w, "%d:%s", len([]byte(valTyped)), valTyped); err != nil {
+		if err := e.writeString(valTyped); err != nil {
 			return &Error{Type: ErrEncodeWriteError, Msg: "failed to write string", WrappedErr: err}
 		}
 		return nil
+	case RawMessage:
+		if len(valTyped) == 0 {
+			return &Error{Type: ErrEncodeInvalidRaw, Msg: "raw message is empty"}
+		}
+		if e.requireCanonical {
+			violations, err := CheckCanonical(valTyped)
+			if err != nil {
+				return &Error{Type: ErrEncodeNonCanonical, Msg: "raw message is not valid bencode", WrappedErr: err}
+			}
+			if len(violations) > 0 {
+				return &Error{Type: ErrEncodeNonCanonical, Msg: fmt.Sprintf("raw message has %d canonical encoding violations", len(violations)), WrappedErr: violations}
+			}
+		} else if e.validateRaw {
+			if _, err := CheckCanonical(valTyped); err != nil {
+				return &Error{Type: ErrEncodeInvalidRaw, Msg: "raw message is not valid bencode", WrappedErr: err}
+			}
+		}
+		if _, err := e.w.Write(valTyped); err != nil {
+			return &Error{Type: ErrEncodeWriteError, Msg: "failed to write raw message", WrappedErr: err}
+		}
+		return nil
+	case reflect.Value:
+		if !valTyped.IsValid() {
+			return &Error{Type: ErrEncodeUnsupportedType, Msg: "cannot marshal invalid reflect.Value"}
+		}
+		if !valTyped.CanInterface() {
+			return &Error{Type: ErrEncodeUnsupportedType, Msg: fmt.Sprintf("cannot marshal reflect.Value of type %s obtained from an unexported field", valTyped.Type())}
+		}
+		if done, err := e.encodeScalarValue(valTyped); done {
+			return err
+		}
+		return e.encode(valTyped.Interface())
+	case Number:
+		if !validNumber(string(valTyped)) {
+			return &Error{Type: ErrEncodeUnsupportedType, Msg: fmt.Sprintf("Number %q is not a canonical integer", string(valTyped))}
+		}
+		if _, err := fmt.Fprintf(e.w, "i%se", string(valTyped)); err != nil {
+			return &Error{Type: ErrEncodeWriteError, Msg: "failed to write integer", WrappedErr: err}
+		}
+		return nil
+	case verbatimInt:
+		return e.encodeVerbatimInt(valTyped)
+	case Uint64String:
+		digits := valTyped.String()
+		if err := e.writeString(digits); err != nil {
+			return &Error{Type: ErrEncodeWriteError, Msg: "failed to write uint64 string", WrappedErr: err}
+		}
+		return nil
+	case optional:
+		inner, present := valTyped.optionalValue()
+		if !present {
+			return &Error{Type: ErrEncodeUnsupportedType, Msg: fmt.Sprintf("cannot marshal absent %T outside a struct field", v)}
+		}
+		return e.encode(inner)
+	case map[string]string:
+		if e.hooked() {
+			return e.encodeMap(reflect.ValueOf(valTyped))
+		}
+		return encodeStringMap(e, valTyped, e.writeString)
+	case map[string][]byte:
+		if e.hooked() {
+			return e.encodeMap(reflect.ValueOf(valTyped))
+		}
+		return encodeStringMap(e, valTyped, e.writeBytes)
 	case []byte:
 		if _, err := fmt.Fprintf(e.w, "%d:%s", len(valTyped), valTyped); err != nil {
 			return &Error{Type: ErrEncodeWriteError, Msg: "failed to write byte slice", WrappedErr: err}
@@ -70,87 +498,366 @@ func (e *Encoder) Encode(v any) error {
 		return nil
 	default:
 		val := reflect.ValueOf(v)
+		if isSQLNull(val.Type()) {
+			held, err := sqlNullValue(val)
+			if err != nil {
+				return err
+			}
+			if held == nil {
+				return &Error{Type: ErrEncodeNil, Msg: fmt.Sprintf("cannot marshal invalid %T outside a struct field", v)}
+			}
+			return e.encode(held)
+		}
 
 		switch val.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if _, err := fmt.Fprintf(e.w, "i%de", val.Int()); err != nil {
+				return &Error{Type: ErrEncodeWriteError, Msg: "failed to write integer", WrappedErr: err}
+			}
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if _, err := fmt.Fprintf(e.w, "i%de", val.Uint()); err != nil {
+				return &Error{Type: ErrEncodeWriteError, Msg: "failed to write integer", WrappedErr: err}
+			}
+			return nil
+		case reflect.String:
+			if err := e.writeString(val.String()); err != nil {
+				return &Error{Type: ErrEncodeWriteError, Msg: "failed to write string", WrappedErr: err}
+			}
+			return nil
 		case reflect.Slice:
+			if val.Type().Elem().Kind() == reflect.Uint8 {
+				return e.encodeByteString(val)
+			}
+			depth := len(e.visiting)
+			if entered, err := e.enter(val); err != nil {
+				return err
+			} else if entered {
+				defer e.leave()
+			}
 			if _, err := e.w.Write([]byte{'l'}); err != nil {
 				return &Error{Type: ErrEncodeWriteError, Msg: "failed to write list start token 'l'", WrappedErr: err}
 			}
 			for i := range val.Len() {
-				if err := e.Encode(val.Index(i).Interface()); err != nil {
+				elem := val.Index(i).Interface()
+				if elem == nil && e.omitNil {
+					continue
+				}
+				if err := e.encode(elem); err != nil {
 					// Propagate error, potentially wrapping if it's a write error from a sub-call
 					// For now, assume Encode returns *Error or nil
-					return err
+					return e.cyclePath(errPath(err, strconv.Itoa(i)), depth)
 				}
 			}
 			if _, err := e.w.Write([]byte{'e'}); err != nil {
 				return &Error{Type: ErrEncodeWriteError, Msg: "failed to write list end token 'e'", WrappedErr: err}
 			}
 			return nil
+		case reflect.Array:
+			if val.Type().Elem().Kind() != reflect.Uint8 {
+				return &Error{Type: ErrEncodeUnsupportedType, Msg: fmt.Sprintf("cannot marshal type %T (array of %s)", v, val.Type().Elem().Kind())}
+			}
+			return e.encodeByteString(val)
 		case reflect.Map:
-			if val.Type().Key().Kind() != reflect.String {
-				return &Error{Type: ErrEncodeMapKeyNotString, Msg: fmt.Sprintf("map key type %s is not supported; only string keys are allowed", val.Type().Key().Kind())}
+			return e.encodeMap(val)
+		case reflect.Struct:
+			return e.encodeStruct(val)
+		case reflect.Pointer:
+			if val.IsNil() {
+				return &Error{Type: ErrEncodeNil, Msg: fmt.Sprintf("cannot marshal nil %s", val.Type())}
 			}
-			sortedKeys := make([]string, 0, val.Len())
-			mapKeys := val.MapKeys()
-			for _, key := range mapKeys {
-				sortedKeys = append(sortedKeys, key.String())
+			if entered, err := e.enter(val); err != nil {
+				return err
+			} else if entered {
+				defer e.leave()
 			}
-			slices.Sort(sortedKeys)
-
-			if _, err := e.w.Write([]byte{'d'}); err != nil {
-				return &Error{Type: ErrEncodeWriteError, Msg: "failed to write dictionary start token 'd'", WrappedErr: err}
+			if done, err := e.encodeDirect(val.Elem()); done {
+				return err
 			}
-			for _, keyStr := range sortedKeys {
-				// Encode key (which is a string)
-				if _, err := fmt.Fprintf(e.w, "%d:%s", len([]byte(keyStr)), keyStr); err != nil {
-					return &Error{Type: ErrEncodeWriteError, Msg: fmt.Sprintf("failed to write dictionary key %q", keyStr), WrappedErr: err, FieldName: keyStr}
-				}
-				// Encode value
-				if err := e.Encode(val.MapIndex(reflect.ValueOf(keyStr)).Interface()); err != nil {
-					// If err is already *Error, add FieldName context if not present or enhance.
-					if bErr, ok := err.(*Error); ok {
-						if bErr.FieldName == "" {
-							bErr.FieldName = keyStr
-						}
-						return bErr
-					}
-					return &Error{Type: ErrEncodeWriteError, Msg: fmt.Sprintf("failed to encode value for dictionary key %q", keyStr), WrappedErr: err, FieldName: keyStr}
-				}
-			}
-			if _, err := e.w.Write([]byte{'e'}); err != nil {
-				return &Error{Type: ErrEncodeWriteError, Msg: "failed to write dictionary end token 'e'", WrappedErr: err}
+			return e.encode(val.Elem().Interface())
+		case reflect.Func:
+			switch {
+			case isSeq(val.Type()):
+				return e.encodeSeq(val)
+			case isSeq2(val.Type()):
+				return e.encodeSeq2(val)
 			}
-			return nil
-		case reflect.Struct:
-			if _, err := e.w.Write([]byte{'d'}); err != nil {
-				return &Error{Type: ErrEncodeWriteError, Msg: "failed to write dictionary start token 'd' for struct", WrappedErr: err}
-			}
-			cachedFields := getCachedStructInfo(val.Type()) // Assuming this doesn't error or panics on setup
-			for _, fieldInfo := range cachedFields {
-				fieldVal := val.FieldByIndex([]int{fieldInfo.index})
-				// Encode key (bencodeTag)
-				if _, err := fmt.Fprintf(e.w, "%d:%s", len([]byte(fieldInfo.bencodeTag)), fieldInfo.bencodeTag); err != nil {
-					return &Error{Type: ErrEncodeWriteError, Msg: fmt.Sprintf("failed to write struct field key %q", fieldInfo.bencodeTag), WrappedErr: err, FieldName: fieldInfo.bencodeTag}
-				}
-				// Encode field value
-				if err := e.Encode(fieldVal.Interface()); err != nil {
-					if bErr, ok := err.(*Error); ok {
-						if bErr.FieldName == "" { // Add context if sub-encoding didn't
-							bErr.FieldName = fieldInfo.bencodeTag
-						}
-						return bErr
-					}
-					return &Error{Type: ErrEncodeWriteError, Msg: fmt.Sprintf("failed to encode struct field %q (tag %q)", fieldInfo.fieldName, fieldInfo.bencodeTag), WrappedErr: err, FieldName: fieldInfo.bencodeTag}
+			return &Error{Type: ErrEncodeUnsupportedType, Msg: fmt.Sprintf("cannot marshal type %T (%s)", v, val.Kind())}
+		default:
+			return &Error{Type: ErrEncodeUnsupportedType, Msg: fmt.Sprintf("cannot marshal type %T (%s)", v, val.Kind())}
+		}
+	}
+
+}
+
+// encodeMap writes val, a map with string keys, as a dictionary.
+func (e *Encoder) encodeMap(val reflect.Value) error {
+	if val.Type().Key().Kind() != reflect.String {
+		return &Error{Type: ErrEncodeMapKeyNotString, Msg: fmt.Sprintf("map key type %s is not supported; only string keys are allowed", val.Type().Key().Kind())}
+	}
+	depth := len(e.visiting)
+	if entered, err := e.enter(val); err != nil {
+		return err
+	} else if entered {
+		defer e.leave()
+	}
+	sortedKeys := make([]string, 0, val.Len())
+	mapKeys := val.MapKeys()
+	for _, key := range mapKeys {
+		if e.omitNil && val.MapIndex(key).Kind() == reflect.Interface && val.MapIndex(key).IsNil() {
+			continue
+		}
+		sortedKeys = append(sortedKeys, key.String())
+	}
+	slices.Sort(sortedKeys)
+	keyType := val.Type().Key()
+
+	if _, err := e.w.Write([]byte{'d'}); err != nil {
+		return &Error{Type: ErrEncodeWriteError, Msg: "failed to write dictionary start token 'd'", WrappedErr: err}
+	}
+	for _, keyStr := range sortedKeys {
+		// Encode key (which is a string)
+		if err := e.writeString(keyStr); err != nil {
+			return &Error{Type: ErrEncodeWriteError, Msg: fmt.Sprintf("failed to write dictionary key %q", keyStr), WrappedErr: err, FieldName: keyStr}
+		}
+		// Encode value
+		if err := e.encode(val.MapIndex(reflect.ValueOf(keyStr).Convert(keyType)).Interface()); err != nil {
+			// If err is already *Error, add FieldName context if not present or enhance.
+			if bErr, ok := errPath(err, keyStr).(*Error); ok {
+				if bErr.FieldName == "" {
+					bErr.FieldName = keyStr
 				}
+				return e.cyclePath(bErr, depth)
 			}
-			if _, err := e.w.Write([]byte{'e'}); err != nil {
-				return &Error{Type: ErrEncodeWriteError, Msg: "failed to write dictionary end token 'e' for struct", WrappedErr: err}
+			return &Error{Type: ErrEncodeWriteError, Msg: fmt.Sprintf("failed to encode value for dictionary key %q", keyStr), WrappedErr: err, FieldName: keyStr}
+		}
+	}
+	if _, err := e.w.Write([]byte{'e'}); err != nil {
+		return &Error{Type: ErrEncodeWriteError, Msg: "failed to write dictionary end token 'e'", WrappedErr: err}
+	}
+	return nil
+}
+
+// encodeStruct writes the struct val as a dictionary of its fields.
+func (e *Encoder) encodeStruct(val reflect.Value) error {
+	cachedFields := getCachedStructInfo(val.Type())
+	if slices.ContainsFunc(cachedFields, cachedStructFieldInfo.mergesEntries) {
+		return e.encodeInlineStruct(val, cachedFields)
+	}
+	if e.fieldOrder {
+		var err error
+		if cachedFields, err = orderedFields(val.Type(), cachedFields); err != nil {
+			return err
+		}
+	}
+	if err := e.writeByte('d'); err != nil {
+		return &Error{Type: ErrEncodeWriteError, Msg: "failed to write dictionary start token 'd' for struct", WrappedErr: err}
+	}
+	for _, fieldInfo := range cachedFields {
+		fieldVal, ok, err := e.structField(val, fieldInfo)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		// Encode key (bencodeTag)
+		if err := e.writeString(fieldInfo.bencodeTag); err != nil {
+			return &Error{Type: ErrEncodeWriteError, Msg: fmt.Sprintf("failed to write struct field key %q", fieldInfo.bencodeTag), WrappedErr: err, FieldName: fieldInfo.bencodeTag}
+		}
+		if err := e.encodeStructField(fieldVal, fieldInfo); err != nil {
+			return err
+		}
+	}
+	if err := e.writeByte('e'); err != nil {
+		return &Error{Type: ErrEncodeWriteError, Msg: "failed to write dictionary end token 'e' for struct", WrappedErr: err}
+	}
+	return nil
+}
+
+// structField returns the value to encode for a field of the struct val,
+// resolving union fields, and whether the field is written at all.
+func (e *Encoder) structField(val reflect.Value, fieldInfo cachedStructFieldInfo) (reflect.Value, bool, error) {
+	fieldVal := val.Field(fieldInfo.index)
+	if fieldInfo.union != nil {
+		var err error
+		if fieldVal, err = unionValue(fieldVal, fieldInfo); err != nil {
+			return reflect.Value{}, false, err
+		}
+	}
+	if e.omitNil && fieldVal.Kind() == reflect.Interface && fieldVal.IsNil() {
+		return fieldVal, false, nil
+	}
+	if fieldVal.Kind() == reflect.Struct && fieldVal.Type().Implements(optionalType) {
+		if _, present := fieldVal.Interface().(optional).optionalValue(); !present {
+			return fieldVal, false, nil // absent optional fields are omitted
+		}
+	}
+	if fieldVal.Kind() == reflect.Pointer && fieldVal.IsNil() {
+		return fieldVal, false, nil // nil pointer fields are omitted, like absent optionals
+	}
+	if fieldVal.Type() == rawMessageType && fieldVal.Len() == 0 {
+		return fieldVal, false, nil // so are empty RawMessage fields, which hold no value
+	}
+	if isSQLNull(fieldVal.Type()) {
+		if held, err := sqlNullValue(fieldVal); err != nil || held == nil {
+			return fieldVal, false, err // invalid database/sql Null fields are omitted
+		}
+	}
+	return fieldVal, true, nil
+}
+
+// encodeStructField writes the value of a struct field, naming the field in
+// any error.
+func (e *Encoder) encodeStructField(fieldVal reflect.Value, fieldInfo cachedStructFieldInfo) error {
+	var err error
+	if fieldInfo.asList && isByteSequence(fieldVal.Type()) {
+		err = e.encodeByteList(fieldVal)
+	} else if done, directErr := e.encodeDirect(fieldVal); done {
+		err = directErr
+	} else {
+		err = e.encode(fieldVal.Interface())
+	}
+	if err != nil {
+		if bErr, ok := errPath(err, fieldInfo.bencodeTag).(*Error); ok {
+			if bErr.FieldName == "" { // Add context if sub-encoding didn't
+				bErr.FieldName = fieldInfo.bencodeTag
 			}
-			return nil
-		default:
-			return &Error{Type: ErrEncodeUnsupportedType, Msg: fmt.Sprintf("cannot marshal type %T (%s)", v, val.Kind())}
+			return bErr
+		}
+		return &Error{Type: ErrEncodeWriteError, Msg: fmt.Sprintf("failed to encode struct field %q (tag %q)", fieldInfo.fieldName, fieldInfo.bencodeTag), WrappedErr: err, FieldName: fieldInfo.bencodeTag}
+	}
+	return nil
+}
+
+// encodeInlineStruct writes a struct with inline or rest fields. Each such
+// field must encode as a dictionary, whose entries are merged with those of the
+// other fields; where keys collide, the field tagged with the key wins, and
+// otherwise the inline field declared last. Since
+// the entries only come together once all fields are encoded, they are
+// encoded into a buffer first.
+func (e *Encoder) encodeInlineStruct(val reflect.Value, fields []cachedStructFieldInfo) error {
+	w := e.w
+	defer func() { e.w = w }()
+	var buf bytes.Buffer
+	e.w = &buf
+
+	var inline []cachedStructFieldInfo
+	for _, fieldInfo := range fields {
+		if fieldInfo.mergesEntries() {
+			inline = append(inline, fieldInfo)
+		}
+	}
+	slices.SortFunc(inline, func(a, b cachedStructFieldInfo) int { return a.index - b.index })
+
+	merged := make(map[string][]byte)
+	for _, fieldInfo := range inline {
+		fieldVal := val.Field(fieldInfo.index)
+		switch fieldVal.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice:
+			if fieldVal.IsNil() || fieldVal.Kind() == reflect.Slice && fieldVal.Len() == 0 {
+				continue // nothing to inline
+			}
+		}
+		buf.Reset()
+		if err := e.encodeStructField(fieldVal, fieldInfo); err != nil {
+			return err
 		}
+		entries, err := rawDictEntries(bytes.Clone(buf.Bytes()))
+		if err != nil {
+			return &Error{Type: ErrEncodeUnsupportedType, Msg: fmt.Sprintf("inline field %s does not encode as a dictionary", fieldInfo.fieldName), WrappedErr: err, FieldName: fieldInfo.bencodeTag}
+		}
+		for _, entry := range entries {
+			merged[entry.key] = entry.value
+		}
+	}
+	for _, fieldInfo := range fields {
+		if fieldInfo.mergesEntries() {
+			continue
+		}
+		fieldVal, ok, err := e.structField(val, fieldInfo)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			delete(merged, fieldInfo.bencodeTag)
+			continue
+		}
+		buf.Reset()
+		if err := e.encodeStructField(fieldVal, fieldInfo); err != nil {
+			return err
+		}
+		merged[fieldInfo.bencodeTag] = bytes.Clone(buf.Bytes())
+	}
+
+	entries := make([]rawEntry, 0, len(merged))
+	for key, value := range merged {
+		entries = append(entries, rawEntry{key: key, value: value})
+	}
+	if _, err := w.Write(appendRawDict(nil, entries)); err != nil {
+		return &Error{Type: ErrEncodeWriteError, Msg: "failed to write dictionary for struct", WrappedErr: err}
+	}
+	return nil
+}
+
+// errPath prepends the list index or dictionary key seg to the FieldName of
+// an ErrEncodeNil or ErrEncodeCycle error, so that the error names the full
+// path to the offending value. Other errors are returned unchanged.
+func errPath(err error, seg string) error {
+	bErr, ok := err.(*Error)
+	if !ok || (bErr.Type != ErrEncodeNil && bErr.Type != ErrEncodeCycle) {
+		return err
 	}
+	if bErr.FieldName != "" {
+		seg = JoinPath(seg, bErr.FieldName)
+	}
+	bErr.FieldName, bErr.fullPath = seg, true
+	return bErr
+}
+
+// containerRef identifies the backing storage of a map, slice or pointer
+// being encoded. Slices are told apart by length too, as a slice and a shorter
+// slice of it share a pointer, and all containers by type, as a pointer to a
+// struct and a pointer to its first field share an address.
+type containerRef struct {
+	ptr uintptr
+	len int
+	typ reflect.Type
+}
 
+// enter records that the map or slice val is being encoded, failing with an
+// ErrEncodeCycle error if it already is, further up the path. It reports
+// whether val was recorded, in which case leave must be called once it is
+// done. Empty containers cannot hold themselves and are not recorded.
+func (e *Encoder) enter(val reflect.Value) (bool, error) {
+	var n int
+	if val.Kind() != reflect.Pointer {
+		if n = val.Len(); n == 0 {
+			return false, nil
+		}
+	}
+	ref := containerRef{ptr: val.Pointer(), len: n, typ: val.Type()}
+	for i, r := range e.visiting {
+		if r == ref {
+			e.cycleStart = i
+			return false, &Error{Type: ErrEncodeCycle, Msg: fmt.Sprintf("%s contains itself", val.Type())}
+		}
+	}
+	e.visiting = append(e.visiting, ref)
+	return true, nil
+}
+
+// leave undoes a successful enter.
+func (e *Encoder) leave() {
+	e.visiting = e.visiting[:len(e.visiting)-1]
+}
+
+// cyclePath completes an ErrEncodeCycle error as it passes the container at
+// depth where the cycle starts: by then its FieldName is the path leading
+// from that container back to itself, which is added to the message.
+func (e *Encoder) cyclePath(err error, depth int) error {
+	if bErr, ok := err.(*Error); ok && bErr.Type == ErrEncodeCycle && depth == e.cycleStart {
+		bErr.Msg += fmt.Sprintf(" via %q", bErr.FieldName)
+	}
+	return err
 }
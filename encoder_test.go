@@ -3,7 +3,13 @@ package bencode
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
+	"iter"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -295,3 +301,485 @@ func TestEncodeZeroValue(t *testing.T) {
 		t.Errorf("Encode() = %s, want %s", b.Bytes(), expected)
 	}
 }
+
+func TestEncodeSeq(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    any
+		expected string
+	}{
+		{
+			name:     "seq of ints",
+			value:    slices.Values([]int{1, 2, 3}),
+			expected: "li1ei2ei3ee",
+		},
+		{
+			name:     "empty seq",
+			value:    slices.Values([]string(nil)),
+			expected: "le",
+		},
+		{
+			name:     "seq2 sorted into dictionary",
+			value:    maps.All(map[string]int{"b": 2, "a": 1}),
+			expected: "d1:ai1e1:bi2ee",
+		},
+		{
+			name: "seq inside struct",
+			value: struct {
+				Files iter.Seq[string] `bencode:"files"`
+			}{Files: slices.Values([]string{"x", "y"})},
+			expected: "d5:filesl1:x1:yee",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(tt.value)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("Marshal() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEncodeSeqErrors(t *testing.T) {
+	yielded := 0
+	seq := func(yield func(any) bool) {
+		for _, v := range []any{1, make(chan int), 3} {
+			yielded++
+			if !yield(v) {
+				return
+			}
+		}
+	}
+	_, err := Marshal(iter.Seq[any](seq))
+	if bErr, ok := err.(*Error); !ok || bErr.Type != ErrEncodeUnsupportedType {
+		t.Errorf("Marshal() error = %v, want %q", err, ErrEncodeUnsupportedType)
+	}
+	if yielded != 2 {
+		t.Errorf("sequence was not stopped after the error, yielded %d", yielded)
+	}
+
+	dup := func(yield func(string, int) bool) {
+		_ = yield("a", 1) && yield("a", 2)
+	}
+	_, err = Marshal(iter.Seq2[string, int](dup))
+	if bErr, ok := err.(*Error); !ok || bErr.Type != ErrEncodeDuplicateKey || bErr.FieldName != "a" {
+		t.Errorf("Marshal() error = %v, want %q", err, ErrEncodeDuplicateKey)
+	}
+
+	// Sequences that ignore a false from yield keep the first error.
+	stubborn := func(yield func(any) bool) {
+		for _, v := range []any{make(chan int), 2} {
+			yield(v)
+		}
+	}
+	_, err = Marshal(iter.Seq[any](stubborn))
+	if bErr, ok := err.(*Error); !ok || bErr.Type != ErrEncodeUnsupportedType {
+		t.Errorf("Marshal() of a sequence ignoring yield error = %v, want %q", err, ErrEncodeUnsupportedType)
+	}
+	stubbornDup := func(yield func(string, int) bool) {
+		yield("a", 1)
+		yield("a", 2)
+		yield("b", 3)
+	}
+	_, err = Marshal(iter.Seq2[string, int](stubbornDup))
+	if bErr, ok := err.(*Error); !ok || bErr.Type != ErrEncodeDuplicateKey || bErr.FieldName != "a" {
+		t.Errorf("Marshal() of a sequence ignoring yield error = %v, want %q", err, ErrEncodeDuplicateKey)
+	}
+
+	_, err = Marshal(func() {})
+	if bErr, ok := err.(*Error); !ok || bErr.Type != ErrEncodeUnsupportedType {
+		t.Errorf("Marshal() of plain func error = %v, want %q", err, ErrEncodeUnsupportedType)
+	}
+}
+
+func TestEncodeReflectValue(t *testing.T) {
+	got, err := Marshal(reflect.ValueOf(map[string]any{"a": []int{1, 2}}))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want := "d1:ali1ei2eee"; string(got) != want {
+		t.Errorf("Marshal() = %q, want %q", got, want)
+	}
+
+	var bErr *Error
+	if _, err := Marshal(reflect.Value{}); !errors.As(err, &bErr) || bErr.Type != ErrEncodeUnsupportedType {
+		t.Errorf("Marshal() of invalid reflect.Value error = %v, want %q", err, ErrEncodeUnsupportedType)
+	}
+	hidden := reflect.ValueOf(struct{ n int }{n: 1}).Field(0)
+	if _, err := Marshal(hidden); !errors.As(err, &bErr) || bErr.Type != ErrEncodeUnsupportedType {
+		t.Errorf("Marshal() of unexported field error = %v, want %q", err, ErrEncodeUnsupportedType)
+	}
+}
+
+func TestEncodeDecodedValue(t *testing.T) {
+	inputs := []string{
+		"0:",
+		"i-42e",
+		"le",
+		"de",
+		"l0:i0eledee",
+		"d1:ad1:bdee1:cllleee4:data3:\x00\xff\x014:listl3:fooi1eld1:xleeeee",
+		"d8:announce38:udp://tracker.publicbt.com:80/announce4:infod6:lengthi170917888e4:name4:test12:piece lengthi262144eee",
+	}
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			decoded, err := NewDecoder(strings.NewReader(input)).DecodeValue()
+			if err != nil {
+				t.Fatalf("DecodeValue() error = %v", err)
+			}
+			var b bytes.Buffer
+			enc := NewEncoder(&b)
+			enc.RequireCanonical()
+			if err := enc.Encode(decoded); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if b.String() != input {
+				t.Errorf("Encode() = %q, want %q", b.String(), input)
+			}
+			if _, err := Marshal(reflect.ValueOf(decoded)); err != nil {
+				t.Errorf("Marshal() of reflect.Value error = %v", err)
+			}
+		})
+	}
+}
+
+func TestEncodeNil(t *testing.T) {
+	type peer struct {
+		IP any `bencode:"ip"`
+	}
+	tests := []struct {
+		name     string
+		value    any
+		path     string
+		expected string // with OmitNil
+	}{
+		{name: "root", value: nil},
+		{name: "map value", value: map[string]any{"a": 1, "x": nil}, path: "x", expected: "d1:ai1ee"},
+		{name: "list element", value: []any{1, nil, 2}, path: "1", expected: "li1ei2ee"},
+		{name: "struct field", value: peer{}, path: "ip", expected: "de"},
+		{name: "nested", value: map[string]any{"peers": []any{peer{IP: "a"}, peer{}}}, path: "peers.1.ip", expected: "d5:peersld2:ip1:aedeee"},
+		{name: "sequence", value: slices.Values([]any{nil}), path: "0", expected: "le"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Marshal(tt.value)
+			var bErr *Error
+			if !errors.As(err, &bErr) || bErr.Type != ErrEncodeNil || bErr.FieldName != tt.path {
+				t.Fatalf("Marshal() error = %v, want %q at %q", err, ErrEncodeNil, tt.path)
+			}
+
+			var b bytes.Buffer
+			enc := NewEncoder(&b)
+			enc.OmitNil()
+			err = enc.Encode(tt.value)
+			if tt.expected == "" {
+				if !errors.As(err, &bErr) || bErr.Type != ErrEncodeNil {
+					t.Errorf("Encode() with OmitNil error = %v, want %q", err, ErrEncodeNil)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Encode() with OmitNil error = %v", err)
+			}
+			if b.String() != tt.expected {
+				t.Errorf("Encode() with OmitNil = %q, want %q", b.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestEncoderEncodeValue(t *testing.T) {
+	type name string
+	type record struct {
+		ID     int8   `bencode:"id"`
+		Name   name   `bencode:"name"`
+		hidden string // unexported, read via reflection below
+	}
+	rec := record{ID: -3, Name: "spam", hidden: "x"}
+	recVal := reflect.ValueOf(&rec).Elem()
+	tests := []struct {
+		name     string
+		value    reflect.Value
+		expected string
+	}{
+		{name: "int field", value: recVal.Field(0), expected: "i-3e"},
+		{name: "named string field", value: recVal.Field(1), expected: "4:spam"},
+		{name: "struct", value: recVal, expected: "d2:idi-3e4:name4:spame"},
+		{name: "uint64 string", value: reflect.ValueOf(Uint64String(42)), expected: "2:42"},
+		{name: "uint", value: reflect.ValueOf(uint16(65535)), expected: "i65535e"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := NewEncoder(&b).EncodeValue(tt.value); err != nil {
+				t.Fatalf("EncodeValue() error = %v", err)
+			}
+			if b.String() != tt.expected {
+				t.Errorf("EncodeValue() = %q, want %q", b.String(), tt.expected)
+			}
+		})
+	}
+
+	for _, v := range []reflect.Value{{}, recVal.Field(2)} {
+		err := NewEncoder(io.Discard).EncodeValue(v)
+		var bErr *Error
+		if !errors.As(err, &bErr) || bErr.Type != ErrEncodeUnsupportedType {
+			t.Errorf("EncodeValue(%v) error = %v, want %q", v, err, ErrEncodeUnsupportedType)
+		}
+	}
+
+	enc := NewEncoder(io.Discard)
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = enc.EncodeValue(recVal.Field(0))
+		_ = enc.EncodeValue(recVal.Field(1))
+	})
+	if allocs != 0 {
+		t.Errorf("EncodeValue() of scalars allocated %v times per run, want 0", allocs)
+	}
+}
+
+// stringRecorder is a writer implementing io.StringWriter that records the
+// strings passed to WriteString.
+type stringRecorder struct {
+	bytes.Buffer
+	strs []string
+}
+
+func (r *stringRecorder) WriteString(s string) (int, error) {
+	r.strs = append(r.strs, s)
+	return r.Buffer.WriteString(s)
+}
+
+func TestEncodeStringWriter(t *testing.T) {
+	type announce struct {
+		Interval int               `bencode:"interval"`
+		Peers    map[string]string `bencode:"peers"`
+		Reason   string            `bencode:"reason"`
+	}
+	v := announce{Interval: 1800, Peers: map[string]string{"a": "x"}, Reason: "ok"}
+	const expected = "d8:intervali1800e5:peersd1:a1:xe6:reason2:oke"
+
+	var rec stringRecorder
+	if err := NewEncoder(&rec).Encode(v); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if rec.String() != expected {
+		t.Errorf("Encode() = %q, want %q", rec.String(), expected)
+	}
+	want := []string{"interval", "peers", "a", "x", "reason", "ok"}
+	if !slices.Equal(rec.strs, want) {
+		t.Errorf("WriteString() calls = %q, want %q", rec.strs, want)
+	}
+
+	// Writers without WriteString get the same bytes.
+	var b strings.Builder
+	if err := NewEncoder(struct{ io.Writer }{&b}).Encode(v); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if b.String() != expected {
+		t.Errorf("Encode() to plain writer = %q, want %q", b.String(), expected)
+	}
+
+	// The fast path is kept when counting bytes for metrics.
+	rec = stringRecorder{}
+	enc := NewEncoder(&rec)
+	enc.UseMetrics(new(Metrics))
+	if err := enc.Encode(v); err != nil {
+		t.Fatalf("Encode() with metrics error = %v", err)
+	}
+	if !slices.Equal(rec.strs, want) {
+		t.Errorf("WriteString() calls with metrics = %q, want %q", rec.strs, want)
+	}
+}
+
+func TestEncodeByteKinds(t *testing.T) {
+	type myByte byte
+	type record struct {
+		Named  []myByte  `bencode:"named"`
+		Array  [2]myByte `bencode:"array"`
+		Flags  []byte    `bencode:"flags,list"`
+		Matrix [3]uint8  `bencode:"matrix,list"`
+	}
+	tests := []struct {
+		name     string
+		value    any
+		expected string
+	}{
+		{name: "named byte slice", value: []myByte("ab"), expected: "2:ab"},
+		{name: "named byte array", value: [3]myByte{'x', 'y', 'z'}, expected: "3:xyz"},
+		{name: "nested", value: [][]myByte{[]myByte("a")}, expected: "l1:ae"},
+		{
+			name:     "struct with list option",
+			value:    record{Named: []myByte("n"), Array: [2]myByte{1, 2}, Flags: []byte{0, 255}, Matrix: [3]uint8{7, 8, 9}},
+			expected: "d5:array2:\x01\x025:flagsli0ei255ee6:matrixli7ei8ei9ee5:named1:ne",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(tt.value)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("Marshal() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	in := record{Named: []myByte("n"), Array: [2]myByte{1, 2}, Flags: []byte{0, 255}, Matrix: [3]uint8{7, 8, 9}}
+	data, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var out record
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+}
+
+func TestEncodeCycle(t *testing.T) {
+	self := map[string]any{"a": 1}
+	self["self"] = self
+
+	list := []any{1, nil}
+	list[1] = list
+
+	inner := map[string]any{}
+	outer := map[string]any{"info": map[string]any{"inner": inner}}
+	inner["back"] = []any{outer}
+
+	type wrapper struct {
+		Data map[string]any `bencode:"data"`
+	}
+
+	tests := []struct {
+		name  string
+		value any
+		path  string
+		via   string
+	}{
+		{name: "map", value: self, path: "self", via: "self"},
+		{name: "list", value: list, path: "1", via: "1"},
+		{name: "indirect", value: outer, path: "info.inner.back.0", via: "info.inner.back.0"},
+		{name: "below root", value: wrapper{Data: map[string]any{"x": list}}, path: "data.x.1", via: "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Marshal(tt.value)
+			var bErr *Error
+			if !errors.As(err, &bErr) || bErr.Type != ErrEncodeCycle || bErr.FieldName != tt.path {
+				t.Fatalf("Marshal() error = %v, want %q at %q", err, ErrEncodeCycle, tt.path)
+			}
+			if want := fmt.Sprintf("via %q", tt.via); !strings.HasSuffix(bErr.Msg, want) {
+				t.Errorf("Marshal() error message = %q, want suffix %q", bErr.Msg, want)
+			}
+		})
+	}
+
+	// The same value appearing twice without containing itself is fine.
+	shared := []any{1}
+	got, err := Marshal(map[string]any{"a": shared, "b": shared, "c": shared[:0]})
+	if err != nil {
+		t.Fatalf("Marshal() of shared value error = %v", err)
+	}
+	if string(got) != "d1:ali1ee1:bli1ee1:clee" {
+		t.Errorf("Marshal() of shared value = %q", got)
+	}
+
+	// A pointer to a struct and a pointer to its first field share an
+	// address without being the same value.
+	type firstSlice struct {
+		L []int  `bencode:"l"`
+		P *[]int `bencode:"p"`
+	}
+	w := &firstSlice{L: []int{1}}
+	w.P = &w.L
+	if got, err := Marshal(w); err != nil || string(got) != "d1:lli1ee1:pli1eee" {
+		t.Errorf("Marshal() of pointer to first field = %q, %v", got, err)
+	}
+	type firstInt struct {
+		N int  `bencode:"n"`
+		P *int `bencode:"p"`
+	}
+	type outerStruct struct {
+		X firstInt `bencode:"x"`
+	}
+	o := &outerStruct{}
+	o.X.P = &o.X.N
+	if got, err := Marshal(o); err != nil || string(got) != "d1:xd1:ni0e1:pi0eee" {
+		t.Errorf("Marshal() of pointer to nested first field = %q, %v", got, err)
+	}
+}
+
+func TestEncoderAddRootKey(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	if err := enc.AddRootKey("m", 1); err != nil {
+		t.Fatalf("AddRootKey() error = %v", err)
+	}
+	for _, tc := range []struct {
+		value any
+		want  string
+	}{
+		{value: map[string]int{"a": 2, "z": 3}, want: "d1:ai2e1:mi1e1:zi3ee"},
+		{value: map[string]int{"m": 2}, want: "d1:mi2ee"},
+		{value: struct{}{}, want: "d1:mi1ee"},
+		{value: []int{1}, want: "li1ee"},
+	} {
+		buf.Reset()
+		if err := enc.Encode(tc.value); err != nil {
+			t.Fatalf("Encode(%v) error = %v", tc.value, err)
+		}
+		if buf.String() != tc.want {
+			t.Errorf("Encode(%v) = %q, want %q", tc.value, buf.String(), tc.want)
+		}
+	}
+	if err := enc.AddRootKey("f", func() {}); err == nil {
+		t.Error("AddRootKey() of a func succeeded, want error")
+	}
+}
+
+func TestEncoderUseFieldOrder(t *testing.T) {
+	type legacy struct {
+		Name    string         `bencode:"name"`
+		Type    string         `bencode:"type,order=-1"`
+		Size    int            `bencode:"size"`
+		Attrs   map[string]int `bencode:"attrs,order=5"`
+		Comment string         `bencode:"comment"`
+	}
+	v := legacy{Name: "x", Type: "file", Size: 3, Attrs: map[string]int{"z": 1, "a": 2}, Comment: "c"}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.UseFieldOrder()
+	if err := enc.Encode(v); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	want := "d4:type4:file4:name1:x4:sizei3e7:comment1:c5:attrsd1:ai2e1:zi1eee"
+	if buf.String() != want {
+		t.Errorf("Encode() = %q, want %q", buf.String(), want)
+	}
+
+	// The default remains sorted.
+	encoded, err := Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want := "d5:attrsd1:ai2e1:zi1ee7:comment1:c4:name1:x4:sizei3e4:type4:filee"; string(encoded) != want {
+		t.Errorf("Marshal() = %q, want %q", encoded, want)
+	}
+
+	var bErr *Error
+	if err := enc.Encode(struct {
+		A int `bencode:"a,order=first"`
+	}{}); !errors.As(err, &bErr) || bErr.Type != ErrUsage {
+		t.Errorf("Encode() with bad order error = %v, want %q", err, ErrUsage)
+	}
+}
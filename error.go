@@ -1,6 +1,7 @@
 package bencode
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 )
@@ -15,10 +16,17 @@ type Error struct {
 	FieldName string
 	// WrappedErr holds the underlying error, if any.
 	WrappedErr error
+	// Context shows the input around a decoding error when the Decoder was
+	// configured with ErrorContext. It is empty otherwise.
+	Context string
+
+	// fullPath records that FieldName holds the whole path to the value,
+	// joined by JoinPath, rather than a single key or index.
+	fullPath bool
 }
 
 // Error returns a string representation of the bencode error.
-// It includes the error type, field name (if applicable), message, and any wrapped error.
+// It includes the field name (if applicable), message, any wrapped error and any input context.
 func (e *Error) Error() string {
 	var sb strings.Builder
 	sb.WriteString("bencode: ")
@@ -30,13 +38,69 @@ func (e *Error) Error() string {
 		sb.WriteString(": ")
 		sb.WriteString(e.WrappedErr.Error())
 	}
+	if e.Context != "" {
+		sb.WriteString(" (")
+		sb.WriteString(e.Context)
+		sb.WriteString(")")
+	}
 	return sb.String()
 }
 
+// Path returns the dotted path to the value the error concerns, such as
+// "files.1.length", by joining the FieldNames along the chain of wrapped
+// *Errors from the outermost in with JoinPath. FieldNames that already hold
+// the whole path, as those of ErrEncodeNil errors do, replace what was
+// joined so far. The chain is not followed into errors joined by
+// CollectErrors.
+func (e *Error) Path() string {
+	var path string
+	for err := error(e); err != nil; err = errors.Unwrap(err) {
+		bErr, ok := err.(*Error)
+		if !ok || bErr.FieldName == "" {
+			continue
+		}
+		if bErr.fullPath {
+			path = bErr.FieldName
+		} else {
+			path = JoinPath(path, bErr.FieldName)
+		}
+	}
+	return path
+}
+
+// JoinPath appends the dictionary key or list index seg to the dotted path,
+// as Error.Path does. Paths that other packages report, such as
+// bencodeschema violations, are joined the same way.
+func JoinPath(path, seg string) string {
+	if path == "" {
+		return seg
+	}
+	return path + "." + seg
+}
+
 // Unwrap returns the underlying error, if any, to support error chaining.
 func (e *Error) Unwrap() error {
 	return e.WrappedErr
 }
 
-// ErrorType defines the category of a bencode error.
+// Is reports whether e is of the ErrorType target, so that errors can be
+// classified with errors.Is(err, ErrSyntaxInteger) rather than by extracting
+// the *Error with errors.As and comparing its Type. Like any errors.Is
+// target, it also matches an *Error of that type wrapped further down the
+// chain.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(ErrorType)
+	return ok && e.Type == t
+}
+
+// ErrorType defines the category of a bencode error. Every error returned
+// by this package is an *Error, and every ErrorType constant, including
+// those defined by packages built on this one, is an error value that serves
+// as the sentinel for its category with errors.Is.
 type ErrorType string
+
+// Error returns the name of the category, making each ErrorType usable as an
+// errors.Is target. ErrorType values are never returned as errors themselves.
+func (t ErrorType) Error() string {
+	return string(t)
+}
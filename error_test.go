@@ -0,0 +1,73 @@
+package bencode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestErrorTypeIs(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		dest  any
+		want  ErrorType
+	}{
+		{name: "leading zero", input: "i01e", dest: new(int), want: ErrSyntaxInteger},
+		{name: "unsorted keys", input: "d1:bi1e1:ai2ee", dest: new(map[string]int), want: ErrStructureDictKeySort},
+		{name: "truncated", input: "4:sp", dest: new(string), want: ErrSyntaxEOF},
+		{name: "overflow", input: "i300e", dest: new(uint8), want: ErrUnmarshalOverflow},
+		{name: "nested type mismatch", input: "d1:ad1:bi1eee", dest: new(struct {
+			A struct {
+				B string `bencode:"b"`
+			} `bencode:"a"`
+		}), want: ErrUnmarshalType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Unmarshal([]byte(tt.input), tt.dest)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Unmarshal(%q) error = %v, want errors.Is %q", tt.input, err, tt.want)
+			}
+			if errors.Is(err, ErrUsage) {
+				t.Errorf("Unmarshal(%q) error = %v also matches %q", tt.input, err, ErrUsage)
+			}
+		})
+	}
+
+	// The shared sentinel errors still match by identity.
+	if err := Unmarshal([]byte("d1:ai1e1:ai1ee"), new(any)); !errors.Is(err, ErrDuplicateDictionaryKey) || !errors.Is(err, ErrStructureDictKeyDup) {
+		t.Errorf("Unmarshal() of duplicate keys error = %v, want both sentinels", err)
+	}
+
+	if _, err := Number("18446744073709551616").Uint64(); !errors.Is(err, ErrUnmarshalOverflow) {
+		t.Errorf("Number.Uint64() error = %v, want %q", err, ErrUnmarshalOverflow)
+	}
+	if _, err := Number("1x").Int64(); !errors.Is(err, ErrSyntaxInteger) {
+		t.Errorf("Number.Int64() error = %v, want %q", err, ErrSyntaxInteger)
+	}
+}
+
+func TestErrorPath(t *testing.T) {
+	type nested struct {
+		A map[string]uint8 `bencode:"a"`
+	}
+	// A key holding the separator is joined like any other key.
+	err := Unmarshal([]byte("d1:ad3:a.bi300eee"), new(nested))
+	var bErr *Error
+	if !errors.As(err, &bErr) || bErr.Path() != "a.a.b" {
+		t.Errorf("Unmarshal() error = %v, want path a.a.b", err)
+	}
+
+	dec := NewDecoder(strings.NewReader("d1:ad3:a.bi1e5:a.bcdi1eee"))
+	dec.MaxKeyLength(3)
+	err = dec.Decode(new(nested))
+	if !errors.As(err, &bErr) || !errors.Is(err, ErrStructureDictKeyLength) || bErr.Path() != "a.a.bcd" {
+		t.Errorf("Decode() error = %v, want %q at a.a.bcd", err, ErrStructureDictKeyLength)
+	}
+
+	_, err = Marshal(map[string]any{"a": map[string]any{"a.b": nil}})
+	if !errors.As(err, &bErr) || !errors.Is(err, ErrEncodeNil) || bErr.Path() != "a.a.b" {
+		t.Errorf("Marshal() error = %v, want %q at a.a.b", err, ErrEncodeNil)
+	}
+}
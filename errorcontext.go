@@ -0,0 +1,85 @@
+package bencode
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrorContext makes the Decoder attach up to n bytes of input on either
+// side of the failure point to the Context field of decoding errors, shown
+// as hex and as printable text:
+//
+//	offset 14: 64 34 3a 6e 61 6d 65 | 78 34 3a 73 70 61 6d  "d4:name" | "x4:spam"
+//
+// The bytes left of the bar were consumed before the error was detected and
+// those to the right had not yet been read. Keeping the trailing context
+// costs a copy of each consumed byte, so it is disabled by default; n <= 0
+// disables it again.
+func (d *Decoder) ErrorContext(n int) {
+	d.contextSize = max(n, 0)
+	d.history = nil
+}
+
+// consumed records that s has been read from the input.
+func (d *Decoder) consumed(s string) {
+	d.offset += int64(len(s))
+	if d.contextSize > 0 {
+		d.remember(append(d.history, s...))
+	}
+	if d.tee != nil {
+		d.teeBytes([]byte(s))
+	}
+	if d.teeHash != nil {
+		io.WriteString(d.teeHash, s)
+	}
+}
+
+// consumedBytes is consumed for byte slices.
+func (d *Decoder) consumedBytes(b []byte) {
+	d.offset += int64(len(b))
+	if d.contextSize > 0 {
+		d.remember(append(d.history, b...))
+	}
+	if d.tee != nil {
+		d.teeBytes(b)
+	}
+	if d.teeHash != nil {
+		d.teeHash.Write(b)
+	}
+}
+
+// remember stores history, trimming it to the last contextSize bytes once it
+// has grown to twice that.
+func (d *Decoder) remember(history []byte) {
+	if len(history) > 2*d.contextSize {
+		history = append(history[:0], history[len(history)-d.contextSize:]...)
+	}
+	d.history = history
+}
+
+// annotate adds input context to err if enabled. Sentinel errors are shared
+// and are returned unchanged.
+func (d *Decoder) annotate(err error) error {
+	bErr, ok := err.(*Error)
+	if d.contextSize == 0 || !ok || bErr == ErrNullRootValue || bErr == ErrUnexpectedEOF ||
+		bErr == ErrDuplicateDictionaryKey || bErr == ErrDictionaryKeysNotSorted {
+		return err
+	}
+	before := d.history[max(len(d.history)-d.contextSize, 0):]
+	after, _ := d.r.Peek(d.contextSize) // partial data is returned alongside EOF
+	bErr.Context = fmt.Sprintf("offset %d: %s | %s  %q | %q", d.offset, hexBytes(before), hexBytes(after), before, after)
+	return bErr
+}
+
+// hexBytes formats b as space-separated hex pairs.
+func hexBytes(b []byte) string {
+	var sb strings.Builder
+	for i, c := range b {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		fmt.Fprintf(&sb, "%02x", c)
+	}
+	return sb.String()
+}
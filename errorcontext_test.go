@@ -0,0 +1,54 @@
+package bencode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecoderErrorContext(t *testing.T) {
+	decoder := NewDecoder(strings.NewReader("d3:age4:spam4:namex2:ok"))
+	decoder.ErrorContext(4)
+
+	_, err := decoder.DecodeValue()
+	var bErr *Error
+	if !errors.As(err, &bErr) || bErr.Type != ErrSyntaxUnexpectedToken {
+		t.Fatalf("DecodeValue() error = %v, want %q", err, ErrSyntaxUnexpectedToken)
+	}
+	expected := `offset 18: 6e 61 6d 65 | 78 32 3a 6f  "name" | "x2:o"`
+	if bErr.Context != expected {
+		t.Errorf("Context = %q, want %q", bErr.Context, expected)
+	}
+	if !strings.HasSuffix(err.Error(), "("+expected+")") {
+		t.Errorf("Error() = %q, want context suffix", err.Error())
+	}
+}
+
+func TestDecoderErrorContextDisabled(t *testing.T) {
+	_, err := NewDecoder(strings.NewReader("i12x")).DecodeValue()
+	var bErr *Error
+	if !errors.As(err, &bErr) || bErr.Context != "" {
+		t.Errorf("DecodeValue() error = %v, want no context by default", err)
+	}
+
+	decoder := NewDecoder(strings.NewReader(""))
+	decoder.ErrorContext(8)
+	if _, err := decoder.DecodeValue(); err != ErrNullRootValue || ErrNullRootValue.Context != "" {
+		t.Errorf("DecodeValue() at EOF error = %v, want unmodified sentinel", err)
+	}
+}
+
+func TestDecoderErrorContextLongInput(t *testing.T) {
+	input := "l" + strings.Repeat("4:spam", 100) + "?"
+	decoder := NewDecoder(strings.NewReader(input))
+	decoder.ErrorContext(6)
+	_, err := decoder.DecodeValue()
+	var bErr *Error
+	if !errors.As(err, &bErr) {
+		t.Fatalf("DecodeValue() error = %v", err)
+	}
+	expected := `offset 601: 34 3a 73 70 61 6d | 3f  "4:spam" | "?"`
+	if bErr.Context != expected {
+		t.Errorf("Context = %q, want %q", bErr.Context, expected)
+	}
+}
@@ -0,0 +1,141 @@
+package bencode
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FormatOptions controls the output of Value.Format.
+type FormatOptions struct {
+	// Indent is repeated once per nesting level; the default is two spaces.
+	Indent string
+	// MaxPreview is the number of bytes of a string shown, as text or as a
+	// hex or base64 preview; the default is 32. Longer strings are cut off
+	// and marked with "...".
+	MaxPreview int
+	// Base64 previews binary strings in base64 rather than hex.
+	Base64 bool
+}
+
+// Format writes an annotated, indented dump of v to w, one value per line,
+// for inspecting documents by eye:
+//
+//	dict (2 entries)
+//	  "info": dict (1 entry)
+//	    "piece length": int 262144
+//	  "pieces": string(20 bytes, binary, sha1?) 6a09e667f3bcc908b2fb1366ea957d3e3adec175
+//
+// Strings that are valid UTF-8 without control characters other than
+// whitespace are shown as quoted text, and others as a hex or base64
+// preview, annotated with a guess at what they hold when their length is
+// that of a SHA-1 or SHA-256 hash or a run of SHA-1 hashes. Dictionary keys
+// are shown in sorted order.
+func (v *Value) Format(w io.Writer, opts FormatOptions) error {
+	if opts.Indent == "" {
+		opts.Indent = "  "
+	}
+	if opts.MaxPreview <= 0 {
+		opts.MaxPreview = 32
+	}
+	bw := bufio.NewWriter(w)
+	v.format(bw, opts, 0)
+	if err := bw.Flush(); err != nil {
+		return &Error{Type: ErrEncodeWriteError, Msg: "failed to write formatted value", WrappedErr: err}
+	}
+	return nil
+}
+
+// format writes v, whose label if any has already been written, and its
+// children at the given depth.
+func (v *Value) format(w *bufio.Writer, opts FormatOptions, depth int) {
+	switch v.kind {
+	case KindString:
+		w.WriteString(formatString(v.str, opts))
+	case KindInteger:
+		w.WriteString("int ")
+		w.WriteString(strconv.FormatInt(v.num, 10))
+	case KindList:
+		fmt.Fprintf(w, "list (%s)", plural(len(v.list), "element"))
+		for i, elem := range v.list {
+			w.WriteByte('\n')
+			w.WriteString(strings.Repeat(opts.Indent, depth+1))
+			fmt.Fprintf(w, "[%d] ", i)
+			elem.format(w, opts, depth+1)
+		}
+	case KindDict:
+		fmt.Fprintf(w, "dict (%s)", plural(len(v.dict), "entry"))
+		for _, key := range v.Keys() {
+			w.WriteByte('\n')
+			w.WriteString(strings.Repeat(opts.Indent, depth+1))
+			w.WriteString(strconv.Quote(key))
+			w.WriteString(": ")
+			v.dict[key].format(w, opts, depth+1)
+		}
+	default:
+		w.WriteString("invalid")
+	}
+	if depth == 0 {
+		w.WriteByte('\n')
+	}
+}
+
+// formatString describes and previews the string b.
+func formatString(b []byte, opts FormatOptions) string {
+	preview, more := b, ""
+	if len(preview) > opts.MaxPreview {
+		preview, more = preview[:opts.MaxPreview], "..."
+	}
+	if isText(b) {
+		// Cut the preview at a rune boundary so it stays valid text.
+		for len(preview) > 0 && !utf8.Valid(preview) {
+			preview = preview[:len(preview)-1]
+		}
+		return fmt.Sprintf("string(%s, text) %s%s", plural(len(b), "byte"), strconv.Quote(string(preview)), more)
+	}
+	annotation := "binary"
+	switch {
+	case len(b) == 20:
+		annotation += ", sha1?"
+	case len(b) == 32:
+		annotation += ", sha256?"
+	case len(b) > 20 && len(b)%20 == 0:
+		annotation += fmt.Sprintf(", %d sha1 hashes?", len(b)/20)
+	}
+	encoded := hex.EncodeToString(preview)
+	if opts.Base64 {
+		encoded = base64.StdEncoding.EncodeToString(preview)
+	}
+	return fmt.Sprintf("string(%s, %s) %s%s", plural(len(b), "byte"), annotation, encoded, more)
+}
+
+// isText reports whether b is valid UTF-8 free of control characters other
+// than tabs and line breaks.
+func isText(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if unicode.IsControl(r) && r != '\t' && r != '\n' && r != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+// plural formats n followed by noun, pluralised unless n is 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	if strings.HasSuffix(noun, "y") {
+		return fmt.Sprintf("%d %sies", n, noun[:len(noun)-1])
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
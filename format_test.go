@@ -0,0 +1,58 @@
+package bencode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValueFormat(t *testing.T) {
+	hash := strings.Repeat("\x01\xff", 10)
+	v, err := ValueOf(map[string]any{
+		"announce": "http://tracker.example/announce?key=0123456789",
+		"info": map[string]any{
+			"piece length": 262144,
+			"pieces":       hash + hash,
+		},
+		"url-list": []any{"a", hash, ""},
+		"sha256":   strings.Repeat("\x00", 32),
+		"data":     "\x00\x01\x02",
+	})
+	if err != nil {
+		t.Fatalf("ValueOf() error = %v", err)
+	}
+
+	var sb strings.Builder
+	if err := v.Format(&sb, FormatOptions{}); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	want := `dict (5 entries)
+  "announce": string(46 bytes, text) "http://tracker.example/announce?"...
+  "data": string(3 bytes, binary) 000102
+  "info": dict (2 entries)
+    "piece length": int 262144
+    "pieces": string(40 bytes, binary, 2 sha1 hashes?) 01ff01ff01ff01ff01ff01ff01ff01ff01ff01ff01ff01ff01ff01ff01ff01ff...
+  "sha256": string(32 bytes, binary, sha256?) 0000000000000000000000000000000000000000000000000000000000000000
+  "url-list": list (3 elements)
+    [0] string(1 byte, text) "a"
+    [1] string(20 bytes, binary, sha1?) 01ff01ff01ff01ff01ff01ff01ff01ff01ff01ff
+    [2] string(0 bytes, text) ""
+`
+	if sb.String() != want {
+		t.Errorf("Format() =\n%s\nwant\n%s", sb.String(), want)
+	}
+
+	sb.Reset()
+	if err := v.dict["data"].Format(&sb, FormatOptions{Base64: true, MaxPreview: 2}); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	if want := "string(3 bytes, binary) AAE=...\n"; sb.String() != want {
+		t.Errorf("Format() with Base64 = %q, want %q", sb.String(), want)
+	}
+
+	sb.Reset()
+	text, _ := ValueOf("héllo")
+	text.Format(&sb, FormatOptions{MaxPreview: 2})
+	if want := "string(6 bytes, text) \"h\"...\n"; sb.String() != want {
+		t.Errorf("Format() cutting a rune = %q, want %q", sb.String(), want)
+	}
+}
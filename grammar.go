@@ -0,0 +1,43 @@
+package bencode
+
+// The bytes that delimit bencode values. A string has no opening token: it
+// starts with the decimal digits of its length, followed by StringSeparator.
+const (
+	// TokenInteger opens an integer, e.g. the i of i42e.
+	TokenInteger byte = 'i'
+	// TokenList opens a list, e.g. the l of l4:spame.
+	TokenList byte = 'l'
+	// TokenDict opens a dictionary, e.g. the d of d3:key5:valuee.
+	TokenDict byte = 'd'
+	// TokenEnd closes an integer, list or dictionary.
+	TokenEnd byte = 'e'
+	// StringSeparator separates the length of a string from its contents.
+	StringSeparator byte = ':'
+)
+
+// MaxSafeInteger is the largest integer that a float64 holds exactly,
+// 2^53-1. Integers up to the int64 range decode exactly with this package,
+// and larger ones with UseNumber, but peers and tools that parse numbers as
+// floating point, as JavaScript does, round anything beyond MaxSafeInteger.
+// Protocols exchanging integers with such software can check against it.
+const MaxSafeInteger = 1<<53 - 1
+
+// IsValidKeyByte reports whether c may appear in a dictionary key accepted
+// by Decoder.RequirePrintableKeys: the printable ASCII bytes 0x20 to 0x7e,
+// which cover every key of the BitTorrent protocols. Bencode itself allows
+// any byte in a key.
+func IsValidKeyByte(c byte) bool {
+	return c >= 0x20 && c <= 0x7e
+}
+
+// StringHeaderLen returns the length of the header that precedes a string
+// of n bytes: the decimal digits of n and the separator, 3 for the "10:" of
+// a ten-byte string. The whole encoded string is StringHeaderLen(n)+n bytes.
+// n must not be negative.
+func StringHeaderLen(n int) int {
+	digits := 1
+	for ; n >= 10; n /= 10 {
+		digits++
+	}
+	return digits + 1
+}
@@ -0,0 +1,53 @@
+package bencode
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGrammarTokens(t *testing.T) {
+	encoded, err := Marshal(map[string]any{"l": []any{int64(1)}, "d": map[string]any{}})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := string([]byte{TokenDict, '1', StringSeparator, 'd', TokenDict, TokenEnd, '1', StringSeparator, 'l', TokenList, TokenInteger, '1', TokenEnd, TokenEnd, TokenEnd})
+	if string(encoded) != want {
+		t.Errorf("Marshal() = %q, want %q", encoded, want)
+	}
+}
+
+func TestStringHeaderLen(t *testing.T) {
+	for _, n := range []int{0, 1, 9, 10, 99, 100, 12345, math.MaxInt} {
+		if got, want := StringHeaderLen(n), len(strconv.Itoa(n))+1; got != want {
+			t.Errorf("StringHeaderLen(%d) = %d, want %d", n, got, want)
+		}
+	}
+	encoded, _ := Marshal(strings.Repeat("x", 1000))
+	if got, want := StringHeaderLen(1000)+1000, len(encoded); got != want {
+		t.Errorf("StringHeaderLen(1000)+1000 = %d, want encoded length %d", got, want)
+	}
+}
+
+func TestIsValidKeyByte(t *testing.T) {
+	for c := range 256 {
+		dec := NewDecoder(strings.NewReader("d1:" + string([]byte{byte(c)}) + "i1ee"))
+		dec.RequirePrintableKeys()
+		_, err := dec.DecodeValue()
+		if got := IsValidKeyByte(byte(c)); got != (err == nil) {
+			t.Errorf("IsValidKeyByte(%#02x) = %t, but RequirePrintableKeys error = %v", c, got, err)
+		}
+	}
+}
+
+func TestMaxSafeInteger(t *testing.T) {
+	n := int64(MaxSafeInteger)
+	if int64(float64(n)) != n {
+		t.Errorf("float64(MaxSafeInteger) is not exact")
+	}
+	// Beyond it, distinct integers round to the same float64.
+	if float64(n+1) != float64(n+2) {
+		t.Errorf("float64(MaxSafeInteger+1) != float64(MaxSafeInteger+2), want MaxSafeInteger to be the largest safe integer")
+	}
+}
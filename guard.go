@@ -0,0 +1,32 @@
+package bencode
+
+import "sync/atomic"
+
+// useGuard detects overlapping calls into a single Encoder or Decoder.
+//
+// Encoders and Decoders hold stream state and are not safe for concurrent
+// use; interleaved calls corrupt the output or desynchronise the input. When
+// enabled, the guard turns such misuse into an ErrUsage error on the call
+// that arrives second, instead of silently producing garbage.
+type useGuard struct {
+	enabled bool
+	busy    atomic.Bool
+}
+
+// acquire marks the guarded value as in use by the caller named op.
+func (g *useGuard) acquire(op string) error {
+	if !g.enabled {
+		return nil
+	}
+	if !g.busy.CompareAndSwap(false, true) {
+		return &Error{Type: ErrUsage, Msg: op + " called concurrently with another call on the same value"}
+	}
+	return nil
+}
+
+// release undoes a successful acquire.
+func (g *useGuard) release() {
+	if g.enabled {
+		g.busy.Store(false)
+	}
+}
@@ -0,0 +1,94 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// blockingWriter signals entry on entered and blocks until release is closed.
+type blockingWriter struct {
+	entered chan struct{}
+	release chan struct{}
+	buf     bytes.Buffer
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	select {
+	case w.entered <- struct{}{}:
+	default:
+	}
+	<-w.release
+	return w.buf.Write(p)
+}
+
+func TestEncoderDetectConcurrentUse(t *testing.T) {
+	w := &blockingWriter{entered: make(chan struct{}, 1), release: make(chan struct{})}
+	enc := NewEncoder(w)
+	enc.DetectConcurrentUse()
+
+	done := make(chan error)
+	go func() { done <- enc.Encode("first") }()
+	<-w.entered
+
+	err := enc.Encode("second")
+	var bErr *Error
+	if !errors.As(err, &bErr) || bErr.Type != ErrUsage {
+		t.Errorf("concurrent Encode() error = %v, want %q", err, ErrUsage)
+	}
+
+	close(w.release)
+	if err := <-done; err != nil {
+		t.Fatalf("first Encode() error = %v", err)
+	}
+	if got := w.buf.String(); got != "5:first" {
+		t.Errorf("output = %q, want %q", got, "5:first")
+	}
+	if err := enc.Encode("third"); err != nil {
+		t.Errorf("sequential Encode() error = %v", err)
+	}
+}
+
+// blockingReader signals entry on entered and blocks until release is closed.
+type blockingReader struct {
+	entered chan struct{}
+	release chan struct{}
+	r       io.Reader
+}
+
+func (r *blockingReader) Read(p []byte) (int, error) {
+	select {
+	case r.entered <- struct{}{}:
+	default:
+	}
+	<-r.release
+	return r.r.Read(p)
+}
+
+func TestDecoderDetectConcurrentUse(t *testing.T) {
+	r := &blockingReader{entered: make(chan struct{}, 1), release: make(chan struct{}), r: bytes.NewReader([]byte("i1ei2e"))}
+	dec := NewDecoder(r)
+	dec.DetectConcurrentUse()
+
+	done := make(chan error)
+	go func() {
+		var n int
+		done <- dec.Decode(&n)
+	}()
+	<-r.entered
+
+	_, err := dec.DecodeValue()
+	var bErr *Error
+	if !errors.As(err, &bErr) || bErr.Type != ErrUsage {
+		t.Errorf("concurrent DecodeValue() error = %v, want %q", err, ErrUsage)
+	}
+
+	close(r.release)
+	if err := <-done; err != nil {
+		t.Fatalf("first Decode() error = %v", err)
+	}
+	if v, err := dec.DecodeValue(); err != nil || v != int64(2) {
+		t.Errorf("sequential DecodeValue() = %v, %v", v, err)
+	}
+}
@@ -0,0 +1,119 @@
+package bencode
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrUnmarshalHook indicates that a decode hook rejected a value.
+const ErrUnmarshalHook ErrorType = "unmarshal hook error"
+
+// A DecodeHookFunc converts a decoded value before it is assigned to a Go
+// value of type to. from is the kind of data, which holds the generic value
+// described by Decoder.DecodeValue, or KindInvalid if an earlier hook already
+// replaced it. A hook returns data unchanged to leave it alone.
+//
+// Hooks allow conversions such as string to time.Duration, string to an enum
+// or integer to bool without implementing a decoding method on the type.
+type DecodeHookFunc func(from Kind, to reflect.Type, data any) (any, error)
+
+// UseDecodeHook adds hook to the hooks run, in the order they were added,
+// before each value is assigned, including nested values and struct fields.
+// If the hooks replace the value with one of a type other than the generic
+// ones and assignable to the destination, it is stored as is; otherwise it is
+// assigned by the usual rules, so a hook may for instance turn a string into
+// an int64 for an integer destination. An error returned by a
+// hook stops decoding and is wrapped in an ErrUnmarshalHook error unless it
+// is already an *Error.
+func (d *Decoder) UseDecodeHook(hook DecodeHookFunc) {
+	d.decodeHooks = append(d.decodeHooks, hook)
+}
+
+// runDecodeHooks passes data through the decode hooks for a destination of
+// type to.
+func (d *Decoder) runDecodeHooks(to reflect.Type, data any) (any, error) {
+	for _, hook := range d.decodeHooks {
+		var err error
+		if data, err = hook(kindOfDecoded(data), to, data); err != nil {
+			if bErr, ok := err.(*Error); ok {
+				return nil, bErr
+			}
+			return nil, &Error{Type: ErrUnmarshalHook, Msg: fmt.Sprintf("decode hook for %s", to), WrappedErr: err}
+		}
+	}
+	return data, nil
+}
+
+// ErrEncodeHook indicates that an encode hook rejected a value.
+const ErrEncodeHook ErrorType = "encode: hook error"
+
+// An EncodeHookFunc converts a value before it is encoded. from is the type
+// of data. A hook returns data unchanged to leave it alone; whatever it
+// returns is encoded by the usual rules, without running the hooks again.
+//
+// Hooks keep wire-format concerns such as encoding a time.Duration as a
+// number of seconds, or an enum as its name, out of struct definitions.
+type EncodeHookFunc func(from reflect.Type, data any) (any, error)
+
+// globalEncodeHooks holds the hooks added by RegisterEncodeHook.
+var (
+	globalEncodeHooks      atomic.Pointer[[]EncodeHookFunc]
+	globalEncodeHooksMutex sync.Mutex
+)
+
+// RegisterEncodeHook adds hook to the hooks run by every Encoder, including
+// the one used by Marshal, before the hooks added with UseEncodeHook. It is
+// intended to be called from init functions.
+func RegisterEncodeHook(hook EncodeHookFunc) {
+	globalEncodeHooksMutex.Lock()
+	defer globalEncodeHooksMutex.Unlock()
+	var hooks []EncodeHookFunc
+	if old := globalEncodeHooks.Load(); old != nil {
+		hooks = slices.Clone(*old)
+	}
+	hooks = append(hooks, hook)
+	globalEncodeHooks.Store(&hooks)
+}
+
+// UseEncodeHook adds hook to the hooks run, in the order they were added,
+// on every non-nil value the Encoder encodes, including list elements,
+// dictionary values and struct fields. An error returned by a hook stops
+// encoding and is wrapped in an ErrEncodeHook error unless it is already an
+// *Error.
+func (e *Encoder) UseEncodeHook(hook EncodeHookFunc) {
+	e.encodeHooks = append(e.encodeHooks, hook)
+}
+
+// hooked reports whether any encode hooks, global or the Encoder's own,
+// are set.
+func (e *Encoder) hooked() bool {
+	return len(e.encodeHooks) > 0 || globalEncodeHooks.Load() != nil
+}
+
+// runEncodeHooks passes v through the registered hooks and then the
+// Encoder's own.
+func (e *Encoder) runEncodeHooks(v any) (any, error) {
+	var global []EncodeHookFunc
+	if hooks := globalEncodeHooks.Load(); hooks != nil {
+		global = *hooks
+	}
+	for _, hooks := range [][]EncodeHookFunc{global, e.encodeHooks} {
+		for _, hook := range hooks {
+			if v == nil {
+				return nil, nil
+			}
+			from := reflect.TypeOf(v)
+			var err error
+			if v, err = hook(from, v); err != nil {
+				if bErr, ok := err.(*Error); ok {
+					return nil, bErr
+				}
+				return nil, &Error{Type: ErrEncodeHook, Msg: fmt.Sprintf("encode hook for %s", from), WrappedErr: err}
+			}
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,105 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeHook(t *testing.T) {
+	type level int
+	type config struct {
+		Interval time.Duration `bencode:"interval"`
+		Enabled  bool          `bencode:"enabled"`
+		Level    level         `bencode:"level"`
+		Name     string        `bencode:"name"`
+	}
+	durations := func(from Kind, to reflect.Type, data any) (any, error) {
+		if from != KindString || to != reflect.TypeFor[time.Duration]() {
+			return data, nil
+		}
+		return time.ParseDuration(string(data.([]byte)))
+	}
+	bools := func(from Kind, to reflect.Type, data any) (any, error) {
+		if from != KindInteger || to.Kind() != reflect.Bool {
+			return data, nil
+		}
+		return data.(int64) != 0, nil
+	}
+	levels := func(from Kind, to reflect.Type, data any) (any, error) {
+		if from != KindString || to != reflect.TypeFor[level]() {
+			return data, nil
+		}
+		n, err := strconv.ParseInt(string(data.([]byte)), 10, 64)
+		return n, err // an int64 is assigned by the usual rules
+	}
+
+	dec := NewDecoder(strings.NewReader("d7:enabledi1e8:interval2:1m5:level1:34:name1:xe"))
+	dec.UseDecodeHook(durations)
+	dec.UseDecodeHook(bools)
+	dec.UseDecodeHook(levels)
+	var got config
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	want := config{Interval: time.Minute, Enabled: true, Level: 3, Name: "x"}
+	if got != want {
+		t.Errorf("Decode() = %+v, want %+v", got, want)
+	}
+
+	dec = NewDecoder(strings.NewReader("d8:interval4:soone"))
+	dec.UseDecodeHook(durations)
+	err := dec.Decode(&got)
+	var bErr *Error
+	if !errors.As(err, &bErr) || bErr.Type != ErrUnmarshalHook || bErr.FieldName != "interval" {
+		t.Errorf("Decode() error = %v, want %q for field %q", err, ErrUnmarshalHook, "interval")
+	}
+}
+
+func TestEncodeHook(t *testing.T) {
+	type color int
+	type settings struct {
+		Color    color         `bencode:"color"`
+		Interval time.Duration `bencode:"interval"`
+		Tags     []color       `bencode:"tags"`
+	}
+	// The registered hook would otherwise stay for the rest of the tests,
+	// slowing the allocation-free paths they check.
+	saved := globalEncodeHooks.Load()
+	t.Cleanup(func() { globalEncodeHooks.Store(saved) })
+	RegisterEncodeHook(func(from reflect.Type, data any) (any, error) {
+		if c, ok := data.(color); ok {
+			return []string{"red", "green"}[c], nil
+		}
+		return data, nil
+	})
+	seconds := func(from reflect.Type, data any) (any, error) {
+		if d, ok := data.(time.Duration); ok {
+			if d%time.Second != 0 {
+				return nil, errors.New("not a whole number of seconds")
+			}
+			return int64(d / time.Second), nil
+		}
+		return data, nil
+	}
+
+	var b bytes.Buffer
+	enc := NewEncoder(&b)
+	enc.UseEncodeHook(seconds)
+	if err := enc.Encode(settings{Color: 1, Interval: time.Minute, Tags: []color{0, 1}}); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if want := "d5:color5:green8:intervali60e4:tagsl3:red5:greenee"; b.String() != want {
+		t.Errorf("Encode() = %q, want %q", b.String(), want)
+	}
+
+	err := enc.Encode(settings{Interval: time.Millisecond})
+	var bErr *Error
+	if !errors.As(err, &bErr) || bErr.Type != ErrEncodeHook || bErr.FieldName != "interval" {
+		t.Errorf("Encode() error = %v, want %q for field %q", err, ErrEncodeHook, "interval")
+	}
+}
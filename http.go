@@ -0,0 +1,87 @@
+package bencode
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+)
+
+// HTTPContentType is the Content-Type used by ServeValue. Trackers
+// conventionally serve bencoded responses as plain text.
+const HTTPContentType = "text/plain; charset=utf-8"
+
+// ServeValue bencodes v straight into the response body with status 200,
+// without holding the encoding in memory.
+//
+// The header is sent with the first byte of the body. An encode error found
+// before then, such as an unsupported type at the root, is reported as a 500
+// Internal Server Error; one found later can only cut the body short, and is
+// returned to the caller like any write error. Use ServeValueBuffered when a
+// truncated body is worse than buffering the whole encoding.
+func ServeValue(w http.ResponseWriter, v any) error {
+	hw := &headerWriter{w: w}
+	if err := NewEncoder(hw).Encode(v); err != nil {
+		if !hw.sent {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return err
+	}
+	if !hw.sent {
+		hw.sendHeader()
+	}
+	return nil
+}
+
+// ServeValueBuffered is like ServeValue, but encodes v in full before
+// anything is written, so that every encode error can still be reported as a
+// 500 Internal Server Error instead of a truncated body, and the response
+// carries a Content-Length. Write errors after the header has been sent are
+// returned to the caller.
+func ServeValueBuffered(w http.ResponseWriter, v any) error {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(v); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return err
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	(&headerWriter{w: w}).sendHeader()
+	if _, err := buf.WriteTo(w); err != nil {
+		return &Error{Type: ErrEncodeWriteError, Msg: "failed to write HTTP response", WrappedErr: err}
+	}
+	return nil
+}
+
+// headerWriter sends the response header of a successful ServeValue before
+// the first byte of the body.
+type headerWriter struct {
+	w    http.ResponseWriter
+	sent bool
+}
+
+func (hw *headerWriter) sendHeader() {
+	hw.w.Header().Set("Content-Type", HTTPContentType)
+	hw.w.WriteHeader(http.StatusOK)
+	hw.sent = true
+}
+
+func (hw *headerWriter) Write(p []byte) (int, error) {
+	if !hw.sent {
+		hw.sendHeader()
+	}
+	return hw.w.Write(p)
+}
+
+// HandlerFunc adapts a function producing a value into an http.Handler that
+// serves the value with ServeValue. A non-nil error from fn is reported as a
+// 500 Internal Server Error.
+type HandlerFunc func(r *http.Request) (any, error)
+
+// ServeHTTP implements http.Handler.
+func (f HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v, err := f(r)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	_ = ServeValue(w, v)
+}
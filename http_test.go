@@ -0,0 +1,88 @@
+package bencode
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServeValue(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := ServeValue(rec, map[string]any{"interval": 1800}); err != nil {
+		t.Fatalf("ServeValue() error = %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("Content-Type"); got != HTTPContentType {
+		t.Errorf("Content-Type = %q, want %q", got, HTTPContentType)
+	}
+	if got := rec.Body.String(); got != "d8:intervali1800ee" {
+		t.Errorf("body = %q", got)
+	}
+}
+
+func TestServeValueEncodeError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := ServeValue(rec, make(chan int))
+	var bErr *Error
+	if !errors.As(err, &bErr) || bErr.Type != ErrEncodeUnsupportedType {
+		t.Errorf("ServeValue() error = %v, want %q", err, ErrEncodeUnsupportedType)
+	}
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestServeValueStreamed(t *testing.T) {
+	// An error after the body has begun can no longer become a 500.
+	rec := httptest.NewRecorder()
+	err := ServeValue(rec, map[string]any{"a": 1, "b": make(chan int)})
+	if !errors.Is(err, ErrEncodeUnsupportedType) {
+		t.Errorf("ServeValue() error = %v, want %q", err, ErrEncodeUnsupportedType)
+	}
+	if rec.Code != http.StatusOK || rec.Body.String() != "d1:ai1e1:b" {
+		t.Errorf("ServeValue() = %d %q, want the truncated body", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Length"); got != "" {
+		t.Errorf("Content-Length = %q, want none while streaming", got)
+	}
+}
+
+func TestServeValueBuffered(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := ServeValueBuffered(rec, []string{"ok"}); err != nil {
+		t.Fatalf("ServeValueBuffered() error = %v", err)
+	}
+	if rec.Code != http.StatusOK || rec.Body.String() != "l2:oke" || rec.Header().Get("Content-Length") != "6" || rec.Header().Get("Content-Type") != HTTPContentType {
+		t.Errorf("ServeValueBuffered() = %d %q, header %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	err := ServeValueBuffered(rec, map[string]any{"a": 1, "b": make(chan int)})
+	if !errors.Is(err, ErrEncodeUnsupportedType) || rec.Code != http.StatusInternalServerError {
+		t.Errorf("ServeValueBuffered() = %d, %v, want %d", rec.Code, err, http.StatusInternalServerError)
+	}
+}
+
+func TestHandlerFunc(t *testing.T) {
+	h := HandlerFunc(func(r *http.Request) (any, error) {
+		if r.URL.Query().Get("fail") != "" {
+			return nil, errors.New("boom")
+		}
+		return []string{"ok"}, nil
+	})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/announce", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "l2:oke" {
+		t.Errorf("ServeHTTP() = %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/announce?fail=1", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("ServeHTTP() status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,67 @@
+package bencode
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// InfoHash is the SHA-1 hash of a torrent's bencoded info dictionary, which
+// identifies a v1 torrent. It is comparable, so it can be used as a map key,
+// and bencodes as a 20-byte string.
+type InfoHash [20]byte
+
+// InfoHashV2 is the SHA-256 hash of the info dictionary of a v2 torrent
+// (BEP 52). It bencodes as a 32-byte string.
+type InfoHashV2 [32]byte
+
+// String returns the hash as 40 lowercase hex digits.
+func (h InfoHash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// String returns the hash as 64 lowercase hex digits.
+func (h InfoHashV2) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// Truncate returns the first 20 bytes of h, which stand in for a v2 torrent
+// wherever a 20-byte info hash is expected, such as in tracker announces and
+// the DHT.
+func (h InfoHashV2) Truncate() InfoHash {
+	return InfoHash(h[:20])
+}
+
+// InfoHashFromHex parses a hash written as 40 hex digits in either case.
+func InfoHashFromHex(s string) (InfoHash, error) {
+	var h InfoHash
+	return h, decodeHash(h[:], s, "hex", hex.DecodeString)
+}
+
+// InfoHashFromBase32 parses a hash written as 32 base32 digits, the other
+// form allowed in the xt=urn:btih: parameter of magnet links. Either case is
+// accepted.
+func InfoHashFromBase32(s string) (InfoHash, error) {
+	var h InfoHash
+	return h, decodeHash(h[:], strings.ToUpper(s), "base32", base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString)
+}
+
+// InfoHashV2FromHex parses a hash written as 64 hex digits in either case.
+func InfoHashV2FromHex(s string) (InfoHashV2, error) {
+	var h InfoHashV2
+	return h, decodeHash(h[:], s, "hex", hex.DecodeString)
+}
+
+// decodeHash decodes s into dst, which it must fill exactly.
+func decodeHash(dst []byte, s, encoding string, decode func(string) ([]byte, error)) error {
+	b, err := decode(s)
+	if err != nil {
+		return &Error{Type: ErrUsage, Msg: fmt.Sprintf("invalid %s hash %q", encoding, s), WrappedErr: err}
+	}
+	if len(b) != len(dst) {
+		return &Error{Type: ErrUsage, Msg: fmt.Sprintf("%s hash %q is %d bytes, want %d", encoding, s, len(b), len(dst))}
+	}
+	copy(dst, b)
+	return nil
+}
@@ -0,0 +1,80 @@
+package bencode
+
+import (
+	"crypto/sha256"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestInfoHashParse(t *testing.T) {
+	const hexHash = "c9e15763f722f23e98a29decdfae341b98d53056"
+	want := InfoHash{0xc9, 0xe1, 0x57, 0x63, 0xf7, 0x22, 0xf2, 0x3e, 0x98, 0xa2, 0x9d, 0xec, 0xdf, 0xae, 0x34, 0x1b, 0x98, 0xd5, 0x30, 0x56}
+	tests := []struct {
+		name  string
+		parse func(string) (InfoHash, error)
+		input string
+		ok    bool
+	}{
+		{name: "hex", parse: InfoHashFromHex, input: hexHash, ok: true},
+		{name: "upper hex", parse: InfoHashFromHex, input: strings.ToUpper(hexHash), ok: true},
+		{name: "base32", parse: InfoHashFromBase32, input: "ZHQVOY7XELZD5GFCTXWN7LRUDOMNKMCW", ok: true},
+		{name: "lower base32", parse: InfoHashFromBase32, input: "zhqvoy7xelzd5gfctxwn7lrudomnkmcw", ok: true},
+		{name: "short hex", parse: InfoHashFromHex, input: hexHash[:38]},
+		{name: "bad hex", parse: InfoHashFromHex, input: "zz" + hexHash[2:]},
+		{name: "short base32", parse: InfoHashFromBase32, input: "ZHQVOY7X"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parse(tt.input)
+			if !tt.ok {
+				var bErr *Error
+				if !errors.As(err, &bErr) || bErr.Type != ErrUsage {
+					t.Errorf("parse(%q) error = %v, want %q", tt.input, err, ErrUsage)
+				}
+				return
+			}
+			if err != nil || got != want {
+				t.Errorf("parse(%q) = %v, %v, want %v", tt.input, got, err, want)
+			}
+			if got.String() != hexHash {
+				t.Errorf("String() = %q, want %q", got.String(), hexHash)
+			}
+		})
+	}
+}
+
+func TestInfoHashBencode(t *testing.T) {
+	type announce struct {
+		InfoHash InfoHash   `bencode:"info_hash"`
+		V2       InfoHashV2 `bencode:"v2"`
+	}
+	in := announce{InfoHash: InfoHash{1, 2, 3}, V2: sha256.Sum256([]byte("info"))}
+	encoded, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := "d9:info_hash20:\x01\x02\x03" + strings.Repeat("\x00", 17) + "2:v232:" + string(in.V2[:]) + "e"
+	if string(encoded) != want {
+		t.Errorf("Marshal() = %q, want %q", encoded, want)
+	}
+	var out announce
+	if err := Unmarshal(encoded, &out); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if out != in {
+		t.Errorf("Unmarshal() = %+v, want %+v", out, in)
+	}
+	if out.V2.Truncate() != InfoHash(in.V2[:20]) {
+		t.Errorf("Truncate() = %v", out.V2.Truncate())
+	}
+
+	var h InfoHash
+	var bErr *Error
+	if err := Unmarshal([]byte("3:abc"), &h); !errors.As(err, &bErr) || bErr.Type != ErrUnmarshalType {
+		t.Errorf("Unmarshal() of short string error = %v, want %q", err, ErrUnmarshalType)
+	}
+	if _, err := Marshal([2]int{}); !errors.As(err, &bErr) || bErr.Type != ErrEncodeUnsupportedType {
+		t.Errorf("Marshal() of int array error = %v, want %q", err, ErrEncodeUnsupportedType)
+	}
+}
@@ -0,0 +1,35 @@
+package bencode
+
+// maxInternedKeys bounds the interning table of a Decoder, so that a
+// long-lived Decoder fed many distinct keys does not grow without limit.
+// Keys seen after the table is full are allocated as usual.
+const maxInternedKeys = 4096
+
+// InternKeys makes the Decoder keep one copy of each distinct dictionary key
+// it decodes and reuse it for every later occurrence. Large documents repeat
+// the same keys many times, such as "length" and "path" in the file list of
+// a multi-file torrent, and the generic maps returned by DecodeValue and
+// UnmarshalAny then share those strings instead of holding a copy each.
+// The table lives as long as the Decoder and holds at most a few thousand
+// keys.
+func (d *Decoder) InternKeys() {
+	if d.keys == nil {
+		d.keys = make(map[string]string)
+	}
+}
+
+// internKey returns b as a string, shared with earlier occurrences of the
+// same key when InternKeys is in effect.
+func (d *Decoder) internKey(b []byte) string {
+	if d.keys == nil {
+		return string(b)
+	}
+	if s, ok := d.keys[string(b)]; ok {
+		return s
+	}
+	s := string(b)
+	if len(d.keys) < maxInternedKeys {
+		d.keys[s] = s
+	}
+	return s
+}
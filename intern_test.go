@@ -0,0 +1,55 @@
+package bencode
+
+import (
+	"bytes"
+	"testing"
+	"unsafe"
+)
+
+func TestDecoderInternKeys(t *testing.T) {
+	input := []byte("l" + "d6:lengthi1e4:pathl1:aee" + "d6:lengthi2e4:pathl1:bee" + "e")
+
+	decodeFiles := func(intern bool) []any {
+		t.Helper()
+		dec := NewDecoder(bytes.NewReader(input))
+		if intern {
+			dec.InternKeys()
+		}
+		v, err := dec.DecodeValue()
+		if err != nil {
+			t.Fatalf("DecodeValue() error = %v", err)
+		}
+		return v.([]any)
+	}
+	keyData := func(m any, key string) *byte {
+		for k := range m.(map[string]any) {
+			if k == key {
+				return unsafe.StringData(k)
+			}
+		}
+		t.Fatalf("key %q not found", key)
+		return nil
+	}
+
+	files := decodeFiles(true)
+	for _, key := range []string{"length", "path"} {
+		if keyData(files[0], key) != keyData(files[1], key) {
+			t.Errorf("with InternKeys, key %q is not shared", key)
+		}
+	}
+	files = decodeFiles(false)
+	if keyData(files[0], "length") == keyData(files[1], "length") {
+		t.Errorf("without InternKeys, key %q is shared", "length")
+	}
+}
+
+func TestDecoderInternKeysLimit(t *testing.T) {
+	dec := NewDecoder(nil)
+	dec.InternKeys()
+	for i := range maxInternedKeys + 10 {
+		dec.internKey([]byte{byte(i), byte(i >> 8), byte(i >> 16)})
+	}
+	if len(dec.keys) != maxInternedKeys {
+		t.Errorf("interning table holds %d keys, want %d", len(dec.keys), maxInternedKeys)
+	}
+}
@@ -0,0 +1,138 @@
+package bencode
+
+import (
+	"fmt"
+	"iter"
+	"reflect"
+	"strconv"
+
+	"github.com/stupoid/bencode/scanner"
+)
+
+// Values returns an iterator over the elements of the list encoded in data,
+// yielding each element's index and encoding without decoding it.
+//
+// Iteration stops early if data is not a list or is malformed; validate
+// untrusted input first (for example with Index) if the difference between
+// an empty and a malformed list matters. The yielded RawMessages alias data.
+func Values(data []byte) iter.Seq2[int, RawMessage] {
+	return func(yield func(int, RawMessage) bool) {
+		var s scanner.Scanner
+		s.Reset(data)
+		if tok, err := s.Next(); err != nil || tok.Kind != scanner.ListStart {
+			return
+		}
+		for i := 0; s.Peek() != scanner.End; i++ {
+			tok, err := s.Skip()
+			if err != nil {
+				return
+			}
+			if !yield(i, RawMessage(data[tok.Offset:tok.End():tok.End()])) {
+				return
+			}
+		}
+	}
+}
+
+// Items returns an iterator over the entries of the dictionary encoded in
+// data, yielding each key and the encoding of its value without decoding it.
+//
+// Iteration stops early if data is not a dictionary or is malformed, as for
+// Values. Keys are yielded in input order; their sort order is not checked.
+func Items(data []byte) iter.Seq2[string, RawMessage] {
+	return func(yield func(string, RawMessage) bool) {
+		var s scanner.Scanner
+		s.Reset(data)
+		if tok, err := s.Next(); err != nil || tok.Kind != scanner.DictStart {
+			return
+		}
+		for s.Peek() != scanner.End {
+			keyTok, err := s.Next()
+			if err != nil || keyTok.Kind != scanner.String {
+				return
+			}
+			valTok, err := s.Skip()
+			if err != nil {
+				return
+			}
+			key := string(data[keyTok.ValueOffset : keyTok.ValueOffset+keyTok.ValueLen])
+			if !yield(key, RawMessage(data[valTok.Offset:valTok.End():valTok.End()])) {
+				return
+			}
+		}
+	}
+}
+
+// isSeq reports whether typ has the shape of iter.Seq[T], and isSeq2 whether
+// it has the shape of iter.Seq2[K, V] with a string-kinded K.
+func isSeq(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Func && typ.NumIn() == 1 && typ.NumOut() == 0 &&
+		isYield(typ.In(0), 1)
+}
+
+func isSeq2(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Func && typ.NumIn() == 1 && typ.NumOut() == 0 &&
+		isYield(typ.In(0), 2) && typ.In(0).In(0).Kind() == reflect.String
+}
+
+// isYield reports whether typ is func(...) bool taking n arguments.
+func isYield(typ reflect.Type, n int) bool {
+	return typ.Kind() == reflect.Func && typ.NumIn() == n && typ.NumOut() == 1 &&
+		typ.Out(0).Kind() == reflect.Bool
+}
+
+// encodeSeq streams the elements of an iter.Seq into a bencode list.
+func (e *Encoder) encodeSeq(seq reflect.Value) error {
+	if _, err := e.w.Write([]byte{'l'}); err != nil {
+		return &Error{Type: ErrEncodeWriteError, Msg: "failed to write list start token 'l'", WrappedErr: err}
+	}
+	var encErr error
+	var i int
+	yield := reflect.MakeFunc(seq.Type().In(0), func(args []reflect.Value) []reflect.Value {
+		if encErr != nil { // the sequence ignored an earlier false
+			return []reflect.Value{reflect.ValueOf(false)}
+		}
+		elem, idx := args[0].Interface(), i
+		i++
+		if elem == nil && e.omitNil {
+			return []reflect.Value{reflect.ValueOf(true)}
+		}
+		if encErr = e.encode(elem); encErr != nil {
+			encErr = errPath(encErr, strconv.Itoa(idx))
+		}
+		return []reflect.Value{reflect.ValueOf(encErr == nil)}
+	})
+	seq.Call([]reflect.Value{yield})
+	if encErr != nil {
+		return encErr
+	}
+	if _, err := e.w.Write([]byte{'e'}); err != nil {
+		return &Error{Type: ErrEncodeWriteError, Msg: "failed to write list end token 'e'", WrappedErr: err}
+	}
+	return nil
+}
+
+// encodeSeq2 collects the pairs of an iter.Seq2 with string keys and encodes
+// them as a dictionary. The pairs are buffered because keys must be sorted.
+func (e *Encoder) encodeSeq2(seq reflect.Value) error {
+	entries := make(map[string]any)
+	var dupKey string
+	var dup bool
+	yield := reflect.MakeFunc(seq.Type().In(0), func(args []reflect.Value) []reflect.Value {
+		if dup { // the sequence ignored an earlier false
+			return []reflect.Value{reflect.ValueOf(false)}
+		}
+		key := args[0].String()
+		if _, dup = entries[key]; dup {
+			dupKey = key
+			return []reflect.Value{reflect.ValueOf(false)}
+		}
+		entries[key] = args[1].Interface()
+		return []reflect.Value{reflect.ValueOf(true)}
+	})
+	seq.Call([]reflect.Value{yield})
+	if dup {
+		return &Error{Type: ErrEncodeDuplicateKey, Msg: fmt.Sprintf("sequence yielded key %q more than once", dupKey), FieldName: dupKey}
+	}
+	return e.encode(entries)
+}
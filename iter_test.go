@@ -0,0 +1,55 @@
+package bencode
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValues(t *testing.T) {
+	var got []string
+	for i, raw := range Values([]byte("l4:spami42eli1eed1:a0:ee")) {
+		if i != len(got) {
+			t.Errorf("Values() index = %d, want %d", i, len(got))
+		}
+		got = append(got, string(raw))
+	}
+	expected := []string{"4:spam", "i42e", "li1ee", "d1:a0:e"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Values() = %q, want %q", got, expected)
+	}
+
+	for _, input := range []string{"", "d1:ai1ee", "l4:sp"} {
+		for range Values([]byte(input)) {
+			t.Errorf("Values(%q) yielded an element", input)
+		}
+	}
+}
+
+func TestItems(t *testing.T) {
+	got := map[string]string{}
+	var keys []string
+	for k, raw := range Items([]byte("d1:ai1e1:bl1:xe1:cd1:yi2eee")) {
+		keys = append(keys, k)
+		got[k] = string(raw)
+	}
+	expected := map[string]string{"a": "i1e", "b": "l1:xe", "c": "d1:yi2ee"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Items() = %q, want %q", got, expected)
+	}
+	if !reflect.DeepEqual(keys, []string{"a", "b", "c"}) {
+		t.Errorf("Items() keys = %q", keys)
+	}
+
+	count := 0
+	for range Items([]byte("d1:ai1e1:bi2ee")) {
+		count++
+		break
+	}
+	if count != 1 {
+		t.Errorf("Items() did not stop on break")
+	}
+
+	for range Items([]byte("li1ee")) {
+		t.Errorf("Items() on a list yielded an entry")
+	}
+}
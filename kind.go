@@ -0,0 +1,82 @@
+package bencode
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Kind identifies the type of a bencode value.
+type Kind int
+
+const (
+	// KindInvalid is the zero Kind and does not describe any value.
+	KindInvalid Kind = iota
+	// KindString is a bencode byte string, e.g. 4:spam.
+	KindString
+	// KindInteger is a bencode integer, e.g. i42e.
+	KindInteger
+	// KindList is a bencode list, e.g. l4:spame.
+	KindList
+	// KindDict is a bencode dictionary, e.g. d3:key5:valuee.
+	KindDict
+)
+
+// String returns the name of the kind.
+func (k Kind) String() string {
+	switch k {
+	case KindString:
+		return "string"
+	case KindInteger:
+		return "integer"
+	case KindList:
+		return "list"
+	case KindDict:
+		return "dictionary"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// kindOfToken returns the kind of value introduced by the token byte c.
+func kindOfToken(c byte) Kind {
+	switch {
+	case c >= '0' && c <= '9':
+		return KindString
+	case c == TokenInteger:
+		return KindInteger
+	case c == TokenList:
+		return KindList
+	case c == TokenDict:
+		return KindDict
+	default:
+		return KindInvalid
+	}
+}
+
+// PeekKind reports the kind of the next value in the input without
+// consuming it, so that callers can choose a destination type before calling
+// Decode. For example, a tracker's "peers" may be either a list of
+// dictionaries or a compact string.
+//
+// At the end of the input PeekKind returns ErrNullRootValue, as Decode does.
+func (d *Decoder) PeekKind() (Kind, error) {
+	if err := d.failed(); err != nil {
+		return KindInvalid, err
+	}
+	if err := d.drainString(); err != nil {
+		return KindInvalid, err
+	}
+	next, err := d.r.Peek(1)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return KindInvalid, ErrNullRootValue
+		}
+		return KindInvalid, d.readError(err, "peeking next token")
+	}
+	kind := kindOfToken(next[0])
+	if kind == KindInvalid {
+		return KindInvalid, &Error{Type: ErrSyntaxUnexpectedToken, Msg: fmt.Sprintf("unexpected token %q", rune(next[0]))}
+	}
+	return kind, nil
+}
@@ -0,0 +1,77 @@
+package bencode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecoderPeekKind(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Kind
+	}{
+		{input: "4:spam", expected: KindString},
+		{input: "i42e", expected: KindInteger},
+		{input: "l4:spame", expected: KindList},
+		{input: "d1:ai1ee", expected: KindDict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expected.String(), func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tt.input))
+			got, err := dec.PeekKind()
+			if err != nil {
+				t.Fatalf("PeekKind() error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("PeekKind() = %v, want %v", got, tt.expected)
+			}
+			// The value must still be decodable after peeking.
+			if _, err := dec.DecodeValue(); err != nil {
+				t.Errorf("DecodeValue() after PeekKind() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestDecoderPeekKindErrors(t *testing.T) {
+	_, err := NewDecoder(strings.NewReader("")).PeekKind()
+	if !errors.Is(err, ErrNullRootValue) {
+		t.Errorf("PeekKind() at EOF error = %v, want %v", err, ErrNullRootValue)
+	}
+
+	_, err = NewDecoder(strings.NewReader("x")).PeekKind()
+	var bErr *Error
+	if !errors.As(err, &bErr) || bErr.Type != ErrSyntaxUnexpectedToken {
+		t.Errorf("PeekKind() error = %v, want %q", err, ErrSyntaxUnexpectedToken)
+	}
+}
+
+func TestPeekKindBranching(t *testing.T) {
+	type Peer struct {
+		IP   string `bencode:"ip"`
+		Port int    `bencode:"port"`
+	}
+
+	dec := NewDecoder(strings.NewReader("6:abcdefld2:ip9:127.0.0.14:porti6881eee"))
+	for range 2 {
+		kind, err := dec.PeekKind()
+		if err != nil {
+			t.Fatalf("PeekKind() error = %v", err)
+		}
+		switch kind {
+		case KindString:
+			var compact string
+			if err := dec.Decode(&compact); err != nil || compact != "abcdef" {
+				t.Errorf("Decode() compact = %q, %v", compact, err)
+			}
+		case KindList:
+			var peers []Peer
+			if err := dec.Decode(&peers); err != nil || len(peers) != 1 || peers[0].Port != 6881 {
+				t.Errorf("Decode() peers = %+v, %v", peers, err)
+			}
+		default:
+			t.Fatalf("unexpected kind %v", kind)
+		}
+	}
+}
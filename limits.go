@@ -0,0 +1,257 @@
+package bencode
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	// ErrLimitExceeded indicates the input exceeds a limit configured on the Decoder.
+	ErrLimitExceeded ErrorType = "decode limit exceeded"
+	// ErrStructureDictKeyCharset indicates a dictionary key contains a byte
+	// other than printable ASCII while RequirePrintableKeys is in effect.
+	ErrStructureDictKeyCharset ErrorType = "dictionary key charset error"
+	// ErrMessageTooLarge indicates decoding a value would allocate more
+	// memory than allowed by MaxDecodedBytes.
+	ErrMessageTooLarge ErrorType = "message too large"
+	// ErrIntegerTooLong indicates an integer, or a string length, with more
+	// digits than allowed by MaxIntDigits.
+	ErrIntegerTooLong ErrorType = "integer too long"
+	// ErrStructureDictKeyNUL indicates a dictionary key contains a NUL byte
+	// while RejectNULKeys is in effect. It wraps ErrStructureDictKeyCharset.
+	ErrStructureDictKeyNUL ErrorType = "dictionary key contains NUL"
+	// ErrStructureDictKeyLength indicates a dictionary key longer than
+	// allowed by MaxKeyLength. It wraps ErrLimitExceeded.
+	ErrStructureDictKeyLength ErrorType = "dictionary key too long"
+	// ErrStructureDictEntries indicates a dictionary with more entries than
+	// allowed by MaxDictEntries. It wraps ErrLimitExceeded.
+	ErrStructureDictEntries ErrorType = "too many dictionary entries"
+)
+
+// DefaultMaxIntDigits is the number of digits an integer may have unless
+// changed with Decoder.MaxIntDigits: enough for any int64 or uint64, with
+// room to spare.
+const DefaultMaxIntDigits = 25
+
+// maxLengthDigits is the number of digits a string length may have, enough
+// for any length that fits in an int.
+const maxLengthDigits = 20
+
+// valueOverhead is the memory charged against MaxDecodedBytes for every
+// decoded value besides the contents of strings: an estimate of its header
+// and its slot in the enclosing list or dictionary.
+const valueOverhead = 32
+
+// decodeLimits holds the structural limits enforced by a Decoder. Zero
+// values mean no limit.
+type decodeLimits struct {
+	maxElements    int
+	maxDictEntries int
+	maxKeyLength   int
+	printableKeys  bool
+	nulKeys        bool
+	maxBytes       int64
+	maxIntDigits   int // 0 for DefaultMaxIntDigits, negative for no limit
+
+	elements int      // values started in the current top-level value
+	bytes    int64    // memory charged for the current top-level value
+	path     []string // keys and indices leading to the current value, when checking keys
+}
+
+// MaxElements limits the number of values, counting every string, integer,
+// list and dictionary including keys and nested values, that a single call to
+// Decode or DecodeValue may read. Bencode containers cost two bytes, so a
+// small message can otherwise expand into millions of tiny allocations, for
+// example as deeply nested or long runs of empty lists. Exceeding the limit
+// stops decoding with an ErrLimitExceeded error. n <= 0 removes the limit.
+func (d *Decoder) MaxElements(n int) {
+	d.limits.maxElements = max(n, 0)
+}
+
+// MaxDictEntries limits the number of key/value pairs in any one dictionary.
+// Exceeding the limit stops decoding with an ErrStructureDictEntries error,
+// which matches ErrLimitExceeded with errors.Is. n <= 0 removes the limit.
+func (d *Decoder) MaxDictEntries(n int) {
+	d.limits.maxDictEntries = max(n, 0)
+}
+
+// countElement records the start of a value, enforcing MaxElements.
+func (d *Decoder) countElement() error {
+	d.limits.elements++
+	if d.limits.maxElements > 0 && d.limits.elements > d.limits.maxElements {
+		return &Error{Type: ErrLimitExceeded, Msg: fmt.Sprintf("more than %d elements", d.limits.maxElements)}
+	}
+	return nil
+}
+
+// checkDictEntries enforces MaxDictEntries before entry number n of a
+// dictionary is read.
+func (d *Decoder) checkDictEntries(n int) error {
+	if d.limits.maxDictEntries > 0 && n > d.limits.maxDictEntries {
+		return &Error{Type: ErrStructureDictEntries, Msg: fmt.Sprintf("dictionary has more than %d entries", d.limits.maxDictEntries), WrappedErr: ErrLimitExceeded}
+	}
+	return nil
+}
+
+// MaxKeyLength limits the length in bytes of dictionary keys. A longer key
+// stops decoding with an ErrStructureDictKeyLength error, which matches
+// ErrLimitExceeded with errors.Is, whose FieldName is the dotted path to the
+// key, such as "files.3.path", and whose message gives its input offset.
+// n <= 0 removes the limit.
+func (d *Decoder) MaxKeyLength(n int) {
+	d.limits.maxKeyLength = max(n, 0)
+}
+
+// RequirePrintableKeys restricts dictionary keys to printable ASCII, the
+// bytes 0x20 to 0x7e, as used by every key of the BitTorrent protocols. Any
+// other key stops decoding with an ErrStructureDictKeyCharset error carrying
+// its path and offset as for MaxKeyLength.
+func (d *Decoder) RequirePrintableKeys() {
+	d.limits.printableKeys = true
+}
+
+// RejectNULKeys rejects dictionary keys containing a NUL byte, which no
+// protocol uses and which C-based peers and trackers may truncate, with an
+// ErrStructureDictKeyNUL error carrying its path and offset as for
+// MaxKeyLength. With RequirePrintableKeys, such keys are reported with this
+// error rather than ErrStructureDictKeyCharset.
+func (d *Decoder) RejectNULKeys() {
+	d.limits.nulKeys = true
+}
+
+// MaxDecodedBytes limits the memory a single call to Decode or DecodeValue
+// may allocate for the decoded values, estimated as the length of every
+// string plus a fixed overhead for every value. Unlike MaxElements, it also
+// bounds long strings, and it is checked before a string is allocated, so a
+// message cannot claim a huge string and cause it to be allocated. Exceeding
+// the budget stops decoding with an ErrMessageTooLarge error whose FieldName
+// is the dotted path to the value being decoded. n <= 0 removes the limit.
+func (d *Decoder) MaxDecodedBytes(n int64) {
+	d.limits.maxBytes = max(n, 0)
+}
+
+// MaxIntDigits limits the number of digits, not counting a minus sign, of
+// an integer. The integer is rejected with an ErrIntegerTooLong error as soon
+// as the limit is passed, so a hostile message cannot make the Decoder
+// buffer megabytes of digits looking for the end of the token. The default,
+// DefaultMaxIntDigits, covers every integer that fits in an int64 or uint64;
+// raise it to decode larger integers with UseNumber. n <= 0 removes the
+// limit. String lengths are always limited to 20 digits.
+func (d *Decoder) MaxIntDigits(n int) {
+	if n <= 0 {
+		n = -1
+	}
+	d.limits.maxIntDigits = n
+}
+
+// intDigits returns the digit limit for integers, or 0 for none.
+func (d *Decoder) intDigits() int {
+	switch n := d.limits.maxIntDigits; {
+	case n == 0:
+		return DefaultMaxIntDigits
+	case n < 0:
+		return 0
+	default:
+		return n
+	}
+}
+
+// readNumber reads the digits of an integer or string length up to and
+// including delim. If maxDigits is positive, it stops reading with an
+// ErrIntegerTooLong error once more than maxDigits digits, plus a sign,
+// have been seen without finding delim. Other errors are those of the
+// underlying bufio.Reader.
+func (d *Decoder) readNumber(delim byte, maxDigits int) (string, error) {
+	var token []byte
+	for {
+		chunk, err := d.r.ReadSlice(delim)
+		if maxDigits > 0 && len(token)+len(chunk) > maxDigits+2 {
+			d.consumedBytes(chunk)
+			return "", &Error{Type: ErrIntegerTooLong, Msg: fmt.Sprintf("more than %d digits", maxDigits)}
+		}
+		d.consumedBytes(chunk)
+		token = append(token, chunk...)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if digits := len(bytes.TrimPrefix(token, []byte{'-'})) - 1; err == nil && maxDigits > 0 && digits > maxDigits {
+			return "", &Error{Type: ErrIntegerTooLong, Msg: fmt.Sprintf("%d digits, more than %d", digits, maxDigits)}
+		}
+		return string(token), err
+	}
+}
+
+// chargeBytes records that n more bytes are about to be allocated,
+// enforcing MaxDecodedBytes.
+func (d *Decoder) chargeBytes(n int64) error {
+	if d.limits.maxBytes == 0 {
+		return nil
+	}
+	d.limits.bytes += n
+	if d.limits.bytes > d.limits.maxBytes {
+		return &Error{Type: ErrMessageTooLarge, Msg: fmt.Sprintf("decoded values need more than %d bytes", d.limits.maxBytes), FieldName: d.currentPath(), fullPath: true}
+	}
+	return nil
+}
+
+// checkKeys reports whether dictionary keys are checked.
+func (d *Decoder) checkKeys() bool {
+	return d.limits.maxKeyLength > 0 || d.limits.printableKeys || d.limits.nulKeys
+}
+
+// tracksPath reports whether the path to the current value is tracked for
+// error reports.
+func (d *Decoder) tracksPath() bool {
+	return d.checkKeys() || d.limits.maxBytes > 0
+}
+
+// currentPath returns the path to the current value, joined as Error.Path
+// joins it.
+func (d *Decoder) currentPath() string {
+	var path string
+	for _, seg := range d.limits.path {
+		path = JoinPath(path, seg)
+	}
+	return path
+}
+
+// pushPath enters the list element or dictionary value at seg.
+func (d *Decoder) pushPath(seg string) {
+	if d.tracksPath() {
+		d.limits.path = append(d.limits.path, seg)
+	}
+}
+
+// popPath leaves the element entered by the last pushPath.
+func (d *Decoder) popPath() {
+	if d.tracksPath() {
+		d.limits.path = d.limits.path[:len(d.limits.path)-1]
+	}
+}
+
+// checkKey enforces RejectNULKeys, MaxKeyLength and RequirePrintableKeys on
+// the dictionary key read at offset.
+func (d *Decoder) checkKey(key string, offset int64) error {
+	if !d.checkKeys() {
+		return nil
+	}
+	path := JoinPath(d.currentPath(), key)
+	if d.limits.nulKeys {
+		if i := strings.IndexByte(key, 0); i >= 0 {
+			return &Error{Type: ErrStructureDictKeyNUL, Msg: fmt.Sprintf("key at offset %d has NUL at position %d", offset, i), FieldName: path, fullPath: true, WrappedErr: ErrStructureDictKeyCharset}
+		}
+	}
+	if d.limits.maxKeyLength > 0 && len(key) > d.limits.maxKeyLength {
+		return &Error{Type: ErrStructureDictKeyLength, Msg: fmt.Sprintf("key at offset %d is %d bytes, more than %d", offset, len(key), d.limits.maxKeyLength), FieldName: path, fullPath: true, WrappedErr: ErrLimitExceeded}
+	}
+	if d.limits.printableKeys {
+		for i := range len(key) {
+			if !IsValidKeyByte(key[i]) {
+				return &Error{Type: ErrStructureDictKeyCharset, Msg: fmt.Sprintf("key at offset %d has byte %#02x at position %d", offset, key[i], i), FieldName: path, fullPath: true}
+			}
+		}
+	}
+	return nil
+}
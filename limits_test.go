@@ -0,0 +1,190 @@
+package bencode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecoderLimits(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		maxElements int
+		maxEntries  int
+		wantErr     bool
+	}{
+		{name: "no limits", input: "llllleeeee"},
+		{name: "elements within limit", input: "li1ei2ee", maxElements: 3},
+		{name: "nested empty lists", input: "llllleeeee", maxElements: 4, wantErr: true},
+		{name: "keys count as elements", input: "d1:ai1ee", maxElements: 2, wantErr: true},
+		{name: "entries within limit", input: "d1:ai1e1:bi2ee", maxEntries: 2},
+		{name: "too many entries", input: "d1:ai1e1:bi2e1:ci3ee", maxEntries: 2, wantErr: true},
+		{name: "nested dictionary", input: "d1:ad1:xi1e1:yi2eee", maxEntries: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tt.input))
+			dec.MaxElements(tt.maxElements)
+			dec.MaxDictEntries(tt.maxEntries)
+			_, err := dec.DecodeValue()
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("DecodeValue() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrLimitExceeded) {
+				t.Errorf("DecodeValue() error = %v, want %q", err, ErrLimitExceeded)
+			}
+		})
+	}
+}
+
+func TestMaxElementsPerValue(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("li1eeli2ee"))
+	dec.MaxElements(2)
+	for i := range 2 {
+		if _, err := dec.DecodeValue(); err != nil {
+			t.Fatalf("DecodeValue() #%d error = %v", i, err)
+		}
+	}
+}
+
+func TestDecoderKeyChecks(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		maxLength int
+		printable bool
+		nul       bool
+		errType   ErrorType
+		path      string
+	}{
+		{name: "within limits", input: "d4:name4:spame", maxLength: 4, printable: true},
+		{name: "long key", input: "d5:filesld4:pathi1e6:length1:xeee", maxLength: 5, errType: ErrStructureDictKeyLength, path: "files.0.length"},
+		{name: "NUL byte", input: "d1:ad2:b\x00i1eee", printable: true, nul: true, errType: ErrStructureDictKeyNUL, path: "a.b\x00"},
+		{name: "control byte", input: "d1:ad2:b\x00i1eee", printable: true, errType: ErrStructureDictKeyCharset, path: "a.b\x00"},
+		{name: "non-ASCII", input: "d2:\xc3\xa9i1ee", printable: true, errType: ErrStructureDictKeyCharset, path: "\xc3\xa9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tt.input))
+			dec.MaxKeyLength(tt.maxLength)
+			if tt.printable {
+				dec.RequirePrintableKeys()
+			}
+			if tt.nul {
+				dec.RejectNULKeys()
+			}
+			_, err := dec.DecodeValue()
+			if tt.errType == "" {
+				if err != nil {
+					t.Fatalf("DecodeValue() error = %v", err)
+				}
+				return
+			}
+			// The innermost error names the full path; enclosing
+			// dictionaries wrap it with their own key.
+			var bErr *Error
+			for e := err; errors.As(e, &bErr); e = bErr.WrappedErr {
+				if bErr.WrappedErr == nil {
+					break
+				}
+			}
+			if bErr == nil || bErr.Type != tt.errType || bErr.FieldName != tt.path || !strings.Contains(bErr.Msg, "offset") {
+				t.Errorf("DecodeValue() error = %v, want %q at %q", err, tt.errType, tt.path)
+			}
+		})
+	}
+}
+
+func TestMaxDecodedBytes(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		budget  int64
+		path    string
+		wantErr bool
+	}{
+		{name: "within budget", input: "d5:filesld4:path5:aaaaaeee", budget: 206},
+		{name: "string contents", input: "d5:filesld4:path5:aaaaaeee", budget: 203, path: "files.0.path", wantErr: true},
+		{name: "claimed length", input: "999999999:abc", budget: 1 << 20, wantErr: true},
+		{name: "many small values", input: "l" + strings.Repeat("le", 100) + "e", budget: 50 * valueOverhead, path: "49", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tt.input))
+			dec.MaxDecodedBytes(tt.budget)
+			_, err := dec.DecodeValue()
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("DecodeValue() error = %v", err)
+				}
+				return
+			}
+			var bErr *Error
+			for e := err; errors.As(e, &bErr); e = bErr.WrappedErr {
+				if bErr.WrappedErr == nil {
+					break
+				}
+			}
+			if bErr == nil || bErr.Type != ErrMessageTooLarge || bErr.FieldName != tt.path {
+				t.Errorf("DecodeValue() error = %v, want %q at %q", err, ErrMessageTooLarge, tt.path)
+			}
+		})
+	}
+
+	dec := NewDecoder(strings.NewReader("4:spam4:eggs"))
+	dec.MaxDecodedBytes(valueOverhead + 4)
+	for i := range 2 {
+		if _, err := dec.DecodeValue(); err != nil {
+			t.Fatalf("DecodeValue() #%d error = %v", i, err)
+		}
+	}
+}
+
+func TestMaxIntDigits(t *testing.T) {
+	digits := func(n int) string { return strings.Repeat("9", n) }
+	tests := []struct {
+		name    string
+		input   string
+		limit   int
+		number  bool
+		wantErr ErrorType
+	}{
+		{name: "int64 max", input: "i9223372036854775807e"},
+		{name: "default limit", input: "i-" + digits(DefaultMaxIntDigits) + "e", number: true},
+		{name: "past default limit", input: "i" + digits(DefaultMaxIntDigits+1) + "e", number: true, wantErr: ErrIntegerTooLong},
+		{name: "unterminated flood", input: "i" + digits(1<<20), wantErr: ErrIntegerTooLong},
+		{name: "raised limit", input: "i" + digits(100) + "e", limit: 100, number: true},
+		{name: "no limit", input: "i" + digits(10000) + "e", limit: -1, number: true},
+		{name: "lowered limit", input: "i1000e", limit: 3, wantErr: ErrIntegerTooLong},
+		{name: "string length flood", input: digits(1 << 20), wantErr: ErrIntegerTooLong},
+		{name: "string length", input: "4:spam"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tt.input))
+			if tt.limit != 0 {
+				dec.MaxIntDigits(tt.limit)
+			}
+			if tt.number {
+				dec.UseNumber()
+			}
+			_, err := dec.DecodeValue()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("DecodeValue() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("DecodeValue() error = %v, want %q", err, tt.wantErr)
+			}
+			if dec.offset > 8192 {
+				t.Errorf("DecodeValue() consumed %d bytes before rejecting the token", dec.offset)
+			}
+		})
+	}
+}
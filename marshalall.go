@@ -0,0 +1,66 @@
+package bencode
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// marshalBuffers holds the buffers MarshalAll encodes into.
+var marshalBuffers = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// MarshalAll returns the encodings of values, in order, as Marshal would
+// return them one at a time. The values are encoded concurrently by up to
+// parallelism goroutines, runtime.GOMAXPROCS(0) if parallelism <= 0, into
+// buffers reused across calls, which suits servers that build many
+// independent messages, such as per-peer announce responses, at once. The
+// values must be safe to read concurrently.
+//
+// If any value fails to encode, MarshalAll stops starting new ones and
+// returns the error of the first failing value, wrapped with its index.
+func MarshalAll(values []any, parallelism int) ([][]byte, error) {
+	if parallelism <= 0 {
+		parallelism = runtime.GOMAXPROCS(0)
+	}
+	parallelism = min(parallelism, len(values))
+
+	out := make([][]byte, len(values))
+	errs := make([]error, len(values))
+	var next atomic.Int64
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for range parallelism {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := marshalBuffers.Get().(*bytes.Buffer)
+			defer marshalBuffers.Put(buf)
+			enc := NewEncoder(buf)
+			for !failed.Load() {
+				i := int(next.Add(1) - 1)
+				if i >= len(values) {
+					return
+				}
+				buf.Reset()
+				if err := enc.Encode(values[i]); err != nil {
+					errs[i] = err
+					failed.Store(true)
+					return
+				}
+				out[i] = bytes.Clone(buf.Bytes())
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Values are claimed in order, so every value before a failing one has
+	// been encoded and the first error found is the first overall.
+	for i, err := range errs {
+		if err != nil {
+			return nil, &Error{Type: typeOf(err), Msg: fmt.Sprintf("encoding value %d", i), WrappedErr: err}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,38 @@
+package bencode
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestMarshalAll(t *testing.T) {
+	values := make([]any, 100)
+	for i := range values {
+		values[i] = map[string]any{"interval": i, "peers": fmt.Sprintf("peer-%d", i)}
+	}
+	for _, parallelism := range []int{0, 1, 7, 1000} {
+		got, err := MarshalAll(values, parallelism)
+		if err != nil {
+			t.Fatalf("MarshalAll(%d) error = %v", parallelism, err)
+		}
+		for i, v := range values {
+			want, _ := Marshal(v)
+			if string(got[i]) != string(want) {
+				t.Fatalf("MarshalAll(%d)[%d] = %q, want %q", parallelism, i, got[i], want)
+			}
+		}
+	}
+
+	if got, err := MarshalAll(nil, 4); err != nil || len(got) != 0 {
+		t.Errorf("MarshalAll(nil) = %q, %v", got, err)
+	}
+
+	values[40] = make(chan int)
+	values[60] = make(chan int)
+	_, err := MarshalAll(values, 4)
+	var bErr *Error
+	if !errors.As(err, &bErr) || bErr.Msg != "encoding value 40" || !errors.Is(err, ErrEncodeUnsupportedType) {
+		t.Errorf("MarshalAll() with unsupported value error = %v", err)
+	}
+}
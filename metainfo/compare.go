@@ -0,0 +1,81 @@
+package metainfo
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+
+	"github.com/stupoid/bencode"
+)
+
+// SameContent reports whether a and b describe the same content, as when an
+// indexer finds one torrent listed with different trackers. Torrents that
+// are both v2, hybrid or not, are compared by their v2 info hashes, and
+// otherwise torrents that are both v1 by their v1 info hashes. The hashes of
+// torrents from Load or LoadFS are those of their info dictionaries as
+// loaded, so that keys Info does not model, such as "private" or "source",
+// are taken into account; those of other torrents are computed by Info.Hash
+// and Info.HashV2. A v1-only and a v2-only torrent never have the same
+// content by this measure, even if they describe the same files.
+func SameContent(a, b *MetaInfo) bool {
+	switch {
+	case a.Info.HasV2() && b.Info.HasV2():
+		ha, errA := a.hashV2()
+		hb, errB := b.hashV2()
+		return errA == nil && errB == nil && ha == hb
+	case a.Info.HasV1() && b.Info.HasV1():
+		ha, errA := a.hash()
+		hb, errB := b.hash()
+		return errA == nil && errB == nil && ha == hb
+	default:
+		return false
+	}
+}
+
+// hash returns the v1 info hash of mi, from its info dictionary as loaded
+// if it was.
+func (mi *MetaInfo) hash() (bencode.InfoHash, error) {
+	if mi.rawInfo != nil {
+		return sha1.Sum(mi.rawInfo), nil
+	}
+	return mi.Info.Hash()
+}
+
+// hashV2 is hash for v2 info hashes.
+func (mi *MetaInfo) hashV2() (bencode.InfoHashV2, error) {
+	if mi.rawInfo != nil {
+		return sha256.Sum256(mi.rawInfo), nil
+	}
+	return mi.Info.HashV2()
+}
+
+// MergeTrackers returns an announce-list (BEP 12) holding the trackers of a
+// followed by those of b. The tiers of each torrent are its announce-list,
+// or its announce URL as a single tier if it has none. Trackers already
+// listed in an earlier tier are left out, as are tiers left empty.
+func MergeTrackers(a, b *MetaInfo) [][]string {
+	var merged [][]string
+	seen := make(map[string]bool)
+	for _, mi := range []*MetaInfo{a, b} {
+		for _, tier := range mi.tiers() {
+			var kept []string
+			for _, url := range tier {
+				if url != "" && !seen[url] {
+					seen[url] = true
+					kept = append(kept, url)
+				}
+			}
+			if len(kept) > 0 {
+				merged = append(merged, kept)
+			}
+		}
+	}
+	return merged
+}
+
+// tiers returns the tracker tiers of mi.
+func (mi *MetaInfo) tiers() [][]string {
+	if len(mi.AnnounceList) > 0 {
+		return mi.AnnounceList
+	}
+	return [][]string{{mi.Announce}}
+}
@@ -0,0 +1,86 @@
+package metainfo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stupoid/bencode"
+)
+
+func TestSameContent(t *testing.T) {
+	v1 := Info{Name: "file.txt", PieceLength: 16, Pieces: "01234567890123456789", Length: 5}
+	tree := bencode.RawMessage("d8:file.txtd0:d6:lengthi5e11:pieces root32:" + string(make([]byte, 32)) + "eee")
+	v2 := Info{Name: "file.txt", PieceLength: 16, MetaVersion: 2, FileTree: tree}
+	hybrid := v1
+	hybrid.MetaVersion, hybrid.FileTree = 2, tree
+	renamed := v1
+	renamed.Name = "other.txt"
+
+	tests := []struct {
+		name string
+		a, b Info
+		want bool
+	}{
+		{"same v1", v1, v1, true},
+		{"different v1", v1, renamed, false},
+		{"same v2", v2, v2, true},
+		{"same hybrid", hybrid, hybrid, true},
+		{"v1 and v2", v1, v2, false},
+		{"v1 and hybrid", v1, hybrid, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &MetaInfo{Announce: "http://a.example/announce", Info: tt.a}
+			b := &MetaInfo{Announce: "http://b.example/announce", Info: tt.b}
+			if got := SameContent(a, b); got != tt.want {
+				t.Errorf("SameContent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Loaded torrents are compared by their info dictionaries as loaded,
+	// including keys Info does not model.
+	load := func(info string) *MetaInfo {
+		mi, err := Load(strings.NewReader("d8:announce1:a4:info" + info + "e"))
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		return mi
+	}
+	public := load("d6:lengthi5e4:name8:file.txt12:piece lengthi16e6:pieces20:01234567890123456789e")
+	private := load("d6:lengthi5e4:name8:file.txt12:piece lengthi16e6:pieces20:012345678901234567897:privatei1ee")
+	if SameContent(public, private) {
+		t.Errorf("SameContent() of public and private torrents = true, want false")
+	}
+	if !SameContent(public, &MetaInfo{Info: v1}) || !SameContent(public, load("d6:lengthi5e4:name8:file.txt12:piece lengthi16e6:pieces20:01234567890123456789e")) {
+		t.Errorf("SameContent() of the same loaded and built torrents = false, want true")
+	}
+
+	if !v2.HasV2() || v2.HasV1() || !hybrid.HasV1() || !hybrid.HasV2() || v1.HasV2() {
+		t.Errorf("HasV1/HasV2 misreport v1 %+v, v2 %+v or hybrid %+v", v1, v2, hybrid)
+	}
+}
+
+func TestMergeTrackers(t *testing.T) {
+	a := &MetaInfo{
+		Announce:     "http://a.example/announce",
+		AnnounceList: [][]string{{"http://a.example/announce", "udp://a.example:80"}, {"http://backup.example/announce"}},
+	}
+	b := &MetaInfo{Announce: "udp://a.example:80"}
+	c := &MetaInfo{Announce: "http://c.example/announce"}
+
+	got := MergeTrackers(a, b)
+	want := [][]string{{"http://a.example/announce", "udp://a.example:80"}, {"http://backup.example/announce"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeTrackers(a, b) = %q, want %q", got, want)
+	}
+	got = MergeTrackers(b, c)
+	want = [][]string{{"udp://a.example:80"}, {"http://c.example/announce"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeTrackers(b, c) = %q, want %q", got, want)
+	}
+	if got := MergeTrackers(&MetaInfo{}, &MetaInfo{}); got != nil {
+		t.Errorf("MergeTrackers() without trackers = %q, want nil", got)
+	}
+}
@@ -0,0 +1,276 @@
+// Package metainfo loads and builds BitTorrent metainfo (.torrent) files
+// on top of the bencode package.
+package metainfo
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/stupoid/bencode"
+)
+
+const (
+	// ErrMetainfo indicates a metainfo file could not be loaded or built.
+	ErrMetainfo bencode.ErrorType = "metainfo error"
+)
+
+// MetaInfo is the top-level dictionary of a .torrent file.
+type MetaInfo struct {
+	Announce     string     `bencode:"announce"`
+	AnnounceList [][]string `bencode:"announce-list"`
+	Comment      string     `bencode:"comment"`
+	CreatedBy    string     `bencode:"created by"`
+	CreationDate int64      `bencode:"creation date"`
+	Info         Info       `bencode:"info"`
+
+	rawInfo bencode.RawMessage // the info dictionary as loaded, keys Info omits included
+}
+
+// Info is the info dictionary of a .torrent file. Single-file torrents set
+// Length; multi-file torrents set Files instead. BitTorrent v2 torrents
+// (BEP 52) set MetaVersion to 2 and describe their files in FileTree; hybrid
+// torrents also carry the v1 fields.
+type Info struct {
+	PieceLength int64              `bencode:"piece length"`
+	Pieces      string             `bencode:"pieces"`
+	Name        string             `bencode:"name"`
+	Length      int64              `bencode:"length"`
+	Files       []File             `bencode:"files"`
+	MetaVersion int64              `bencode:"meta version"`
+	FileTree    bencode.RawMessage `bencode:"file tree"`
+}
+
+// File describes one file of a multi-file torrent.
+type File struct {
+	Length int64    `bencode:"length"`
+	Path   []string `bencode:"path"`
+}
+
+// Load decodes a metainfo file from r. The info dictionary is also kept as
+// read, so that SameContent compares the real info hashes of loaded files.
+func Load(r io.Reader) (*MetaInfo, error) {
+	var mi MetaInfo
+	raw, err := bencode.NewDecoder(r).DecodeRaw(&mi)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := bencode.Index(raw)
+	if err != nil {
+		return nil, err
+	}
+	if mi.rawInfo, err = doc.Get("info"); err != nil {
+		return nil, err
+	}
+	return &mi, nil
+}
+
+// InfoHashOf returns the info hash of the metainfo file in data: the SHA-1
+// hash of its info dictionary exactly as encoded, including any keys this
+// package does not model.
+func InfoHashOf(data []byte) (bencode.InfoHash, error) {
+	doc, err := bencode.Index(data)
+	if err != nil {
+		return bencode.InfoHash{}, err
+	}
+	return doc.SHA1Sum("info")
+}
+
+// Hash returns the info hash of a torrent with this info dictionary as
+// written by MetaInfo.Write, such as one built with BuildFS. Use InfoHashOf
+// for loaded files, whose info dictionaries may hold keys Info omits.
+func (info *Info) Hash() (bencode.InfoHash, error) {
+	data, err := bencode.Marshal(info.dict())
+	if err != nil {
+		return bencode.InfoHash{}, err
+	}
+	return sha1.Sum(data), nil
+}
+
+// HashV2 returns the v2 info hash of a torrent with this info dictionary as
+// written by MetaInfo.Write: the SHA-256 hash of its encoding. It is only
+// meaningful if HasV2 reports true.
+func (info *Info) HashV2() (bencode.InfoHashV2, error) {
+	data, err := bencode.Marshal(info.dict())
+	if err != nil {
+		return bencode.InfoHashV2{}, err
+	}
+	return sha256.Sum256(data), nil
+}
+
+// LoadFS reads and decodes the named metainfo file from fsys.
+func LoadFS(fsys fs.FS, name string) (*MetaInfo, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, &bencode.Error{Type: ErrMetainfo, Msg: fmt.Sprintf("opening %q", name), WrappedErr: err}
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Write bencodes mi to w. Optional keys that are unset are omitted, and the
+// info dictionary holds either "length" or "files", never both.
+func (mi *MetaInfo) Write(w io.Writer) error {
+	return bencode.NewEncoder(w).Encode(mi.dict())
+}
+
+func (mi *MetaInfo) dict() map[string]any {
+	d := map[string]any{"info": mi.Info.dict()}
+	if mi.Announce != "" {
+		d["announce"] = mi.Announce
+	}
+	if len(mi.AnnounceList) > 0 {
+		d["announce-list"] = mi.AnnounceList
+	}
+	if mi.Comment != "" {
+		d["comment"] = mi.Comment
+	}
+	if mi.CreatedBy != "" {
+		d["created by"] = mi.CreatedBy
+	}
+	if mi.CreationDate != 0 {
+		d["creation date"] = mi.CreationDate
+	}
+	return d
+}
+
+func (info *Info) dict() map[string]any {
+	d := map[string]any{
+		"piece length": info.PieceLength,
+		"name":         info.Name,
+	}
+	if info.HasV1() {
+		d["pieces"] = info.Pieces
+		if len(info.Files) > 0 {
+			d["files"] = info.Files
+		} else {
+			d["length"] = info.Length
+		}
+	}
+	if info.HasV2() {
+		d["meta version"] = info.MetaVersion
+		d["file tree"] = info.FileTree
+	}
+	return d
+}
+
+// HasV1 reports whether info describes a v1 torrent, possibly a hybrid one:
+// one with v1 piece hashes, or without a v2 file tree.
+func (info *Info) HasV1() bool {
+	return info.Pieces != "" || !info.HasV2()
+}
+
+// HasV2 reports whether info describes a v2 torrent, possibly a hybrid one.
+func (info *Info) HasV2() bool {
+	return info.MetaVersion == 2 && len(info.FileTree) > 0
+}
+
+// BuildFS builds the info dictionary for root within fsys, hashing its
+// contents in pieces of pieceLength bytes. If root is a regular file a
+// single-file info is built; if it is a directory, every regular file below
+// it is included in lexical order.
+func BuildFS(fsys fs.FS, root string, pieceLength int64) (*Info, error) {
+	if pieceLength <= 0 {
+		return nil, &bencode.Error{Type: bencode.ErrUsage, Msg: fmt.Sprintf("piece length must be positive, got %d", pieceLength)}
+	}
+	name := path.Base(root)
+	if name == "." || name == "/" {
+		return nil, &bencode.Error{Type: bencode.ErrUsage, Msg: fmt.Sprintf("cannot derive torrent name from root %q", root)}
+	}
+	stat, err := fs.Stat(fsys, root)
+	if err != nil {
+		return nil, &bencode.Error{Type: ErrMetainfo, Msg: fmt.Sprintf("stat %q", root), WrappedErr: err}
+	}
+
+	info := &Info{Name: name, PieceLength: pieceLength}
+	h := &pieceHasher{pieceLength: pieceLength, hash: sha1.New()}
+
+	if !stat.IsDir() {
+		n, err := hashFile(fsys, root, h)
+		if err != nil {
+			return nil, err
+		}
+		info.Length = n
+	} else {
+		err = fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			n, err := hashFile(fsys, p, h)
+			if err != nil {
+				return err
+			}
+			rel := strings.TrimPrefix(p, root+"/")
+			info.Files = append(info.Files, File{Length: n, Path: strings.Split(rel, "/")})
+			return nil
+		})
+		if err != nil {
+			var bErr *bencode.Error
+			if errors.As(err, &bErr) {
+				return nil, err
+			}
+			return nil, &bencode.Error{Type: ErrMetainfo, Msg: fmt.Sprintf("walking %q", root), WrappedErr: err}
+		}
+		if len(info.Files) == 0 {
+			return nil, &bencode.Error{Type: ErrMetainfo, Msg: fmt.Sprintf("no files found under %q", root)}
+		}
+	}
+
+	info.Pieces = string(h.finish())
+	return info, nil
+}
+
+func hashFile(fsys fs.FS, name string, h *pieceHasher) (int64, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return 0, &bencode.Error{Type: ErrMetainfo, Msg: fmt.Sprintf("opening %q", name), WrappedErr: err}
+	}
+	defer f.Close()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return n, &bencode.Error{Type: ErrMetainfo, Msg: fmt.Sprintf("reading %q", name), WrappedErr: err}
+	}
+	return n, nil
+}
+
+// pieceHasher accumulates SHA-1 piece hashes over a stream spanning files.
+type pieceHasher struct {
+	pieceLength int64
+	hash        hash.Hash
+	filled      int64
+	pieces      []byte
+}
+
+func (h *pieceHasher) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		n := min(int64(len(p)), h.pieceLength-h.filled)
+		h.hash.Write(p[:n])
+		h.filled += n
+		p = p[n:]
+		if h.filled == h.pieceLength {
+			h.pieces = h.hash.Sum(h.pieces)
+			h.hash.Reset()
+			h.filled = 0
+		}
+	}
+	return written, nil
+}
+
+func (h *pieceHasher) finish() []byte {
+	if h.filled > 0 {
+		h.pieces = h.hash.Sum(h.pieces)
+		h.hash.Reset()
+		h.filled = 0
+	}
+	return h.pieces
+}
@@ -0,0 +1,112 @@
+package metainfo
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stupoid/bencode"
+	"github.com/stupoid/bencode/bencodetest"
+)
+
+func TestBuildFSSingleFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"data/file.txt": {Data: []byte("hello world")},
+	}
+	info, err := BuildFS(fsys, "data/file.txt", 4)
+	if err != nil {
+		t.Fatalf("BuildFS() error = %v", err)
+	}
+	if info.Name != "file.txt" || info.Length != 11 || info.Files != nil {
+		t.Errorf("BuildFS() = %+v", info)
+	}
+	var want []byte
+	for _, chunk := range []string{"hell", "o wo", "rld"} {
+		sum := sha1.Sum([]byte(chunk))
+		want = append(want, sum[:]...)
+	}
+	if info.Pieces != string(want) {
+		t.Errorf("BuildFS() pieces = %x, want %x", info.Pieces, want)
+	}
+}
+
+func TestBuildFSDirectory(t *testing.T) {
+	fsys := fstest.MapFS{
+		"root/b.txt":     {Data: []byte("bb")},
+		"root/a/one.txt": {Data: []byte("aaa")},
+	}
+	info, err := BuildFS(fsys, "root", 16)
+	if err != nil {
+		t.Fatalf("BuildFS() error = %v", err)
+	}
+	wantFiles := []File{
+		{Length: 3, Path: []string{"a", "one.txt"}},
+		{Length: 2, Path: []string{"b.txt"}},
+	}
+	if !reflect.DeepEqual(info.Files, wantFiles) {
+		t.Errorf("BuildFS() files = %+v, want %+v", info.Files, wantFiles)
+	}
+	sum := sha1.Sum([]byte("aaabb"))
+	if info.Pieces != string(sum[:]) {
+		t.Errorf("BuildFS() pieces = %x, want %x", info.Pieces, sum)
+	}
+}
+
+func TestWriteLoadFS(t *testing.T) {
+	mi := &MetaInfo{
+		Announce: "http://tracker.example/announce",
+		Info: Info{
+			Name:        "file.txt",
+			PieceLength: 16,
+			Pieces:      "01234567890123456789",
+			Length:      5,
+		},
+	}
+	var buf bytes.Buffer
+	if err := mi.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	want := "d8:announce31:http://tracker.example/announce4:infod6:lengthi5e4:name8:file.txt12:piece lengthi16e6:pieces20:01234567890123456789ee"
+	bencodetest.Diff(t, []byte(want), buf.Bytes())
+
+	fsys := fstest.MapFS{"test.torrent": {Data: buf.Bytes()}}
+	loaded, err := LoadFS(fsys, "test.torrent")
+	if err != nil {
+		t.Fatalf("LoadFS() error = %v", err)
+	}
+	if info := "d6:lengthi5e4:name8:file.txt12:piece lengthi16e6:pieces20:01234567890123456789e"; string(loaded.rawInfo) != info {
+		t.Errorf("LoadFS() kept info %q, want %q", loaded.rawInfo, info)
+	}
+	loaded.rawInfo = nil
+	if !reflect.DeepEqual(loaded, mi) {
+		t.Errorf("LoadFS() = %+v, want %+v", loaded, mi)
+	}
+
+	if _, err := LoadFS(fsys, "missing.torrent"); err == nil {
+		t.Errorf("LoadFS() of missing file expected error")
+	}
+}
+
+func TestInfoHash(t *testing.T) {
+	info := Info{Name: "file.txt", PieceLength: 16, Pieces: "01234567890123456789", Length: 5}
+	var buf bytes.Buffer
+	if err := (&MetaInfo{Info: info}).Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	want := bencode.InfoHash(sha1.Sum([]byte("d6:lengthi5e4:name8:file.txt12:piece lengthi16e6:pieces20:01234567890123456789e")))
+	if got, err := info.Hash(); err != nil || got != want {
+		t.Errorf("Hash() = %v, %v, want %v", got, err, want)
+	}
+	if got, err := InfoHashOf(buf.Bytes()); err != nil || got != want {
+		t.Errorf("InfoHashOf() = %v, %v, want %v", got, err, want)
+	}
+
+	// Keys Info does not model, such as "private", still count.
+	private := []byte("d4:infod6:lengthi5e7:privatei1eee")
+	want = sha1.Sum([]byte("d6:lengthi5e7:privatei1ee"))
+	if got, err := InfoHashOf(private); err != nil || got != want {
+		t.Errorf("InfoHashOf() = %v, %v, want %v", got, err, want)
+	}
+}
@@ -0,0 +1,72 @@
+package metainfo
+
+import (
+	"fmt"
+	"io"
+	"iter"
+
+	"github.com/stupoid/bencode"
+)
+
+// PieceLayer is one entry of the "piece layers" dictionary of a BitTorrent
+// v2 torrent (BEP 52): the piece hashes of one file.
+type PieceLayer struct {
+	// Root is the "pieces root" of the file the layer belongs to.
+	Root [32]byte
+	// Hashes reads the layer, Len bytes of concatenated SHA-256 piece
+	// hashes. It is valid only until the iteration continues.
+	Hashes io.Reader
+	Len    int64
+}
+
+// PieceLayers returns an iterator over the piece layers of the v2 torrent
+// read from r. The layers of a large torrent can take hundreds of megabytes;
+// PieceLayers streams them one at a time without decoding the rest of the
+// file, so memory use does not grow with their size. Layers the loop body
+// does not read are skipped.
+//
+// The first error, which is yielded with a zero PieceLayer, ends the
+// iteration. A torrent without piece layers yields nothing.
+func PieceLayers(r io.Reader) iter.Seq2[PieceLayer, error] {
+	return func(yield func(PieceLayer, error) bool) {
+		dec := bencode.NewDecoder(r)
+		for key, err := range dec.Entries() {
+			if err != nil {
+				yield(PieceLayer{}, err)
+				return
+			}
+			if key == "piece layers" {
+				pieceLayers(dec, yield)
+				return
+			}
+		}
+	}
+}
+
+// pieceLayers yields the entries of the "piece layers" dictionary.
+func pieceLayers(dec *bencode.Decoder, yield func(PieceLayer, error) bool) {
+	for root, err := range dec.Entries() {
+		if err != nil {
+			yield(PieceLayer{}, err)
+			return
+		}
+		var layer PieceLayer
+		if len(root) != len(layer.Root) {
+			yield(PieceLayer{}, &bencode.Error{Type: ErrMetainfo, Msg: fmt.Sprintf("piece layer key is %d bytes, want %d", len(root), len(layer.Root))})
+			return
+		}
+		copy(layer.Root[:], root)
+		layer.Hashes, layer.Len, err = dec.StringReader()
+		if err != nil {
+			yield(PieceLayer{}, err)
+			return
+		}
+		if layer.Len == 0 || layer.Len%32 != 0 {
+			yield(PieceLayer{}, &bencode.Error{Type: ErrMetainfo, Msg: fmt.Sprintf("piece layer for %x is %d bytes, not a multiple of 32", layer.Root, layer.Len)})
+			return
+		}
+		if !yield(layer, nil) {
+			return
+		}
+	}
+}
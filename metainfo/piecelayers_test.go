@@ -0,0 +1,56 @@
+package metainfo
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stupoid/bencode"
+)
+
+func TestPieceLayers(t *testing.T) {
+	rootA, rootB := strings.Repeat("a", 32), strings.Repeat("b", 32)
+	layerA, layerB := strings.Repeat("1", 64), strings.Repeat("2", 32)
+	data, err := bencode.Marshal(map[string]any{
+		"announce": "http://tracker.example/announce",
+		"info":     map[string]any{"name": "x", "meta version": 2},
+		"piece layers": map[string]any{
+			rootA: layerA,
+			rootB: layerB,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var roots, layers []string
+	for layer, err := range PieceLayers(bytes.NewReader(data)) {
+		if err != nil {
+			t.Fatalf("PieceLayers() error = %v", err)
+		}
+		roots = append(roots, string(layer.Root[:]))
+		if layer.Root[0] == 'b' {
+			continue // skipped unread
+		}
+		hashes, err := io.ReadAll(layer.Hashes)
+		if err != nil || int64(len(hashes)) != layer.Len {
+			t.Fatalf("reading layer = %d bytes, %v, want %d", len(hashes), err, layer.Len)
+		}
+		layers = append(layers, string(hashes))
+	}
+	if len(roots) != 2 || roots[0] != rootA || roots[1] != rootB || len(layers) != 1 || layers[0] != layerA {
+		t.Errorf("PieceLayers() roots %q, layers %q", roots, layers)
+	}
+
+	bad, _ := bencode.Marshal(map[string]any{"piece layers": map[string]any{rootA: "short"}})
+	for _, err := range PieceLayers(bytes.NewReader(bad)) {
+		if !errors.Is(err, ErrMetainfo) {
+			t.Errorf("PieceLayers() of bad layer error = %v, want %q", err, ErrMetainfo)
+		}
+	}
+	for range PieceLayers(strings.NewReader("d4:name1:xe")) {
+		t.Error("PieceLayers() of torrent without layers yielded a layer")
+	}
+}
@@ -0,0 +1,125 @@
+package bencode
+
+import (
+	"encoding/json"
+	"io"
+	"maps"
+	"sync"
+	"sync/atomic"
+)
+
+// Metrics accumulates counters from any number of Encoders and Decoders.
+// It is safe for concurrent use, and implements expvar.Var through its
+// String method, so it can be published directly:
+//
+//	var metrics bencode.Metrics
+//	expvar.Publish("bencode", &metrics)
+//	dec.UseMetrics(&metrics)
+//
+// The zero value is ready to use.
+type Metrics struct {
+	valuesDecoded atomic.Int64
+	bytesDecoded  atomic.Int64
+	valuesEncoded atomic.Int64
+	bytesEncoded  atomic.Int64
+
+	mu     sync.Mutex
+	errors map[ErrorType]int64
+}
+
+// MetricsSnapshot is a point-in-time copy of the counters of a Metrics.
+type MetricsSnapshot struct {
+	// ValuesDecoded and ValuesEncoded count top-level values successfully
+	// decoded and encoded.
+	ValuesDecoded int64 `json:"values_decoded"`
+	ValuesEncoded int64 `json:"values_encoded"`
+	// BytesDecoded and BytesEncoded count bytes read and written, including
+	// those of calls that failed.
+	BytesDecoded int64 `json:"bytes_decoded"`
+	BytesEncoded int64 `json:"bytes_encoded"`
+	// Errors counts failed calls by the ErrorType of the returned error.
+	Errors map[ErrorType]int64 `json:"errors"`
+}
+
+// Snapshot returns a copy of the current counters.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	errs := maps.Clone(m.errors)
+	m.mu.Unlock()
+	if errs == nil {
+		errs = map[ErrorType]int64{}
+	}
+	return MetricsSnapshot{
+		ValuesDecoded: m.valuesDecoded.Load(),
+		ValuesEncoded: m.valuesEncoded.Load(),
+		BytesDecoded:  m.bytesDecoded.Load(),
+		BytesEncoded:  m.bytesEncoded.Load(),
+		Errors:        errs,
+	}
+}
+
+// String returns the snapshot as JSON, implementing expvar.Var.
+func (m *Metrics) String() string {
+	b, _ := json.Marshal(m.Snapshot())
+	return string(b)
+}
+
+// recordError counts err by its ErrorType. Errors that are not *Error are
+// counted under ErrInternal.
+func (m *Metrics) recordError(err error) {
+	typ := ErrInternal
+	if bErr, ok := err.(*Error); ok {
+		typ = bErr.Type
+	}
+	m.mu.Lock()
+	if m.errors == nil {
+		m.errors = make(map[ErrorType]int64)
+	}
+	m.errors[typ]++
+	m.mu.Unlock()
+}
+
+// UseMetrics makes the Decoder add its activity to m. Passing nil stops
+// recording.
+func (d *Decoder) UseMetrics(m *Metrics) {
+	d.metrics = m
+}
+
+// recordDecode adds a call that started at input offset start to d's metrics.
+func (d *Decoder) recordDecode(start int64, err error) {
+	if d.metrics == nil {
+		return
+	}
+	d.metrics.bytesDecoded.Add(d.offset - start)
+	if err != nil {
+		d.metrics.recordError(err)
+		return
+	}
+	d.metrics.valuesDecoded.Add(1)
+}
+
+// UseMetrics makes the Encoder add its activity to m. Passing nil stops
+// recording.
+func (e *Encoder) UseMetrics(m *Metrics) {
+	e.metrics = m
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// WriteString passes s on to the underlying writer, keeping the encoder's
+// io.StringWriter fast path when counting.
+func (cw *countingWriter) WriteString(s string) (int, error) {
+	n, err := io.WriteString(cw.w, s)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,62 @@
+package bencode
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var _ expvar.Var = (*Metrics)(nil)
+
+func TestMetrics(t *testing.T) {
+	var m Metrics
+
+	decoder := NewDecoder(strings.NewReader("i1e4:spamx"))
+	decoder.UseMetrics(&m)
+	var n int
+	if err := decoder.Decode(&n); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decoder.DecodeValue(); err != nil {
+		t.Fatal(err)
+	}
+	if err := decoder.Decode(&n); err == nil {
+		t.Fatal("expected syntax error")
+	}
+
+	var b bytes.Buffer
+	encoder := NewEncoder(&b)
+	encoder.UseMetrics(&m)
+	if err := encoder.Encode([]int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := encoder.Encode(make(chan int)); err == nil {
+		t.Fatal("expected unsupported type error")
+	}
+
+	expected := MetricsSnapshot{
+		ValuesDecoded: 2,
+		ValuesEncoded: 1,
+		BytesDecoded:  9,
+		BytesEncoded:  8,
+		Errors: map[ErrorType]int64{
+			ErrSyntaxUnexpectedToken: 1,
+			ErrEncodeUnsupportedType: 1,
+		},
+	}
+	got := m.Snapshot()
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Snapshot() = %+v, want %+v", got, expected)
+	}
+
+	var decoded MetricsSnapshot
+	if err := json.Unmarshal([]byte(m.String()), &decoded); err != nil {
+		t.Fatalf("String() is not JSON: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("String() = %s", m.String())
+	}
+}
@@ -0,0 +1,112 @@
+package bencode
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// WriterError is the failure of one of the writers of an Encoder created by
+// NewMultiEncoder. It is found with errors.As in the ErrEncodeWriteError
+// errors such an Encoder returns.
+type WriterError struct {
+	// Index is the position of the writer among those passed to
+	// NewMultiEncoder.
+	Index int
+	// Writer is the writer that failed.
+	Writer io.Writer
+	// Err is the error the writer returned, or io.ErrShortWrite if it
+	// accepted fewer bytes than it was given without one.
+	Err error
+}
+
+// Error returns the index of the writer and its error.
+func (e *WriterError) Error() string {
+	return fmt.Sprintf("writer %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the writer's error.
+func (e *WriterError) Unwrap() error {
+	return e.Err
+}
+
+// NewMultiEncoder returns an Encoder that writes its output to each of ws,
+// such as a file, a network connection and a hash, encoding each value only
+// once. Unlike with io.MultiWriter, a writer that fails does not stop the
+// others: it is written to no more, the value is still written in full to
+// the rest, and the call that was writing it returns an ErrEncodeWriteError
+// error joining a *WriterError for each writer that failed during it. Once
+// every writer has failed, encoding stops early, and every later call fails
+// with an error joining the failures of all writers.
+func NewMultiEncoder(ws ...io.Writer) *Encoder {
+	bw := &broadcastWriter{sinks: ws, failed: make([]bool, len(ws))}
+	return &Encoder{w: bw, broadcast: bw}
+}
+
+// broadcastWriter passes writes on to each sink that has not failed,
+// collecting the failures.
+type broadcastWriter struct {
+	sinks  []io.Writer
+	failed []bool
+	errs   []error // failures not yet reported
+	all    []error // every failure so far
+	dead   error   // the failures of all sinks, once none is left
+}
+
+func (bw *broadcastWriter) Write(p []byte) (int, error) {
+	if bw.dead != nil {
+		return 0, bw.dead
+	}
+	live := 0
+	for i, w := range bw.sinks {
+		if bw.failed[i] {
+			continue
+		}
+		n, err := w.Write(p)
+		if err == nil && n < len(p) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			bw.failed[i] = true
+			werr := &WriterError{Index: i, Writer: w, Err: err}
+			bw.errs = append(bw.errs, werr)
+			bw.all = append(bw.all, werr)
+			continue
+		}
+		live++
+	}
+	if live == 0 && len(bw.sinks) > 0 {
+		bw.dead = errors.Join(bw.all...)
+		bw.errs = nil // reported through the write error
+		return 0, bw.dead
+	}
+	return len(p), nil
+}
+
+// take returns the failures collected since it was last called, joined, and
+// forgets them.
+func (bw *broadcastWriter) take() error {
+	if len(bw.errs) == 0 {
+		return nil
+	}
+	err := errors.Join(bw.errs...)
+	bw.errs = nil
+	return err
+}
+
+// writerErrors adds to err, the outcome of an Encoder call, the failures of
+// the writers of a multi-writer Encoder during that call.
+func (e *Encoder) writerErrors(err error) error {
+	if e.broadcast == nil {
+		return err
+	}
+	failures := e.broadcast.take()
+	switch {
+	case failures == nil:
+		return err
+	case err == nil:
+		return &Error{Type: ErrEncodeWriteError, Msg: "failed to write to some writers", WrappedErr: failures}
+	default:
+		return &Error{Type: typeOf(err), Msg: "failed to write to some writers as well", WrappedErr: errors.Join(err, failures)}
+	}
+}
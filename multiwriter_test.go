@@ -0,0 +1,108 @@
+package bencode
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// shortWriter accepts at most n bytes in all, then writes short.
+type shortWriter struct {
+	bytes.Buffer
+	n int
+}
+
+func (sw *shortWriter) Write(p []byte) (int, error) {
+	p = p[:min(len(p), sw.n-sw.Len())]
+	return sw.Buffer.Write(p)
+}
+
+func TestMultiEncoder(t *testing.T) {
+	msg := map[string]any{"info": map[string]any{"length": 3, "name": "spam"}, "v": "1.0"}
+	want, err := Marshal(msg)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	var file bytes.Buffer
+	conn := &shortWriter{n: 5}
+	h := sha1.New()
+	enc := NewMultiEncoder(&file, conn, h, &failingWriter{err: io.ErrClosedPipe})
+	err = enc.Encode(msg)
+	if !errors.Is(err, ErrEncodeWriteError) {
+		t.Fatalf("Encode() error = %v, want %q", err, ErrEncodeWriteError)
+	}
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) {
+		t.Fatalf("Encode() error = %v, want the writers' errors joined", err)
+	}
+	var failed []int
+	for _, e := range joined.Unwrap() {
+		var we *WriterError
+		if errors.As(e, &we) {
+			failed = append(failed, we.Index)
+		}
+	}
+	if len(failed) != 2 || failed[0] != 3 || failed[1] != 1 {
+		t.Errorf("Encode() failed writers %v, want [3 1]", failed)
+	}
+	if !errors.Is(err, io.ErrClosedPipe) || !errors.Is(err, io.ErrShortWrite) {
+		t.Errorf("Encode() error = %v, want it to wrap both writers' errors", err)
+	}
+
+	// The healthy writers got the whole value, and are written to as usual
+	// afterwards.
+	if sum := sha1.Sum(want); file.String() != string(want) || !bytes.Equal(h.Sum(nil), sum[:]) {
+		t.Errorf("Encode() wrote %q to the other writers, want %q", file.String(), want)
+	}
+	if err := enc.EncodeValue(reflect.ValueOf("x")); err != nil {
+		t.Errorf("EncodeValue() error = %v", err)
+	}
+	if file.String() != string(want)+"1:x" || conn.Len() != 5 {
+		t.Errorf("EncodeValue() wrote %q and %q", file.String(), conn.String())
+	}
+
+	// Once every writer has failed, each call fails.
+	enc = NewMultiEncoder(&failingWriter{err: io.ErrClosedPipe})
+	for i := range 3 {
+		err = enc.Encode(msg)
+		var we *WriterError
+		if !errors.Is(err, ErrEncodeWriteError) || !errors.As(err, &we) || we.Index != 0 {
+			t.Errorf("Encode() #%d with no working writer error = %v, want a WriterError for writer 0", i, err)
+		}
+	}
+	if err := enc.EncodeInt(1); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("EncodeInt() with no working writer error = %v, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+func TestMultiEncoderReportsFailuresPerCall(t *testing.T) {
+	var file bytes.Buffer
+	enc := NewMultiEncoder(&file, &shortWriter{n: 3})
+
+	// A writer failing during a call that fails for another reason is
+	// reported by that call.
+	err := enc.Encode(map[string]any{"a": 1, "b": make(chan int)})
+	var we *WriterError
+	if !errors.Is(err, ErrEncodeUnsupportedType) || !errors.As(err, &we) || we.Index != 1 {
+		t.Errorf("Encode() error = %v, want %q and a WriterError for writer 1", err, ErrEncodeUnsupportedType)
+	}
+	if err := enc.Encode(1); err != nil {
+		t.Errorf("Encode() after the failure was reported error = %v", err)
+	}
+
+	// The composition methods report failures too.
+	enc = NewMultiEncoder(&file, &shortWriter{n: 0})
+	if err := enc.BeginList(); !errors.As(err, &we) || we.Index != 1 {
+		t.Errorf("BeginList() error = %v, want a WriterError for writer 1", err)
+	}
+	if err := enc.EncodeString("x"); err != nil {
+		t.Errorf("EncodeString() error = %v", err)
+	}
+	if err := enc.End(); err != nil {
+		t.Errorf("End() error = %v", err)
+	}
+}
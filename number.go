@@ -0,0 +1,103 @@
+package bencode
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+// Number is a bencode integer held as its decimal text, so that its
+// interpretation can be deferred. BEP 3 puts no bound on integers, and a
+// Decoder normally fails on those outside the int64 range; with UseNumber,
+// generic decodes produce a Number for every integer instead, which can then
+// be read as an int64, a uint64 or a big.Int as the application requires.
+// A Number is encoded as the integer it holds.
+type Number string
+
+var numberType = reflect.TypeFor[Number]()
+
+// String returns the decimal text of n.
+func (n Number) String() string {
+	return string(n)
+}
+
+// Int64 returns n as an int64. An n out of range is reported as an
+// ErrUnmarshalOverflow error and one that is not an integer as an
+// ErrSyntaxInteger error.
+func (n Number) Int64() (int64, error) {
+	v, err := strconv.ParseInt(string(n), 10, 64)
+	return v, numberError(n, "int64", err)
+}
+
+// Uint64 returns n as a uint64, reporting errors as Int64 does.
+func (n Number) Uint64() (uint64, error) {
+	v, err := strconv.ParseUint(string(n), 10, 64)
+	return v, numberError(n, "uint64", err)
+}
+
+// numberError converts a strconv error from parsing n as typ.
+func numberError(n Number, typ string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, strconv.ErrRange) {
+		return &Error{Type: ErrUnmarshalOverflow, Msg: fmt.Sprintf("value %s overflows %s", n, typ), WrappedErr: err}
+	}
+	return &Error{Type: ErrSyntaxInteger, Msg: fmt.Sprintf("cannot parse integer %q", string(n)), WrappedErr: err}
+}
+
+// BigInt returns n as a big.Int, reporting whether n is a valid integer.
+func (n Number) BigInt() (*big.Int, bool) {
+	return new(big.Int).SetString(string(n), 10)
+}
+
+// UseNumber makes the Decoder produce a Number rather than an int64 for every
+// integer decoded into an interface value or returned by DecodeValue. It also
+// lets integers beyond the int64 range decode, as Numbers, and into uint64
+// destinations where they fit. Integers must still be in canonical form,
+// unless AllowNonMinimalIntegers is set, and no longer than MaxIntDigits
+// allows.
+func (d *Decoder) UseNumber() {
+	d.useNumber = true
+}
+
+// validNumber reports whether s is a canonical bencode integer: an optional
+// minus sign followed by digits, without leading zeros or a negative zero.
+func validNumber(s string) bool {
+	digits := s
+	if len(digits) > 0 && digits[0] == '-' {
+		digits = digits[1:]
+	}
+	if digits == "" || (digits[0] == '0' && (len(digits) > 1 || len(s) > 1)) {
+		return false
+	}
+	for i := range len(digits) {
+		if digits[i] < '0' || digits[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// numberToInt converts a Number for an integer destination of type typ.
+func numberToInt(n Number, typ fmt.Stringer) (int64, error) {
+	v, err := n.Int64()
+	if err != nil {
+		return 0, &Error{Type: err.(*Error).Type, Msg: fmt.Sprintf("cannot assign %s to type %s", n, typ), WrappedErr: err}
+	}
+	return v, nil
+}
+
+// numberToUint converts a Number for an unsigned destination of type typ.
+func numberToUint(n Number, typ fmt.Stringer) (uint64, error) {
+	if len(n) > 0 && n[0] == '-' {
+		return 0, &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("cannot assign negative value %s to unsigned type %s", n, typ)}
+	}
+	v, err := n.Uint64()
+	if err != nil {
+		return 0, &Error{Type: err.(*Error).Type, Msg: fmt.Sprintf("cannot assign %s to type %s", n, typ), WrappedErr: err}
+	}
+	return v, nil
+}
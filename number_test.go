@@ -0,0 +1,68 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestUseNumber(t *testing.T) {
+	input := "d3:bigi18446744073709551615e3:negi-5e5:smalli7ee"
+
+	dec := NewDecoder(bytes.NewReader([]byte(input)))
+	dec.UseNumber()
+	v, err := dec.DecodeValue()
+	if err != nil {
+		t.Fatalf("DecodeValue() error = %v", err)
+	}
+	dict := v.(map[string]any)
+	if got, ok := dict["big"].(Number); !ok || got != "18446744073709551615" {
+		t.Errorf("big = %#v, want Number", dict["big"])
+	}
+	if _, err := dict["big"].(Number).Int64(); err == nil {
+		t.Error("Int64() of uint64 max succeeded, want range error")
+	}
+	if b, ok := dict["big"].(Number).BigInt(); !ok || b.String() != "18446744073709551615" {
+		t.Errorf("BigInt() = %v, %v", b, ok)
+	}
+	out, err := Marshal(v)
+	if err != nil || string(out) != input {
+		t.Errorf("Marshal() = %q, %v, want %q", out, err, input)
+	}
+
+	var s struct {
+		Big   uint64 `bencode:"big"`
+		Neg   int8   `bencode:"neg"`
+		Small uint8  `bencode:"small"`
+	}
+	dec = NewDecoder(bytes.NewReader([]byte(input)))
+	dec.UseNumber()
+	if err := dec.Decode(&s); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if s.Big != 18446744073709551615 || s.Neg != -5 || s.Small != 7 {
+		t.Errorf("Decode() = %+v", s)
+	}
+
+	var u struct {
+		Neg uint64 `bencode:"neg"`
+	}
+	dec = NewDecoder(bytes.NewReader([]byte("d3:negi-5ee")))
+	dec.UseNumber()
+	var bErr *Error
+	if err := dec.Decode(&u); !errors.As(err, &bErr) {
+		t.Errorf("Decode() of negative into uint64 error = %v, want *Error", err)
+	}
+
+	for _, bad := range []string{"i03e", "i-0e", "ie", "i+1e", "i1.5e"} {
+		dec = NewDecoder(bytes.NewReader([]byte(bad)))
+		dec.UseNumber()
+		if _, err := dec.DecodeValue(); err == nil {
+			t.Errorf("DecodeValue(%q) succeeded, want syntax error", bad)
+		}
+	}
+
+	if _, err := Marshal(Number("012")); err == nil {
+		t.Error("Marshal(Number(\"012\")) succeeded, want error")
+	}
+}
@@ -0,0 +1,89 @@
+package bencode
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Optional holds a value of type T that may be absent, so that a missing
+// dictionary key can be told apart from one holding the zero value without
+// resorting to a pointer. The zero Optional is absent.
+//
+// A struct field of type Optional[T] is encoded only when present; its key is
+// omitted otherwise. When decoding, a field whose key appears in the
+// dictionary becomes present, even if the value is T's zero value.
+type Optional[T any] struct {
+	value   T
+	present bool
+}
+
+// Some returns a present Optional holding v.
+func Some[T any](v T) Optional[T] {
+	return Optional[T]{value: v, present: true}
+}
+
+// Present reports whether o holds a value.
+func (o Optional[T]) Present() bool {
+	return o.present
+}
+
+// Get returns the value held by o and whether it is present. If o is absent,
+// Get returns T's zero value and false.
+func (o Optional[T]) Get() (T, bool) {
+	return o.value, o.present
+}
+
+// Or returns the value held by o, or def if o is absent.
+func (o Optional[T]) Or(def T) T {
+	if !o.present {
+		return def
+	}
+	return o.value
+}
+
+// String returns the value formatted with %v, or "<absent>".
+func (o Optional[T]) String() string {
+	if !o.present {
+		return "<absent>"
+	}
+	return fmt.Sprint(o.value)
+}
+
+// optionalValue reports the held value and whether it is present; it lets
+// the encoder recognise any instantiation of Optional.
+func (o Optional[T]) optionalValue() (any, bool) {
+	return o.value, o.present
+}
+
+// decodeOptional assigns into a fresh T with assign and, if that succeeds,
+// makes o present with the result.
+func (o *Optional[T]) decodeOptional(assign func(reflect.Value) error) error {
+	var v T
+	if err := assign(reflect.ValueOf(&v).Elem()); err != nil {
+		return err
+	}
+	o.value, o.present = v, true
+	return nil
+}
+
+// optional is implemented by every instantiation of Optional.
+type optional interface {
+	optionalValue() (any, bool)
+}
+
+var optionalType = reflect.TypeFor[optional]()
+
+// optionalDecoder is implemented by pointers to every instantiation of Optional.
+type optionalDecoder interface {
+	decodeOptional(assign func(reflect.Value) error) error
+}
+
+// asOptionalDecoder returns destVal as an optionalDecoder, if it is an
+// addressable Optional.
+func asOptionalDecoder(destVal reflect.Value) (optionalDecoder, bool) {
+	if destVal.Kind() != reflect.Struct || !destVal.CanAddr() {
+		return nil, false
+	}
+	o, ok := destVal.Addr().Interface().(optionalDecoder)
+	return o, ok
+}
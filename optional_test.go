@@ -0,0 +1,70 @@
+package bencode
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOptionalRoundTrip(t *testing.T) {
+	type stats struct {
+		Downloaded Optional[int64]  `bencode:"downloaded"`
+		Name       Optional[string] `bencode:"name"`
+	}
+	tests := []struct {
+		name     string
+		value    stats
+		expected string
+	}{
+		{name: "absent", value: stats{}, expected: "de"},
+		{name: "zero", value: stats{Downloaded: Some[int64](0)}, expected: "d10:downloadedi0ee"},
+		{name: "both", value: stats{Downloaded: Some[int64](7), Name: Some("x")}, expected: "d10:downloadedi7e4:name1:xe"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := Marshal(tt.value)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(encoded) != tt.expected {
+				t.Errorf("Marshal() = %q, want %q", encoded, tt.expected)
+			}
+			var got stats
+			if err := Unmarshal(encoded, &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if got != tt.value {
+				t.Errorf("Unmarshal() = %+v, want %+v", got, tt.value)
+			}
+		})
+	}
+}
+
+func TestOptionalDecode(t *testing.T) {
+	var got struct {
+		Downloaded Optional[uint8] `bencode:"downloaded"`
+	}
+	err := Unmarshal([]byte("d10:downloadedi300ee"), &got)
+	var bErr *Error
+	if !errors.As(err, &bErr) || bErr.Type != ErrUnmarshalOverflow {
+		t.Fatalf("Unmarshal() error = %v, want %q", err, ErrUnmarshalOverflow)
+	}
+	if got.Downloaded.Present() {
+		t.Errorf("Downloaded.Present() = true after failed decode, want false")
+	}
+
+	var list []Optional[string]
+	if err := Unmarshal([]byte("l1:a0:e"), &list); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if len(list) != 2 || list[0].Or("") != "a" || !list[1].Present() {
+		t.Errorf("Unmarshal() = %v, want [a ] all present", list)
+	}
+}
+
+func TestOptionalEncodeAbsentOutsideStruct(t *testing.T) {
+	_, err := Marshal([]Optional[int]{Some(1), {}})
+	var bErr *Error
+	if !errors.As(err, &bErr) || bErr.Type != ErrEncodeUnsupportedType {
+		t.Errorf("Marshal() error = %v, want %q", err, ErrEncodeUnsupportedType)
+	}
+}
@@ -0,0 +1,191 @@
+package bencode
+
+import "io"
+
+// Options gathers the settings of Decoder and Encoder in one value, so that
+// a configuration can be defined once and applied to every Decoder and
+// Encoder a program creates. The presets StrictBEP3, LenientInterop and
+// CanonicalSigning cover the common compatibility profiles; start from one
+// and adjust fields as needed, or add the settings for known client quirks
+// with WithQuirks.
+//
+// The zero value of every field leaves the corresponding setting at its
+// default, so fields added in later versions do not change the behaviour of
+// existing Options values. Whatever the options, the Decoder requires
+// duplicate-free dictionary keys and minimal string lengths; it requires
+// sorted keys unless AllowUnsortedKeys is set and minimal integers unless
+// AllowNonMinimalIntegers is set.
+type Options struct {
+	// MaxElements, MaxDictEntries, MaxKeyLength and MaxDecodedBytes set the
+	// Decoder limits of the same names when positive.
+	MaxElements     int
+	MaxDictEntries  int
+	MaxKeyLength    int
+	MaxDecodedBytes int64
+	// MaxIntDigits calls Decoder.MaxIntDigits when non-zero; negative
+	// values remove the limit.
+	MaxIntDigits int
+	// PrintableKeys calls Decoder.RequirePrintableKeys.
+	PrintableKeys bool
+	// RejectNULKeys calls Decoder.RejectNULKeys.
+	RejectNULKeys bool
+	// UTF8 is passed to Decoder.ValidateUTF8.
+	UTF8 UTF8Mode
+	// CollectErrors calls Decoder.CollectErrors.
+	CollectErrors bool
+	// InternKeys calls Decoder.InternKeys.
+	InternKeys bool
+	// UseNumber calls Decoder.UseNumber.
+	UseNumber bool
+	// UseBytesViews calls Decoder.UseBytesViews.
+	UseBytesViews bool
+	// AllowTrailingWhitespace calls Decoder.AllowTrailingWhitespace.
+	AllowTrailingWhitespace bool
+	// AllowNonMinimalIntegers calls Decoder.AllowNonMinimalIntegers.
+	AllowNonMinimalIntegers bool
+	// AllowUnsortedKeys calls Decoder.AllowUnsortedKeys.
+	AllowUnsortedKeys bool
+	// EmptyListAsMissing calls Decoder.EmptyListAsMissing.
+	EmptyListAsMissing bool
+
+	// RequireCanonical calls Encoder.RequireCanonical.
+	RequireCanonical bool
+	// ValidateRaw calls Encoder.ValidateRaw.
+	ValidateRaw bool
+	// UseFieldOrder calls Encoder.UseFieldOrder. The output is not
+	// canonical.
+	UseFieldOrder bool
+	// OmitNil calls Encoder.OmitNil.
+	OmitNil bool
+}
+
+// Limits applied by StrictBEP3.
+const (
+	StrictMaxKeyLength   = 256
+	StrictMaxDictEntries = 1 << 16
+)
+
+// StrictBEP3 returns Options that hold input to the letter of BEP 3: keys
+// must be printable ASCII without NUL bytes and at most StrictMaxKeyLength
+// bytes long, dictionaries may have at most StrictMaxDictEntries entries,
+// strings decoded into Go strings must be valid UTF-8, and raw messages are
+// only encoded if they are canonical. Each key and dictionary violation has
+// its own ErrorType, so that a filtering proxy can tell them apart.
+func StrictBEP3() Options {
+	return Options{
+		MaxDictEntries:   StrictMaxDictEntries,
+		MaxKeyLength:     StrictMaxKeyLength,
+		PrintableKeys:    true,
+		RejectNULKeys:    true,
+		UTF8:             UTF8Reject,
+		RequireCanonical: true,
+	}
+}
+
+// LenientInterop returns Options for exchanging data with clients that are
+// careless about their encodings. Invalid UTF-8 is replaced rather than
+// rejected, integers with leading zeros are accepted, struct and list
+// decoding reports every failing field instead of stopping at the first, and
+// nil values are left out of encoded output.
+func LenientInterop() Options {
+	return Options{
+		UTF8:                    UTF8Replace,
+		AllowNonMinimalIntegers: true,
+		CollectErrors:           true,
+		OmitNil:                 true,
+	}
+}
+
+// CanonicalSigning returns Options for documents whose encoding is hashed or
+// signed, where any change to the bytes matters. Raw messages must be
+// canonical, strings decoded into Go strings must be valid UTF-8 so that
+// they re-encode to the same bytes, and nil values stay an error rather
+// than being dropped silently.
+func CanonicalSigning() Options {
+	return Options{
+		UTF8:             UTF8Reject,
+		RequireCanonical: true,
+	}
+}
+
+// ConfigureDecoder applies the decoding options to d.
+func (o Options) ConfigureDecoder(d *Decoder) {
+	if o.MaxElements > 0 {
+		d.MaxElements(o.MaxElements)
+	}
+	if o.MaxDictEntries > 0 {
+		d.MaxDictEntries(o.MaxDictEntries)
+	}
+	if o.MaxKeyLength > 0 {
+		d.MaxKeyLength(o.MaxKeyLength)
+	}
+	if o.MaxDecodedBytes > 0 {
+		d.MaxDecodedBytes(o.MaxDecodedBytes)
+	}
+	if o.MaxIntDigits != 0 {
+		d.MaxIntDigits(o.MaxIntDigits)
+	}
+	if o.PrintableKeys {
+		d.RequirePrintableKeys()
+	}
+	if o.RejectNULKeys {
+		d.RejectNULKeys()
+	}
+	if o.UTF8 != UTF8Accept {
+		d.ValidateUTF8(o.UTF8)
+	}
+	if o.CollectErrors {
+		d.CollectErrors()
+	}
+	if o.InternKeys {
+		d.InternKeys()
+	}
+	if o.UseNumber {
+		d.UseNumber()
+	}
+	if o.UseBytesViews {
+		d.UseBytesViews()
+	}
+	if o.AllowTrailingWhitespace {
+		d.AllowTrailingWhitespace()
+	}
+	if o.AllowNonMinimalIntegers {
+		d.AllowNonMinimalIntegers()
+	}
+	if o.AllowUnsortedKeys {
+		d.AllowUnsortedKeys()
+	}
+	if o.EmptyListAsMissing {
+		d.EmptyListAsMissing()
+	}
+}
+
+// ConfigureEncoder applies the encoding options to e.
+func (o Options) ConfigureEncoder(e *Encoder) {
+	if o.RequireCanonical {
+		e.RequireCanonical()
+	}
+	if o.ValidateRaw {
+		e.ValidateRaw()
+	}
+	if o.OmitNil {
+		e.OmitNil()
+	}
+	if o.UseFieldOrder {
+		e.UseFieldOrder()
+	}
+}
+
+// NewDecoder returns a new decoder that reads from r, configured with o.
+func (o Options) NewDecoder(r io.Reader) *Decoder {
+	d := NewDecoder(r)
+	o.ConfigureDecoder(d)
+	return d
+}
+
+// NewEncoder returns a new encoder that writes to w, configured with o.
+func (o Options) NewEncoder(w io.Writer) *Encoder {
+	e := NewEncoder(w)
+	o.ConfigureEncoder(e)
+	return e
+}
@@ -0,0 +1,69 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestOptionsPresets(t *testing.T) {
+	type torrent struct {
+		Name string `bencode:"name"`
+	}
+	tests := []struct {
+		name    string
+		opts    Options
+		input   string
+		errType ErrorType
+		want    string
+	}{
+		{name: "strict rejects invalid UTF-8", opts: StrictBEP3(), input: "d4:name2:\xff\xfee", errType: ErrUnmarshalInvalidUTF8},
+		{name: "strict rejects unprintable key", opts: StrictBEP3(), input: "d4:name1:a2:\x01\x02i1ee", errType: ErrStructureDictKeyCharset},
+		{name: "strict rejects NUL in key", opts: StrictBEP3(), input: "d4:name1:a2:\x00\x01i1ee", errType: ErrStructureDictKeyNUL},
+		{name: "strict rejects long key", opts: StrictBEP3(), input: "d257:" + strings.Repeat("k", 257) + "i1e4:name1:ae", errType: ErrStructureDictKeyLength},
+		{name: "lenient replaces invalid UTF-8", opts: LenientInterop(), input: "d4:name3:a\xffbe", want: "a�b"},
+		{name: "signing rejects invalid UTF-8", opts: CanonicalSigning(), input: "d4:name1:\xffe", errType: ErrUnmarshalInvalidUTF8},
+		{name: "limits", opts: Options{MaxDictEntries: 1}, input: "d1:ai1e4:name1:xe", errType: ErrStructureDictEntries},
+		{name: "zero value", opts: Options{}, input: "d4:name1:\xffe", want: "\xff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got torrent
+			err := tt.opts.NewDecoder(strings.NewReader(tt.input)).Decode(&got)
+			if tt.errType != "" {
+				var bErr *Error
+				if !errors.As(err, &bErr) || bErr.Type != tt.errType {
+					t.Fatalf("Decode() error = %v, want %q", err, tt.errType)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if got.Name != tt.want {
+				t.Errorf("Decode() Name = %q, want %q", got.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptionsEncoder(t *testing.T) {
+	nonCanonical := RawMessage("d1:bi1e1:ai2ee")
+	for _, opts := range []Options{StrictBEP3(), CanonicalSigning()} {
+		var b bytes.Buffer
+		err := opts.NewEncoder(&b).Encode(nonCanonical)
+		var bErr *Error
+		if !errors.As(err, &bErr) || bErr.Type != ErrEncodeNonCanonical {
+			t.Errorf("Encode() with %+v error = %v, want %q", opts, err, ErrEncodeNonCanonical)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := LenientInterop().NewEncoder(&b).Encode(map[string]any{"a": nil, "b": 1}); err != nil {
+		t.Fatalf("Encode() with LenientInterop error = %v", err)
+	}
+	if b.String() != "d1:bi1ee" {
+		t.Errorf("Encode() with LenientInterop = %q, want %q", b.String(), "d1:bi1ee")
+	}
+}
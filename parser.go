@@ -0,0 +1,227 @@
+package bencode
+
+import (
+	"fmt"
+	"math"
+)
+
+// A Parser buffers bencode input pushed to it in chunks of any size, such as
+// the reads of a non-blocking socket, and reports when a complete value has
+// arrived, so that an event loop can serve many connections without a
+// goroutine blocked in Decode for each. The syntax of the input, down to
+// every dictionary key being a string followed by a value, is checked as it
+// is fed, and the scan resumes where the previous chunk left off, so no
+// byte is examined twice however finely the input is split. The zero Parser
+// is ready to use.
+//
+// A Parser is not safe for concurrent use by multiple goroutines.
+type Parser struct {
+	buf     []byte
+	scanned int         // bytes of buf accounted for by the state below
+	state   parserState // what is expected at buf[scanned]
+	open    []byte      // an openList, openKey or openValue per container open
+	n       int         // string length read so far, or string bytes left
+	digits  int         // digits of the string length read so far
+	base    int64       // stream offset of buf[0]
+	maxSize int
+	err     error // syntax or limit error, after which Feed fails
+}
+
+// Kinds of open containers, recording for a dictionary whether a key or a
+// value is expected next.
+const (
+	openList  byte = 'l'
+	openKey   byte = 'k'
+	openValue byte = 'v'
+)
+
+// parserState is the position of a Parser within the value being scanned.
+type parserState uint8
+
+const (
+	parseValue     parserState = iota // a value, or 'e' inside a container
+	parseLength                       // the digits of a string length
+	parseString                       // the bytes of a string
+	parseIntStart                     // the first byte after 'i'
+	parseIntSign                      // the first digit after "i-"
+	parseIntDigits                    // further digits of an integer
+	parseDone                         // a complete value is buffered
+)
+
+// MaxSize limits the values the Parser buffers to n bytes. A value that is
+// found to be longer, as soon as its length is known, makes Feed return an
+// ErrLimitExceeded error; a peer can otherwise make the Parser buffer
+// without bound. Zero, the default, means no limit.
+func (p *Parser) MaxSize(n int) {
+	p.maxSize = n
+}
+
+// Feed appends b to the buffered input and scans it, reporting whether a
+// complete value is buffered and ready for Decode. Input following that
+// value stays buffered, unscanned, until Decode has taken the value; call
+// Feed(nil) then to learn whether the next value is also complete. A syntax
+// error, reported as by Decoder, or a value longer than MaxSize is returned
+// by this and every later call to Feed, since the stream cannot be resynced;
+// Reset starts over.
+//
+// Only the syntax of the input is checked while feeding. Rules such as
+// sorted dictionary keys and minimally encoded integers are checked by
+// Decode, as by Unmarshal.
+func (p *Parser) Feed(b []byte) (complete bool, err error) {
+	if p.err != nil {
+		return false, p.err
+	}
+	p.buf = append(p.buf, b...)
+	if err := p.scan(); err != nil {
+		p.err = err
+		return false, err
+	}
+	return p.state == parseDone, nil
+}
+
+// Decode decodes the complete value Feed reported into the value pointed to
+// by v, as Unmarshal does, and removes it from the buffer. The value is
+// removed even if it cannot be decoded into v. Calling Decode before Feed
+// has reported a complete value is an ErrUsage error.
+func (p *Parser) Decode(v any) error {
+	if p.state != parseDone {
+		return &Error{Type: ErrUsage, Msg: "Parser.Decode called before Feed reported a complete value"}
+	}
+	end := p.scanned
+	err := Unmarshal(p.buf[:end], v)
+	p.buf = p.buf[:copy(p.buf, p.buf[end:])]
+	p.base += int64(end)
+	p.scanned, p.state = 0, parseValue
+	return err
+}
+
+// Buffered returns the number of bytes fed to the Parser and not yet taken
+// by Decode.
+func (p *Parser) Buffered() int {
+	return len(p.buf)
+}
+
+// Reset discards all buffered input and any error, keeping the MaxSize
+// limit, so that the Parser can be reused for a new stream.
+func (p *Parser) Reset() {
+	*p = Parser{buf: p.buf[:0], open: p.open[:0], maxSize: p.maxSize}
+}
+
+// scan advances the state through the buffered input until it runs out or
+// a complete value has been scanned.
+func (p *Parser) scan() error {
+	for p.scanned < len(p.buf) && p.state != parseDone {
+		c := p.buf[p.scanned]
+		switch p.state {
+		case parseValue:
+			var top byte
+			if len(p.open) > 0 {
+				top = p.open[len(p.open)-1]
+			}
+			switch {
+			case top == openKey && c != TokenEnd && (c < '0' || c > '9'):
+				return p.errorf(ErrStructureDict, "dictionary key at offset %d is not a string", p.offset())
+			case top == openValue && c == TokenEnd:
+				return p.errorf(ErrStructureDictValue, "dictionary key without a value at offset %d", p.offset())
+			case c >= '0' && c <= '9':
+				p.state, p.n, p.digits = parseLength, int(c-'0'), 1
+			case c == TokenInteger:
+				p.state = parseIntStart
+			case c == TokenList:
+				p.open = append(p.open, openList)
+			case c == TokenDict:
+				p.open = append(p.open, openKey)
+			case c == TokenEnd && top != 0:
+				p.open = p.open[:len(p.open)-1]
+				p.endValue(1)
+				continue
+			default:
+				return p.errorf(ErrSyntaxUnexpectedToken, "unexpected %q at offset %d", c, p.offset())
+			}
+		case parseLength:
+			switch {
+			case c >= '0' && c <= '9':
+				if p.digits == maxLengthDigits || p.n > (math.MaxInt-int(c-'0'))/10 {
+					return p.errorf(ErrSyntaxStringLength, "string length out of range at offset %d", p.offset())
+				}
+				p.n, p.digits = p.n*10+int(c-'0'), p.digits+1
+			case c == StringSeparator:
+				p.state = parseString
+				if p.n == 0 {
+					p.endValue(1)
+					continue
+				}
+			default:
+				return p.errorf(ErrSyntaxStringLength, "unexpected %q in string length at offset %d", c, p.offset())
+			}
+		case parseString:
+			k := min(p.n, len(p.buf)-p.scanned)
+			p.n -= k
+			if p.n == 0 {
+				p.endValue(k)
+			} else {
+				p.scanned += k
+			}
+			continue
+		case parseIntStart, parseIntSign, parseIntDigits:
+			switch {
+			case c >= '0' && c <= '9':
+				p.state = parseIntDigits
+			case c == '-' && p.state == parseIntStart:
+				p.state = parseIntSign
+			case c == TokenEnd && p.state == parseIntDigits:
+				p.endValue(1)
+				continue
+			default:
+				return p.errorf(ErrSyntaxInteger, "unexpected %q in integer at offset %d", c, p.offset())
+			}
+		}
+		p.scanned++
+		if err := p.checkSize(); err != nil {
+			return err
+		}
+	}
+	return p.checkSize()
+}
+
+// endValue consumes the last n bytes of a string, integer or container and
+// moves on to the next value, or marks the value complete if it was the
+// outermost. Within a dictionary, a key is followed by its value and a value
+// by the next key.
+func (p *Parser) endValue(n int) {
+	p.scanned += n
+	p.state = parseValue
+	switch i := len(p.open) - 1; {
+	case i < 0:
+		p.state = parseDone
+	case p.open[i] == openKey:
+		p.open[i] = openValue
+	case p.open[i] == openValue:
+		p.open[i] = openKey
+	}
+}
+
+// checkSize reports an error if the value being scanned is known to exceed
+// MaxSize.
+func (p *Parser) checkSize() error {
+	if p.maxSize <= 0 {
+		return nil
+	}
+	size := p.scanned
+	if p.state == parseString {
+		size += p.n
+	}
+	if size > p.maxSize {
+		return p.errorf(ErrLimitExceeded, "value at offset %d exceeds %d bytes", p.base, p.maxSize)
+	}
+	return nil
+}
+
+// offset returns the stream offset of the byte being scanned.
+func (p *Parser) offset() int64 {
+	return p.base + int64(p.scanned)
+}
+
+func (p *Parser) errorf(typ ErrorType, format string, args ...any) error {
+	return &Error{Type: typ, Msg: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,112 @@
+package bencode
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParserFeed(t *testing.T) {
+	type message struct {
+		A struct {
+			ID string `bencode:"id"`
+		} `bencode:"a"`
+		Q string `bencode:"q"`
+		T string `bencode:"t"`
+		Y string `bencode:"y"`
+	}
+	first := "d1:ad2:id20:abcdefghij0123456789e1:q4:ping1:t2:aa1:y1:qe"
+	second := "i-42e"
+	third := "l0:li1eee"
+	input := first + second + third
+
+	// However the input is split, each value is reported complete once its
+	// last byte arrives.
+	for size := 1; size <= len(input); size++ {
+		var p Parser
+		var got []any
+		for i := 0; i < len(input); i += size {
+			complete, err := p.Feed([]byte(input[i:min(i+size, len(input))]))
+			for ; complete && err == nil; complete, err = p.Feed(nil) {
+				switch len(got) {
+				case 0:
+					var m message
+					err = p.Decode(&m)
+					got = append(got, m)
+				default:
+					var v any
+					err = p.Decode(&v)
+					got = append(got, v)
+				}
+				if err != nil {
+					break
+				}
+			}
+			if err != nil {
+				t.Fatalf("chunks of %d: error = %v", size, err)
+			}
+		}
+		if len(got) != 3 || got[0].(message).A.ID != "abcdefghij0123456789" || got[0].(message).Q != "ping" ||
+			got[1] != int64(-42) || !reflect.DeepEqual(got[2], []any{[]byte{}, []any{int64(1)}}) {
+			t.Errorf("chunks of %d: decoded %v", size, got)
+		}
+		if p.Buffered() != 0 {
+			t.Errorf("chunks of %d: Buffered() = %d, want 0", size, p.Buffered())
+		}
+	}
+}
+
+func TestParserErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  ErrorType
+	}{
+		{"e", ErrSyntaxUnexpectedToken},
+		{"lx", ErrSyntaxUnexpectedToken},
+		{"4x", ErrSyntaxStringLength},
+		{"99999999999999999999999:", ErrSyntaxStringLength},
+		{"ie", ErrSyntaxInteger},
+		{"i-e", ErrSyntaxInteger},
+		{"i--1e", ErrSyntaxInteger},
+		{"i1-e", ErrSyntaxInteger},
+		{"di1ei2ee", ErrStructureDict},
+		{"dlee", ErrStructureDict},
+		{"d1:ad1:bi1eei2ee", ErrStructureDict},
+		{"d1:ae", ErrStructureDictValue},
+		{"ld1:ai1e1:bee", ErrStructureDictValue},
+		{"l40:", ErrLimitExceeded},
+		{"lllllllllllllllllllllllllllllllll", ErrLimitExceeded},
+	}
+	for _, tt := range tests {
+		var p Parser
+		p.MaxSize(32)
+		_, err := p.Feed([]byte(tt.input))
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Feed(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+		if _, again := p.Feed([]byte("i1e")); again != err {
+			t.Errorf("Feed() after error = %v, want %v", again, err)
+		}
+		p.Reset()
+		if complete, err := p.Feed([]byte("i1e")); !complete || err != nil {
+			t.Errorf("Feed() after Reset = %v, %v, want a complete value", complete, err)
+		}
+	}
+
+	var p Parser
+	var v any
+	if err := p.Decode(&v); !errors.Is(err, ErrUsage) {
+		t.Errorf("Decode() before a complete value error = %v, want %q", err, ErrUsage)
+	}
+	// A complete but non-canonical value is rejected by Decode, and the
+	// next one can still be read.
+	if complete, err := p.Feed([]byte("i01ei2e")); !complete || err != nil {
+		t.Fatalf("Feed() = %v, %v", complete, err)
+	}
+	if err := p.Decode(&v); !errors.Is(err, ErrSyntaxInteger) {
+		t.Errorf("Decode(i01e) error = %v, want %q", err, ErrSyntaxInteger)
+	}
+	if complete, err := p.Feed(nil); !complete || err != nil || p.Decode(&v) != nil || v != int64(2) {
+		t.Errorf("Feed(nil) then Decode() = %v, want 2", v)
+	}
+}
@@ -0,0 +1,134 @@
+package bencode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PeerID is the 20-byte ID a BitTorrent client picks for itself. It is
+// comparable and bencodes as a 20-byte string.
+type PeerID [20]byte
+
+// azureusClients maps the two-letter codes of Azureus-style peer IDs, such
+// as "-TR2940-", to client names.
+var azureusClients = map[string]string{
+	"AG": "Ares",
+	"AZ": "Vuze",
+	"BC": "BitComet",
+	"BI": "BiglyBT",
+	"BT": "BitTorrent",
+	"DE": "Deluge",
+	"FD": "Free Download Manager",
+	"KT": "KTorrent",
+	"LT": "libtorrent (Rasterbar)",
+	"lt": "libTorrent (Rakshasa)",
+	"qB": "qBittorrent",
+	"TR": "Transmission",
+	"UM": "µTorrent Mac",
+	"UT": "µTorrent",
+	"WW": "WebTorrent",
+}
+
+// shadowClients maps the one-letter codes of Shadow-style peer IDs, such as
+// "S58B-----", to client names.
+var shadowClients = map[byte]string{
+	'A': "ABC",
+	'O': "Osprey Permaseed",
+	'Q': "BTQueue",
+	'R': "Tribler",
+	'S': "Shadow",
+	'T': "BitTornado",
+	'U': "UPnP NAT Bit Torrent",
+}
+
+// Client identifies the client that generated the peer ID from the
+// Azureus-style ("-" + two-letter code + four version characters + "-"),
+// Shadow-style (one-letter code + up to five version characters, padded
+// with "-") or Mainline ("M" + version digits separated by "-")
+// conventions. An Azureus-style ID with an unknown code is reported under
+// the code itself. ok is false if id follows none of the conventions.
+func (id PeerID) Client() (name, version string, ok bool) {
+	switch {
+	case id[0] == '-' && id[7] == '-' && isAlnum(id[1]) && isAlnum(id[2]):
+		code := string(id[1:3])
+		if name, ok = azureusClients[code]; !ok {
+			name = code
+		}
+		return name, dotted(id[3:7]), true
+	case id[0] == 'M' && id[2] == '-' && isDigit(id[1]):
+		end := strings.Index(string(id[:]), "--")
+		if end < 0 {
+			return "", "", false
+		}
+		parts := strings.Split(string(id[1:end]), "-")
+		for _, p := range parts {
+			for i := range len(p) {
+				if !isDigit(p[i]) {
+					return "", "", false
+				}
+			}
+		}
+		return "BitTorrent (Mainline)", strings.Join(parts, "."), true
+	}
+	if name, ok = shadowClients[id[0]]; ok {
+		end := 1
+		for end < 6 && isVersionChar(id[end]) {
+			end++
+		}
+		if end > 1 && strings.Trim(string(id[end:9]), "-") == "" {
+			return name, dotted(id[1:end]), true
+		}
+	}
+	return "", "", false
+}
+
+// ClientName returns the client name and version, such as
+// "Transmission 2.9.4.0", or "" if the ID follows no known convention.
+func (id PeerID) ClientName() string {
+	name, version, ok := id.Client()
+	if !ok {
+		return ""
+	}
+	return name + " " + version
+}
+
+// String returns the ID with non-printable bytes escaped, e.g.
+// "-TR2940-\x01...".
+func (id PeerID) String() string {
+	q := fmt.Sprintf("%+q", string(id[:]))
+	return q[1 : len(q)-1]
+}
+
+// dotted formats version characters as dot-separated numbers, reading the
+// letters A to Z as 10 to 35 as Azureus-style clients do. Trailing '-'
+// padding is dropped.
+func dotted(chars []byte) string {
+	var parts []string
+	for _, c := range chars {
+		switch {
+		case isDigit(c):
+			parts = append(parts, string(c))
+		case c >= 'A' && c <= 'Z':
+			parts = append(parts, fmt.Sprint(int(c-'A')+10))
+		case c >= 'a' && c <= 'z':
+			parts = append(parts, fmt.Sprint(int(c-'a')+36))
+		case c == '.':
+			parts = append(parts, "62")
+		case c == '-':
+		default:
+			parts = append(parts, string(c))
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isAlnum(c byte) bool {
+	return isDigit(c) || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+// isVersionChar reports whether c is a Shadow-style version character.
+func isVersionChar(c byte) bool {
+	return isAlnum(c) || c == '.'
+}
@@ -0,0 +1,42 @@
+package bencode
+
+import "testing"
+
+func TestPeerIDClient(t *testing.T) {
+	tests := []struct {
+		id       string
+		expected string
+	}{
+		{id: "-TR2940-k8hj0wgej6ch", expected: "Transmission 2.9.4.0"},
+		{id: "-qB4250-abcdefghijkl", expected: "qBittorrent 4.2.5.0"},
+		{id: "-XX1000-abcdefghijkl", expected: "XX 1.0.0.0"},
+		{id: "S58B-----\x00\x01\x02\x03\x04\x05\x06\x07\x08\x09\x0a", expected: "Shadow 5.8.11"},
+		{id: "T03I--00abcdefghijkl", expected: ""},
+		{id: "M4-3-6--abcdefghijkl", expected: "BitTorrent (Mainline) 4.3.6"},
+		{id: "\x00\x01\x02\x03\x04\x05\x06\x07\x08\x09\x0a\x0b\x0c\x0d\x0e\x0f\x10\x11\x12\x13", expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			id := PeerID([]byte(tt.id))
+			if got := id.ClientName(); got != tt.expected {
+				t.Errorf("ClientName() of %s = %q, want %q", id, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPeerIDBencode(t *testing.T) {
+	var got struct {
+		ID PeerID `bencode:"peer id"`
+	}
+	if err := Unmarshal([]byte("d7:peer id20:-TR2940-k8hj0wgej6che"), &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got.ID.String() != "-TR2940-k8hj0wgej6ch" {
+		t.Errorf("Unmarshal() = %s", got.ID)
+	}
+	encoded, err := Marshal(got)
+	if err != nil || string(encoded) != "d7:peer id20:-TR2940-k8hj0wgej6che" {
+		t.Errorf("Marshal() = %q, %v", encoded, err)
+	}
+}
@@ -0,0 +1,59 @@
+package bencode
+
+import "github.com/stupoid/bencode/scanner"
+
+// presize scans data, the complete input of an Unmarshal call, and records
+// the number of elements of every list and entries of every dictionary in
+// the order the containers start. decode opens containers in the same order,
+// so it can take each count with nextSize and allocate lists and maps at
+// their final size instead of growing them. If data does not scan, no sizes
+// are recorded and decoding reports the problem as usual.
+func (d *Decoder) presize(data []byte) {
+	var s scanner.Scanner
+	s.Reset(data)
+	var sizes []int
+	var open []int // indices into sizes of the containers being scanned
+	var dicts []bool
+	for s.More() {
+		tok, err := s.Next()
+		if err != nil {
+			return
+		}
+		if tok.Kind == scanner.End {
+			if len(open) == 0 {
+				return
+			}
+			top := len(open) - 1
+			if dicts[top] {
+				sizes[open[top]] /= 2
+			}
+			open, dicts = open[:top], dicts[:top]
+			if len(open) == 0 {
+				break
+			}
+			continue
+		}
+		if len(open) > 0 {
+			sizes[open[len(open)-1]]++
+		}
+		if tok.Kind == scanner.ListStart || tok.Kind == scanner.DictStart {
+			open = append(open, len(sizes))
+			dicts = append(dicts, tok.Kind == scanner.DictStart)
+			sizes = append(sizes, 0)
+		} else if len(open) == 0 {
+			break
+		}
+	}
+	d.sizes = sizes
+}
+
+// nextSize returns the size recorded by presize for the next container, or
+// 0 if there is none.
+func (d *Decoder) nextSize() int {
+	if len(d.sizes) == 0 {
+		return 0
+	}
+	n := d.sizes[0]
+	d.sizes = d.sizes[1:]
+	return n
+}
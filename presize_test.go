@@ -0,0 +1,44 @@
+package bencode
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPresize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []int
+	}{
+		{input: "i1e", expected: nil},
+		{input: "le", expected: []int{0}},
+		{input: "li1eli2ei3ee4:spamde", expected: []int{4, 2, 0}},
+		{input: "d5:filesld6:lengthi1eed6:lengthi2eee4:name1:ae", expected: []int{2, 2, 1, 1}},
+		{input: "li1e", expected: []int{1}},
+		{input: "lxe", expected: nil},
+	}
+	for _, tt := range tests {
+		var d Decoder
+		d.presize([]byte(tt.input))
+		if !slices.Equal(d.sizes, tt.expected) {
+			t.Errorf("presize(%q) = %v, want %v", tt.input, d.sizes, tt.expected)
+		}
+	}
+}
+
+func TestUnmarshalPresized(t *testing.T) {
+	input := "l" + strings.Repeat("d6:lengthi1e4:pathl1:aee", 100) + "e"
+	v, err := UnmarshalAny([]byte(input))
+	if err != nil {
+		t.Fatalf("UnmarshalAny() error = %v", err)
+	}
+	files := v.([]any)
+	if len(files) != 100 || cap(files) != 100 {
+		t.Errorf("UnmarshalAny() list has len %d, cap %d, want 100, 100", len(files), cap(files))
+	}
+	path := files[99].(map[string]any)["path"].([]any)
+	if len(path) != 1 || cap(path) != 1 {
+		t.Errorf("UnmarshalAny() nested list has len %d, cap %d, want 1, 1", len(path), cap(path))
+	}
+}
@@ -0,0 +1,166 @@
+package bencode
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// openContainer is a list or dictionary begun with BeginList or BeginDict
+// and not yet ended.
+type openContainer struct {
+	dict      bool
+	needValue bool   // a key has been written and awaits its value
+	hasKey    bool   // lastKey is set
+	lastKey   string // the most recent key of a dictionary
+}
+
+// BeginList writes the start of a list whose elements are written by the
+// following calls, until the matching End. Together with BeginDict,
+// EncodeString, EncodeBytes and EncodeInt it lets output be composed by hand,
+// for example to place a pre-encoded info dictionary among other keys with
+// Encode(RawMessage(info)), while the Encoder still checks that the result is
+// well-formed: values may be written with any of these methods or with
+// Encode, but dictionary keys must be written with EncodeString or
+// EncodeBytes, in strictly increasing order. Misuse is an ErrUsage error,
+// and a key out of order an ErrEncodeKeyOrder or ErrEncodeDuplicateKey
+// error; nothing is written in either case. A value that fails otherwise,
+// such as one of an unsupported type, may have been partly written, so
+// every later call writing inside the open containers, and End, returns an
+// ErrUsage error wrapping that failure. Keys added with AddRootKey are not
+// added to dictionaries composed this way.
+func (e *Encoder) BeginList() error {
+	return e.writerErrors(e.begin('l', false))
+}
+
+// BeginDict writes the start of a dictionary whose keys and values are
+// written by the following calls, until the matching End. See BeginList.
+func (e *Encoder) BeginDict() error {
+	return e.writerErrors(e.begin('d', true))
+}
+
+func (e *Encoder) begin(token byte, dict bool) error {
+	if err := e.startValue(nil, false); err != nil {
+		return err
+	}
+	if _, err := e.w.Write([]byte{token}); err != nil {
+		return e.breakOpen(&Error{Type: ErrEncodeWriteError, Msg: fmt.Sprintf("failed to write start token %q", token), WrappedErr: err})
+	}
+	e.open = append(e.open, openContainer{dict: dict})
+	return nil
+}
+
+// End writes the end of the list or dictionary most recently begun with
+// BeginList or BeginDict. Ending a dictionary whose last key has no value is
+// an ErrUsage error.
+func (e *Encoder) End() (err error) {
+	defer func() { err = e.writerErrors(err) }()
+	if len(e.open) == 0 {
+		return &Error{Type: ErrUsage, Msg: "End called without BeginList or BeginDict"}
+	}
+	if err := e.checkOpen(); err != nil {
+		return err
+	}
+	if c := e.open[len(e.open)-1]; c.needValue {
+		return &Error{Type: ErrUsage, Msg: fmt.Sprintf("dictionary ended before the value of key %q", c.lastKey), FieldName: c.lastKey}
+	}
+	if _, err := e.w.Write([]byte{'e'}); err != nil {
+		return e.breakOpen(&Error{Type: ErrEncodeWriteError, Msg: "failed to write end token 'e'", WrappedErr: err})
+	}
+	e.open = e.open[:len(e.open)-1]
+	return nil
+}
+
+// EncodeString writes s as a bencode string: a value, or a dictionary key.
+// See BeginList.
+func (e *Encoder) EncodeString(s string) (err error) {
+	defer func() { err = e.writerErrors(err) }()
+	if err := e.startValue([]byte(s), true); err != nil {
+		return err
+	}
+	if err := e.writeString(s); err != nil {
+		return e.breakOpen(&Error{Type: ErrEncodeWriteError, Msg: "failed to write string", WrappedErr: err})
+	}
+	return nil
+}
+
+// EncodeBytes writes b as a bencode string: a value, or a dictionary key.
+// See BeginList.
+func (e *Encoder) EncodeBytes(b []byte) (err error) {
+	defer func() { err = e.writerErrors(err) }()
+	if err := e.startValue(b, true); err != nil {
+		return err
+	}
+	header := append(strconv.AppendInt(e.scratch[:0], int64(len(b)), 10), ':')
+	if _, err := e.w.Write(header); err != nil {
+		return e.breakOpen(&Error{Type: ErrEncodeWriteError, Msg: "failed to write string length", WrappedErr: err})
+	}
+	if _, err := e.w.Write(b); err != nil {
+		return e.breakOpen(&Error{Type: ErrEncodeWriteError, Msg: "failed to write string", WrappedErr: err})
+	}
+	return nil
+}
+
+// EncodeInt writes n as a bencode integer. See BeginList.
+func (e *Encoder) EncodeInt(n int64) (err error) {
+	defer func() { err = e.writerErrors(err) }()
+	if err := e.startValue(nil, false); err != nil {
+		return err
+	}
+	if _, err := e.w.Write(append(strconv.AppendInt(append(e.scratch[:0], 'i'), n, 10), 'e')); err != nil {
+		return e.breakOpen(&Error{Type: ErrEncodeWriteError, Msg: "failed to write integer", WrappedErr: err})
+	}
+	return nil
+}
+
+// startValue checks that a value may be written inside the innermost open
+// container, if any, and records it. str holds the value if it is a string,
+// as only strings may be dictionary keys.
+func (e *Encoder) startValue(str []byte, isString bool) error {
+	if len(e.open) == 0 {
+		e.resetHash()
+		return nil
+	}
+	if err := e.checkOpen(); err != nil {
+		return err
+	}
+	c := &e.open[len(e.open)-1]
+	if !c.dict {
+		return nil
+	}
+	if c.needValue {
+		c.needValue = false
+		return nil
+	}
+	if !isString {
+		return &Error{Type: ErrUsage, Msg: "dictionary key must be written with EncodeString or EncodeBytes"}
+	}
+	if c.hasKey {
+		switch CompareKeys([]byte(c.lastKey), str) {
+		case 0:
+			return &Error{Type: ErrEncodeDuplicateKey, Msg: fmt.Sprintf("key %q written twice", str), FieldName: string(str)}
+		case 1:
+			return &Error{Type: ErrEncodeKeyOrder, Msg: fmt.Sprintf("key %q is not lexicographically after %q", str, c.lastKey), FieldName: string(str)}
+		}
+	}
+	c.lastKey, c.hasKey, c.needValue = string(str), true, true
+	return nil
+}
+
+// breakOpen records err, the failure of a value written inside an open
+// container, and returns it. Part of the value may have been written, so
+// the containers cannot be completed, and checkOpen fails from then on.
+func (e *Encoder) breakOpen(err error) error {
+	if len(e.open) > 0 {
+		e.broken = err
+	}
+	return err
+}
+
+// checkOpen returns an ErrUsage error if a value written inside the open
+// containers has failed.
+func (e *Encoder) checkOpen() error {
+	if e.broken == nil {
+		return nil
+	}
+	return &Error{Type: ErrUsage, Msg: "an earlier value written inside the open list or dictionary failed", WrappedErr: e.broken}
+}
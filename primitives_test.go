@@ -0,0 +1,91 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncoderPrimitives(t *testing.T) {
+	info := RawMessage("d4:name4:spam6:lengthi3ee")
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	steps := []func() error{
+		e.BeginDict,
+		func() error { return e.EncodeString("announce") },
+		func() error { return e.EncodeString("http://tracker.example/announce") },
+		func() error { return e.EncodeBytes([]byte("info")) },
+		func() error { return e.Encode(info) },
+		func() error { return e.EncodeString("nodes") },
+		e.BeginList,
+		func() error { return e.EncodeInt(-1) },
+		func() error { return e.Encode([]string{"a"}) },
+		e.End,
+		e.End,
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("step %d error = %v", i, err)
+		}
+	}
+	if want := "d8:announce31:http://tracker.example/announce4:info" + string(info) + "5:nodesli-1el1:aeee"; b.String() != want {
+		t.Errorf("output = %q, want %q", b.String(), want)
+	}
+}
+
+func TestEncoderPrimitivesMisuse(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps func(e *Encoder) error
+		want  ErrorType
+	}{
+		{"end without begin", func(e *Encoder) error { return e.End() }, ErrUsage},
+		{"integer key", func(e *Encoder) error {
+			_ = e.BeginDict()
+			return e.EncodeInt(1)
+		}, ErrUsage},
+		{"list key", func(e *Encoder) error {
+			_ = e.BeginDict()
+			return e.BeginList()
+		}, ErrUsage},
+		{"key without value", func(e *Encoder) error {
+			_ = e.BeginDict()
+			_ = e.EncodeString("a")
+			return e.End()
+		}, ErrUsage},
+		{"unsorted keys", func(e *Encoder) error {
+			_ = e.BeginDict()
+			_ = e.EncodeString("b")
+			_ = e.EncodeInt(1)
+			return e.EncodeString("a")
+		}, ErrEncodeKeyOrder},
+		{"duplicate key", func(e *Encoder) error {
+			_ = e.BeginDict()
+			_ = e.EncodeString("a")
+			_ = e.Encode(1)
+			return e.EncodeBytes([]byte("a"))
+		}, ErrEncodeDuplicateKey},
+		{"end after a failed value", func(e *Encoder) error {
+			_ = e.BeginDict()
+			_ = e.EncodeString("a")
+			if err := e.Encode(make(chan int)); !errors.Is(err, ErrEncodeUnsupportedType) {
+				return err
+			}
+			return e.End()
+		}, ErrUsage},
+		{"value after a failed value", func(e *Encoder) error {
+			_ = e.BeginList()
+			_ = e.Encode(map[string]any{"a": 1, "b": make(chan int)})
+			return e.EncodeInt(1)
+		}, ErrUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			e := NewEncoder(&b)
+			if err := tt.steps(e); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package bencode
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// Quirk names a known deviation from BEP 3 in the output of widely deployed
+// clients and trackers. Options.WithQuirks turns on the lenient settings each
+// one needs, so that programs talking to such peers do not have to
+// rediscover which settings that is.
+type Quirk int
+
+const (
+	// QuirkOldUTorrentUnsortedKeys is dictionary keys written in insertion
+	// order rather than sorted, as old µTorrent releases did in extension
+	// messages. It enables AllowUnsortedKeys.
+	QuirkOldUTorrentUnsortedKeys Quirk = iota + 1
+	// QuirkEmptyListsForMissing is an empty list, "le", written in place
+	// of a dictionary or other value the sender does not have, as trackers
+	// written in PHP do, where an empty array cannot be told apart from an
+	// empty dictionary. It enables EmptyListAsMissing.
+	QuirkEmptyListsForMissing
+	// QuirkLeadingZeroIntegers is integers with leading zeros, such as
+	// i042e, or a negative zero. It enables AllowNonMinimalIntegers.
+	QuirkLeadingZeroIntegers
+	// QuirkLegacyEncodedText is names and comments in a legacy code page
+	// rather than UTF-8, as written by old clients on non-English systems.
+	// It sets UTF8 to UTF8Replace.
+	QuirkLegacyEncodedText
+)
+
+// String returns the name of the quirk.
+func (q Quirk) String() string {
+	switch q {
+	case QuirkOldUTorrentUnsortedKeys:
+		return "OldUTorrentUnsortedKeys"
+	case QuirkEmptyListsForMissing:
+		return "EmptyListsForMissing"
+	case QuirkLeadingZeroIntegers:
+		return "LeadingZeroIntegers"
+	case QuirkLegacyEncodedText:
+		return "LegacyEncodedText"
+	default:
+		return fmt.Sprintf("Quirk(%d)", int(q))
+	}
+}
+
+// WithQuirks returns a copy of o with the settings needed to decode input
+// showing each of quirks turned on, leaving its other settings as they are.
+func (o Options) WithQuirks(quirks ...Quirk) Options {
+	for _, q := range quirks {
+		switch q {
+		case QuirkOldUTorrentUnsortedKeys:
+			o.AllowUnsortedKeys = true
+		case QuirkEmptyListsForMissing:
+			o.EmptyListAsMissing = true
+		case QuirkLeadingZeroIntegers:
+			o.AllowNonMinimalIntegers = true
+		case QuirkLegacyEncodedText:
+			o.UTF8 = UTF8Replace
+		}
+	}
+	return o
+}
+
+// AllowUnsortedKeys makes the Decoder accept dictionaries whose keys are not
+// in sorted order, raising a WarnUnsortedKey warning for each key out of
+// order. Duplicate keys are still rejected. Values decoded from such input
+// are re-encoded canonically, with their keys sorted, so a RawMessage
+// holding one does not keep the original bytes; DecodeRaw returns them.
+func (d *Decoder) AllowUnsortedKeys() {
+	d.unsortedKeys = true
+}
+
+// EmptyListAsMissing makes the Decoder treat an empty list decoded into a
+// struct field that cannot hold a list, such as a nested struct, a map or an
+// integer, as if the field's key were absent: the field is left unchanged,
+// and a required field is reported missing. Fields that hold lists, any
+// value or a RawMessage receive the empty list as usual.
+func (d *Decoder) EmptyListAsMissing() {
+	d.emptyListGaps = true
+}
+
+// isEmptyList reports whether the decoded value v is an empty list.
+func isEmptyList(v any) bool {
+	list, ok := v.([]any)
+	return ok && len(list) == 0
+}
+
+// acceptsList reports whether the struct field f can hold a list.
+func acceptsList(f cachedStructFieldInfo) bool {
+	if f.asList || f.mergesEntries() {
+		return true
+	}
+	if f.union != nil {
+		return slices.Contains(f.union, KindList)
+	}
+	typ := f.typ
+	for typ.Kind() == reflect.Pointer || typ.Implements(optionalType) {
+		if typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		} else {
+			typ = typ.Field(0).Type
+		}
+	}
+	switch typ.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Slice, reflect.Array:
+		return typ == rawMessageType || typ.Elem().Kind() != reflect.Uint8
+	default:
+		return false
+	}
+}
@@ -0,0 +1,171 @@
+package bencode
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// quirkFixtures holds, for each quirk, a message showing it and the struct
+// it decodes into.
+var quirkFixtures = []struct {
+	quirk Quirk
+	input string
+	into  func() any
+	want  any
+}{
+	{
+		quirk: QuirkOldUTorrentUnsortedKeys,
+		input: "d1:md11:ut_metadatai2e6:ut_pexi1ee1:pi6881e1:v13:\xc2\xb5Torrent 1.613:metadata_sizei31235ee",
+		into:  func() any { return new(handshake) },
+		want:  &handshake{M: map[string]int64{"ut_metadata": 2, "ut_pex": 1}, MetadataSize: 31235, P: 6881, V: "µTorrent 1.6"},
+	},
+	{
+		quirk: QuirkEmptyListsForMissing,
+		input: "d5:filesle8:intervali1800e5:peersle10:tracker id0:e",
+		into:  func() any { return new(phpResponse) },
+		want:  &phpResponse{Interval: 1800, Peers: []string{}},
+	},
+	{
+		quirk: QuirkLeadingZeroIntegers,
+		input: "d8:intervali0900ee",
+		into:  func() any { return new(phpResponse) },
+		want:  &phpResponse{Interval: 900},
+	},
+	{
+		quirk: QuirkLegacyEncodedText,
+		input: "d4:name8:caf\xe9.mkv4:sizei1ee",
+		into:  func() any { return new(legacyInfo) },
+		want:  &legacyInfo{Name: "caf�.mkv", Size: 1},
+	},
+}
+
+type handshake struct {
+	M            map[string]int64 `bencode:"m"`
+	MetadataSize int64            `bencode:"metadata_size"`
+	P            int64            `bencode:"p"`
+	V            string           `bencode:"v"`
+}
+
+type phpResponse struct {
+	Files    map[string]struct{} `bencode:"files"`
+	Interval int64               `bencode:"interval"`
+	Peers    []string            `bencode:"peers"`
+}
+
+type legacyInfo struct {
+	Name string `bencode:"name"`
+	Size int64  `bencode:"size"`
+}
+
+func TestQuirks(t *testing.T) {
+	for _, fx := range quirkFixtures {
+		t.Run(fx.quirk.String(), func(t *testing.T) {
+			strict := NewDecoder(strings.NewReader(fx.input))
+			StrictBEP3().ConfigureDecoder(strict)
+			if err := strict.Decode(fx.into()); err == nil {
+				t.Errorf("Decode() with StrictBEP3 succeeded, want the quirk rejected")
+			}
+
+			dec := NewDecoder(strings.NewReader(fx.input))
+			StrictBEP3().WithQuirks(fx.quirk).ConfigureDecoder(dec)
+			got := fx.into()
+			if err := dec.Decode(got); err != nil {
+				t.Fatalf("Decode() with WithQuirks(%s) error = %v", fx.quirk, err)
+			}
+			if !reflect.DeepEqual(got, fx.want) {
+				t.Errorf("Decode() = %+v, want %+v", got, fx.want)
+			}
+		})
+	}
+
+	if got := Quirk(99).String(); got != "Quirk(99)" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestAllowUnsortedKeys(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("d1:bi1e1:ai2e1:bi3ee"))
+	dec.AllowUnsortedKeys()
+	if _, err := dec.DecodeValue(); !errors.Is(err, ErrStructureDictKeyDup) {
+		t.Errorf("DecodeValue() error = %v, want %q", err, ErrStructureDictKeyDup)
+	}
+
+	dec = NewDecoder(strings.NewReader("d1:bi1e1:ai2e1:bi3ee"))
+	dec.AllowUnsortedKeys()
+	var keys []string
+	var err error
+	for key, e := range dec.Entries() {
+		if e != nil {
+			err = e
+			break
+		}
+		keys = append(keys, key)
+	}
+	if !errors.Is(err, ErrStructureDictKeyDup) || !reflect.DeepEqual(keys, []string{"b", "a"}) {
+		t.Errorf("Entries() = %q, %v, want [b a] then %q", keys, err, ErrStructureDictKeyDup)
+	}
+
+	// A RawMessage receives the keys sorted; DecodeRaw keeps the input.
+	dec = NewDecoder(strings.NewReader("d1:bi1e1:ai2ee"))
+	dec.AllowUnsortedKeys()
+	var msg RawMessage
+	raw, err := dec.DecodeRaw(&msg)
+	if err != nil || string(msg) != "d1:ai2e1:bi1ee" || string(raw) != "d1:bi1e1:ai2ee" {
+		t.Errorf("DecodeRaw() = %q, %q, %v", raw, msg, err)
+	}
+
+	// Each key out of order is reported, by Decode and Entries alike.
+	for _, entries := range []bool{false, true} {
+		var warned []string
+		dec = NewDecoder(strings.NewReader("d1:bi1e1:ai2e1:ci3ee"))
+		dec.AllowUnsortedKeys()
+		dec.OnWarning(func(w Warning) {
+			if w.Type == WarnUnsortedKey {
+				warned = append(warned, w.FieldName)
+			}
+		})
+		if entries {
+			for _, e := range dec.Entries() {
+				if e != nil {
+					t.Fatalf("Entries() error = %v", e)
+				}
+				if _, err := dec.DecodeValue(); err != nil {
+					t.Fatalf("DecodeValue() error = %v", err)
+				}
+			}
+		} else if _, err := dec.DecodeValue(); err != nil {
+			t.Fatalf("DecodeValue() error = %v", err)
+		}
+		if !reflect.DeepEqual(warned, []string{"a"}) {
+			t.Errorf("warnings for keys %q, want [a]", warned)
+		}
+	}
+}
+
+func TestEmptyListAsMissing(t *testing.T) {
+	type target struct {
+		Count Optional[int64] `bencode:"count"`
+		List  []string        `bencode:"list"`
+		Need  map[string]any  `bencode:"need,required"`
+		Raw   RawMessage      `bencode:"raw"`
+	}
+	dec := NewDecoder(strings.NewReader("d5:countle4:listle4:needle3:rawlee"))
+	dec.EmptyListAsMissing()
+	var got target
+	err := dec.Decode(&got)
+	if !errors.Is(err, ErrUnmarshalMissingField) {
+		t.Errorf("Decode() error = %v, want %q for need", err, ErrUnmarshalMissingField)
+	}
+
+	dec = NewDecoder(strings.NewReader("d5:countle4:listle4:needd1:ki1ee3:rawlee"))
+	dec.EmptyListAsMissing()
+	got = target{}
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got.Count.Present() || got.List == nil || len(got.List) != 0 || string(got.Raw) != "le" {
+		t.Errorf("Decode() = %+v, want count absent and list and raw empty lists", got)
+	}
+}
@@ -0,0 +1,222 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/stupoid/bencode/scanner"
+)
+
+// rawEntry is a dictionary entry whose value is kept encoded.
+type rawEntry struct {
+	key   string
+	value []byte
+}
+
+// rawDictEntries splits the dictionary encoded in data into its entries, in
+// input order. Values alias data.
+func rawDictEntries(data []byte) ([]rawEntry, error) {
+	var s scanner.Scanner
+	s.Reset(data)
+	tok, err := s.Next()
+	if err != nil {
+		return nil, scanError(err)
+	}
+	if tok.Kind != scanner.DictStart {
+		return nil, &Error{Type: ErrStructureDict, Msg: fmt.Sprintf("expected a dictionary, got %s", tok.Kind)}
+	}
+	var entries []rawEntry
+	for {
+		keyTok, err := s.Next()
+		if err != nil {
+			return nil, scanError(err)
+		}
+		if keyTok.Kind == scanner.End {
+			break
+		}
+		if keyTok.Kind != scanner.String {
+			return nil, &Error{Type: ErrStructureDict, Msg: fmt.Sprintf("dictionary key at offset %d is not a bencode string", keyTok.Offset)}
+		}
+		key := string(data[keyTok.ValueOffset : keyTok.ValueOffset+keyTok.ValueLen])
+		if s.Peek() == scanner.End {
+			return nil, &Error{Type: ErrStructureDictValue, Msg: "missing value", FieldName: key}
+		}
+		valTok, err := s.Skip()
+		if err != nil {
+			return nil, scanError(err)
+		}
+		entries = append(entries, rawEntry{key: key, value: data[valTok.Offset:valTok.End()]})
+	}
+	if s.More() {
+		return nil, &Error{Type: ErrSyntax, Msg: fmt.Sprintf("unexpected data after bencode value at offset %d", s.Offset())}
+	}
+	return entries, nil
+}
+
+// appendRawDict appends a dictionary holding entries, sorted by key, to dst.
+// Keys must be unique.
+func appendRawDict(dst []byte, entries []rawEntry) []byte {
+	slices.SortFunc(entries, func(a, b rawEntry) int {
+		return CompareKeys([]byte(a.key), []byte(b.key))
+	})
+	dst = append(dst, 'd')
+	for _, e := range entries {
+		dst = strconv.AppendInt(dst, int64(len(e.key)), 10)
+		dst = append(dst, ':')
+		dst = append(dst, e.key...)
+		dst = append(dst, e.value...)
+	}
+	return append(dst, 'e')
+}
+
+// MergeDicts merges the top-level entries of the dictionaries encoded in dst
+// and patches, with entries from later arguments replacing those with the
+// same key from earlier ones. The result is a new canonical dictionary with
+// sorted keys; values are copied byte for byte without being decoded.
+//
+// Only top-level keys are merged: a patch entry replaces the whole value of
+// its key, even when both values are dictionaries. This suits overlays such
+// as replacing the announce-list of a torrent without touching its info
+// dictionary, and hence its info hash.
+func MergeDicts(dst []byte, patches ...[]byte) ([]byte, error) {
+	merged := make(map[string][]byte)
+	for i, data := range append([][]byte{dst}, patches...) {
+		entries, err := rawDictEntries(data)
+		if err != nil {
+			return nil, &Error{Type: err.(*Error).Type, Msg: fmt.Sprintf("merging dictionary %d", i), WrappedErr: err}
+		}
+		for _, e := range entries {
+			merged[e.key] = e.value
+		}
+	}
+	entries := make([]rawEntry, 0, len(merged))
+	for k, v := range merged {
+		entries = append(entries, rawEntry{key: k, value: v})
+	}
+	return appendRawDict(nil, entries), nil
+}
+
+// splice returns a copy of data with data[start:end] replaced by repl.
+func splice(data []byte, start, end int, repl ...[]byte) []byte {
+	size := len(data) - (end - start)
+	for _, r := range repl {
+		size += len(r)
+	}
+	out := make([]byte, 0, size)
+	out = append(out, data[:start]...)
+	for _, r := range repl {
+		out = append(out, r...)
+	}
+	return append(out, data[end:]...)
+}
+
+// Set returns a copy of data with the value at path replaced by value, which
+// must hold a single encoded value. Path elements are dictionary keys or, in
+// lists, decimal indexes, as for Document.Get. If the last element names a
+// key missing from its dictionary, the key is inserted at its sorted
+// position; all other path elements must exist. Bytes outside the replaced
+// value are left untouched, and unlike for Index, the integers and string
+// lengths among them need not be minimally encoded.
+func Set(data []byte, path []string, value RawMessage) ([]byte, error) {
+	if err := validateRaw(value); err != nil {
+		return nil, err
+	}
+	doc, err := indexLenient(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(path) == 0 {
+		return bytes.Clone(value), nil
+	}
+	parentPath, last := path[:len(path)-1], path[len(path)-1]
+	parent, err := doc.lookup(parentPath)
+	if err != nil {
+		return nil, err
+	}
+	j, found, err := doc.child(parent, last, parentPath)
+	if err != nil {
+		return nil, err
+	}
+	parentNode := doc.nodes[parent]
+	if found {
+		node := doc.nodes[doc.children[parentNode.firstChild+j]]
+		return splice(data, node.start, node.end, value), nil
+	}
+
+	at := parentNode.end - 1 // before the dictionary's 'e'
+	if j < parentNode.numChildren {
+		at = doc.nodes[doc.children[parentNode.firstChild+j]].keyOffset
+	}
+	key := strconv.AppendInt(nil, int64(len(last)), 10)
+	key = append(append(key, ':'), last...)
+	return splice(data, at, at, key, value), nil
+}
+
+// Delete returns a copy of data with the entry at path removed: the key and
+// value for a dictionary entry, or the element for a list index. Path
+// elements are as for Set; a missing path is an ErrPathNotFound error. Bytes
+// outside the removed entry are left untouched and, as for Set, need not be
+// minimally encoded.
+func Delete(data []byte, path ...string) ([]byte, error) {
+	if len(path) == 0 {
+		return nil, &Error{Type: ErrUsage, Msg: "cannot delete the root value"}
+	}
+	doc, err := indexLenient(data)
+	if err != nil {
+		return nil, err
+	}
+	parentPath := path[:len(path)-1]
+	parent, err := doc.lookup(parentPath)
+	if err != nil {
+		return nil, err
+	}
+	j, found, err := doc.child(parent, path[len(path)-1], parentPath)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, &Error{Type: ErrPathNotFound, Msg: fmt.Sprintf("no key %q at %v", path[len(path)-1], parentPath), FieldName: path[len(path)-1]}
+	}
+	parentNode := doc.nodes[parent]
+	node := doc.nodes[doc.children[parentNode.firstChild+j]]
+	start := node.start
+	if parentNode.kind == KindDict {
+		start = node.keyOffset
+	}
+	return splice(data, start, node.end), nil
+}
+
+// Scrub returns a copy of data with every entry named in paths removed, for
+// redacting sensitive fields such as a "source" tag or a tracker passkey
+// before sharing a torrent. Each path lists dictionary keys and list indexes
+// as for Delete, e.g. {"info", "source"} or {"announce-list", "0", "0"}, so
+// keys may hold any byte. Paths that do not exist are ignored. Bytes outside the removed entries are left
+// untouched, so the info hash survives as long as no path points into info.
+// To replace a value instead of removing it, use Set.
+func Scrub(data []byte, paths ...[]string) ([]byte, error) {
+	if _, err := Index(data); err != nil {
+		return nil, err
+	}
+	out := bytes.Clone(data)
+	for _, p := range paths {
+		scrubbed, err := Delete(out, p...)
+		if err != nil {
+			var bErr *Error
+			if errors.As(err, &bErr) && bErr.Type == ErrPathNotFound {
+				continue
+			}
+			return nil, err
+		}
+		out = scrubbed
+	}
+	return out, nil
+}
+
+// indexLenient indexes data for Set and Delete, which accept integers and
+// string lengths that are not minimally encoded.
+func indexLenient(data []byte) (*Document, error) {
+	return indexDocument(&Document{data: data, lenient: true})
+}
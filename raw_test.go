@@ -0,0 +1,162 @@
+package bencode
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMergeDicts(t *testing.T) {
+	tests := []struct {
+		name     string
+		dst      string
+		patches  []string
+		expected string
+	}{
+		{
+			name:     "no patches",
+			dst:      "d1:ai1ee",
+			expected: "d1:ai1ee",
+		},
+		{
+			name:     "add and override",
+			dst:      "d8:announce5:old/a4:infod6:lengthi1eee",
+			patches:  []string{"d8:announce5:new/a13:announce-listll5:new/aeee"},
+			expected: "d8:announce5:new/a13:announce-listll5:new/aee4:infod6:lengthi1eee",
+		},
+		{
+			name:     "later patches win",
+			dst:      "de",
+			patches:  []string{"d1:bi1e1:ci1ee", "d1:ai0e1:bi2ee"},
+			expected: "d1:ai0e1:bi2e1:ci1ee",
+		},
+		{
+			name:     "unsorted input is sorted",
+			dst:      "d1:bi1e1:ai2ee",
+			expected: "d1:ai2e1:bi1ee",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patches [][]byte
+			for _, p := range tt.patches {
+				patches = append(patches, []byte(p))
+			}
+			got, err := MergeDicts([]byte(tt.dst), patches...)
+			if err != nil {
+				t.Fatalf("MergeDicts() error = %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("MergeDicts() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMergeDictsErrors(t *testing.T) {
+	tests := []struct {
+		dst      string
+		patch    string
+		expected ErrorType
+	}{
+		{dst: "li1ee", patch: "de", expected: ErrStructureDict},
+		{dst: "de", patch: "d1:a", expected: ErrSyntaxEOF},
+		{dst: "de", patch: "d1:ae", expected: ErrStructureDictValue},
+		{dst: "dei1e", patch: "de", expected: ErrSyntax},
+	}
+	for _, tt := range tests {
+		_, err := MergeDicts([]byte(tt.dst), []byte(tt.patch))
+		var bErr *Error
+		if !errors.As(err, &bErr) || bErr.Type != tt.expected {
+			t.Errorf("MergeDicts(%q, %q) error = %v, want %q", tt.dst, tt.patch, err, tt.expected)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		path     []string
+		value    string
+		expected string
+	}{
+		{name: "replace", data: "d1:ai1e1:bi2ee", path: []string{"b"}, value: "3:xyz", expected: "d1:ai1e1:b3:xyze"},
+		{name: "insert first", data: "d1:bi2ee", path: []string{"a"}, value: "i1e", expected: "d1:ai1e1:bi2ee"},
+		{name: "insert last", data: "d1:ai1ee", path: []string{"c"}, value: "le", expected: "d1:ai1e1:clee"},
+		{name: "insert into empty", data: "d1:adee", path: []string{"a", "k"}, value: "0:", expected: "d1:ad1:k0:ee"},
+		{name: "list element", data: "d1:lli1ei2eee", path: []string{"l", "1"}, value: "i9e", expected: "d1:lli1ei9eee"},
+		{name: "root", data: "i1e", value: "i2e", expected: "i2e"},
+		// Non-canonical bytes outside the path are kept as they are.
+		{name: "untouched", data: "d1:a04:spam1:bi0ee", path: []string{"b"}, value: "i1e", expected: "d1:a04:spam1:bi1ee"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Set([]byte(tt.data), tt.path, RawMessage(tt.value))
+			if err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("Set() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		path     []string
+		expected string
+		errType  ErrorType
+	}{
+		{name: "dict entry", data: "d1:ai1e1:bi2ee", path: []string{"a"}, expected: "d1:bi2ee"},
+		{name: "nested", data: "d1:dd1:xi1e1:yi2eee", path: []string{"d", "y"}, expected: "d1:dd1:xi1eee"},
+		{name: "list element", data: "li1ei2ei3ee", path: []string{"1"}, expected: "li1ei3ee"},
+		{name: "missing key", data: "d1:ai1ee", path: []string{"b"}, errType: ErrPathNotFound},
+		{name: "missing parent", data: "d1:ai1ee", path: []string{"b", "c"}, errType: ErrPathNotFound},
+		{name: "root", data: "d1:ai1ee", errType: ErrUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Delete([]byte(tt.data), tt.path...)
+			if tt.errType != "" {
+				var bErr *Error
+				if !errors.As(err, &bErr) || bErr.Type != tt.errType {
+					t.Errorf("Delete() error = %v, want %q", err, tt.errType)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("Delete() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestScrub(t *testing.T) {
+	data := []byte("d8:announce42:http://t.example/abcdef0123456789/announce7:comment4:test4:infod4:name1:x6:source3:PTPee")
+	got, err := Scrub(data, []string{"announce"}, []string{"info", "source"}, []string{"missing", "key"})
+	if err != nil {
+		t.Fatalf("Scrub() error = %v", err)
+	}
+	if want := "d7:comment4:test4:infod4:name1:xee"; string(got) != want {
+		t.Errorf("Scrub() = %q, want %q", got, want)
+	}
+
+	got, err = Scrub(data)
+	if err != nil || string(got) != string(data) {
+		t.Errorf("Scrub() with no paths = %q, %v", got, err)
+	}
+	// Keys are matched whole, whatever bytes they hold.
+	got, err = Scrub([]byte("d3:a.bi1e3:a/bi2ee"), []string{"a/b"}, []string{"a", "b"})
+	if err != nil || string(got) != "d3:a.bi1ee" {
+		t.Errorf("Scrub() of a key holding a separator = %q, %v", got, err)
+	}
+	if _, err := Scrub([]byte("d1:a")); err == nil {
+		t.Error("Scrub() of invalid data succeeded")
+	}
+}
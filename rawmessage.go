@@ -0,0 +1,171 @@
+package bencode
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+
+	"github.com/stupoid/bencode/scanner"
+)
+
+var rawMessageType = reflect.TypeFor[RawMessage]()
+
+// RawMessage is a raw encoded bencode value.
+// It can be used to delay bencode decoding or to precompute an encoding.
+//
+// When encoding, a RawMessage is written verbatim; an empty one, holding no
+// value, is omitted as a struct field and an error elsewhere. When decoding
+// into a RawMessage, it receives the canonical encoding of the decoded
+// value. By default the Decoder only accepts sorted, duplicate-free
+// dictionaries and minimal integers, so this is the bytes of the value as
+// they appeared in the input. With AllowUnsortedKeys it is not: dictionaries
+// are re-encoded with their keys sorted, while integers accepted by
+// AllowNonMinimalIntegers keep their original digits. Use Decoder.DecodeRaw
+// for the exact input bytes. RawMessage destinations work at any level, including as slice
+// elements and map values, which allows heterogeneous lists to be decoded in
+// two phases.
+type RawMessage []byte
+
+// WriteTo writes the raw encoding to w. It implements io.WriterTo, letting
+// cached encodings be handed to writers that special-case it.
+func (m RawMessage) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(m)
+	if err != nil {
+		return int64(n), &Error{Type: ErrEncodeWriteError, Msg: "failed to write raw message", WrappedErr: err}
+	}
+	return int64(n), nil
+}
+
+// ReadFrom replaces m with the contents of r, read until EOF. The data must
+// hold exactly one bencode value. It implements io.ReaderFrom.
+func (m *RawMessage) ReadFrom(r io.Reader) (int64, error) {
+	var buf bytes.Buffer
+	n, err := buf.ReadFrom(r)
+	if err != nil {
+		return n, &Error{Type: ErrSyntax, Msg: "failed to read raw message", WrappedErr: err}
+	}
+	if err := validateRaw(buf.Bytes()); err != nil {
+		return n, err
+	}
+	*m = buf.Bytes()
+	return n, nil
+}
+
+// validateRaw checks that data holds exactly one bencode value.
+func validateRaw(data []byte) error {
+	dec := NewDecoder(bytes.NewReader(data))
+	if _, err := dec.decode(); err != nil {
+		return err
+	}
+	if rest, _ := dec.r.Peek(1); len(rest) > 0 {
+		return &Error{Type: ErrSyntax, Msg: fmt.Sprintf("unexpected data after bencode value: %q", rest)}
+	}
+	return nil
+}
+
+// Kind returns the kind of the value held by m, judged from its first byte,
+// or KindInvalid if m is empty or does not start a bencode value.
+func (m RawMessage) Kind() Kind {
+	if len(m) == 0 {
+		return KindInvalid
+	}
+	return kindOfToken(m[0])
+}
+
+// Len returns the length in bytes of a string, or the number of elements of
+// a list or entries of a dictionary, without decoding them. Integers have no
+// length and yield an ErrUnmarshalType error.
+func (m RawMessage) Len() (int, error) {
+	var s scanner.Scanner
+	tok, err := m.first(&s)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	switch tok.Kind {
+	case scanner.String:
+		n = tok.ValueLen
+	case scanner.ListStart, scanner.DictStart:
+		for s.Peek() != scanner.End {
+			if _, err := s.Skip(); err != nil {
+				return 0, scanError(err)
+			}
+			n++
+		}
+		if _, err := s.Next(); err != nil {
+			return 0, scanError(err)
+		}
+		if tok.Kind == scanner.DictStart {
+			n /= 2
+		}
+	default:
+		return 0, &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("raw %s has no length", m.Kind())}
+	}
+	if err := scanDone(&s); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Int64 returns the value of an integer.
+func (m RawMessage) Int64() (int64, error) {
+	var s scanner.Scanner
+	tok, err := m.first(&s)
+	if err != nil {
+		return 0, err
+	}
+	if tok.Kind != scanner.Integer {
+		return 0, &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("raw %s is not an integer", m.Kind())}
+	}
+	n, err := strconv.ParseInt(string(m[tok.ValueOffset:tok.ValueOffset+tok.ValueLen]), 10, 64)
+	if err != nil {
+		return 0, &Error{Type: ErrSyntaxInteger, Msg: fmt.Sprintf("cannot parse integer %q", m[tok.Offset:tok.End()]), WrappedErr: err}
+	}
+	if err := scanDone(&s); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Bytes returns the contents of a string. The result aliases m.
+func (m RawMessage) Bytes() ([]byte, error) {
+	var s scanner.Scanner
+	tok, err := m.first(&s)
+	if err != nil {
+		return nil, err
+	}
+	if tok.Kind != scanner.String {
+		return nil, &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("raw %s is not a string", m.Kind())}
+	}
+	end := tok.ValueOffset + tok.ValueLen
+	if err := scanDone(&s); err != nil {
+		return nil, err
+	}
+	return m[tok.ValueOffset:end:end], nil
+}
+
+// Decode decodes the value held by m into the value pointed to by v, as
+// Unmarshal does.
+func (m RawMessage) Decode(v any) error {
+	return Unmarshal(m, v)
+}
+
+// first starts s on m and returns the first token.
+func (m RawMessage) first(s *scanner.Scanner) (scanner.Token, error) {
+	s.Reset(m)
+	tok, err := s.Next()
+	if err != nil {
+		return scanner.Token{}, scanError(err)
+	}
+	return tok, nil
+}
+
+// scanDone checks that s, having finished a value, has no data left.
+func scanDone(s *scanner.Scanner) error {
+	if s.More() {
+		return &Error{Type: ErrSyntax, Msg: fmt.Sprintf("unexpected data after bencode value at offset %d", s.Offset())}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRawMessageStructField(t *testing.T) {
+	type Envelope struct {
+		Type string     `bencode:"t"`
+		Body RawMessage `bencode:"body"`
+	}
+
+	input := "d4:bodyd1:ali1ei2ee1:b3:fooe1:t4:teste"
+	var got Envelope
+	if err := Unmarshal([]byte(input), &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if string(got.Body) != "d1:ali1ei2ee1:b3:fooe" {
+		t.Errorf("Body = %q", got.Body)
+	}
+
+	encoded, err := Marshal(got)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(encoded) != input {
+		t.Errorf("Marshal() = %q, want %q", encoded, input)
+	}
+
+	// An empty RawMessage holds no value: it is omitted as a field and
+	// rejected elsewhere.
+	if encoded, err := Marshal(Envelope{Type: "x"}); err != nil || string(encoded) != "d1:t1:xe" {
+		t.Errorf("Marshal(empty Body) = %q, %v, want %q", encoded, err, "d1:t1:xe")
+	}
+	if _, err := Marshal(map[string]RawMessage{"a": nil}); !errors.Is(err, ErrEncodeInvalidRaw) {
+		t.Errorf("Marshal(map with nil RawMessage) error = %v, want %q", err, ErrEncodeInvalidRaw)
+	}
+}
+
+func TestRawMessageWriteTo(t *testing.T) {
+	m := RawMessage("d8:completei5ee")
+	var buf bytes.Buffer
+	n, err := m.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	if n != int64(len(m)) || buf.String() != string(m) {
+		t.Errorf("WriteTo() = %d %q", n, buf.String())
+	}
+}
+
+func TestRawMessageReadFrom(t *testing.T) {
+	var m RawMessage
+	n, err := m.ReadFrom(strings.NewReader("l4:spami42ee"))
+	if err != nil {
+		t.Fatalf("ReadFrom() error = %v", err)
+	}
+	if n != 12 || string(m) != "l4:spami42ee" {
+		t.Errorf("ReadFrom() = %d %q", n, m)
+	}
+
+	var bErr *Error
+	if _, err := m.ReadFrom(strings.NewReader("i1ei2e")); !errors.As(err, &bErr) || bErr.Type != ErrSyntax {
+		t.Errorf("ReadFrom() with trailing data error = %v, want %q", err, ErrSyntax)
+	}
+	if _, err := m.ReadFrom(strings.NewReader("l4:spam")); !errors.As(err, &bErr) || bErr.Type != ErrSyntaxEOF {
+		t.Errorf("ReadFrom() with truncated data error = %v, want %q", err, ErrSyntaxEOF)
+	}
+}
+
+func TestRawMessageContainers(t *testing.T) {
+	t.Run("slice", func(t *testing.T) {
+		var got []RawMessage
+		if err := Unmarshal([]byte("l4:spami42eli1eed1:a0:ee"), &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		expected := []RawMessage{RawMessage("4:spam"), RawMessage("i42e"), RawMessage("li1ee"), RawMessage("d1:a0:e")}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("Unmarshal() = %q, want %q", got, expected)
+		}
+	})
+
+	t.Run("map", func(t *testing.T) {
+		var got map[string]RawMessage
+		if err := Unmarshal([]byte("d1:ai1e1:bl1:xee"), &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		expected := map[string]RawMessage{"a": RawMessage("i1e"), "b": RawMessage("l1:xe")}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("Unmarshal() = %q, want %q", got, expected)
+		}
+	})
+
+	t.Run("announce-list tiers", func(t *testing.T) {
+		type Torrent struct {
+			AnnounceList [][]RawMessage `bencode:"announce-list"`
+		}
+		var got Torrent
+		if err := Unmarshal([]byte("d13:announce-listll3:udp4:httpeli7eeee"), &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		expected := [][]RawMessage{{RawMessage("3:udp"), RawMessage("4:http")}, {RawMessage("i7e")}}
+		if !reflect.DeepEqual(got.AnnounceList, expected) {
+			t.Errorf("Unmarshal() = %q, want %q", got.AnnounceList, expected)
+		}
+
+		encoded, err := Marshal(got)
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		if string(encoded) != "d13:announce-listll3:udp4:httpeli7eeee" {
+			t.Errorf("Marshal() = %q", encoded)
+		}
+	})
+
+	t.Run("top level", func(t *testing.T) {
+		var got RawMessage
+		if err := Unmarshal([]byte("d1:ai1ee"), &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		if string(got) != "d1:ai1ee" {
+			t.Errorf("Unmarshal() = %q", got)
+		}
+	})
+}
+
+func TestRawMessageInspect(t *testing.T) {
+	tests := []struct {
+		raw     string
+		kind    Kind
+		length  int
+		lenErr  ErrorType
+		integer int64
+		intErr  ErrorType
+		bytes   string
+		byteErr ErrorType
+	}{
+		{raw: "4:spam", kind: KindString, length: 4, intErr: ErrUnmarshalType, bytes: "spam"},
+		{raw: "i-42e", kind: KindInteger, lenErr: ErrUnmarshalType, integer: -42, byteErr: ErrUnmarshalType},
+		{raw: "li1eli2eee", kind: KindList, length: 2, intErr: ErrUnmarshalType, byteErr: ErrUnmarshalType},
+		{raw: "d1:ai1e1:bd1:cleee", kind: KindDict, length: 2, intErr: ErrUnmarshalType, byteErr: ErrUnmarshalType},
+		{raw: "4:spamx", kind: KindString, lenErr: ErrSyntax, intErr: ErrUnmarshalType, byteErr: ErrSyntax},
+		{raw: "", kind: KindInvalid, lenErr: ErrSyntaxEOF, intErr: ErrSyntaxEOF, byteErr: ErrSyntaxEOF},
+	}
+	checkErr := func(t *testing.T, method string, err error, want ErrorType) {
+		t.Helper()
+		var bErr *Error
+		if want == "" && err != nil {
+			t.Errorf("%s() error = %v", method, err)
+		} else if want != "" && (!errors.As(err, &bErr) || bErr.Type != want) {
+			t.Errorf("%s() error = %v, want %q", method, err, want)
+		}
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			m := RawMessage(tt.raw)
+			if got := m.Kind(); got != tt.kind {
+				t.Errorf("Kind() = %v, want %v", got, tt.kind)
+			}
+			n, err := m.Len()
+			checkErr(t, "Len", err, tt.lenErr)
+			if n != tt.length {
+				t.Errorf("Len() = %d, want %d", n, tt.length)
+			}
+			i, err := m.Int64()
+			checkErr(t, "Int64", err, tt.intErr)
+			if i != tt.integer {
+				t.Errorf("Int64() = %d, want %d", i, tt.integer)
+			}
+			b, err := m.Bytes()
+			checkErr(t, "Bytes", err, tt.byteErr)
+			if string(b) != tt.bytes {
+				t.Errorf("Bytes() = %q, want %q", b, tt.bytes)
+			}
+		})
+	}
+
+	var dict map[string]int
+	if err := RawMessage("d1:ai1ee").Decode(&dict); err != nil || dict["a"] != 1 {
+		t.Errorf("Decode() = %v, %v", dict, err)
+	}
+}
+
+func TestEncoderValidateRaw(t *testing.T) {
+	var b bytes.Buffer
+	enc := NewEncoder(&b)
+	enc.ValidateRaw()
+
+	// Well-formed but non-canonical values pass.
+	for _, raw := range []string{"i1e", "d1:bi01e1:ai2ee", "l4:spame"} {
+		b.Reset()
+		if err := enc.Encode(map[string]any{"info": RawMessage(raw)}); err != nil {
+			t.Errorf("Encode(%q) error = %v", raw, err)
+		}
+	}
+	for _, raw := range []string{"", "i1", "4:sp", "i1ei2e", "di1ei2ee", "x"} {
+		err := enc.Encode(map[string]any{"info": RawMessage(raw)})
+		var bErr *Error
+		if !errors.As(err, &bErr) || bErr.Type != ErrEncodeInvalidRaw {
+			t.Errorf("Encode(%q) error = %v, want %q", raw, err, ErrEncodeInvalidRaw)
+		}
+	}
+}
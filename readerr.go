@@ -0,0 +1,38 @@
+package bencode
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrRead indicates that the io.Reader a Decoder reads from returned an error
+// other than io.EOF. The reader's error is wrapped, so that errors.Is can
+// match it, for example against os.ErrDeadlineExceeded.
+//
+// The Decoder does not retry a failed read: the value being decoded is
+// abandoned part way through, and the position in the input is lost with it,
+// so every later call on the Decoder returns an ErrRead error wrapping the
+// same reader error without reading further. Short reads, however small, are
+// not errors; a reader that keeps returning no data and no error is given up
+// on after a number of attempts, with an ErrRead error wrapping
+// io.ErrNoProgress.
+const ErrRead ErrorType = "read error"
+
+// readError converts err, returned by the underlying reader while reading
+// what, into an *Error: ErrSyntaxEOF if the input ended early, and otherwise
+// ErrRead, in which case the Decoder is marked as failed.
+func (d *Decoder) readError(err error, what string) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return &Error{Type: ErrSyntaxEOF, Msg: what, WrappedErr: ErrUnexpectedEOF}
+	}
+	d.readErr = err
+	return &Error{Type: ErrRead, Msg: what, WrappedErr: err}
+}
+
+// failed returns an ErrRead error if an earlier read failed.
+func (d *Decoder) failed() error {
+	if d.readErr == nil {
+		return nil
+	}
+	return &Error{Type: ErrRead, Msg: "decoder stopped by an earlier read error", WrappedErr: d.readErr}
+}
@@ -0,0 +1,111 @@
+package bencode
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// stalledReader returns no data and no error, as a non-blocking reader with
+// nothing available does.
+type stalledReader struct{}
+
+func (stalledReader) Read([]byte) (int, error) { return 0, nil }
+
+func TestDecodeShortReads(t *testing.T) {
+	const input = "d3:bari12345e3:fool1:a2:bbi-7ee3:zzz20:0123456789abcdefghije"
+	want, err := UnmarshalAny([]byte(input))
+	if err != nil {
+		t.Fatalf("UnmarshalAny() error = %v", err)
+	}
+	readers := map[string]func(io.Reader) io.Reader{
+		"OneByteReader": iotest.OneByteReader,
+		"HalfReader":    iotest.HalfReader,
+		"DataErrReader": iotest.DataErrReader,
+	}
+	for name, wrap := range readers {
+		t.Run(name, func(t *testing.T) {
+			dec := NewDecoderSize(wrap(strings.NewReader(input)), 16)
+			got, err := dec.DecodeValue()
+			if err != nil {
+				t.Fatalf("DecodeValue() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("DecodeValue() = %v, want %v", got, want)
+			}
+			if err := dec.ExpectEOF(); err != nil {
+				t.Errorf("ExpectEOF() error = %v", err)
+			}
+
+			dec = NewDecoderSize(wrap(strings.NewReader(input)), 16)
+			var keys []string
+			for key, err := range dec.Entries() {
+				if err != nil {
+					t.Fatalf("Entries() error = %v", err)
+				}
+				keys = append(keys, key)
+			}
+			if want := []string{"bar", "foo", "zzz"}; !reflect.DeepEqual(keys, want) {
+				t.Errorf("Entries() keys = %q, want %q", keys, want)
+			}
+		})
+	}
+}
+
+func TestDecodeReadErrors(t *testing.T) {
+	boom := errors.New("boom")
+	tests := []struct {
+		name string
+		r    io.Reader
+		want error
+	}{
+		{"timeout in string", iotest.TimeoutReader(strings.NewReader("d3:bari1e3:fooi2ee")), iotest.ErrTimeout},
+		{"error in integer", io.MultiReader(strings.NewReader("d3:bari12"), iotest.ErrReader(boom)), boom},
+		{"error in string length", io.MultiReader(strings.NewReader("l12"), iotest.ErrReader(boom)), boom},
+		{"error before token", iotest.ErrReader(boom), boom},
+		{"stalled", stalledReader{}, io.ErrNoProgress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoderSize(tt.r, 16)
+			_, err := dec.DecodeValue()
+			if !errors.Is(err, ErrRead) || !errors.Is(err, tt.want) {
+				t.Fatalf("DecodeValue() error = %v, want %q wrapping %v", err, ErrRead, tt.want)
+			}
+			// The failure sticks rather than decoding from the middle of a value.
+			if _, err := dec.DecodeValue(); !errors.Is(err, ErrRead) || !errors.Is(err, tt.want) {
+				t.Errorf("second DecodeValue() error = %v, want %q wrapping %v", err, ErrRead, tt.want)
+			}
+			if _, err := dec.PeekKind(); !errors.Is(err, ErrRead) {
+				t.Errorf("PeekKind() error = %v, want %q", err, ErrRead)
+			}
+			if err := dec.ExpectEOF(); !errors.Is(err, ErrRead) {
+				t.Errorf("ExpectEOF() error = %v, want %q", err, ErrRead)
+			}
+		})
+	}
+
+	dec := NewDecoder(io.MultiReader(strings.NewReader("d1:a10:01234"), iotest.ErrReader(boom)))
+	for _, err := range dec.Entries() {
+		if err != nil {
+			t.Fatalf("Entries() error = %v", err)
+		}
+		r, _, err := dec.StringReader()
+		if err != nil {
+			t.Fatalf("StringReader() error = %v", err)
+		}
+		if _, err := io.ReadAll(r); !errors.Is(err, ErrRead) || !errors.Is(err, boom) {
+			t.Errorf("reading string error = %v, want %q wrapping %v", err, ErrRead, boom)
+		}
+		break
+	}
+
+	// Truncation is still reported as such, not as a read error.
+	_, err := NewDecoder(iotest.OneByteReader(strings.NewReader("d3:bari1"))).DecodeValue()
+	if !errors.Is(err, ErrSyntaxEOF) || errors.Is(err, ErrRead) {
+		t.Errorf("DecodeValue() of truncated input error = %v, want %q", err, ErrSyntaxEOF)
+	}
+}
@@ -0,0 +1,251 @@
+// Package scanner implements a zero-allocation tokenizer for bencode.
+//
+// A Scanner walks a byte slice and reports token boundaries as offsets into
+// it, without copying or converting any data. It checks the lexical syntax of
+// each token but leaves semantic rules, such as dictionary keys being sorted
+// strings or integers being minimally encoded, to its callers.
+package scanner
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kind identifies a token.
+type Kind uint8
+
+const (
+	// Invalid is the zero Kind.
+	Invalid Kind = iota
+	// String is a byte string such as 4:spam.
+	String
+	// Integer is an integer such as i42e.
+	Integer
+	// ListStart is the 'l' opening a list.
+	ListStart
+	// DictStart is the 'd' opening a dictionary.
+	DictStart
+	// End is the 'e' closing a list or dictionary.
+	End
+)
+
+// String returns the name of the kind.
+func (k Kind) String() string {
+	switch k {
+	case String:
+		return "string"
+	case Integer:
+		return "integer"
+	case ListStart:
+		return "list start"
+	case DictStart:
+		return "dict start"
+	case End:
+		return "end"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// Token describes one token as offsets into the scanned data.
+type Token struct {
+	Kind Kind
+	// Offset and Len delimit the whole token, e.g. all of "4:spam".
+	Offset int
+	Len    int
+	// ValueOffset and ValueLen delimit the payload: the bytes of a string
+	// or the digits of an integer. They are zero for other kinds.
+	ValueOffset int
+	ValueLen    int
+}
+
+// End returns the offset just past the token.
+func (t Token) End() int {
+	return t.Offset + t.Len
+}
+
+// ErrUnexpectedEOF is wrapped by SyntaxErrors caused by truncated input.
+var ErrUnexpectedEOF = errors.New("unexpected end of input")
+
+// SyntaxError describes malformed input.
+type SyntaxError struct {
+	// Offset is the byte offset at which the error was detected.
+	Offset int
+	Msg    string
+	Err    error
+}
+
+// Error returns a string representation of the syntax error.
+func (e *SyntaxError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("offset %d: %s: %v", e.Offset, e.Msg, e.Err)
+	}
+	return fmt.Sprintf("offset %d: %s", e.Offset, e.Msg)
+}
+
+// Unwrap returns the underlying error, if any.
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// Scanner tokenizes bencode held in a byte slice. The zero value scans an
+// empty input; use New or Reset to scan data.
+type Scanner struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+// New returns a Scanner over data.
+func New(data []byte) *Scanner {
+	return &Scanner{data: data}
+}
+
+// Reset makes s scan data from the beginning, so a Scanner can be reused
+// without allocating.
+func (s *Scanner) Reset(data []byte) {
+	*s = Scanner{data: data}
+}
+
+// Offset returns the offset of the next token.
+func (s *Scanner) Offset() int {
+	return s.pos
+}
+
+// Depth returns the number of lists and dictionaries currently open.
+func (s *Scanner) Depth() int {
+	return s.depth
+}
+
+// More reports whether any input remains.
+func (s *Scanner) More() bool {
+	return s.pos < len(s.data)
+}
+
+// Peek returns the kind of the next token without consuming it, or Invalid
+// at the end of input or before an unrecognized byte.
+func (s *Scanner) Peek() Kind {
+	if s.pos >= len(s.data) {
+		return Invalid
+	}
+	switch c := s.data[s.pos]; {
+	case c >= '0' && c <= '9':
+		return String
+	case c == 'i':
+		return Integer
+	case c == 'l':
+		return ListStart
+	case c == 'd':
+		return DictStart
+	case c == 'e':
+		return End
+	default:
+		return Invalid
+	}
+}
+
+// Next consumes and returns the next token.
+func (s *Scanner) Next() (Token, error) {
+	start := s.pos
+	if start >= len(s.data) {
+		return Token{}, s.errorf(start, ErrUnexpectedEOF, "expected token")
+	}
+	switch kind := s.Peek(); kind {
+	case String:
+		colon := s.digits(start, false)
+		if colon >= len(s.data) {
+			return Token{}, s.errorf(start, ErrUnexpectedEOF, "unterminated string length")
+		}
+		if s.data[colon] != ':' {
+			return Token{}, s.errorf(colon, nil, "invalid string length")
+		}
+		length, ok := parseLength(s.data[start:colon])
+		if !ok {
+			return Token{}, s.errorf(start, nil, "string length out of range")
+		}
+		if length > len(s.data)-colon-1 {
+			return Token{}, s.errorf(start, ErrUnexpectedEOF, fmt.Sprintf("expected %d bytes for string", length))
+		}
+		s.pos = colon + 1 + length
+		return Token{Kind: String, Offset: start, Len: s.pos - start, ValueOffset: colon + 1, ValueLen: length}, nil
+	case Integer:
+		end := s.digits(start+1, true)
+		if end >= len(s.data) {
+			return Token{}, s.errorf(start, ErrUnexpectedEOF, "integer not terminated by 'e'")
+		}
+		if s.data[end] != 'e' || end == start+1 || (end == start+2 && s.data[start+1] == '-') {
+			return Token{}, s.errorf(start, nil, "invalid integer")
+		}
+		s.pos = end + 1
+		return Token{Kind: Integer, Offset: start, Len: s.pos - start, ValueOffset: start + 1, ValueLen: end - start - 1}, nil
+	case ListStart, DictStart:
+		s.pos++
+		s.depth++
+		return Token{Kind: kind, Offset: start, Len: 1}, nil
+	case End:
+		if s.depth == 0 {
+			return Token{}, s.errorf(start, nil, "unexpected 'e' outside list or dictionary")
+		}
+		s.pos++
+		s.depth--
+		return Token{Kind: End, Offset: start, Len: 1}, nil
+	default:
+		return Token{}, s.errorf(start, nil, fmt.Sprintf("unexpected byte %q", s.data[start]))
+	}
+}
+
+// Skip consumes one complete value, including everything nested inside a
+// list or dictionary, and returns its token span as a String, Integer,
+// ListStart or DictStart token whose Len covers the whole value.
+func (s *Scanner) Skip() (Token, error) {
+	tok, err := s.Next()
+	if err != nil {
+		return Token{}, err
+	}
+	switch tok.Kind {
+	case ListStart, DictStart:
+		depth := s.depth
+		for s.depth >= depth {
+			if _, err := s.Next(); err != nil {
+				return Token{}, err
+			}
+		}
+		tok.Len = s.pos - tok.Offset
+		return tok, nil
+	case End:
+		return Token{}, s.errorf(tok.Offset, nil, "expected value, got 'e'")
+	default:
+		return tok, nil
+	}
+}
+
+// digits returns the offset of the first non-digit at or after i, allowing
+// a leading '-' if signed is true.
+func (s *Scanner) digits(i int, signed bool) int {
+	if signed && i < len(s.data) && s.data[i] == '-' {
+		i++
+	}
+	for i < len(s.data) && s.data[i] >= '0' && s.data[i] <= '9' {
+		i++
+	}
+	return i
+}
+
+func (s *Scanner) errorf(offset int, err error, msg string) error {
+	return &SyntaxError{Offset: offset, Msg: msg, Err: err}
+}
+
+// parseLength parses decimal digits into a non-negative int without
+// allocating.
+func parseLength(digits []byte) (int, bool) {
+	const maxInt = int(^uint(0) >> 1)
+	n := 0
+	for _, c := range digits {
+		d := int(c - '0')
+		if n > (maxInt-d)/10 {
+			return 0, false
+		}
+		n = n*10 + d
+	}
+	return n, true
+}
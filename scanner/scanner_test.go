@@ -0,0 +1,102 @@
+package scanner
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestScannerNext(t *testing.T) {
+	data := []byte("d4:spaml1:ai-12eee")
+	expected := []Token{
+		{Kind: DictStart, Offset: 0, Len: 1},
+		{Kind: String, Offset: 1, Len: 6, ValueOffset: 3, ValueLen: 4},
+		{Kind: ListStart, Offset: 7, Len: 1},
+		{Kind: String, Offset: 8, Len: 3, ValueOffset: 10, ValueLen: 1},
+		{Kind: Integer, Offset: 11, Len: 5, ValueOffset: 12, ValueLen: 3},
+		{Kind: End, Offset: 16, Len: 1},
+		{Kind: End, Offset: 17, Len: 1},
+	}
+
+	s := New(data)
+	var got []Token
+	for s.More() {
+		tok, err := s.Next()
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		got = append(got, tok)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Next() tokens = %+v, want %+v", got, expected)
+	}
+	if string(data[got[4].ValueOffset:][:got[4].ValueLen]) != "-12" {
+		t.Errorf("integer payload = %q", data[got[4].ValueOffset:][:got[4].ValueLen])
+	}
+}
+
+func TestScannerSkip(t *testing.T) {
+	s := New([]byte("ld1:ai1eeli2eee4:tail"))
+	if _, err := s.Next(); err != nil {
+		t.Fatal(err)
+	}
+	tok, err := s.Skip()
+	if err != nil {
+		t.Fatalf("Skip() error = %v", err)
+	}
+	if tok.Kind != DictStart || tok.Offset != 1 || tok.Len != 8 {
+		t.Errorf("Skip() = %+v", tok)
+	}
+	if tok, err = s.Skip(); err != nil || tok.Kind != ListStart || tok.Len != 5 {
+		t.Errorf("Skip() = %+v, %v", tok, err)
+	}
+	if _, err := s.Skip(); err == nil {
+		t.Errorf("Skip() at 'e' expected an error")
+	}
+}
+
+func TestScannerErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		eof   bool
+	}{
+		{input: "", eof: true},
+		{input: "4:ab", eof: true},
+		{input: "4ab", eof: false},
+		{input: "12", eof: true},
+		{input: "i12", eof: true},
+		{input: "ie", eof: false},
+		{input: "i-e", eof: false},
+		{input: "i1-2e", eof: false},
+		{input: "e", eof: false},
+		{input: "x", eof: false},
+		{input: "99999999999999999999999:", eof: false},
+	}
+	for _, tt := range tests {
+		_, err := New([]byte(tt.input)).Next()
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Next(%q) error = %v, want *SyntaxError", tt.input, err)
+			continue
+		}
+		if got := errors.Is(err, ErrUnexpectedEOF); got != tt.eof {
+			t.Errorf("Next(%q) EOF = %v, want %v", tt.input, got, tt.eof)
+		}
+	}
+}
+
+func TestScannerZeroAlloc(t *testing.T) {
+	data := []byte("d4:infod6:lengthi170917888e4:name4:spamee")
+	var s Scanner
+	allocs := testing.AllocsPerRun(100, func() {
+		s.Reset(data)
+		for s.More() {
+			if _, err := s.Next(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("scanning allocated %v times, want 0", allocs)
+	}
+}
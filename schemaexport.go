@@ -0,0 +1,242 @@
+package bencode
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ExportSchema returns a JSON Schema (draft 2020-12) describing the bencode
+// values the type of v encodes to and decodes from, for documenting tracker
+// APIs and other protocols built with this package. v is only used for its
+// type; a zero value or a nil pointer will do.
+//
+// Bencode strings are described as JSON strings, with lengths counted in
+// bytes, lists as arrays and dictionaries as objects, whose properties are
+// the keys of struct fields and whose required properties are those of
+// fields tagged required. Since a JSON string may stand for arbitrary bytes,
+// every schema also names its bencode kind in an "x-bencode-kind" keyword.
+// Named struct types are described once under "$defs" and referenced from
+// each use, which lets recursive types be described. Types that cannot be
+// encoded, such as bool or a map with non-string keys, and malformed tags,
+// are reported with an ErrUsage error.
+func ExportSchema(v any) ([]byte, error) {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil, &Error{Type: ErrUsage, Msg: "ExportSchema(nil): pass a value of the type to describe"}
+	}
+	x := &schemaExporter{defs: make(map[string]any), names: make(map[reflect.Type]string), taken: make(map[string]bool)}
+	root, err := x.schema(t)
+	if err != nil {
+		return nil, err
+	}
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	if len(x.defs) > 0 {
+		root["$defs"] = x.defs
+	}
+	return json.MarshalIndent(root, "", "  ")
+}
+
+// schemaExporter builds the JSON Schema of a type, collecting the schemas of
+// named struct types in defs.
+type schemaExporter struct {
+	defs  map[string]any
+	names map[reflect.Type]string
+	taken map[string]bool
+}
+
+// kindSchema returns a schema of the JSON type jsonType for the bencode kind.
+func kindSchema(jsonType string, kind Kind) map[string]any {
+	return map[string]any{"type": jsonType, "x-bencode-kind": kind.String()}
+}
+
+// schema returns the schema of values of type t.
+func (x *schemaExporter) schema(t reflect.Type) (map[string]any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == rawMessageType, t.Kind() == reflect.Interface:
+		return map[string]any{}, nil
+	case t == numberType:
+		return kindSchema("integer", KindInteger), nil
+	case t == uint64StringType:
+		s := kindSchema("string", KindString)
+		s["pattern"] = "^(0|[1-9][0-9]*)$"
+		return s, nil
+	case isSQLNull(t) && !sqlNullHoldsBencode(t):
+		return nil, &Error{Type: ErrUsage, Msg: fmt.Sprintf("type %s has no bencode form", t)}
+	case t.Implements(optionalType), isSQLNull(t):
+		return x.schema(t.Field(0).Type)
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return kindSchema("string", KindString), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s := kindSchema("integer", KindInteger)
+		if bits := t.Bits(); bits < 64 {
+			s["minimum"], s["maximum"] = int64(-1)<<(bits-1), int64(1)<<(bits-1)-1
+		}
+		return s, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s := kindSchema("integer", KindInteger)
+		s["minimum"] = 0
+		if bits := t.Bits(); bits < 64 {
+			s["maximum"] = uint64(1)<<bits - 1
+		} else {
+			s["maximum"] = uint64(math.MaxInt64) // the decoder's limit without UseNumber
+		}
+		return s, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			s := kindSchema("string", KindString)
+			if t.Kind() == reflect.Array {
+				s["minLength"], s["maxLength"] = t.Len(), t.Len()
+			}
+			return s, nil
+		}
+		if t.Kind() == reflect.Array {
+			break
+		}
+		return x.listSchema(t.Elem())
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, &Error{Type: ErrUsage, Msg: fmt.Sprintf("map type %s has non-string keys", t)}
+		}
+		return x.dictSchema(t.Elem())
+	case reflect.Struct:
+		return x.structSchema(t)
+	case reflect.Func:
+		switch {
+		case isSeq(t):
+			return x.listSchema(t.In(0).In(0))
+		case isSeq2(t):
+			return x.dictSchema(t.In(0).In(1))
+		}
+	}
+	return nil, &Error{Type: ErrUsage, Msg: fmt.Sprintf("type %s has no bencode form", t)}
+}
+
+// listSchema returns the schema of a list of elem.
+func (x *schemaExporter) listSchema(elem reflect.Type) (map[string]any, error) {
+	items, err := x.schema(elem)
+	if err != nil {
+		return nil, err
+	}
+	s := kindSchema("array", KindList)
+	s["items"] = items
+	return s, nil
+}
+
+// dictSchema returns the schema of a dictionary of arbitrary keys holding
+// elem values.
+func (x *schemaExporter) dictSchema(elem reflect.Type) (map[string]any, error) {
+	values, err := x.schema(elem)
+	if err != nil {
+		return nil, err
+	}
+	s := kindSchema("object", KindDict)
+	s["additionalProperties"] = values
+	return s, nil
+}
+
+// structSchema returns a reference to the schema of the named struct type t
+// in defs, adding it if need be, or the schema itself for unnamed structs.
+func (x *schemaExporter) structSchema(t reflect.Type) (map[string]any, error) {
+	if t.Name() == "" {
+		return x.objectSchema(t)
+	}
+	name, ok := x.names[t]
+	if !ok {
+		name = t.String()
+		for i := 2; x.taken[name]; i++ {
+			name = t.String() + "_" + strconv.Itoa(i)
+		}
+		x.names[t], x.taken[name] = name, true
+		x.defs[name] = nil // placeholder, so recursive uses find the name
+		s, err := x.objectSchema(t)
+		if err != nil {
+			return nil, err
+		}
+		x.defs[name] = s
+	}
+	ref := strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+	return map[string]any{"$ref": "#/$defs/" + ref}, nil
+}
+
+// objectSchema returns the schema of the dictionary a struct type t
+// encodes to.
+func (x *schemaExporter) objectSchema(t reflect.Type) (map[string]any, error) {
+	s := kindSchema("object", KindDict)
+	props := make(map[string]any)
+	var required []string
+	if err := x.addFields(t, s, props, &required); err != nil {
+		return nil, err
+	}
+	if len(props) > 0 {
+		s["properties"] = props
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s, nil
+}
+
+// addFields adds the fields of the struct type t to the object schema s,
+// whose properties are props, merging in those of inline structs.
+func (x *schemaExporter) addFields(t reflect.Type, s, props map[string]any, required *[]string) error {
+	fields, err := TypeInfo(t)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		fieldType := f.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		var fs map[string]any
+		switch {
+		case f.Inline && fieldType.Kind() == reflect.Struct && fieldType != rawMessageType:
+			if err := x.addFields(fieldType, s, props, required); err != nil {
+				return err
+			}
+			continue
+		case f.Inline || f.Rest:
+			if fieldType.Kind() != reflect.Map {
+				s["additionalProperties"] = map[string]any{}
+				continue
+			}
+			if s["additionalProperties"], err = x.schema(fieldType.Elem()); err != nil {
+				return err
+			}
+			continue
+		case f.Union != nil:
+			var alts []any
+			for _, kind := range f.Union {
+				alt, _ := unionAlternative(fieldType, kind)
+				as, err := x.schema(alt.typ)
+				if err != nil {
+					return err
+				}
+				alts = append(alts, as)
+			}
+			fs = map[string]any{"oneOf": alts}
+		case f.List:
+			fs = kindSchema("array", KindList)
+			fs["items"] = map[string]any{"type": "integer", "x-bencode-kind": KindInteger.String(), "minimum": 0, "maximum": 255}
+		default:
+			if fs, err = x.schema(f.Type); err != nil {
+				return &Error{Type: typeOf(err), Msg: fmt.Sprintf("field %s of %s", f.Name, t), FieldName: f.Key, WrappedErr: err}
+			}
+		}
+		props[f.Key] = fs
+		if f.Required {
+			*required = append(*required, f.Key)
+		}
+	}
+	return nil
+}
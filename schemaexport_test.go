@@ -0,0 +1,70 @@
+package bencode
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestExportSchema(t *testing.T) {
+	type peer struct {
+		IP   string `bencode:"ip"`
+		Port uint16 `bencode:"port,required"`
+	}
+	type peers struct {
+		List    []peer `bencode:"list"`
+		Compact string `bencode:"string"`
+	}
+	type response struct {
+		Interval int64                 `bencode:"interval,required"`
+		Peers    peers                 `bencode:"peers,union=list|string"`
+		Flags    []byte                `bencode:"flags,list"`
+		Extra    map[string]RawMessage `bencode:",rest"`
+		Next     *response             `bencode:"next"`
+	}
+	out, err := ExportSchema(&response{})
+	if err != nil {
+		t.Fatalf("ExportSchema() error = %v", err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatalf("ExportSchema() output is not JSON: %v\n%s", err, out)
+	}
+	if doc["$ref"] != "#/$defs/bencode.response" {
+		t.Errorf("$ref = %v, want the response definition", doc["$ref"])
+	}
+	defs := doc["$defs"].(map[string]any)
+	resp := defs["bencode.response"].(map[string]any)
+	props := resp["properties"].(map[string]any)
+
+	if got := resp["required"]; !reflect.DeepEqual(got, []any{"interval"}) {
+		t.Errorf("required = %v, want [interval]", got)
+	}
+	if got := resp["additionalProperties"]; !reflect.DeepEqual(got, map[string]any{}) {
+		t.Errorf("additionalProperties = %v, want {} for the rest field", got)
+	}
+	if got := props["next"]; !reflect.DeepEqual(got, map[string]any{"$ref": "#/$defs/bencode.response"}) {
+		t.Errorf("next = %v, want a reference to response", got)
+	}
+	if got := props["flags"].(map[string]any)["x-bencode-kind"]; got != "list" {
+		t.Errorf("flags kind = %v, want list", got)
+	}
+	alts := props["peers"].(map[string]any)["oneOf"].([]any)
+	if len(alts) != 2 || alts[0].(map[string]any)["x-bencode-kind"] != "list" || alts[1].(map[string]any)["x-bencode-kind"] != "string" {
+		t.Errorf("peers oneOf = %v, want list and string alternatives", alts)
+	}
+	port := defs["bencode.peer"].(map[string]any)["properties"].(map[string]any)["port"].(map[string]any)
+	if port["maximum"] != float64(65535) || port["minimum"] != float64(0) {
+		t.Errorf("port = %v, want bounds of uint16", port)
+	}
+
+	type badTag struct {
+		A int `bencode:"a,order=x"`
+	}
+	for _, v := range []any{nil, true, map[int]string{}, struct{ F float64 }{}, badTag{}} {
+		if _, err := ExportSchema(v); !errors.Is(err, ErrUsage) {
+			t.Errorf("ExportSchema(%T) error = %v, want %q", v, err, ErrUsage)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package bencode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// UseLogger makes the Decoder emit a Debug-level trace event to l for every
+// top-level value it decodes, recording the value's kind, its size in bytes,
+// the number of values nested in it and the decode duration, or for
+// failures the error, its type and the path of dictionary keys and list
+// indexes leading to it. Passing nil stops tracing.
+func (d *Decoder) UseLogger(l *slog.Logger) {
+	d.logger = l
+}
+
+// UseLogger makes the Encoder emit a Debug-level trace event to l for every
+// call to Encode, recording the Go type encoded, the bytes written and the
+// duration, or for failures the error, its type and path. Passing nil stops
+// tracing.
+func (e *Encoder) UseLogger(l *slog.Logger) {
+	e.logger = l
+}
+
+// traceDecode logs a decode that started at input offset start.
+func (d *Decoder) traceDecode(start int64, began time.Time, values int, kind Kind, err error) {
+	if d.logger == nil || !d.logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.Int64("offset", start),
+		slog.Int64("bytes", d.offset-start),
+		slog.Duration("duration", time.Since(began)),
+	}
+	if err != nil {
+		attrs = append(attrs, errorAttrs(err)...)
+		d.logger.LogAttrs(context.Background(), slog.LevelDebug, "bencode: decode failed", attrs...)
+		return
+	}
+	attrs = append(attrs, slog.String("kind", kind.String()), slog.Int("values", values))
+	d.logger.LogAttrs(context.Background(), slog.LevelDebug, "bencode: decoded value", attrs...)
+}
+
+// traceEncode logs an Encode call.
+func (e *Encoder) traceEncode(v any, n int64, began time.Time, err error) {
+	if e.logger == nil || !e.logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("type", fmt.Sprintf("%T", v)),
+		slog.Int64("bytes", n),
+		slog.Duration("duration", time.Since(began)),
+	}
+	if err != nil {
+		attrs = append(attrs, errorAttrs(err)...)
+		e.logger.LogAttrs(context.Background(), slog.LevelDebug, "bencode: encode failed", attrs...)
+		return
+	}
+	e.logger.LogAttrs(context.Background(), slog.LevelDebug, "bencode: encoded value", attrs...)
+}
+
+// errorAttrs describes err for a trace event.
+func errorAttrs(err error) []slog.Attr {
+	attrs := []slog.Attr{slog.String("error", err.Error())}
+	var bErr *Error
+	if errors.As(err, &bErr) {
+		attrs = append(attrs, slog.String("error_type", string(bErr.Type)))
+		if path := bErr.Path(); path != "" {
+			attrs = append(attrs, slog.String("path", path))
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,74 @@
+package bencode
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// newTestLogger returns a logger writing text records without timestamps or durations.
+func newTestLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "duration" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+func TestDecoderUseLogger(t *testing.T) {
+	type Info struct {
+		Length int `bencode:"length"`
+	}
+	type Torrent struct {
+		Info Info `bencode:"info"`
+	}
+
+	var buf bytes.Buffer
+	decoder := NewDecoder(strings.NewReader("d4:infod6:lengthi1eeed4:infod6:length1:xee"))
+	decoder.UseLogger(newTestLogger(&buf))
+
+	var got Torrent
+	if err := decoder.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if err := decoder.Decode(&got); err == nil {
+		t.Fatal("expected type mismatch")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want 2:\n%s", len(lines), buf.String())
+	}
+	expected := `level=DEBUG msg="bencode: decoded value" offset=0 bytes=21 kind=dictionary values=5`
+	if lines[0] != expected {
+		t.Errorf("log line = %s, want %s", lines[0], expected)
+	}
+	for _, want := range []string{`msg="bencode: decode failed"`, "offset=21", `error_type="unmarshal type mismatch"`, "path=info.length"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("log line %s does not contain %s", lines[1], want)
+		}
+	}
+}
+
+func TestEncoderUseLogger(t *testing.T) {
+	var buf bytes.Buffer
+	var out bytes.Buffer
+	encoder := NewEncoder(&out)
+	encoder.UseLogger(newTestLogger(&buf))
+
+	if err := encoder.Encode(map[string]int{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "d1:ai1ee" {
+		t.Errorf("Encode() wrote %q", out.String())
+	}
+	expected := `level=DEBUG msg="bencode: encoded value" type=map[string]int bytes=8`
+	if got := strings.TrimSpace(buf.String()); got != expected {
+		t.Errorf("log line = %s, want %s", got, expected)
+	}
+}
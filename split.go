@@ -0,0 +1,56 @@
+package bencode
+
+import (
+	"errors"
+
+	"github.com/stupoid/bencode/scanner"
+)
+
+// Split partitions data, which holds zero or more concatenated bencode
+// values, into one slice per value without decoding them. This suits
+// journals and append-only logs of bencoded records. The returned slices
+// alias data.
+//
+// The values are checked for syntax only, as by the scanner package; use
+// CheckCanonical or Unmarshal to validate them further.
+func Split(data []byte) ([][]byte, error) {
+	var values [][]byte
+	var s scanner.Scanner
+	s.Reset(data)
+	for s.More() {
+		tok, err := s.Skip()
+		if err != nil {
+			return nil, scanError(err)
+		}
+		values = append(values, data[tok.Offset:tok.End():tok.End()])
+	}
+	return values, nil
+}
+
+// ScanValues is a bufio.SplitFunc that yields each complete bencode value of
+// a stream of concatenated values, the streaming equivalent of Split:
+//
+//	s := bufio.NewScanner(r)
+//	s.Split(bencode.ScanValues)
+//	for s.Scan() {
+//		record := s.Bytes()
+//	}
+//
+// A value cut short by the end of the input is reported as an ErrSyntaxEOF
+// error. Values larger than the bufio.Scanner's buffer, 64 KiB by default,
+// need a larger one set with its Buffer method.
+func ScanValues(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if len(data) == 0 {
+		return 0, nil, nil
+	}
+	var s scanner.Scanner
+	s.Reset(data)
+	tok, err := s.Skip()
+	if err != nil {
+		if errors.Is(err, scanner.ErrUnexpectedEOF) && !atEOF {
+			return 0, nil, nil // request more data
+		}
+		return 0, nil, scanError(err)
+	}
+	return tok.End(), data[:tok.End()], nil
+}
@@ -0,0 +1,68 @@
+package bencode
+
+import (
+	"bufio"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+		errType  ErrorType
+	}{
+		{input: "", expected: nil},
+		{input: "i1e", expected: []string{"i1e"}},
+		{input: "d1:ai1ee4:spamli1eli2eeei-3e", expected: []string{"d1:ai1ee", "4:spam", "li1eli2eee", "i-3e"}},
+		{input: "i1e4:spa", errType: ErrSyntaxEOF},
+		{input: "i1ee", errType: ErrSyntax},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			values, err := Split([]byte(tt.input))
+			if tt.errType != "" {
+				var bErr *Error
+				if !errors.As(err, &bErr) || bErr.Type != tt.errType {
+					t.Errorf("Split() error = %v, want %q", err, tt.errType)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Split() error = %v", err)
+			}
+			var got []string
+			for _, v := range values {
+				got = append(got, string(v))
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Split() = %q, want %q", got, tt.expected)
+			}
+
+			s := bufio.NewScanner(iotest.OneByteReader(strings.NewReader(tt.input)))
+			s.Split(ScanValues)
+			got = nil
+			for s.Scan() {
+				got = append(got, s.Text())
+			}
+			if err := s.Err(); err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Scan() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	s := bufio.NewScanner(strings.NewReader("i1e4:spa"))
+	s.Split(ScanValues)
+	for s.Scan() {
+	}
+	var bErr *Error
+	if !errors.As(s.Err(), &bErr) || bErr.Type != ErrSyntaxEOF {
+		t.Errorf("Scan() of truncated stream error = %v, want %q", s.Err(), ErrSyntaxEOF)
+	}
+}
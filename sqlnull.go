@@ -0,0 +1,79 @@
+package bencode
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// isSQLNull reports whether typ is one of the Null types of database/sql,
+// such as sql.NullInt64, sql.NullString or sql.Null[T]. Like Optional, those
+// holding a string or an integer are decoded from the value itself and
+// their struct fields are omitted when not valid, so that database-backed
+// code can tell a missing key apart from a zero value without a wrapper
+// type. The others, such as sql.NullTime and sql.NullBool, hold values with
+// no bencode form and are rejected rather than encoded as structs.
+func isSQLNull(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Struct && typ.PkgPath() == "database/sql" && strings.HasPrefix(typ.Name(), "Null")
+}
+
+// sqlNullHoldsBencode reports whether the database/sql Null type typ holds
+// a string or an integer.
+func sqlNullHoldsBencode(typ reflect.Type) bool {
+	switch typ.Field(0).Type.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
+
+// scanSQLNull assigns srcData to the database/sql Null value destVal with
+// its Scan method. Strings only scan into Null types holding strings, and
+// integers into those holding integers.
+func (d *Decoder) scanSQLNull(destVal reflect.Value, srcData any) error {
+	held := destVal.Field(0).Kind()
+	var src any
+	switch s := srcData.(type) {
+	case []byte:
+		if held == reflect.String {
+			str, err := d.decodeString(s)
+			if err != nil {
+				return err
+			}
+			src = str
+		}
+	case int64:
+		if held >= reflect.Int && held <= reflect.Uint64 {
+			src = s
+		}
+	case Number:
+		if held >= reflect.Int && held <= reflect.Uint64 {
+			src = string(s)
+		}
+	}
+	if src == nil {
+		return &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("cannot decode %s into %s", kindOfDecoded(srcData), destVal.Type())}
+	}
+	if err := destVal.Addr().Interface().(sql.Scanner).Scan(src); err != nil {
+		return &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("scanning into %s", destVal.Type()), WrappedErr: err}
+	}
+	return nil
+}
+
+// sqlNullValue returns the value held by the database/sql Null value v, or
+// nil if it is not valid.
+func sqlNullValue(v reflect.Value) (any, error) {
+	if !sqlNullHoldsBencode(v.Type()) {
+		return nil, &Error{Type: ErrEncodeUnsupportedType, Msg: fmt.Sprintf("cannot marshal %s, which holds neither a string nor an integer", v.Type())}
+	}
+	held, err := v.Interface().(driver.Valuer).Value()
+	if err != nil {
+		return nil, &Error{Type: ErrEncodeUnsupportedType, Msg: fmt.Sprintf("getting value of %s", v.Type()), WrappedErr: err}
+	}
+	return held, nil
+}
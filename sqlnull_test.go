@@ -0,0 +1,77 @@
+package bencode
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+type indexedTorrent struct {
+	Seeders   *int64           `bencode:"complete"`
+	Name      *string          `bencode:"name"`
+	Leechers  sql.NullInt64    `bencode:"incomplete"`
+	Comment   sql.NullString   `bencode:"comment"`
+	Downloads sql.Null[uint32] `bencode:"downloaded"`
+}
+
+func TestDecodePointersAndSQLNull(t *testing.T) {
+	var got indexedTorrent
+	if err := Unmarshal([]byte("d7:comment0:8:completei0e10:incompletei0e4:name4:spame"), &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got.Seeders == nil || *got.Seeders != 0 || got.Name == nil || *got.Name != "spam" {
+		t.Errorf("Unmarshal() pointers = %v, %v", got.Seeders, got.Name)
+	}
+	if got.Leechers != (sql.NullInt64{Valid: true}) || got.Comment != (sql.NullString{Valid: true}) || got.Downloads.Valid {
+		t.Errorf("Unmarshal() nulls = %+v, %+v, %+v", got.Leechers, got.Comment, got.Downloads)
+	}
+
+	data, err := Marshal(got)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want := "d7:comment0:8:completei0e10:incompletei0e4:name4:spame"; string(data) != want {
+		t.Errorf("Marshal() = %q, want %q", data, want)
+	}
+	if data, err := Marshal(indexedTorrent{}); err != nil || string(data) != "de" {
+		t.Errorf("Marshal() of empty = %q, %v, want \"de\"", data, err)
+	}
+
+	var bErr *Error
+	if err := Unmarshal([]byte("d10:incomplete1:5e"), &got); !errors.As(err, &bErr) || bErr.Type != ErrUnmarshalType {
+		t.Errorf("Unmarshal() string into sql.NullInt64 error = %v, want %q", err, ErrUnmarshalType)
+	}
+	if _, err := Marshal(sql.NullString{}); !errors.Is(err, ErrEncodeNil) {
+		t.Errorf("Marshal() of invalid sql.NullString error = %v, want %q", err, ErrEncodeNil)
+	}
+
+	// Null types holding values with no bencode form are rejected, whether
+	// valid or not.
+	type event struct {
+		At sql.NullTime `bencode:"at"`
+	}
+	for _, v := range []any{event{}, event{At: sql.NullTime{Time: time.Unix(1, 0), Valid: true}}, sql.NullBool{Valid: true}, sql.NullFloat64{}} {
+		if _, err := Marshal(v); !errors.Is(err, ErrEncodeUnsupportedType) {
+			t.Errorf("Marshal(%+v) error = %v, want %q", v, err, ErrEncodeUnsupportedType)
+		}
+	}
+	var flag sql.NullBool
+	if err := Unmarshal([]byte("i1e"), &flag); !errors.Is(err, ErrUnmarshalType) {
+		t.Errorf("Unmarshal() into sql.NullBool error = %v, want %q", err, ErrUnmarshalType)
+	}
+	if _, err := ExportSchema(event{}); !errors.Is(err, ErrUsage) {
+		t.Errorf("ExportSchema() with sql.NullTime error = %v, want %q", err, ErrUsage)
+	}
+}
+
+func TestEncodePointerCycle(t *testing.T) {
+	type node struct {
+		Next *node `bencode:"next"`
+	}
+	n := &node{}
+	n.Next = n
+	if _, err := Marshal(n); !errors.Is(err, ErrEncodeCycle) {
+		t.Errorf("Marshal() of cyclic pointer error = %v, want %q", err, ErrEncodeCycle)
+	}
+}
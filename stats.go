@@ -0,0 +1,80 @@
+package bencode
+
+import "github.com/stupoid/bencode/scanner"
+
+// DecodeStats describes the shape of the values read by a Decoder.
+type DecodeStats struct {
+	// MaxDepth is the deepest list/dictionary nesting reached. A top-level
+	// list or dictionary has depth 1; a top-level string or integer has depth 0.
+	MaxDepth int
+	// Strings, Integers, Lists and Dicts count the values of each kind
+	// decoded, including dictionary keys and nested values.
+	Strings  int
+	Integers int
+	Lists    int
+	Dicts    int
+	// MaxStringLen is the length in bytes of the longest string, including
+	// dictionary keys.
+	MaxStringLen int
+	// Bytes is the number of input bytes consumed by successfully decoded values.
+	Bytes int64
+}
+
+// Stats returns statistics accumulated over every value the Decoder has
+// decoded since it was created. Values that failed to decode contribute only
+// the nested values that completed before the error.
+func (d *Decoder) Stats() DecodeStats {
+	return d.stats
+}
+
+// enterContainer records entry into a list or dictionary.
+func (d *Decoder) enterContainer() {
+	d.depth++
+	if d.depth > d.stats.MaxDepth {
+		d.stats.MaxDepth = d.depth
+	}
+}
+
+// leaveContainer records exit from a list or dictionary.
+func (d *Decoder) leaveContainer() {
+	d.depth--
+}
+
+// Measure scans data, which must hold exactly one bencode value, and returns
+// the statistics a Decoder would report for it, without decoding anything.
+// It does not allocate, so servers accepting untrusted input such as
+// user-provided torrents can use it for admission control, rejecting data
+// that is too deep, has too many values or too large a string before paying
+// for a full decode. Measure checks the syntax only; dictionary key order is
+// left to the Decoder.
+func Measure(data []byte) (DecodeStats, error) {
+	var st DecodeStats
+	var s scanner.Scanner
+	s.Reset(data)
+	for {
+		tok, err := s.Next()
+		if err != nil {
+			return DecodeStats{}, scanError(err)
+		}
+		switch tok.Kind {
+		case scanner.String:
+			st.Strings++
+			st.MaxStringLen = max(st.MaxStringLen, tok.ValueLen)
+		case scanner.Integer:
+			st.Integers++
+		case scanner.ListStart:
+			st.Lists++
+		case scanner.DictStart:
+			st.Dicts++
+		}
+		st.MaxDepth = max(st.MaxDepth, s.Depth())
+		if s.Depth() == 0 {
+			break
+		}
+	}
+	if err := scanDone(&s); err != nil {
+		return DecodeStats{}, err
+	}
+	st.Bytes = int64(len(data))
+	return st, nil
+}
@@ -0,0 +1,67 @@
+package bencode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecoderStats(t *testing.T) {
+	input := "d4:listli1ei2eli3eee4:name4:spame"
+	dec := NewDecoder(strings.NewReader(input + "i7e"))
+	if _, err := dec.DecodeValue(); err != nil {
+		t.Fatalf("DecodeValue() error = %v", err)
+	}
+	want := DecodeStats{
+		MaxDepth:     3,
+		Strings:      3,
+		Integers:     3,
+		Lists:        2,
+		Dicts:        1,
+		MaxStringLen: 4,
+		Bytes:        int64(len(input)),
+	}
+	if got := dec.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+
+	if _, err := dec.DecodeValue(); err != nil {
+		t.Fatalf("DecodeValue() error = %v", err)
+	}
+	want.Integers++
+	want.Bytes += 3
+	if got := dec.Stats(); got != want {
+		t.Errorf("Stats() after second value = %+v, want %+v", got, want)
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	for _, input := range []string{
+		"d4:listli1ei2eli3eee4:name4:spame",
+		"i42e",
+		"11:hello world",
+		"lllleeee",
+	} {
+		dec := NewDecoder(strings.NewReader(input))
+		if _, err := dec.DecodeValue(); err != nil {
+			t.Fatalf("DecodeValue(%q) error = %v", input, err)
+		}
+		got, err := Measure([]byte(input))
+		if err != nil {
+			t.Fatalf("Measure(%q) error = %v", input, err)
+		}
+		if want := dec.Stats(); got != want {
+			t.Errorf("Measure(%q) = %+v, want %+v", input, got, want)
+		}
+	}
+
+	for _, input := range []string{"", "li1e", "i1ei2e", "x"} {
+		if _, err := Measure([]byte(input)); err == nil {
+			t.Errorf("Measure(%q) succeeded, want error", input)
+		}
+	}
+
+	data := []byte("d5:filesld6:lengthi1eee4:name4:spame")
+	if allocs := testing.AllocsPerRun(100, func() { _, _ = Measure(data) }); allocs != 0 {
+		t.Errorf("Measure() allocated %v times, want 0", allocs)
+	}
+}
@@ -0,0 +1,243 @@
+package bencode
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// Entries returns an iterator that reads the dictionary that is the next
+// value of the input one entry at a time, for dictionaries too large to
+// decode whole. For each entry it reads the key and yields it, leaving the
+// value unread: the loop body may decode it with Decode, DecodeValue or
+// Entries, stream a string value with StringReader, or do nothing, in which
+// case the value is skipped as if by Skip. Keys are checked for order and
+// duplicates as by Decode.
+//
+// The first error, which is yielded with an empty key, ends the iteration.
+// Breaking out of the loop leaves the rest of the dictionary unread, and the
+// Decoder cannot be used for anything else afterwards.
+func (d *Decoder) Entries() iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		if err := d.entries(yield); err != nil {
+			yield("", d.annotate(err))
+		}
+	}
+}
+
+func (d *Decoder) entries(yield func(string, error) bool) error {
+	if err := d.expectToken('d', "dictionary"); err != nil {
+		return err
+	}
+	var prevKey string
+	var seen map[string]bool // keys read so far, needed only if they may be unsorted
+	if d.unsortedKeys {
+		seen = make(map[string]bool)
+	}
+	for first := true; ; first = false {
+		if err := d.drainString(); err != nil {
+			return err
+		}
+		peeked, err := d.r.Peek(1)
+		if err != nil {
+			return d.readError(err, "dictionary not terminated by 'e'")
+		}
+		if peeked[0] == 'e' {
+			_, _ = d.r.Discard(1)
+			d.consumed("e")
+			d.stats.Dicts++
+			d.stats.Bytes += 2
+			return nil
+		}
+		if kindOfToken(peeked[0]) != KindString {
+			return &Error{Type: ErrStructureDict, Msg: fmt.Sprintf("dictionary key starting with %q is not a bencode string", peeked[0])}
+		}
+		keyOffset := d.offset
+		keyVal, err := d.decode()
+		if err != nil {
+			return err
+		}
+		key := d.internKey(keyVal.([]byte))
+		if err := d.checkKey(key, keyOffset); err != nil {
+			return err
+		}
+		if !first && key == prevKey || seen[key] {
+			return &Error{Type: ErrStructureDictKeyDup, Msg: fmt.Sprintf("key %q", key), WrappedErr: ErrDuplicateDictionaryKey, FieldName: key}
+		}
+		if !first && prevKey > key {
+			msg := fmt.Sprintf("key %q is not lexicographically after %q", key, prevKey)
+			if !d.unsortedKeys {
+				return &Error{Type: ErrStructureDictKeySort, Msg: msg, WrappedErr: ErrDictionaryKeysNotSorted, FieldName: key}
+			}
+			d.warn(Warning{Type: WarnUnsortedKey, Msg: msg, FieldName: key})
+		}
+		prevKey = key
+		if seen != nil {
+			seen[key] = true
+		}
+		if peeked, err := d.r.Peek(1); err != nil && !errors.Is(err, io.EOF) {
+			return d.readError(err, "peeking dictionary value")
+		} else if err != nil || peeked[0] == 'e' {
+			return &Error{Type: ErrStructureDictValue, Msg: "missing value", WrappedErr: ErrUnexpectedEOF, FieldName: key}
+		}
+
+		valueOffset := d.offset
+		if !yield(key, nil) {
+			return nil
+		}
+		if d.offset == valueOffset {
+			if err := d.skip(); err != nil {
+				return &Error{Type: typeOf(err), Msg: "skipping value", WrappedErr: err, FieldName: key}
+			}
+		}
+	}
+}
+
+// StringReader reads the length of the string that is the next value of the
+// input and returns a reader over its contents, so that a large string can
+// be processed without holding it in memory. The reader is valid until the
+// Decoder is next used; any contents left unread are then discarded.
+func (d *Decoder) StringReader() (io.Reader, int64, error) {
+	length, err := d.openString()
+	if err != nil {
+		return nil, 0, d.annotate(err)
+	}
+	return &stringReader{d: d, gen: d.readerGen}, length, nil
+}
+
+// openString reads the length of the next string and leaves its contents
+// pending.
+func (d *Decoder) openString() (int64, error) {
+	kind, err := d.PeekKind()
+	if err != nil {
+		return 0, err
+	}
+	if kind != KindString {
+		return 0, &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("expected string, found %s", kind)}
+	}
+	length, headerLen, err := d.readStringLength()
+	if err != nil {
+		return 0, err
+	}
+	d.pending = int64(length)
+	d.readerGen++
+	d.stats.Strings++
+	d.stats.MaxStringLen = max(d.stats.MaxStringLen, length)
+	d.stats.Bytes += int64(headerLen + length)
+	return int64(length), nil
+}
+
+// stringReader reads the contents of the string opened by StringReader.
+type stringReader struct {
+	d   *Decoder
+	gen int
+}
+
+func (sr *stringReader) Read(p []byte) (int, error) {
+	d := sr.d
+	if sr.gen != d.readerGen || d.pending == 0 {
+		return 0, io.EOF
+	}
+	if err := d.failed(); err != nil {
+		return 0, err
+	}
+	if int64(len(p)) > d.pending {
+		p = p[:d.pending]
+	}
+	n, err := d.r.Read(p)
+	d.consumedBytes(p[:n])
+	d.pending -= int64(n)
+	switch {
+	case errors.Is(err, io.EOF) && d.pending > 0:
+		err = &Error{Type: ErrSyntaxEOF, Msg: fmt.Sprintf("string ended %d bytes early", d.pending), WrappedErr: ErrUnexpectedEOF}
+	case err != nil && !errors.Is(err, io.EOF):
+		err = d.readError(err, "reading string")
+	}
+	return n, err
+}
+
+// drainString discards what is left of the string opened by StringReader.
+func (d *Decoder) drainString() error {
+	if d.pending == 0 {
+		return nil
+	}
+	_, err := io.CopyN(io.Discard, &stringReader{d: d, gen: d.readerGen}, d.pending)
+	if err != nil {
+		d.pending = 0
+		if errors.Is(err, io.EOF) {
+			return &Error{Type: ErrSyntaxEOF, Msg: "string not terminated", WrappedErr: ErrUnexpectedEOF}
+		}
+		return err
+	}
+	return nil
+}
+
+// Skip reads the next value of the input without decoding it. Strings are
+// discarded as they are read, so skipping needs little memory however large
+// the value is.
+func (d *Decoder) Skip() error {
+	return d.annotate(d.skip())
+}
+
+func (d *Decoder) skip() error {
+	kind, err := d.PeekKind()
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case KindString:
+		if _, err := d.openString(); err != nil {
+			return err
+		}
+		return d.drainString()
+	case KindList:
+		if err := d.expectToken('l', "list"); err != nil {
+			return err
+		}
+		for {
+			peeked, err := d.r.Peek(1)
+			if err != nil {
+				return d.readError(err, "list not terminated by 'e'")
+			}
+			if peeked[0] == 'e' {
+				_, _ = d.r.Discard(1)
+				d.consumed("e")
+				d.stats.Lists++
+				d.stats.Bytes += 2
+				return nil
+			}
+			if err := d.skip(); err != nil {
+				return err
+			}
+		}
+	case KindDict:
+		return d.entries(func(string, error) bool { return true })
+	default:
+		_, err := d.decode()
+		return err
+	}
+}
+
+// expectToken consumes the opening token of a container.
+func (d *Decoder) expectToken(token byte, what string) error {
+	kind, err := d.PeekKind()
+	if err != nil {
+		return err
+	}
+	if next, _ := d.r.Peek(1); next[0] != token {
+		return &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("expected %s, found %s", what, kind)}
+	}
+	_, _ = d.r.Discard(1)
+	d.consumed(string(token))
+	return nil
+}
+
+// typeOf returns the ErrorType of err, or ErrSyntax if it is not an *Error.
+func typeOf(err error) ErrorType {
+	var bErr *Error
+	if errors.As(err, &bErr) {
+		return bErr.Type
+	}
+	return ErrSyntax
+}
@@ -0,0 +1,98 @@
+package bencode
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecoderEntries(t *testing.T) {
+	input := "d1:ad1:xli1eee1:bi2e1:c5:hello1:d11:hello world1:e3:endei9e"
+	dec := NewDecoder(strings.NewReader(input))
+	var keys []string
+	var b int
+	var c, d string
+	for key, err := range dec.Entries() {
+		if err != nil {
+			t.Fatalf("Entries() error = %v", err)
+		}
+		keys = append(keys, key)
+		switch key {
+		case "b":
+			if err := dec.Decode(&b); err != nil {
+				t.Fatalf("Decode(%q) error = %v", key, err)
+			}
+		case "c":
+			r, n, err := dec.StringReader()
+			if err != nil || n != 5 {
+				t.Fatalf("StringReader() = %d, %v, want 5", n, err)
+			}
+			data, _ := io.ReadAll(r)
+			c = string(data)
+		case "d":
+			r, _, err := dec.StringReader()
+			if err != nil {
+				t.Fatalf("StringReader() error = %v", err)
+			}
+			buf := make([]byte, 5)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			d = string(buf) // the rest is discarded
+		}
+	}
+	if got := strings.Join(keys, ","); got != "a,b,c,d,e" || b != 2 || c != "hello" || d != "hello" {
+		t.Errorf("Entries() keys %q, b = %d, c = %q, d = %q", got, b, c, d)
+	}
+	var next int
+	if err := dec.Decode(&next); err != nil || next != 9 {
+		t.Errorf("Decode() after Entries() = %d, %v, want 9", next, err)
+	}
+}
+
+func TestDecoderEntriesErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  ErrorType
+	}{
+		{"li1ee", ErrUnmarshalType},
+		{"d1:bi1e1:ai2ee", ErrStructureDictKeySort},
+		{"d1:ai1e1:ai2ee", ErrStructureDictKeyDup},
+		{"di1ei2ee", ErrStructureDict},
+		{"d1:ae", ErrStructureDictValue},
+		{"d1:a5:abc", ErrSyntaxEOF},
+		{"d1:ai1e", ErrSyntaxEOF},
+	}
+	for _, tt := range tests {
+		dec := NewDecoder(strings.NewReader(tt.input))
+		var err error
+		for _, err = range dec.Entries() {
+			if err != nil {
+				break
+			}
+		}
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Entries(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestDecoderSkip(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("d1:ali1e3:abcee4:spami-3ei4e"))
+	for range 3 {
+		if err := dec.Skip(); err != nil {
+			t.Fatalf("Skip() error = %v", err)
+		}
+	}
+	var n int
+	if err := dec.Decode(&n); err != nil || n != 4 {
+		t.Errorf("Decode() after Skip() = %d, %v, want 4", n, err)
+	}
+	if err := NewDecoder(strings.NewReader("l1:a")).Skip(); !errors.Is(err, ErrSyntaxEOF) {
+		t.Errorf("Skip() of truncated list error = %v, want %q", err, ErrSyntaxEOF)
+	}
+	if _, _, err := NewDecoder(strings.NewReader("i1e")).StringReader(); !errors.Is(err, ErrUnmarshalType) {
+		t.Errorf("StringReader() of integer error = %v, want %q", err, ErrUnmarshalType)
+	}
+}
@@ -0,0 +1,85 @@
+package bencode
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"unicode/utf8"
+)
+
+var (
+	stringMapType = reflect.TypeFor[map[string]string]()
+	bytesMapType  = reflect.TypeFor[map[string][]byte]()
+	intMapType    = reflect.TypeFor[map[string]int64]()
+)
+
+// encodeStringMap writes m as a dictionary without going through reflection.
+// Maps of strings to strings or byte slices, such as the "m" dictionary of
+// an extension handshake, are common enough to be worth the fast path; the
+// Encoder uses it only when no hooks could change how the values encode.
+// Values are written by write, e.writeString or e.writeBytes, so none is
+// converted to an interface on the way.
+func encodeStringMap[V string | []byte](e *Encoder, m map[string]V, write func(V) error) error {
+	if err := e.writeByte('d'); err != nil {
+		return &Error{Type: ErrEncodeWriteError, Msg: "failed to write dictionary start token 'd'", WrappedErr: err}
+	}
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		if err := e.writeString(key); err != nil {
+			return &Error{Type: ErrEncodeWriteError, Msg: fmt.Sprintf("failed to write dictionary key %q", key), WrappedErr: err, FieldName: key}
+		}
+		if err := write(m[key]); err != nil {
+			return &Error{Type: ErrEncodeWriteError, Msg: fmt.Sprintf("failed to write value for dictionary key %q", key), WrappedErr: err, FieldName: key}
+		}
+	}
+	if err := e.writeByte('e'); err != nil {
+		return &Error{Type: ErrEncodeWriteError, Msg: "failed to write dictionary end token 'e'", WrappedErr: err}
+	}
+	return nil
+}
+
+// assignStringMap stores srcMap in destVal without reflection when destVal
+// is a map[string]string, map[string][]byte or map[string]int64 and every
+// value has the matching decoded type, as in extension handshake "m"
+// dictionaries and scrape "files" statistics. It reports whether it did;
+// otherwise the caller decodes the map element by element. Strings that are
+// not valid UTF-8 are left to the caller unless the UTF-8 mode accepts them.
+func (d *Decoder) assignStringMap(destVal reflect.Value, srcMap map[string]any) bool {
+	var m any
+	var ok bool
+	switch destVal.Type() {
+	case stringMapType:
+		m, ok = typedMap(srcMap, func(item any) (string, bool) {
+			b, ok := item.([]byte)
+			return string(b), ok && (d.utf8Mode == UTF8Accept || utf8.Valid(b))
+		})
+	case bytesMapType:
+		m, ok = typedMap(srcMap, func(item any) ([]byte, bool) {
+			b, ok := item.([]byte)
+			return b, ok
+		})
+	case intMapType:
+		m, ok = typedMap(srcMap, func(item any) (int64, bool) {
+			n, ok := item.(int64)
+			return n, ok
+		})
+	}
+	if ok {
+		destVal.Set(reflect.ValueOf(m))
+	}
+	return ok
+}
+
+// typedMap converts each value of src with conv, failing if any conversion
+// does.
+func typedMap[V any](src map[string]any, conv func(any) (V, bool)) (map[string]V, bool) {
+	dst := make(map[string]V, len(src))
+	for key, item := range src {
+		v, ok := conv(item)
+		if !ok {
+			return nil, false
+		}
+		dst[key] = v
+	}
+	return dst, true
+}
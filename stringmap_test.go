@@ -0,0 +1,118 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncodeStringMaps(t *testing.T) {
+	type headers map[string]string // takes the reflection path
+	tests := []struct {
+		name  string
+		input any
+		want  string
+	}{
+		{"strings", map[string]string{"v": "client 1.0", "p": "6881", "": "x"}, "d0:1:x1:p4:68811:v10:client 1.0e"},
+		{"bytes", map[string][]byte{"ut_pex": []byte("\x01"), "ut_metadata": {}}, "d11:ut_metadata0:6:ut_pex1:\x01e"},
+		{"empty", map[string]string{}, "de"},
+		{"nil bytes map", map[string][]byte(nil), "de"},
+		{"named", headers{"b": "2", "a": "1"}, "d1:a1:11:b1:2e"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(tt.input)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// The UTF-8 mode still applies.
+	for _, tt := range []struct {
+		opts Options
+		want map[string]string
+		err  ErrorType
+	}{
+		{StrictBEP3(), nil, ErrUnmarshalInvalidUTF8},
+		{LenientInterop(), map[string]string{"k": "\uFFFD"}, ""},
+	} {
+		dec := NewDecoder(strings.NewReader("d1:k2:\xff\xfee"))
+		tt.opts.ConfigureDecoder(dec)
+		var got map[string]string
+		err := dec.Decode(&got)
+		if tt.err != "" && !errors.Is(err, tt.err) || tt.err == "" && (err != nil || !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("Decode(invalid UTF-8) = %q, %v, want %q, %q", got, err, tt.want, tt.err)
+		}
+	}
+
+	// Hooks still see the values.
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.UseEncodeHook(func(from reflect.Type, data any) (any, error) {
+		if s, ok := data.(string); ok {
+			return strings.ToUpper(s), nil
+		}
+		return data, nil
+	})
+	if err := enc.Encode(map[string]string{"k": "v"}); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if got, want := buf.String(), "d1:k1:Ve"; got != want {
+		t.Errorf("Encode() with hook = %q, want %q", got, want)
+	}
+
+	writeErr := errors.New("disk full")
+	err := NewEncoder(&failingWriter{err: writeErr}).Encode(map[string][]byte{"a": nil})
+	if !errors.Is(err, ErrEncodeWriteError) || !errors.Is(err, writeErr) {
+		t.Errorf("Encode() to failing writer error = %v, want %q wrapping %v", err, ErrEncodeWriteError, writeErr)
+	}
+}
+
+func TestDecodeStringMaps(t *testing.T) {
+	var m struct {
+		Files map[string]int64  `bencode:"files"`
+		M     map[string][]byte `bencode:"m"`
+		V     map[string]string `bencode:"v"`
+	}
+	input := "d5:filesd8:completei5e10:incompletei-1ee1:md6:ut_pex1:\x01e1:vd1:a1:be1:xi1ee"
+	if err := Unmarshal([]byte(input), &m); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if want := map[string]int64{"complete": 5, "incomplete": -1}; !reflect.DeepEqual(m.Files, want) {
+		t.Errorf("Files = %v, want %v", m.Files, want)
+	}
+	if want := map[string][]byte{"ut_pex": {1}}; !reflect.DeepEqual(m.M, want) {
+		t.Errorf("M = %v, want %v", m.M, want)
+	}
+	if want := map[string]string{"a": "b"}; !reflect.DeepEqual(m.V, want) {
+		t.Errorf("V = %v, want %v", m.V, want)
+	}
+
+	var strs map[string]string
+	err := Unmarshal([]byte("d1:ai1ee"), &strs)
+	var bErr *Error
+	if !errors.As(err, &bErr) || bErr.Type != ErrUnmarshalType || bErr.FieldName != "a" {
+		t.Errorf("Unmarshal(int into map[string]string) error = %v, want %q for key %q", err, ErrUnmarshalType, "a")
+	}
+
+	// Hooks still see the values.
+	dec := NewDecoder(strings.NewReader("d1:k1:ve"))
+	dec.UseDecodeHook(func(from Kind, to reflect.Type, data any) (any, error) {
+		if b, ok := data.([]byte); ok && to.Kind() == reflect.String {
+			return []byte(strings.ToUpper(string(b))), nil
+		}
+		return data, nil
+	})
+	if err := dec.Decode(&strs); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if want := map[string]string{"k": "V"}; !reflect.DeepEqual(strs, want) {
+		t.Errorf("Decode() with hook = %v, want %v", strs, want)
+	}
+}
@@ -3,6 +3,7 @@ package bencode
 import (
 	"reflect"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 )
@@ -23,6 +24,19 @@ type cachedStructFieldInfo struct {
 	bencodeTag string
 	index      int
 	typ        reflect.Type
+	required   bool
+	asList     bool   // encode a byte slice or array as a list of integers
+	union      []Kind // wire kinds accepted by a union field, nil otherwise
+	inline     bool   // holds the whole dictionary rather than one key
+	rest       bool   // holds the keys no other field is tagged with
+	order      int    // position weight from the order=N option
+	orderErr   bool   // the order option is not an integer
+}
+
+// mergesEntries reports whether the field's value is a dictionary whose
+// entries are encoded alongside those of the other fields.
+func (f cachedStructFieldInfo) mergesEntries() bool {
+	return f.inline || f.rest
 }
 
 // getCachedStructInfo retrieves or computes and caches metadata for a struct type.
@@ -48,18 +62,31 @@ func getCachedStructInfo(typ reflect.Type) []cachedStructFieldInfo {
 			continue
 		}
 
-		bencodeName := field.Tag.Get(bencodeTagName)
+		bencodeName, opts, _ := strings.Cut(field.Tag.Get(bencodeTagName), ",")
 
 		if bencodeName == "" {
 			// If no tag is specified, use the field name as the bencode tag.
 			bencodeName = field.Name
 		}
 
+		order, orderErr := 0, false
+		if v, ok := tagOptionValue(opts, "order"); ok {
+			n, err := strconv.Atoi(v)
+			order, orderErr = n, err != nil
+		}
+
 		fields = append(fields, cachedStructFieldInfo{
 			fieldName:  field.Name,
 			bencodeTag: bencodeName,
 			index:      i,
 			typ:        field.Type,
+			required:   hasTagOption(opts, "required"),
+			asList:     hasTagOption(opts, "list"),
+			union:      parseUnionKinds(opts),
+			inline:     hasTagOption(opts, "inline"),
+			rest:       hasTagOption(opts, "rest"),
+			order:      order,
+			orderErr:   orderErr,
 		})
 	}
 
@@ -71,6 +98,30 @@ func getCachedStructInfo(typ reflect.Type) []cachedStructFieldInfo {
 	return fields
 }
 
+// hasTagOption reports whether the comma-separated tag options contain name.
+func hasTagOption(opts, name string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == name {
+			return true
+		}
+	}
+	return false
+}
+
+// tagOptionValue returns the value of a key=value tag option.
+func tagOptionValue(opts, key string) (string, bool) {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if k, v, ok := strings.Cut(opt, "="); ok && k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
 func ClearStructInfoCache() {
 	structInfoCacheMutex.Lock()
 	defer structInfoCacheMutex.Unlock()
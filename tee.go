@@ -0,0 +1,106 @@
+package bencode
+
+import (
+	"bytes"
+	"hash"
+	"io"
+)
+
+// TeeRaw makes the Decoder copy to w exactly the bytes it consumes for each
+// value it decodes, so that the original encoding of a message can be
+// logged, hashed or passed on without encoding it again. The bytes are
+// written as they are read; if decoding fails, those consumed before the
+// failure have been written. After a write error nothing more is written,
+// and the error, as an ErrEncodeWriteError error, is returned by every
+// successful Decode or DecodeValue from the one during which it occurred
+// until TeeRaw is called again. Passing nil stops copying.
+func (d *Decoder) TeeRaw(w io.Writer) {
+	d.tee = w
+	d.teeErr = nil
+}
+
+// TeeHash makes the Decoder feed h the bytes it consumes for each value it
+// decodes, as TeeRaw does, resetting h as each Decode or DecodeValue begins.
+// After a successful call h.Sum returns the hash of exactly the encoding of
+// the value just decoded, so a received info dictionary can be checked
+// against its info-hash without a second pass over the data. Bytes consumed
+// through Entries or StringReader are fed to h as they are read,
+// without resets. TeeHash and TeeRaw may be used together. Passing nil stops
+// hashing.
+func (d *Decoder) TeeHash(h hash.Hash) {
+	d.teeHash = h
+}
+
+// DecodeRaw is like Decode, but also returns the bytes the value was decoded
+// from. Unlike a RawMessage destination, which holds the value encoded
+// again, these are the input bytes themselves.
+func (d *Decoder) DecodeRaw(v any) (RawMessage, error) {
+	var buf bytes.Buffer
+	tee := d.tee
+	if tee != nil {
+		d.tee = io.MultiWriter(tee, &buf)
+	} else {
+		d.tee = &buf
+	}
+	err := d.Decode(v)
+	d.tee = tee
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// teeBytes copies consumed input to the TeeRaw writer.
+func (d *Decoder) teeBytes(b []byte) {
+	if d.teeErr != nil {
+		return
+	}
+	if _, err := d.tee.Write(b); err != nil {
+		d.teeErr = &Error{Type: ErrEncodeWriteError, Msg: "failed to copy raw input", WrappedErr: err}
+	}
+}
+
+// TeeHash makes the Encoder feed h exactly the bytes it writes for each
+// top-level value, resetting h as each value begins: after a successful
+// Encode, or a list or dictionary composed with BeginList or BeginDict and
+// ended, h.Sum returns the hash of that value's encoding. Torrent creators
+// and BEP 44 signers can thus hash their output while writing it rather than
+// encoding it twice. Bytes are hashed only once written, so after a write
+// error h holds those that were accepted. Passing nil stops hashing.
+func (e *Encoder) TeeHash(h hash.Hash) {
+	if hw, ok := e.w.(*hashingWriter); ok {
+		e.w = hw.w
+	}
+	e.teeHash = h
+	if h != nil {
+		e.w = &hashingWriter{w: e.w, h: h}
+	}
+}
+
+// resetHash resets the TeeHash hash if a top-level value is about to be
+// written.
+func (e *Encoder) resetHash() {
+	if e.teeHash != nil && len(e.open) == 0 {
+		e.teeHash.Reset()
+	}
+}
+
+// hashingWriter passes on writes, hashing the bytes that were written.
+type hashingWriter struct {
+	w io.Writer
+	h hash.Hash
+}
+
+func (hw *hashingWriter) Write(p []byte) (int, error) {
+	n, err := hw.w.Write(p)
+	hw.h.Write(p[:n])
+	return n, err
+}
+
+// WriteString passes s on to the underlying writer, keeping the encoder's
+// io.StringWriter fast path when hashing.
+func (hw *hashingWriter) WriteString(s string) (int, error) {
+	n, err := io.WriteString(hw.w, s)
+	io.WriteString(hw.h, s[:n])
+	return n, err
+}
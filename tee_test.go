@@ -0,0 +1,141 @@
+package bencode
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecoderTeeRaw(t *testing.T) {
+	first := "d4:infod4:name4:spame1:ti7ee"
+	second := "li1ei2ee"
+	dec := NewDecoder(strings.NewReader(first + second + "\n"))
+	dec.AllowTrailingWhitespace()
+
+	h := sha1.New()
+	dec.TeeRaw(h)
+	var msg struct {
+		Info RawMessage `bencode:"info"`
+	}
+	if err := dec.Decode(&msg); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got, want := h.Sum(nil), sha1.Sum([]byte(first)); !bytes.Equal(got, want[:]) {
+		t.Errorf("TeeRaw() hashed %x, want %x", got, want)
+	}
+	dec.TeeRaw(nil)
+
+	var list []int
+	raw, err := dec.DecodeRaw(&list)
+	if err != nil {
+		t.Fatalf("DecodeRaw() error = %v", err)
+	}
+	if string(raw) != second || len(list) != 2 {
+		t.Errorf("DecodeRaw() = %q, %v, want %q", raw, list, second)
+	}
+	if err := dec.ExpectEOF(); err != nil {
+		t.Errorf("ExpectEOF() error = %v", err)
+	}
+
+	dec = NewDecoder(strings.NewReader("i1e"))
+	dec.TeeRaw(&failingWriter{err: io.ErrClosedPipe})
+	if _, err := dec.DecodeValue(); !errors.Is(err, ErrEncodeWriteError) {
+		t.Errorf("DecodeValue() with failing tee error = %v, want %q", err, ErrEncodeWriteError)
+	}
+}
+
+func TestDecoderTeeHash(t *testing.T) {
+	first := "d4:infod6:lengthi3e4:name4:spamee"
+	second := "d6:lengthi5e4:name4:eggse"
+	dec := NewDecoder(strings.NewReader(first + " " + second + "\n"))
+	dec.AllowTrailingWhitespace()
+
+	h := sha1.New()
+	var raw bytes.Buffer
+	dec.TeeHash(h)
+	dec.TeeRaw(&raw)
+	var msg struct {
+		Info RawMessage `bencode:"info"`
+	}
+	if err := dec.Decode(&msg); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got, want := h.Sum(nil), sha1.Sum([]byte(first)); !bytes.Equal(got, want[:]) {
+		t.Errorf("TeeHash() hashed %x, want %x", got, want)
+	}
+	if err := dec.ExpectEOF(); !errors.Is(err, ErrTrailingData) {
+		t.Fatalf("ExpectEOF() error = %v, want %q", err, ErrTrailingData)
+	}
+
+	// Each value is hashed on its own, without the whitespace before it.
+	if _, err := dec.DecodeValue(); err != nil {
+		t.Fatalf("DecodeValue() error = %v", err)
+	}
+	if got, want := h.Sum(nil), sha1.Sum([]byte(second)); !bytes.Equal(got, want[:]) {
+		t.Errorf("TeeHash() hashed %x, want %x", got, want)
+	}
+	if raw.String() != first+second {
+		t.Errorf("TeeRaw() copied %q, want %q", raw.String(), first+second)
+	}
+
+	dec.TeeHash(nil)
+	if err := dec.ExpectEOF(); err != nil {
+		t.Errorf("ExpectEOF() error = %v", err)
+	}
+}
+
+func TestEncoderTeeHash(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	h := sha1.New()
+	enc.TeeHash(h)
+
+	info := map[string]any{"length": 3, "name": "spam"}
+	if err := enc.Encode(info); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if got, want := h.Sum(nil), sha1.Sum(buf.Bytes()); !bytes.Equal(got, want[:]) {
+		t.Errorf("TeeHash() hashed %x, want %x", got, want)
+	}
+
+	// A composed dictionary is hashed as one value, including what Encode
+	// writes inside it.
+	buf.Reset()
+	if err := enc.BeginDict(); err != nil {
+		t.Fatalf("BeginDict() error = %v", err)
+	}
+	_ = enc.EncodeString("info")
+	_ = enc.Encode(info)
+	_ = enc.EncodeString("v")
+	_ = enc.EncodeInt(1)
+	if err := enc.End(); err != nil {
+		t.Fatalf("End() error = %v", err)
+	}
+	if got, want := h.Sum(nil), sha1.Sum(buf.Bytes()); !bytes.Equal(got, want[:]) {
+		t.Errorf("TeeHash() hashed %x, want %x of %q", got, want, buf.String())
+	}
+
+	buf.Reset()
+	if err := enc.EncodeValue(reflect.ValueOf("spam")); err != nil {
+		t.Fatalf("EncodeValue() error = %v", err)
+	}
+	if got, want := h.Sum(nil), sha1.Sum([]byte("4:spam")); !bytes.Equal(got, want[:]) {
+		t.Errorf("TeeHash() hashed %x, want %x", got, want)
+	}
+
+	enc.TeeHash(nil)
+	sum := h.Sum(nil)
+	if err := enc.Encode(info); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(h.Sum(nil), sum) {
+		t.Errorf("TeeHash(nil) did not stop hashing")
+	}
+	if buf.String() != "4:spamd6:lengthi3e4:name4:spame" {
+		t.Errorf("Encode() wrote %q after TeeHash(nil)", buf.String())
+	}
+}
@@ -0,0 +1,366 @@
+// Package tracker implements the HTTP announce round trip of BitTorrent
+// (BEP 3) on top of the bencode package: requests are encoded into the
+// announce URL's query string and responses are bencoded dictionaries.
+package tracker
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/stupoid/bencode"
+)
+
+const (
+	// ErrTracker indicates an announce could not be completed.
+	ErrTracker bencode.ErrorType = "tracker error"
+	// ErrTrackerFailure indicates the tracker refused an announce with a
+	// "failure reason".
+	ErrTrackerFailure bencode.ErrorType = "tracker failure"
+	// ErrTrackerWarning indicates the tracker answered with a "warning
+	// message" alongside an otherwise successful response.
+	ErrTrackerWarning bencode.ErrorType = "tracker warning"
+)
+
+// Event is the event parameter of an announce.
+type Event string
+
+// Events sent with an announce. EventNone is a regular, periodic announce.
+const (
+	EventNone      Event = ""
+	EventStarted   Event = "started"
+	EventStopped   Event = "stopped"
+	EventCompleted Event = "completed"
+)
+
+// AnnounceRequest holds the parameters of an announce.
+type AnnounceRequest struct {
+	InfoHash   bencode.InfoHash
+	PeerID     bencode.PeerID
+	Port       uint16
+	Uploaded   int64
+	Downloaded int64
+	Left       int64
+	// Compact asks for the peer list in the compact form of BEP 23.
+	Compact bool
+	Event   Event
+	// NumWant is the number of peers wanted; the tracker chooses if absent.
+	NumWant bencode.Optional[int]
+	// Key, TrackerID and IP are sent only if non-empty.
+	Key       string
+	TrackerID string
+	IP        string
+}
+
+// Query returns the request encoded as a URL query string. The raw bytes of
+// the info hash and peer ID are percent-encoded byte by byte, as trackers
+// expect, rather than as UTF-8 text.
+func (r AnnounceRequest) Query() string {
+	var sb strings.Builder
+	param := func(key, value string) {
+		if sb.Len() > 0 {
+			sb.WriteByte('&')
+		}
+		sb.WriteString(key)
+		sb.WriteByte('=')
+		sb.WriteString(escape(value))
+	}
+	param("info_hash", string(r.InfoHash[:]))
+	param("peer_id", string(r.PeerID[:]))
+	param("port", strconv.Itoa(int(r.Port)))
+	param("uploaded", strconv.FormatInt(r.Uploaded, 10))
+	param("downloaded", strconv.FormatInt(r.Downloaded, 10))
+	param("left", strconv.FormatInt(r.Left, 10))
+	if r.Compact {
+		param("compact", "1")
+	} else {
+		param("compact", "0")
+	}
+	if r.Event != EventNone {
+		param("event", string(r.Event))
+	}
+	if n, ok := r.NumWant.Get(); ok {
+		param("numwant", strconv.Itoa(n))
+	}
+	if r.Key != "" {
+		param("key", r.Key)
+	}
+	if r.TrackerID != "" {
+		param("trackerid", r.TrackerID)
+	}
+	if r.IP != "" {
+		param("ip", r.IP)
+	}
+	return sb.String()
+}
+
+// URL returns announceURL with the request's query appended, keeping any
+// query parameters it already has, such as a private tracker's passkey.
+func (r AnnounceRequest) URL(announceURL string) string {
+	sep := "?"
+	if strings.Contains(announceURL, "?") {
+		sep = "&"
+	}
+	return announceURL + sep + r.Query()
+}
+
+// escape percent-encodes every byte of s other than the unreserved
+// characters of RFC 3986.
+func escape(s string) string {
+	const hex = "0123456789ABCDEF"
+	var sb strings.Builder
+	for i := range len(s) {
+		switch c := s[i]; {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '-', c == '.', c == '_', c == '~':
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte('%')
+			sb.WriteByte(hex[c>>4])
+			sb.WriteByte(hex[c&0xf])
+		}
+	}
+	return sb.String()
+}
+
+// AnnounceResponse is the bencoded dictionary returned by a tracker.
+type AnnounceResponse struct {
+	// FailureReason and WarningMessage are only present in responses that
+	// carry them: clients take a "failure reason" key, even an empty one,
+	// to mean that the request failed.
+	FailureReason  bencode.Optional[string] `bencode:"failure reason"`
+	WarningMessage bencode.Optional[string] `bencode:"warning message"`
+	Interval       int64                    `bencode:"interval"`
+	MinInterval    int64                    `bencode:"min interval"`
+	TrackerID      string                   `bencode:"tracker id"`
+	Complete       int64                    `bencode:"complete"`
+	Incomplete     int64                    `bencode:"incomplete"`
+	Peers          Peers                    `bencode:"peers,union=list|string"`
+	// Peers6 holds IPv6 peers in compact form (BEP 7).
+	Peers6 string `bencode:"peers6"`
+}
+
+// Peers is the "peers" value of a response: a list of dictionaries, or a
+// string of compact IPv4 peers when the request set Compact.
+type Peers struct {
+	Kind    bencode.Kind
+	List    []Peer `bencode:"list"`
+	Compact string `bencode:"string"`
+}
+
+// Peer is an entry of a non-compact peer list. ID is kept as sent, since
+// trackers pass on whatever peer IDs clients announced and one malformed ID
+// should not fail the whole response; PeerID checks it.
+type Peer struct {
+	ID   string `bencode:"peer id"`
+	IP   string `bencode:"ip"`
+	Port uint16 `bencode:"port"`
+}
+
+// PeerID returns the ID of p as a bencode.PeerID and whether it is one,
+// that is, whether it is 20 bytes long.
+func (p Peer) PeerID() (bencode.PeerID, bool) {
+	if len(p.ID) != len(bencode.PeerID{}) {
+		return bencode.PeerID{}, false
+	}
+	return bencode.PeerID([]byte(p.ID)), true
+}
+
+// ScrapeResponse is the bencoded dictionary returned by a tracker's scrape
+// URL. Files is keyed by the raw 20-byte info hashes of the torrents. As in
+// AnnounceResponse, FailureReason and WarningMessage are only present in
+// responses that carry them.
+type ScrapeResponse struct {
+	FailureReason  bencode.Optional[string]   `bencode:"failure reason"`
+	WarningMessage bencode.Optional[string]   `bencode:"warning message"`
+	Files          map[string]ScrapeFileStats `bencode:"files"`
+}
+
+// ScrapeFileStats holds the statistics a scrape reports for one torrent.
+type ScrapeFileStats struct {
+	// Complete is the number of seeders and Incomplete that of leechers.
+	Complete   int64 `bencode:"complete"`
+	Incomplete int64 `bencode:"incomplete"`
+	// Downloaded is the number of completed downloads the tracker has seen.
+	Downloaded int64 `bencode:"downloaded"`
+	// Name is the torrent's name, which some trackers add.
+	Name string `bencode:"name"`
+}
+
+// DecodeResponse decodes a tracker response dictionary from r into the
+// value pointed to by v, such as an announce or scrape response, and reports
+// the "failure reason" and "warning message" keys that any tracker response
+// may carry, whether or not v has fields for them.
+//
+// A failure reason is returned as an ErrTrackerFailure error, and otherwise a
+// warning message as an ErrTrackerWarning error; in both cases the error's
+// Msg is the tracker's text. The rest of the response is decoded into v
+// regardless, so a warning can be logged and the response used as usual. If
+// a failed response also does not fit v, the decoding error is wrapped by
+// the ErrTrackerFailure error.
+func DecodeResponse(r io.Reader, v any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return &bencode.Error{Type: ErrTracker, Msg: "reading response", WrappedErr: err}
+	}
+	doc, err := bencode.Index(data)
+	if err != nil {
+		return err
+	}
+	if kind, _ := doc.Kind(); kind != bencode.KindDict {
+		return &bencode.Error{Type: ErrTracker, Msg: fmt.Sprintf("response is a %s, not a dictionary", kind)}
+	}
+	decodeErr := doc.Decode(nil, v)
+	if reason, ok := responseText(doc, "failure reason"); ok {
+		return &bencode.Error{Type: ErrTrackerFailure, Msg: reason, FieldName: "failure reason", WrappedErr: decodeErr}
+	}
+	if decodeErr != nil {
+		return decodeErr
+	}
+	if warning, ok := responseText(doc, "warning message"); ok {
+		return &bencode.Error{Type: ErrTrackerWarning, Msg: warning, FieldName: "warning message"}
+	}
+	return nil
+}
+
+// DecodeHTTPResponse reads the body of the tracker response resp, closes
+// it and decodes it into the value pointed to by v as DecodeResponse does,
+// so that failure reasons and warnings are reported the same way. A body
+// compressed with gzip, and not already decompressed by the HTTP transport,
+// is decompressed transparently. If maxBytes is positive, a body, after
+// decompression, longer than maxBytes is rejected with an
+// ErrMessageTooLarge error, without being read in full if its
+// Content-Length already gives it away.
+//
+// A status other than 200 OK is an ErrTracker error, unless the body carries
+// a failure reason, which is reported as by DecodeResponse.
+func DecodeHTTPResponse(resp *http.Response, v any, maxBytes int64) error {
+	defer resp.Body.Close()
+	gzipped := strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") && !resp.Uncompressed
+	if maxBytes > 0 && resp.ContentLength > maxBytes && !gzipped {
+		return &bencode.Error{Type: bencode.ErrMessageTooLarge, Msg: fmt.Sprintf("response of %d bytes exceeds limit of %d", resp.ContentLength, maxBytes)}
+	}
+	body := io.Reader(resp.Body)
+	if gzipped {
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return &bencode.Error{Type: ErrTracker, Msg: "reading gzip response", WrappedErr: err}
+		}
+		defer zr.Close()
+		body = zr
+	}
+	if maxBytes > 0 {
+		body = io.LimitReader(body, maxBytes+1)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return &bencode.Error{Type: ErrTracker, Msg: "reading response", WrappedErr: err}
+	}
+	if maxBytes > 0 && int64(len(data)) > maxBytes {
+		return &bencode.Error{Type: bencode.ErrMessageTooLarge, Msg: fmt.Sprintf("response exceeds limit of %d bytes", maxBytes)}
+	}
+
+	err = DecodeResponse(bytes.NewReader(data), v)
+	if resp.StatusCode != http.StatusOK {
+		var bErr *bencode.Error
+		if errors.As(err, &bErr) && bErr.Type == ErrTrackerFailure {
+			return err
+		}
+		return &bencode.Error{Type: ErrTracker, Msg: fmt.Sprintf("tracker responded %s", resp.Status)}
+	}
+	return err
+}
+
+// responseText returns the string at key of a response, if present.
+func responseText(doc *bencode.Document, key string) (string, bool) {
+	raw, err := doc.Get(key)
+	if err != nil {
+		return "", false
+	}
+	b, err := raw.Bytes()
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// DecodeAnnounceResponse decodes a tracker response from r. A response with
+// a failure reason is returned together with an ErrTrackerFailure error. A
+// warning message is left in WarningMessage and not reported as an error.
+func DecodeAnnounceResponse(r io.Reader) (*AnnounceResponse, error) {
+	var resp AnnounceResponse
+	return announceResult(&resp, DecodeResponse(r, &resp))
+}
+
+// announceResult returns resp and err, the result of decoding an announce
+// response, as DecodeAnnounceResponse documents.
+func announceResult(resp *AnnounceResponse, err error) (*AnnounceResponse, error) {
+	var bErr *bencode.Error
+	switch {
+	case err == nil, errors.As(err, &bErr) && bErr.Type == ErrTrackerWarning:
+		return resp, nil
+	case bErr != nil && bErr.Type == ErrTrackerFailure:
+		return resp, err
+	default:
+		return nil, err
+	}
+}
+
+// PeerAddrs returns the addresses of all peers in the response, from the
+// peer list or compact peers followed by the compact IPv6 peers. Peers whose
+// "ip" is a hostname rather than an address are skipped.
+func (resp *AnnounceResponse) PeerAddrs() ([]netip.AddrPort, error) {
+	var addrs []netip.AddrPort
+	for _, p := range resp.Peers.List {
+		if ip, err := netip.ParseAddr(p.IP); err == nil {
+			addrs = append(addrs, netip.AddrPortFrom(ip, p.Port))
+		}
+	}
+	for _, compact := range []struct {
+		key, peers string
+		size       int
+	}{
+		{key: "peers", peers: resp.Peers.Compact, size: 6},
+		{key: "peers6", peers: resp.Peers6, size: 18},
+	} {
+		if len(compact.peers)%compact.size != 0 {
+			return nil, &bencode.Error{Type: ErrTracker, Msg: fmt.Sprintf("compact peers of %d bytes is not a multiple of %d", len(compact.peers), compact.size), FieldName: compact.key}
+		}
+		for b := []byte(compact.peers); len(b) > 0; b = b[compact.size:] {
+			ip, _ := netip.AddrFromSlice(b[:compact.size-2])
+			port := uint16(b[compact.size-2])<<8 | uint16(b[compact.size-1])
+			addrs = append(addrs, netip.AddrPortFrom(ip, port))
+		}
+	}
+	return addrs, nil
+}
+
+// MaxResponseSize is the limit on the size of a response that Announce
+// accepts. Announce responses are small even with hundreds of peers.
+const MaxResponseSize = 1 << 20
+
+// Announce sends req to the tracker at announceURL using client, or
+// http.DefaultClient if client is nil, and decodes the response as
+// DecodeAnnounceResponse does. Responses larger than MaxResponseSize are
+// rejected.
+func Announce(ctx context.Context, client *http.Client, announceURL string, req AnnounceRequest) (*AnnounceResponse, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, req.URL(announceURL), nil)
+	if err != nil {
+		return nil, &bencode.Error{Type: bencode.ErrUsage, Msg: fmt.Sprintf("invalid announce URL %q", announceURL), WrappedErr: err}
+	}
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, &bencode.Error{Type: ErrTracker, Msg: "sending announce", WrappedErr: err}
+	}
+	var resp AnnounceResponse
+	return announceResult(&resp, DecodeHTTPResponse(httpResp, &resp, MaxResponseSize))
+}
@@ -0,0 +1,264 @@
+package tracker
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stupoid/bencode"
+)
+
+func TestAnnounceRequestQuery(t *testing.T) {
+	req := AnnounceRequest{
+		InfoHash: [20]byte{0x12, 0x34, ' ', '+', 0xff, 'a', '~'},
+		PeerID:   [20]byte{'-', 'G', 'O', '0', '0', '0', '1', '-'},
+		Port:     6881,
+		Left:     100,
+		Compact:  true,
+		Event:    EventStarted,
+		NumWant:  bencode.Some(0),
+	}
+	want := "info_hash=%124%20%2B%FFa~%00%00%00%00%00%00%00%00%00%00%00%00%00" +
+		"&peer_id=-GO0001-%00%00%00%00%00%00%00%00%00%00%00%00" +
+		"&port=6881&uploaded=0&downloaded=0&left=100&compact=1&event=started&numwant=0"
+	if got := req.Query(); got != want {
+		t.Errorf("Query() = %q, want %q", got, want)
+	}
+
+	values, err := url.ParseQuery(req.Query())
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+	if values.Get("info_hash") != string(req.InfoHash[:]) {
+		t.Errorf("info_hash decodes to %x, want %x", values.Get("info_hash"), req.InfoHash)
+	}
+
+	if got := req.URL("http://t.example/announce?passkey=abc"); got != "http://t.example/announce?passkey=abc&"+want {
+		t.Errorf("URL() = %q", got)
+	}
+}
+
+func TestAnnounce(t *testing.T) {
+	var gotQuery url.Values
+	srv := httptest.NewServer(bencode.HandlerFunc(func(r *http.Request) (any, error) {
+		gotQuery = r.URL.Query()
+		return map[string]any{
+			"interval": 1800,
+			"peers":    "\x0a\x00\x00\x01\x1a\xe1",
+			"peers6":   "\x20\x01\x0d\xb8\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x1a\xe1",
+		}, nil
+	}))
+	defer srv.Close()
+
+	req := AnnounceRequest{InfoHash: [20]byte{0xff}, Port: 6881, Compact: true}
+	resp, err := Announce(context.Background(), srv.Client(), srv.URL, req)
+	if err != nil {
+		t.Fatalf("Announce() error = %v", err)
+	}
+	if gotQuery.Get("info_hash") != string(req.InfoHash[:]) {
+		t.Errorf("tracker received info_hash %x", gotQuery.Get("info_hash"))
+	}
+	if resp.Interval != 1800 || resp.Peers.Kind != bencode.KindString {
+		t.Errorf("Announce() = %+v", resp)
+	}
+	addrs, err := resp.PeerAddrs()
+	if err != nil {
+		t.Fatalf("PeerAddrs() error = %v", err)
+	}
+	want := []netip.AddrPort{netip.MustParseAddrPort("10.0.0.1:6881"), netip.MustParseAddrPort("[2001:db8::1]:6881")}
+	if !reflect.DeepEqual(addrs, want) {
+		t.Errorf("PeerAddrs() = %v, want %v", addrs, want)
+	}
+}
+
+func TestDecodeAnnounceResponse(t *testing.T) {
+	resp, err := DecodeAnnounceResponse(strings.NewReader("d8:intervali60e5:peersld2:ip8:10.0.0.27:peer id20:-TR2940-abcdefghijkl4:porti51413eed2:ip9:localhost7:peer id5:short4:porti1eeee"))
+	if err != nil {
+		t.Fatalf("DecodeAnnounceResponse() error = %v", err)
+	}
+	// A malformed peer ID does not fail the response.
+	if id, ok := resp.Peers.List[0].PeerID(); !ok || id.ClientName() != "Transmission 2.9.4.0" {
+		t.Errorf("PeerID() = %v, %v, want a Transmission peer ID", id, ok)
+	}
+	if _, ok := resp.Peers.List[1].PeerID(); ok {
+		t.Errorf("PeerID() of %q reported a valid peer ID", resp.Peers.List[1].ID)
+	}
+	addrs, err := resp.PeerAddrs()
+	if err != nil {
+		t.Fatalf("PeerAddrs() error = %v", err)
+	}
+	if want := []netip.AddrPort{netip.MustParseAddrPort("10.0.0.2:51413")}; !reflect.DeepEqual(addrs, want) {
+		t.Errorf("PeerAddrs() = %v, want %v", addrs, want)
+	}
+
+	resp, err = DecodeAnnounceResponse(strings.NewReader("d14:failure reason12:unregisterede"))
+	var bErr *bencode.Error
+	if !errors.As(err, &bErr) || bErr.Type != ErrTrackerFailure || resp.FailureReason.Or("") != "unregistered" {
+		t.Errorf("DecodeAnnounceResponse() = %+v, %v, want %q", resp, err, ErrTrackerFailure)
+	}
+
+	resp, err = DecodeAnnounceResponse(strings.NewReader("d5:peers5:abcdee"))
+	if err != nil {
+		t.Fatalf("DecodeAnnounceResponse() error = %v", err)
+	}
+	if _, err := resp.PeerAddrs(); !errors.As(err, &bErr) || bErr.Type != ErrTracker || bErr.FieldName != "peers" {
+		t.Errorf("PeerAddrs() error = %v, want %q", err, ErrTracker)
+	}
+}
+
+func TestDecodeScrapeResponse(t *testing.T) {
+	hashA, hashB := strings.Repeat("a", 20), strings.Repeat("b", 20)
+	input := "d5:filesd20:" + hashA + "d8:completei5e10:incompletei2ee20:" + hashB + "d8:completei1e10:downloadedi7e10:incompletei0e4:name4:spameee"
+	var resp ScrapeResponse
+	if err := DecodeResponse(strings.NewReader(input), &resp); err != nil {
+		t.Fatalf("DecodeResponse() error = %v", err)
+	}
+	want := map[string]ScrapeFileStats{
+		hashA: {Complete: 5, Incomplete: 2},
+		hashB: {Complete: 1, Downloaded: 7, Name: "spam"},
+	}
+	if !reflect.DeepEqual(resp.Files, want) {
+		t.Errorf("Files = %+v, want %+v", resp.Files, want)
+	}
+	encoded, err := bencode.Marshal(ScrapeResponse{Files: want})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if wantEnc := "d5:filesd20:" + hashA + "d8:completei5e10:downloadedi0e10:incompletei2e4:name0:e20:" + hashB + "d8:completei1e10:downloadedi7e10:incompletei0e4:name4:spameee"; string(encoded) != wantEnc {
+		t.Errorf("Marshal() = %q, want %q", encoded, wantEnc)
+	}
+
+	err = DecodeResponse(strings.NewReader("d5:filesd20:"+hashA+"d8:complete3:alleee"), &resp)
+	var bErr *bencode.Error
+	if !errors.As(err, &bErr) || bErr.Type != bencode.ErrUnmarshalType || bErr.Path() != "files."+hashA+".complete" {
+		t.Errorf("DecodeResponse() error = %v, want %q at files.<hash>.complete", err, bencode.ErrUnmarshalType)
+	}
+}
+
+func TestDecodeResponse(t *testing.T) {
+	type scrapeFile struct {
+		Complete   int64 `bencode:"complete"`
+		Downloaded int64 `bencode:"downloaded"`
+		Incomplete int64 `bencode:"incomplete"`
+	}
+	type scrapeResponse struct {
+		Files map[string]scrapeFile `bencode:"files"`
+	}
+	tests := []struct {
+		name    string
+		input   string
+		errType bencode.ErrorType
+		msg     string
+		files   int
+	}{
+		{name: "ok", input: "d5:filesd20:aaaaaaaaaaaaaaaaaaaad8:completei1e10:downloadedi2e10:incompletei3eeee", files: 1},
+		{name: "warning", input: "d5:filesde15:warning message4:slowe", errType: ErrTrackerWarning, msg: "slow"},
+		{name: "failure", input: "d14:failure reason12:unregistered15:warning message4:slowe", errType: ErrTrackerFailure, msg: "unregistered"},
+		{name: "failure with mismatched fields", input: "d14:failure reason3:bad5:files3:xyze", errType: ErrTrackerFailure, msg: "bad"},
+		{name: "not a dictionary", input: "le", errType: ErrTracker},
+		{name: "invalid", input: "d5:files", errType: bencode.ErrSyntaxEOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp scrapeResponse
+			err := DecodeResponse(strings.NewReader(tt.input), &resp)
+			if tt.errType == "" {
+				if err != nil {
+					t.Fatalf("DecodeResponse() error = %v", err)
+				}
+			} else {
+				var bErr *bencode.Error
+				if !errors.As(err, &bErr) || bErr.Type != tt.errType {
+					t.Fatalf("DecodeResponse() error = %v, want %q", err, tt.errType)
+				}
+				if tt.msg != "" && bErr.Msg != tt.msg {
+					t.Errorf("DecodeResponse() error Msg = %q, want %q", bErr.Msg, tt.msg)
+				}
+			}
+			if len(resp.Files) != tt.files {
+				t.Errorf("DecodeResponse() decoded %d files, want %d", len(resp.Files), tt.files)
+			}
+		})
+	}
+
+	resp, err := DecodeAnnounceResponse(strings.NewReader("d8:intervali900e15:warning message4:slowe"))
+	if err != nil || resp.WarningMessage.Or("") != "slow" || resp.Interval != 900 {
+		t.Errorf("DecodeAnnounceResponse() with warning = %+v, %v", resp, err)
+	}
+}
+
+func TestDecodeHTTPResponse(t *testing.T) {
+	gzipped := func(s string) string {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write([]byte(s))
+		zw.Close()
+		return buf.String()
+	}
+	tests := []struct {
+		name     string
+		status   int
+		header   http.Header
+		body     string
+		maxBytes int64
+		wantErr  bencode.ErrorType
+	}{
+		{name: "plain", status: http.StatusOK, body: "d8:intervali60ee"},
+		{name: "gzip", status: http.StatusOK, header: http.Header{"Content-Encoding": {"gzip"}}, body: gzipped("d8:intervali60ee"), maxBytes: 16},
+		{name: "too large", status: http.StatusOK, body: "d8:intervali60ee", maxBytes: 15, wantErr: bencode.ErrMessageTooLarge},
+		{name: "gzip too large", status: http.StatusOK, header: http.Header{"Content-Encoding": {"gzip"}}, body: gzipped("d8:intervali60ee"), maxBytes: 15, wantErr: bencode.ErrMessageTooLarge},
+		{name: "failure with error status", status: http.StatusBadRequest, body: "d14:failure reason7:unknowne", wantErr: ErrTrackerFailure},
+		{name: "error status", status: http.StatusBadGateway, body: "<html>", wantErr: ErrTracker},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &closeRecorder{Reader: strings.NewReader(tt.body)}
+			resp := &http.Response{
+				StatusCode:    tt.status,
+				Status:        http.StatusText(tt.status),
+				Header:        tt.header,
+				Body:          body,
+				ContentLength: int64(len(tt.body)),
+			}
+			if resp.Header == nil {
+				resp.Header = http.Header{}
+			}
+			var v struct {
+				Interval int `bencode:"interval"`
+			}
+			err := DecodeHTTPResponse(resp, &v, tt.maxBytes)
+			if !body.closed {
+				t.Error("DecodeHTTPResponse() did not close the body")
+			}
+			if tt.wantErr == "" {
+				if err != nil || v.Interval != 60 {
+					t.Errorf("DecodeHTTPResponse() = %+v, %v, want interval 60", v, err)
+				}
+				return
+			}
+			var bErr *bencode.Error
+			if !errors.As(err, &bErr) || bErr.Type != tt.wantErr {
+				t.Errorf("DecodeHTTPResponse() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
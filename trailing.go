@@ -0,0 +1,74 @@
+package bencode
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrTrailingData indicates that input remains after a value that was
+// expected to end it.
+const ErrTrailingData ErrorType = "trailing data"
+
+// UnmarshalStrict is like Unmarshal, but fails with an ErrTrailingData error
+// if data holds anything after the bencode value, including whitespace.
+// Unmarshal ignores such bytes; UnmarshalPrefix returns them.
+func UnmarshalStrict(data []byte, v any) error {
+	dec := &Decoder{r: bufio.NewReaderSize(bytes.NewReader(data), len(data))}
+	dec.presize(data)
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	return dec.ExpectEOF()
+}
+
+// AllowTrailingWhitespace makes ExpectEOF accept, and consume, ASCII
+// whitespace after the last value. Some tools append a newline to the
+// .torrent files they write, which is otherwise reported as trailing data.
+func (d *Decoder) AllowTrailingWhitespace() {
+	d.trailingSpace = true
+}
+
+// ExpectEOF reports whether the input ends after the values decoded so far,
+// returning an ErrTrailingData error if it does not. The trailing data itself
+// is left unread, but whitespace before it is consumed when
+// AllowTrailingWhitespace is set, as is the rest of a string opened by
+// StringReader. Call it after decoding a document that must be the whole
+// input, such as a .torrent file, to detect files with garbage appended.
+func (d *Decoder) ExpectEOF() error {
+	if err := d.failed(); err != nil {
+		return err
+	}
+	if err := d.drainString(); err != nil {
+		return err
+	}
+	tee, teeHash := d.tee, d.teeHash
+	d.tee, d.teeHash = nil, nil // whitespace is not part of a value
+	defer func() { d.tee, d.teeHash = tee, teeHash }()
+	for {
+		next, err := d.r.Peek(1)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return d.readError(err, "peeking for end of input")
+		}
+		if !d.trailingSpace || !isASCIISpace(next[0]) {
+			return d.annotate(&Error{Type: ErrTrailingData, Msg: fmt.Sprintf("unexpected %q after bencode value at offset %d", next[0], d.offset)})
+		}
+		d.consumedBytes(next[:1])
+		d.r.Discard(1)
+	}
+}
+
+// isASCIISpace reports whether b is a space, tab, newline, carriage return,
+// vertical tab or form feed.
+func isASCIISpace(b byte) bool {
+	switch b {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package bencode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExpectEOF(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		whitespace bool
+		wantErr    bool
+	}{
+		{name: "exact", input: "d1:ai1ee"},
+		{name: "garbage", input: "d1:ai1eeXYZ", wantErr: true},
+		{name: "newline", input: "d1:ai1ee\n", wantErr: true},
+		{name: "newline allowed", input: "d1:ai1ee\r\n", whitespace: true},
+		{name: "garbage after whitespace", input: "d1:ai1ee \nx", whitespace: true, wantErr: true},
+		{name: "second value", input: "d1:ai1eei2e", whitespace: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tt.input))
+			if tt.whitespace {
+				dec.AllowTrailingWhitespace()
+			}
+			var v map[string]int
+			if err := dec.Decode(&v); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			err := dec.ExpectEOF()
+			var bErr *Error
+			if tt.wantErr != (err != nil) || err != nil && (!errors.As(err, &bErr) || bErr.Type != ErrTrailingData) {
+				t.Errorf("ExpectEOF() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// ExpectEOF does not consume the trailing value.
+	dec := NewDecoder(strings.NewReader("i1ei2e"))
+	var n int
+	if err := dec.Decode(&n); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if dec.ExpectEOF() == nil {
+		t.Fatal("ExpectEOF() = nil, want error")
+	}
+	if err := dec.Decode(&n); err != nil || n != 2 {
+		t.Errorf("Decode() after ExpectEOF = %d, %v, want 2", n, err)
+	}
+}
+
+func TestUnmarshalStrict(t *testing.T) {
+	var v int
+	if err := UnmarshalStrict([]byte("i1e"), &v); err != nil || v != 1 {
+		t.Errorf("UnmarshalStrict() = %d, %v, want 1", v, err)
+	}
+	var bErr *Error
+	if err := UnmarshalStrict([]byte("i1e\n"), &v); !errors.As(err, &bErr) || bErr.Type != ErrTrailingData {
+		t.Errorf("UnmarshalStrict() with trailing newline error = %v, want %q", err, ErrTrailingData)
+	}
+	if err := Unmarshal([]byte("i1e\n"), &v); err != nil {
+		t.Errorf("Unmarshal() with trailing newline error = %v, want nil", err)
+	}
+}
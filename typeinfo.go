@@ -0,0 +1,70 @@
+package bencode
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// FieldInfo describes how a struct field is encoded and decoded, as resolved
+// from its bencode tag.
+type FieldInfo struct {
+	// Name is the Go name of the field and Index its position in the struct,
+	// for use with reflect.Value.Field.
+	Name  string
+	Index int
+	Type  reflect.Type
+	// Key is the dictionary key of the field: the name in its tag, or Name
+	// if the tag gives none.
+	Key string
+	// The tag options. Union holds the kinds accepted by a union field, and
+	// Order the weight given by the order option, used by
+	// Encoder.UseFieldOrder.
+	Required bool
+	List     bool
+	Union    []Kind
+	Inline   bool
+	Rest     bool
+	Order    int
+}
+
+// TypeInfo returns the exported fields of the struct type t, or of the
+// struct t points to, in the order their keys are encoded: sorted by key, as
+// canonical bencode requires. It reads the same metadata the Encoder and
+// Decoder use, so that documentation generators and schema exporters cannot
+// drift from their behavior. Tags that would fail encoding or decoding, such
+// as a non-integer order option or a malformed union, are reported as an
+// ErrUsage error.
+func TypeInfo(t reflect.Type) ([]FieldInfo, error) {
+	if t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, &Error{Type: ErrUsage, Msg: fmt.Sprintf("TypeInfo(%v): expected a struct type", t)}
+	}
+	cached := getCachedStructInfo(t)
+	infos := make([]FieldInfo, 0, len(cached))
+	for _, f := range cached {
+		if f.orderErr {
+			return nil, &Error{Type: ErrUsage, Msg: fmt.Sprintf("field %s of %s has a non-integer order option", f.fieldName, t), FieldName: f.bencodeTag}
+		}
+		if f.union != nil {
+			if err := checkUnionField(f); err != nil {
+				return nil, err
+			}
+		}
+		infos = append(infos, FieldInfo{
+			Name:     f.fieldName,
+			Index:    f.index,
+			Type:     f.typ,
+			Key:      f.bencodeTag,
+			Required: f.required,
+			List:     f.asList,
+			Union:    slices.Clone(f.union),
+			Inline:   f.inline,
+			Rest:     f.rest,
+			Order:    f.order,
+		})
+	}
+	return infos, nil
+}
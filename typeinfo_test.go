@@ -0,0 +1,48 @@
+package bencode
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTypeInfo(t *testing.T) {
+	type peers struct {
+		List   []int  `bencode:"list"`
+		String string `bencode:"string"`
+	}
+	type message struct {
+		Zed      int            `bencode:",order=1"`
+		Name     string         `bencode:"name,required,order=-1"`
+		Hash     []byte         `bencode:"hash,list"`
+		Peers    peers          `bencode:"peers,union=list|string"`
+		Extra    map[string]any `bencode:",rest"`
+		internal int
+	}
+	got, err := TypeInfo(reflect.TypeFor[*message]())
+	if err != nil {
+		t.Fatalf("TypeInfo() error = %v", err)
+	}
+	want := []FieldInfo{
+		{Name: "Extra", Index: 4, Type: reflect.TypeFor[map[string]any](), Key: "Extra", Rest: true},
+		{Name: "Zed", Index: 0, Type: reflect.TypeFor[int](), Key: "Zed", Order: 1},
+		{Name: "Hash", Index: 2, Type: reflect.TypeFor[[]byte](), Key: "hash", List: true},
+		{Name: "Name", Index: 1, Type: reflect.TypeFor[string](), Key: "name", Required: true, Order: -1},
+		{Name: "Peers", Index: 3, Type: reflect.TypeFor[peers](), Key: "peers", Union: []Kind{KindList, KindString}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TypeInfo() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	type badOrder struct {
+		A int `bencode:"a,order=first"`
+	}
+	type badUnion struct {
+		A peers `bencode:"a,union=list|dict"`
+	}
+	for _, typ := range []reflect.Type{nil, reflect.TypeFor[int](), reflect.TypeFor[badOrder](), reflect.TypeFor[badUnion]()} {
+		if _, err := TypeInfo(typ); !errors.Is(err, ErrUsage) {
+			t.Errorf("TypeInfo(%v) error = %v, want %q", typ, err, ErrUsage)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package bencode
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+var uint64StringType = reflect.TypeFor[Uint64String]()
+
+// Uint64String is a uint64 carried as a bencode string of decimal digits,
+// for protocols whose unsigned values can exceed the int64 range of bencode
+// integers. It encodes as "20:18446744073709551615" for the maximum value.
+//
+// When decoding, the digits must be canonical: non-empty, unsigned and
+// without leading zeros. A non-negative bencode integer is also accepted, as
+// some peers send small values that way.
+type Uint64String uint64
+
+// String returns the decimal representation of u.
+func (u Uint64String) String() string {
+	return strconv.FormatUint(uint64(u), 10)
+}
+
+// parseUint64String converts decoded data to a Uint64String.
+func parseUint64String(srcData any) (Uint64String, error) {
+	switch src := srcData.(type) {
+	case int64:
+		if src < 0 {
+			return 0, &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("cannot assign negative value %d to %s", src, uint64StringType)}
+		}
+		return Uint64String(src), nil
+	case []byte:
+		if len(src) == 0 || (len(src) > 1 && src[0] == '0') || src[0] < '0' || src[0] > '9' {
+			return 0, &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("invalid decimal string %q for %s", src, uint64StringType)}
+		}
+		u, err := strconv.ParseUint(string(src), 10, 64)
+		if err != nil {
+			if errors.Is(err, strconv.ErrRange) {
+				return 0, &Error{Type: ErrUnmarshalOverflow, Msg: fmt.Sprintf("value %s overflows %s", src, uint64StringType), WrappedErr: err}
+			}
+			return 0, &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("invalid decimal string %q for %s", src, uint64StringType), WrappedErr: err}
+		}
+		return Uint64String(u), nil
+	default:
+		return 0, &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("expected string or integer for %s, got %T", uint64StringType, srcData)}
+	}
+}
@@ -0,0 +1,75 @@
+package bencode
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestUint64StringRoundTrip(t *testing.T) {
+	type counters struct {
+		Downloaded Uint64String `bencode:"downloaded"`
+	}
+	tests := []struct {
+		value    Uint64String
+		expected string
+	}{
+		{value: 0, expected: "d10:downloaded1:0e"},
+		{value: 42, expected: "d10:downloaded2:42e"},
+		{value: math.MaxUint64, expected: "d10:downloaded20:18446744073709551615e"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value.String(), func(t *testing.T) {
+			encoded, err := Marshal(counters{Downloaded: tt.value})
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(encoded) != tt.expected {
+				t.Errorf("Marshal() = %q, want %q", encoded, tt.expected)
+			}
+			var got counters
+			if err := Unmarshal(encoded, &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if got.Downloaded != tt.value {
+				t.Errorf("Unmarshal() = %d, want %d", got.Downloaded, tt.value)
+			}
+		})
+	}
+}
+
+func TestUint64StringDecode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Uint64String
+		errType  ErrorType
+	}{
+		{input: "i7e", expected: 7},
+		{input: "2:07", errType: ErrUnmarshalType},
+		{input: "0:", errType: ErrUnmarshalType},
+		{input: "2:+7", errType: ErrUnmarshalType},
+		{input: "2:-7", errType: ErrUnmarshalType},
+		{input: "i-7e", errType: ErrUnmarshalType},
+		{input: "20:18446744073709551616", errType: ErrUnmarshalOverflow},
+		{input: "le", errType: ErrUnmarshalType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var got Uint64String
+			err := Unmarshal([]byte(tt.input), &got)
+			if tt.errType != "" {
+				var bErr *Error
+				if !errors.As(err, &bErr) || bErr.Type != tt.errType {
+					t.Errorf("Unmarshal() error = %v, want %q", err, tt.errType)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Unmarshal() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
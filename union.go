@@ -0,0 +1,139 @@
+package bencode
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var kindType = reflect.TypeFor[Kind]()
+
+// parseUnionKinds parses the union=kind|kind tag option, which maps a key
+// whose bencode type varies onto a union struct (see the README). Unknown names
+// are kept as KindInvalid so the error can be reported when the field is used.
+func parseUnionKinds(opts string) []Kind {
+	value, ok := tagOptionValue(opts, "union")
+	if !ok {
+		return nil
+	}
+	var kinds []Kind
+	for name := range strings.SplitSeq(value, "|") {
+		kinds = append(kinds, kindFromName(name))
+	}
+	return kinds
+}
+
+// kindFromName returns the kind named in a union tag.
+func kindFromName(name string) Kind {
+	switch name {
+	case "string":
+		return KindString
+	case "integer":
+		return KindInteger
+	case "list":
+		return KindList
+	case "dict", "dictionary":
+		return KindDict
+	default:
+		return KindInvalid
+	}
+}
+
+// kindOfDecoded returns the kind of a value produced by d.decode().
+func kindOfDecoded(v any) Kind {
+	switch v.(type) {
+	case []byte:
+		return KindString
+	case int64, Number, verbatimInt:
+		return KindInteger
+	case []any:
+		return KindList
+	case map[string]any:
+		return KindDict
+	default:
+		return KindInvalid
+	}
+}
+
+// unionAlternative finds the field of the union struct type typ holding kind.
+func unionAlternative(typ reflect.Type, kind Kind) (cachedStructFieldInfo, bool) {
+	for _, fieldInfo := range getCachedStructInfo(typ) {
+		if fieldInfo.typ != kindType && kindFromName(fieldInfo.bencodeTag) == kind {
+			return fieldInfo, true
+		}
+	}
+	return cachedStructFieldInfo{}, false
+}
+
+// checkUnionField validates the declaration of a union field.
+func checkUnionField(fieldInfo cachedStructFieldInfo) error {
+	if fieldInfo.typ.Kind() != reflect.Struct {
+		return &Error{Type: ErrUsage, Msg: fmt.Sprintf("union field %s must be a struct, got %s", fieldInfo.fieldName, fieldInfo.typ), FieldName: fieldInfo.bencodeTag}
+	}
+	for _, kind := range fieldInfo.union {
+		if kind == KindInvalid {
+			return &Error{Type: ErrUsage, Msg: fmt.Sprintf("union field %s has an unknown kind in its tag", fieldInfo.fieldName), FieldName: fieldInfo.bencodeTag}
+		}
+		if _, ok := unionAlternative(fieldInfo.typ, kind); !ok {
+			return &Error{Type: ErrUsage, Msg: fmt.Sprintf("union type %s has no field for kind %s", fieldInfo.typ, kind), FieldName: fieldInfo.bencodeTag}
+		}
+	}
+	return nil
+}
+
+// assignUnion populates the alternative of the union struct unionVal named
+// after the kind of srcData, and sets any field of type Kind to that kind.
+func (d *Decoder) assignUnion(unionVal reflect.Value, srcData any, fieldInfo cachedStructFieldInfo) error {
+	if err := checkUnionField(fieldInfo); err != nil {
+		return err
+	}
+	kind := kindOfDecoded(srcData)
+	allowed := false
+	for _, k := range fieldInfo.union {
+		allowed = allowed || k == kind
+	}
+	if !allowed {
+		return &Error{Type: ErrUnmarshalType, Msg: fmt.Sprintf("bencode %s is not one of the kinds accepted by union %s", kind, unionVal.Type())}
+	}
+
+	alt, _ := unionAlternative(unionVal.Type(), kind)
+	unionVal.Set(reflect.Zero(unionVal.Type()))
+	if err := d.assignDecodedToValue(unionVal.Field(alt.index), srcData); err != nil {
+		return err
+	}
+	for _, f := range getCachedStructInfo(unionVal.Type()) {
+		if f.typ == kindType {
+			unionVal.Field(f.index).Set(reflect.ValueOf(kind))
+		}
+	}
+	return nil
+}
+
+// unionValue returns the alternative of the union struct unionVal to encode:
+// the one selected by its Kind field, else the first non-zero alternative.
+func unionValue(unionVal reflect.Value, fieldInfo cachedStructFieldInfo) (reflect.Value, error) {
+	if err := checkUnionField(fieldInfo); err != nil {
+		return reflect.Value{}, err
+	}
+	fields := getCachedStructInfo(unionVal.Type())
+	for _, f := range fields {
+		if f.typ != kindType {
+			continue
+		}
+		if kind := unionVal.Field(f.index).Interface().(Kind); kind != KindInvalid {
+			alt, ok := unionAlternative(unionVal.Type(), kind)
+			if !ok {
+				return reflect.Value{}, &Error{Type: ErrEncodeUnsupportedType, Msg: fmt.Sprintf("union type %s has no field for kind %s", unionVal.Type(), kind)}
+			}
+			return unionVal.Field(alt.index), nil
+		}
+	}
+	for _, kind := range fieldInfo.union {
+		alt, _ := unionAlternative(unionVal.Type(), kind)
+		if v := unionVal.Field(alt.index); !v.IsZero() {
+			return v, nil
+		}
+	}
+	alt, _ := unionAlternative(unionVal.Type(), fieldInfo.union[0])
+	return unionVal.Field(alt.index), nil
+}
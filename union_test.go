@@ -0,0 +1,117 @@
+package bencode
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type unionPeer struct {
+	IP   string `bencode:"ip"`
+	Port int    `bencode:"port"`
+}
+
+type unionPeers struct {
+	Kind    Kind
+	List    []unionPeer `bencode:"list"`
+	Compact string      `bencode:"string"`
+}
+
+type unionResponse struct {
+	Interval int        `bencode:"interval"`
+	Peers    unionPeers `bencode:"peers,union=list|string"`
+}
+
+func TestDecodeUnion(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected unionPeers
+	}{
+		{
+			name:  "list",
+			input: "d8:intervali10e5:peersld2:ip9:127.0.0.14:porti6881eeee",
+			expected: unionPeers{
+				Kind: KindList,
+				List: []unionPeer{{IP: "127.0.0.1", Port: 6881}},
+			},
+		},
+		{
+			name:     "compact string",
+			input:    "d8:intervali10e5:peers6:abcdefe",
+			expected: unionPeers{Kind: KindString, Compact: "abcdef"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got unionResponse
+			if err := Unmarshal([]byte(tt.input), &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.Peers, tt.expected) {
+				t.Errorf("Unmarshal() peers = %+v, want %+v", got.Peers, tt.expected)
+			}
+
+			encoded, err := Marshal(got)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(encoded) != tt.input {
+				t.Errorf("Marshal() = %q, want %q", encoded, tt.input)
+			}
+		})
+	}
+}
+
+func TestDecodeUnionErrors(t *testing.T) {
+	var got unionResponse
+	err := Unmarshal([]byte("d5:peersi1ee"), &got)
+	var bErr *Error
+	if !errors.As(err, &bErr) || bErr.Type != ErrUnmarshalType || bErr.FieldName != "peers" {
+		t.Errorf("Unmarshal() with disallowed kind error = %v, want %q", err, ErrUnmarshalType)
+	}
+
+	var badTag struct {
+		Peers unionPeers `bencode:"peers,union=list|float"`
+	}
+	err = Unmarshal([]byte("d5:peers0:e"), &badTag)
+	if !errors.As(err, &bErr) || bErr.Type != ErrUsage {
+		t.Errorf("Unmarshal() with unknown union kind error = %v, want %q", err, ErrUsage)
+	}
+
+	var missingAlt struct {
+		Peers unionPeers `bencode:"peers,union=list|dict"`
+	}
+	err = Unmarshal([]byte("d5:peersdee"), &missingAlt)
+	if !errors.As(err, &bErr) || bErr.Type != ErrUsage {
+		t.Errorf("Unmarshal() with missing alternative error = %v, want %q", err, ErrUsage)
+	}
+}
+
+func TestEncodeUnionWithoutKind(t *testing.T) {
+	type Value struct {
+		Number int    `bencode:"integer"`
+		Text   string `bencode:"string"`
+	}
+	type Message struct {
+		V Value `bencode:"v,union=integer|string"`
+	}
+
+	tests := []struct {
+		value    Message
+		expected string
+	}{
+		{value: Message{V: Value{Text: "hi"}}, expected: "d1:v2:hie"},
+		{value: Message{V: Value{Number: 3}}, expected: "d1:vi3ee"},
+		{value: Message{}, expected: "d1:vi0ee"},
+	}
+	for _, tt := range tests {
+		got, err := Marshal(tt.value)
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		if string(got) != tt.expected {
+			t.Errorf("Marshal() = %q, want %q", got, tt.expected)
+		}
+	}
+}
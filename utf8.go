@@ -0,0 +1,49 @@
+package bencode
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrUnmarshalInvalidUTF8 indicates a string decoded into a Go string is not valid UTF-8.
+const ErrUnmarshalInvalidUTF8 ErrorType = "unmarshal invalid UTF-8"
+
+// WarnInvalidUTF8 indicates invalid UTF-8 in a decoded string was replaced.
+const WarnInvalidUTF8 WarningType = "invalid UTF-8 replaced"
+
+// UTF8Mode selects how a Decoder treats bencode strings that are not valid
+// UTF-8 when decoding them into Go strings.
+type UTF8Mode int
+
+const (
+	// UTF8Accept stores the bytes unchanged. It is the default.
+	UTF8Accept UTF8Mode = iota
+	// UTF8Reject fails with an ErrUnmarshalInvalidUTF8 error.
+	UTF8Reject
+	// UTF8Replace replaces each run of invalid bytes with U+FFFD and raises
+	// a WarnInvalidUTF8 warning.
+	UTF8Replace
+)
+
+// ValidateUTF8 sets how strings decoded into string-kinded destinations,
+// such as a torrent's name or comment, are checked for valid UTF-8, which
+// protects downstream systems that assume it. []byte destinations, map keys
+// and the generic values of DecodeValue are never checked, as bencode strings
+// are arbitrary bytes.
+func (d *Decoder) ValidateUTF8(mode UTF8Mode) {
+	d.utf8Mode = mode
+}
+
+// decodeString converts b for a string destination according to the
+// Decoder's UTF8Mode.
+func (d *Decoder) decodeString(b []byte) (string, error) {
+	if d.utf8Mode == UTF8Accept || utf8.Valid(b) {
+		return string(b), nil
+	}
+	if d.utf8Mode == UTF8Reject {
+		return "", &Error{Type: ErrUnmarshalInvalidUTF8, Msg: fmt.Sprintf("string %q is not valid UTF-8", b)}
+	}
+	d.warn(Warning{Type: WarnInvalidUTF8, Msg: fmt.Sprintf("string %q is not valid UTF-8", b)})
+	return strings.ToValidUTF8(string(b), string(utf8.RuneError)), nil
+}
@@ -0,0 +1,42 @@
+package bencode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateUTF8(t *testing.T) {
+	type torrent struct {
+		Name   string `bencode:"name"`
+		Pieces []byte `bencode:"pieces"`
+	}
+	const input = "d4:name5:a\xffb\xfe!6:pieces2:\xff\xfee"
+
+	var got torrent
+	if err := Unmarshal([]byte(input), &got); err != nil || got.Name != "a\xffb\xfe!" {
+		t.Errorf("Unmarshal() = %q, %v, want bytes unchanged by default", got.Name, err)
+	}
+
+	dec := NewDecoder(strings.NewReader(input))
+	dec.ValidateUTF8(UTF8Reject)
+	err := dec.Decode(&got)
+	var bErr *Error
+	if !errors.As(err, &bErr) || bErr.Type != ErrUnmarshalInvalidUTF8 || bErr.FieldName != "name" {
+		t.Errorf("Decode() with UTF8Reject error = %v, want %q for field %q", err, ErrUnmarshalInvalidUTF8, "name")
+	}
+
+	var warnings []Warning
+	dec = NewDecoder(strings.NewReader(input))
+	dec.ValidateUTF8(UTF8Replace)
+	dec.OnWarning(func(w Warning) { warnings = append(warnings, w) })
+	if err := dec.Decode(&got); err != nil {
+		t.Fatalf("Decode() with UTF8Replace error = %v", err)
+	}
+	if got.Name != "a�b�!" || string(got.Pieces) != "\xff\xfe" {
+		t.Errorf("Decode() with UTF8Replace = %+q", got)
+	}
+	if len(warnings) != 1 || warnings[0].Type != WarnInvalidUTF8 {
+		t.Errorf("warnings = %v, want one %q", warnings, WarnInvalidUTF8)
+	}
+}
@@ -0,0 +1,191 @@
+package bencode
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// Value is a mutable bencode value, for tools such as torrent editors that
+// read a document, change parts of it and write it back without a Go type
+// describing its shape. Lists and dictionaries hold their elements as
+// *Value, so a nested value obtained with Index or Key can be changed in
+// place. The zero Value is invalid; use ParseValue or ValueOf.
+type Value struct {
+	kind Kind
+	str  []byte
+	num  int64
+	raw  string // original digits of a non-minimal integer, from ParseValueVerbatim
+	list []*Value
+	dict map[string]*Value
+}
+
+// ParseValue decodes data, which must hold exactly one bencode value, into a
+// Value. Data following the value is an ErrTrailingData error.
+func ParseValue(data []byte) (*Value, error) {
+	dec := &Decoder{r: bufio.NewReaderSize(bytes.NewReader(data), len(data))}
+	dec.presize(data)
+	generic, err := dec.DecodeValue()
+	if err != nil {
+		return nil, err
+	}
+	if err := dec.ExpectEOF(); err != nil {
+		return nil, err
+	}
+	return valueOfGeneric(generic), nil
+}
+
+// ValueOf returns x as a Value. x is encoded as by Marshal, so it may be
+// any encodable value, including another *Value, which is copied along with
+// any original digits kept by ParseValueVerbatim.
+func ValueOf(x any) (*Value, error) {
+	if v, ok := x.(*Value); ok {
+		x = v.generic()
+	}
+	data, err := Marshal(x)
+	if err != nil {
+		return nil, err
+	}
+	return ParseValueVerbatim(data)
+}
+
+// valueOfGeneric converts a tree of the generic decoded types.
+func valueOfGeneric(generic any) *Value {
+	switch g := generic.(type) {
+	case []byte:
+		return &Value{kind: KindString, str: g}
+	case int64:
+		return &Value{kind: KindInteger, num: g}
+	case verbatimInt:
+		return &Value{kind: KindInteger, num: g.n.(int64), raw: g.text}
+	case []any:
+		v := &Value{kind: KindList, list: make([]*Value, len(g))}
+		for i, elem := range g {
+			v.list[i] = valueOfGeneric(elem)
+		}
+		return v
+	case map[string]any:
+		v := &Value{kind: KindDict, dict: make(map[string]*Value, len(g))}
+		for key, elem := range g {
+			v.dict[key] = valueOfGeneric(elem)
+		}
+		return v
+	default:
+		panic(fmt.Sprintf("bencode: unexpected decoded type %T", generic))
+	}
+}
+
+// generic converts v back into the generic decoded types.
+func (v *Value) generic() any {
+	switch v.kind {
+	case KindString:
+		return v.str
+	case KindInteger:
+		if v.raw != "" {
+			return verbatimInt{n: v.num, text: v.raw}
+		}
+		return v.num
+	case KindList:
+		list := make([]any, len(v.list))
+		for i, elem := range v.list {
+			list[i] = elem.generic()
+		}
+		return list
+	case KindDict:
+		dict := make(map[string]any, len(v.dict))
+		for key, elem := range v.dict {
+			dict[key] = elem.generic()
+		}
+		return dict
+	default:
+		return nil
+	}
+}
+
+// Kind returns the kind of v.
+func (v *Value) Kind() Kind {
+	return v.kind
+}
+
+// Bytes returns the contents of a string, or nil for other kinds.
+func (v *Value) Bytes() []byte {
+	return v.str
+}
+
+// Int returns the value of an integer, or 0 for other kinds.
+func (v *Value) Int() int64 {
+	return v.num
+}
+
+// Len returns the number of elements of a list or entries of a dictionary.
+func (v *Value) Len() int {
+	if v.kind == KindDict {
+		return len(v.dict)
+	}
+	return len(v.list)
+}
+
+// Index returns element i of a list, or nil if v is not a list or i is out
+// of range.
+func (v *Value) Index(i int) *Value {
+	if i < 0 || i >= len(v.list) {
+		return nil
+	}
+	return v.list[i]
+}
+
+// Key returns the value of key in a dictionary and whether it exists.
+func (v *Value) Key(key string) (*Value, bool) {
+	elem, ok := v.dict[key]
+	return elem, ok
+}
+
+// Keys returns the keys of a dictionary in sorted order.
+func (v *Value) Keys() []string {
+	return slices.Sorted(maps.Keys(v.dict))
+}
+
+// SetKey sets key of a dictionary to x, converted as by ValueOf.
+func (v *Value) SetKey(key string, x any) error {
+	if v.kind != KindDict {
+		return &Error{Type: ErrUsage, Msg: fmt.Sprintf("cannot set key %q of %s", key, v.kind), FieldName: key}
+	}
+	elem, err := ValueOf(x)
+	if err != nil {
+		return err
+	}
+	v.dict[key] = elem
+	return nil
+}
+
+// Append adds x, converted as by ValueOf, to the end of a list.
+func (v *Value) Append(x any) error {
+	if v.kind != KindList {
+		return &Error{Type: ErrUsage, Msg: fmt.Sprintf("cannot append to %s", v.kind)}
+	}
+	elem, err := ValueOf(x)
+	if err != nil {
+		return err
+	}
+	v.list = append(v.list, elem)
+	return nil
+}
+
+// Delete removes key from a dictionary, reporting whether it was present.
+func (v *Value) Delete(key string) bool {
+	if _, ok := v.dict[key]; !ok {
+		return false
+	}
+	delete(v.dict, key)
+	return true
+}
+
+// Marshal returns the canonical encoding of v, with dictionary keys sorted.
+func (v *Value) Marshal() ([]byte, error) {
+	if v.kind == KindInvalid {
+		return nil, &Error{Type: ErrEncodeUnsupportedType, Msg: "cannot marshal invalid Value"}
+	}
+	return Marshal(v.generic())
+}
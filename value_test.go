@@ -0,0 +1,84 @@
+package bencode
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestValueEdit(t *testing.T) {
+	v, err := ParseValue([]byte("d8:announce12:http://a/ann4:infod6:lengthi5e4:name1:xe4:tagsl1:aee"))
+	if err != nil {
+		t.Fatalf("ParseValue() error = %v", err)
+	}
+	if got := v.Keys(); !slices.Equal(got, []string{"announce", "info", "tags"}) {
+		t.Errorf("Keys() = %q", got)
+	}
+
+	info, ok := v.Key("info")
+	if !ok || info.Kind() != KindDict {
+		t.Fatalf("Key(info) = %v, %v", info, ok)
+	}
+	if length, _ := info.Key("length"); length.Int() != 5 {
+		t.Errorf("info length = %d, want 5", length.Int())
+	}
+	if err := info.SetKey("private", 1); err != nil {
+		t.Fatalf("SetKey() error = %v", err)
+	}
+	tags, _ := v.Key("tags")
+	if err := tags.Append("b"); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if err := v.SetKey("announce-list", []any{[]string{"http://b/ann"}}); err != nil {
+		t.Fatalf("SetKey() error = %v", err)
+	}
+	if !v.Delete("announce") || v.Delete("announce") {
+		t.Errorf("Delete() did not report presence correctly")
+	}
+
+	got, err := v.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	const expected = "d13:announce-listll12:http://b/annee4:infod6:lengthi5e4:name1:x7:privatei1ee4:tagsl1:a1:bee"
+	if string(got) != expected {
+		t.Errorf("Marshal() = %q, want %q", got, expected)
+	}
+	if tags.Len() != 2 || string(tags.Index(1).Bytes()) != "b" || tags.Index(2) != nil {
+		t.Errorf("tags = %d elements, Index(1) = %q", tags.Len(), tags.Index(1).Bytes())
+	}
+}
+
+func TestValueErrors(t *testing.T) {
+	v, err := ValueOf(int64(3))
+	if err != nil {
+		t.Fatalf("ValueOf() error = %v", err)
+	}
+	var bErr *Error
+	if err := v.SetKey("a", 1); !errors.As(err, &bErr) || bErr.Type != ErrUsage {
+		t.Errorf("SetKey() on integer error = %v, want %q", err, ErrUsage)
+	}
+	if err := v.Append(1); !errors.As(err, &bErr) || bErr.Type != ErrUsage {
+		t.Errorf("Append() on integer error = %v, want %q", err, ErrUsage)
+	}
+	list, _ := ValueOf([]int{1})
+	if err := list.Append(map[int]int{1: 1}); !errors.As(err, &bErr) || bErr.Type != ErrEncodeMapKeyNotString {
+		t.Errorf("Append() of unencodable value error = %v, want %q", err, ErrEncodeMapKeyNotString)
+	}
+	if _, err := new(Value).Marshal(); !errors.As(err, &bErr) || bErr.Type != ErrEncodeUnsupportedType {
+		t.Errorf("Marshal() of zero Value error = %v, want %q", err, ErrEncodeUnsupportedType)
+	}
+	if _, err := ParseValue([]byte("i1ei2e")); !errors.Is(err, ErrTrailingData) {
+		t.Errorf("ParseValue() with trailing data error = %v, want %q", err, ErrTrailingData)
+	}
+
+	// Values are copied when set, so later edits do not leak between trees.
+	dict, _ := ValueOf(map[string]int{})
+	if err := dict.SetKey("l", list); err != nil {
+		t.Fatalf("SetKey() error = %v", err)
+	}
+	_ = list.Append(2)
+	if got, _ := dict.Marshal(); string(got) != "d1:lli1eee" {
+		t.Errorf("Marshal() = %q, want %q", got, "d1:lli1eee")
+	}
+}
@@ -0,0 +1,104 @@
+package bencode
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// verbatimInt is a decoded integer whose encoding was not minimal, as
+// accepted by AllowNonMinimalIntegers. It keeps the original digits so that
+// RawMessage destinations and ParseValueVerbatim reproduce the input.
+type verbatimInt struct {
+	n    any // int64, or Number when the Decoder uses Number
+	text string
+}
+
+// AllowNonMinimalIntegers makes the Decoder accept integers with leading
+// zeros or a negative zero, such as i042e and i-0e, which BEP 3 forbids but
+// some clients write. They decode to their value, each raising a
+// WarnNonMinimalInteger warning. A RawMessage destination holding such an
+// integer keeps its original digits rather than writing it minimally, so a
+// proxy can inspect a message and still forward its parts unchanged, also
+// when UseNumber is set; DecodeValue and destinations of type any see plain
+// int64 values, or minimal Numbers. String lengths must still be minimal.
+func (d *Decoder) AllowNonMinimalIntegers() {
+	d.nonMinimalInts = true
+}
+
+// minimalInteger returns the canonical form of the decimal integer s,
+// without leading zeros or the sign of a negative zero. Other malformations
+// are left for parsing to report.
+func minimalInteger(s string) string {
+	if s == "" || s == "-" {
+		return s
+	}
+	neg := s[0] == '-'
+	digits := strings.TrimLeft(strings.TrimPrefix(s, "-"), "0")
+	switch {
+	case digits == "":
+		return "0"
+	case neg:
+		return "-" + digits
+	default:
+		return digits
+	}
+}
+
+// plainGeneric replaces the verbatimInts in a tree of generic decoded
+// values with their int64 values.
+func plainGeneric(generic any) any {
+	switch g := generic.(type) {
+	case verbatimInt:
+		return g.n
+	case []any:
+		for i, elem := range g {
+			g[i] = plainGeneric(elem)
+		}
+	case map[string]any:
+		for key, elem := range g {
+			g[key] = plainGeneric(elem)
+		}
+	}
+	return generic
+}
+
+// ParseValueVerbatim is like ParseValue, but accepts integers that are not
+// minimally encoded, as AllowNonMinimalIntegers does, and keeps their
+// original digits: unless Normalize is called, Marshal writes them as they
+// were read, so an unmodified Value marshals to data.
+func ParseValueVerbatim(data []byte) (*Value, error) {
+	dec := NewDecoder(bytes.NewReader(data))
+	dec.AllowNonMinimalIntegers()
+	generic, err := dec.decodeRoot(nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := dec.ExpectEOF(); err != nil {
+		return nil, err
+	}
+	return valueOfGeneric(generic), nil
+}
+
+// Normalize discards the original digits kept by ParseValueVerbatim for v
+// and every value it contains, so that Marshal writes canonical bencode.
+func (v *Value) Normalize() {
+	v.raw = ""
+	for _, elem := range v.list {
+		elem.Normalize()
+	}
+	for _, elem := range v.dict {
+		elem.Normalize()
+	}
+}
+
+// encodeVerbatimInt writes the original digits of v.
+func (e *Encoder) encodeVerbatimInt(v verbatimInt) error {
+	if e.requireCanonical {
+		return &Error{Type: ErrEncodeNonCanonical, Msg: fmt.Sprintf("integer %q is not minimal", v.text)}
+	}
+	if _, err := fmt.Fprintf(e.w, "i%se", v.text); err != nil {
+		return &Error{Type: ErrEncodeWriteError, Msg: "failed to write integer", WrappedErr: err}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package bencode
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAllowNonMinimalIntegers(t *testing.T) {
+	input := "d4:argsd2:idi007ee1:ni-0e4:porti0068ee"
+	if err := Unmarshal([]byte(input), new(any)); !errors.Is(err, ErrSyntaxInteger) {
+		t.Fatalf("Unmarshal() without option error = %v, want %q", err, ErrSyntaxInteger)
+	}
+
+	var msg struct {
+		Args RawMessage `bencode:"args"`
+		N    int        `bencode:"n"`
+		Port uint16     `bencode:"port"`
+	}
+	dec := NewDecoder(bytes.NewReader([]byte(input)))
+	dec.AllowNonMinimalIntegers()
+	if err := dec.Decode(&msg); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if string(msg.Args) != "d2:idi007ee" || msg.N != 0 || msg.Port != 68 {
+		t.Errorf("Decode() = %q, %d, %d", msg.Args, msg.N, msg.Port)
+	}
+
+	dec = NewDecoder(bytes.NewReader([]byte(input)))
+	dec.AllowNonMinimalIntegers()
+	v, err := dec.DecodeValue()
+	if err != nil {
+		t.Fatalf("DecodeValue() error = %v", err)
+	}
+	if got, _ := Marshal(v); string(got) != "d4:argsd2:idi7ee1:ni0e4:porti68ee" {
+		t.Errorf("DecodeValue() re-encoded as %q", got)
+	}
+
+	var warnings []Warning
+	dec = NewDecoder(strings.NewReader("d1:ai007e1:bi7ee"))
+	dec.AllowNonMinimalIntegers()
+	dec.OnWarning(func(w Warning) { warnings = append(warnings, w) })
+	if err := dec.Decode(new(any)); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if len(warnings) != 1 || warnings[0].Type != WarnNonMinimalInteger {
+		t.Errorf("Decode() warnings = %v, want one %q", warnings, WarnNonMinimalInteger)
+	}
+
+	// With UseNumber, integers decode to their minimal Number but a
+	// RawMessage still keeps the original digits.
+	var numMsg struct {
+		Args RawMessage `bencode:"args"`
+		N    any        `bencode:"n"`
+		Port any        `bencode:"port"`
+	}
+	dec = NewDecoder(bytes.NewReader([]byte(input)))
+	dec.AllowNonMinimalIntegers()
+	dec.UseNumber()
+	if err := dec.Decode(&numMsg); err != nil {
+		t.Fatalf("Decode() with UseNumber error = %v", err)
+	}
+	if string(numMsg.Args) != "d2:idi007ee" || numMsg.N != Number("0") || numMsg.Port != Number("68") {
+		t.Errorf("Decode() with UseNumber = %q, %#v, %#v", numMsg.Args, numMsg.N, numMsg.Port)
+	}
+
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	e.RequireCanonical()
+	if err := e.Encode(msg.Args); !errors.Is(err, ErrEncodeNonCanonical) {
+		t.Errorf("Encode() of non-minimal raw with RequireCanonical error = %v, want %q", err, ErrEncodeNonCanonical)
+	}
+}
+
+func TestParseValueVerbatim(t *testing.T) {
+	input := "d1:ai042e1:bli-0ei5eee"
+	v, err := ParseValueVerbatim([]byte(input))
+	if err != nil {
+		t.Fatalf("ParseValueVerbatim() error = %v", err)
+	}
+	if a, _ := v.Key("a"); a.Int() != 42 {
+		t.Errorf("a = %d, want 42", a.Int())
+	}
+	if got, err := v.Marshal(); err != nil || string(got) != input {
+		t.Errorf("Marshal() = %q, %v, want %q", got, err, input)
+	}
+	c, err := ValueOf(v)
+	if err != nil {
+		t.Fatalf("ValueOf() error = %v", err)
+	}
+	v.Normalize()
+	if got, _ := v.Marshal(); string(got) != "d1:ai42e1:bli0ei5eee" {
+		t.Errorf("Marshal() after Normalize() = %q", got)
+	}
+	if got, _ := c.Marshal(); string(got) != input {
+		t.Errorf("copy Marshal() = %q, want %q", got, input)
+	}
+	if _, err := ParseValue([]byte(input)); !errors.Is(err, ErrSyntaxInteger) {
+		t.Errorf("ParseValue() error = %v, want %q", err, ErrSyntaxInteger)
+	}
+}
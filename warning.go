@@ -0,0 +1,50 @@
+package bencode
+
+import "fmt"
+
+// WarningType categorizes a Warning.
+type WarningType string
+
+const (
+	// WarnUnknownField indicates a dictionary key with no matching struct field was skipped.
+	WarnUnknownField WarningType = "unknown field skipped"
+	// WarnNonMinimalInteger indicates an integer with leading zeros or a
+	// negative zero was accepted, as AllowNonMinimalIntegers permits.
+	WarnNonMinimalInteger WarningType = "non-minimal integer accepted"
+	// WarnUnsortedKey indicates a dictionary key out of sorted order was
+	// accepted, as AllowUnsortedKeys permits.
+	WarnUnsortedKey WarningType = "unsorted key accepted"
+)
+
+// Warning describes a recoverable irregularity noticed while decoding. Unlike
+// an Error, a Warning does not stop decoding.
+type Warning struct {
+	// Type categorizes the warning.
+	Type WarningType
+	// Msg provides a human-readable description of the warning.
+	Msg string
+	// FieldName is the dictionary key the warning relates to, if any.
+	FieldName string
+}
+
+// String returns a string representation of the warning.
+func (w Warning) String() string {
+	if w.FieldName != "" {
+		return fmt.Sprintf("bencode: warning: field %q: %s", w.FieldName, w.Msg)
+	}
+	return "bencode: warning: " + w.Msg
+}
+
+// OnWarning registers fn to be called, synchronously during decoding, for
+// each Warning raised while decoding, so that irregular input can be logged
+// or counted without failing the decode. Passing nil removes the callback.
+func (d *Decoder) OnWarning(fn func(Warning)) {
+	d.onWarning = fn
+}
+
+// warn reports w to the registered callback, if any.
+func (d *Decoder) warn(w Warning) {
+	if d.onWarning != nil {
+		d.onWarning(w)
+	}
+}
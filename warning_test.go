@@ -0,0 +1,43 @@
+package bencode
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecoderOnWarning(t *testing.T) {
+	type Inner struct {
+		Name string `bencode:"name"`
+	}
+	type Outer struct {
+		Inner Inner `bencode:"inner"`
+		Size  int   `bencode:"size"`
+	}
+
+	var warnings []Warning
+	decoder := NewDecoder(strings.NewReader("d5:extra0:5:innerd4:name1:x4:zzzzi1ee4:sizei3ee"))
+	decoder.OnWarning(func(w Warning) { warnings = append(warnings, w) })
+
+	var got Outer
+	if err := decoder.Decode(&got); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got.Inner.Name != "x" || got.Size != 3 {
+		t.Errorf("Decode() = %+v", got)
+	}
+
+	var fields []string
+	for _, w := range warnings {
+		if w.Type != WarnUnknownField {
+			t.Errorf("unexpected warning type %q", w.Type)
+		}
+		fields = append(fields, w.FieldName)
+	}
+	if expected := []string{"zzzz", "extra"}; !reflect.DeepEqual(fields, expected) {
+		t.Errorf("warnings for fields %v, want %v", fields, expected)
+	}
+	if s := warnings[0].String(); !strings.Contains(s, `field "zzzz"`) {
+		t.Errorf("Warning.String() = %q", s)
+	}
+}